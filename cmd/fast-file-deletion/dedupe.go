@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/yourusername/fast-file-deletion/internal/dedupe"
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+	"github.com/yourusername/fast-file-deletion/internal/progress"
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// displayDuplicates reports the groups of identical files found by --dedupe
+// and the bytes removing their copies reclaims, listing the largest groups
+// with the copy each keeps. Every group is written to the log.
+func displayDuplicates(config *Config, scanResult *scanner.ScanResult) {
+	var copies int
+	var reclaimable int64
+	for _, g := range scanResult.Duplicates {
+		copies += len(g.Copies)
+		reclaimable += g.Reclaimable()
+	}
+
+	action := "deleted"
+	if config.DedupeLink {
+		action = "replaced with hard links"
+	}
+	if len(scanResult.Duplicates) == 0 {
+		fmt.Println("\n🧹 No duplicate files found")
+		return
+	}
+	fmt.Printf("\n🧹 Duplicate files: %s groups, %s copies to be %s, %s reclaimable\n",
+		progress.FormatNumber(len(scanResult.Duplicates)), progress.FormatNumber(copies), action, progress.FormatBytes(reclaimable))
+
+	for i, g := range scanResult.Duplicates {
+		logger.Info("Duplicates of %s (%d bytes, sha256 %s): %d copies", g.Survivor.Path, g.Size, g.Hash, len(g.Copies))
+		for _, c := range g.Copies {
+			logger.Debug("Duplicate copy: %s", c.Path)
+		}
+		if i >= maxListedControls {
+			continue
+		}
+		fmt.Printf("   %s  %s, keeping %s\n", progress.FormatBytes(g.Reclaimable()), pluralCopies(len(g.Copies)), relativeTo(config.TargetDir, g.Survivor.Path))
+		for j, c := range g.Copies {
+			if j == 3 {
+				fmt.Printf("      ... and %d more\n", len(g.Copies)-j)
+				break
+			}
+			fmt.Printf("      %s\n", relativeTo(config.TargetDir, c.Path))
+		}
+	}
+	if extra := len(scanResult.Duplicates) - maxListedControls; extra > 0 {
+		fmt.Printf("   ... and %d more groups (see log)\n", extra)
+	}
+}
+
+// pluralCopies returns "1 copy" or "n copies".
+func pluralCopies(n int) string {
+	if n == 1 {
+		return "1 copy"
+	}
+	return fmt.Sprintf("%d copies", n)
+}
+
+// relativeTo returns path relative to dir, or path itself if it is not below dir.
+func relativeTo(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+// runDedupeLink replaces each copy found by --dedupe with a hard link to the
+// copy its group keeps, instead of deleting it. Copies that changed since the
+// scan, or are on another file system, are left as they are. Ctrl+C stops
+// after the current copy.
+// Returns an exit code: 0 for success, 1 if some copies could not be replaced.
+func runDedupeLink(config *Config, scanResult *scanner.ScanResult) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Println()
+	if config.DryRun {
+		fmt.Println("Starting dry run (no files will be linked)...")
+	} else {
+		fmt.Println("Replacing copies with hard links...")
+	}
+
+	var linked, failed int
+	var reclaimed int64
+	for _, g := range scanResult.Duplicates {
+		for _, c := range g.Copies {
+			if ctx.Err() != nil {
+				break
+			}
+			if !config.DryRun {
+				if err := dedupe.Link(g.Survivor, c); err != nil {
+					failed++
+					logger.LogFileWarning(c.Path, fmt.Sprintf("Cannot replace with a hard link: %v", err))
+					continue
+				}
+				logger.Debug("Linked %s to %s", c.Path, g.Survivor.Path)
+			}
+			linked++
+			reclaimed += c.Size
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Println("\n❌ Linking cancelled.")
+		logger.Info("Linking cancelled by user after %d copies", linked)
+	}
+	verb := "Replaced"
+	if config.DryRun {
+		verb = "Would replace"
+	}
+	fmt.Printf("\n%s %s copies with hard links (%s of file data)\n", verb, progress.FormatNumber(linked), progress.FormatBytes(reclaimed))
+	logger.Info("Dedupe link: %d copies linked, %d failed", linked, failed)
+
+	if failed > 0 {
+		fmt.Printf("⚠️  Warning: %d copies could not be replaced and were left in place\n", failed)
+		if config.LogFile != "" {
+			fmt.Printf("   See log file for details: %s\n", config.LogFile)
+		}
+		fmt.Println()
+		return 1
+	}
+	if config.DryRun {
+		fmt.Println("✓ Dry run completed successfully.")
+	} else {
+		fmt.Println("✓ Linking completed successfully.")
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/engine"
+	"github.com/yourusername/fast-file-deletion/internal/estimate"
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+	"github.com/yourusername/fast-file-deletion/internal/progress"
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// runEstimateMode estimates the size of the target directory and how long
+// scanning and deleting it would take, without scanning or deleting it.
+// Returns an exit code: 0 on success (or when cancelled), 2 if the directory
+// cannot be estimated.
+func runEstimateMode(config *Config) int {
+	history, err := estimate.LoadHistory(estimate.DefaultHistoryPath())
+	if err != nil {
+		logger.Warning("Ignoring run history: %v", err)
+	}
+
+	fmt.Printf("\nEstimating %s...\n", config.TargetDir)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	result, err := estimate.Estimate(ctx, config.TargetDir, 0, history)
+	stop()
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("\n❌ Estimate cancelled.")
+		logger.Info("Estimate cancelled by user")
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Cannot estimate the directory: %v\n\n", err)
+		logger.Error("Estimate failed: %v", err)
+		return 2
+	}
+
+	displayEstimate(config, result)
+	return 0
+}
+
+// displayEstimate prints an estimate with its bounds and where its figures
+// come from.
+func displayEstimate(config *Config, result *estimate.Result) {
+	fmt.Println()
+	switch {
+	case result.Method == estimate.MethodFileSystem:
+		fmt.Println("Method:   file system statistics (the directory is a mount point)")
+	case result.Sample.Exact:
+		fmt.Printf("Method:   exact count (the whole tree was read in %s)\n", formatDuration(result.Sample.Elapsed))
+	default:
+		fmt.Printf("Method:   sampling (%d random descents through %s directories in %s)\n",
+			result.Sample.Probes, progress.FormatNumber(result.Sample.DirsRead), formatDuration(result.Sample.Elapsed))
+	}
+
+	entries, bytes := result.Entries, result.Bytes
+	fmt.Printf("Entries:  %s\n", formatRange(entries, func(n int64) string { return progress.FormatNumber(int(n)) }))
+	size := formatRange(bytes, progress.FormatBytes)
+	if result.Method == estimate.MethodFileSystem {
+		size += " on disk"
+	}
+	fmt.Printf("Size:     %s\n", size)
+	fmt.Printf("Scan:     %s\n", formatDurationRange(result.Scan, result.ScanRate))
+	fmt.Printf("Delete:   %s\n", formatDurationRange(result.Delete, result.DeleteRate))
+
+	if result.Sample != nil && result.Sample.Unreadable > 0 {
+		fmt.Printf("\n⚠️  %d directories could not be read and were counted as empty\n", result.Sample.Unreadable)
+	}
+	if config.KeepDays != nil || config.OlderThan > 0 || config.NewerThan > 0 || len(config.Include) > 0 ||
+		len(config.Exclude) > 0 || len(config.ExcludeFrom) > 0 || config.Where != "" ||
+		config.KeepNewest > 0 || config.MaxTotalSize > 0 {
+		fmt.Println("\nFilters are not applied: the estimate covers the whole directory, which a scan reads anyway.")
+	}
+	if result.Method == estimate.MethodSampling && !result.Sample.Exact {
+		fmt.Println("\nBounds are approximate 95% intervals; very uneven trees can fall outside them.")
+	}
+}
+
+// formatRange formats an estimate as "~value (low - high)", or just the value
+// when it is exact.
+func formatRange(r scanner.EstimateRange, format func(int64) string) string {
+	if r.Low == r.High {
+		return format(r.Value)
+	}
+	return fmt.Sprintf("~%s (%s - %s)", format(r.Value), format(r.Low), format(r.High))
+}
+
+// formatDurationRange formats an estimated duration with its bounds and the
+// rate it was derived from.
+func formatDurationRange(d estimate.DurationRange, rate estimate.Rate) string {
+	var source string
+	switch rate.Source {
+	case estimate.RateFromHistory:
+		source = fmt.Sprintf("median of %d previous runs", rate.Runs)
+		if rate.Runs == 1 {
+			source = "measured in the previous run"
+		}
+	case estimate.RateFromSample:
+		source = "measured while sampling"
+	default:
+		source = "default rate, no previous runs"
+	}
+	return fmt.Sprintf("~%s (%s - %s) at %s entries/s, %s",
+		formatEstimatedDuration(d.Value), formatEstimatedDuration(d.Low), formatEstimatedDuration(d.High),
+		progress.FormatNumber(int(rate.Value)), source)
+}
+
+// formatEstimatedDuration formats short durations to the millisecond and long
+// ones with hours.
+func formatEstimatedDuration(d time.Duration) string {
+	if d < time.Minute {
+		return formatDuration(d)
+	}
+	return progress.FormatDuration(d)
+}
+
+// recordRun adds the scan and deletion rates of a run to the run history used
+// by --estimate. Dry runs only record the scan. A damaged history is replaced;
+// failing to record is logged and otherwise ignored.
+func recordRun(config *Config, scanResult *scanner.ScanResult, result *engine.DeletionResult) {
+	history, err := estimate.LoadHistory(estimate.DefaultHistoryPath())
+	if err != nil {
+		logger.Warning("%v; starting a new run history", err)
+	}
+
+	run := estimate.Run{
+		Time:        time.Now().UTC(),
+		Root:        scanResult.ScannedPath,
+		Scanned:     scanResult.TotalScanned,
+		ScanSeconds: scanResult.ScanDuration.Seconds(),
+	}
+	if !config.DryRun {
+		run.Deleted = result.DeletedCount
+		run.DeleteSeconds = result.DurationSeconds
+	}
+	if err := history.Record(run); err != nil {
+		logger.Warning("Not recording this run: %v", err)
+	}
+}
@@ -6,19 +6,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/yourusername/fast-file-deletion/internal/backend"
+	"github.com/yourusername/fast-file-deletion/internal/dedupe"
+	"github.com/yourusername/fast-file-deletion/internal/diskspace"
 	"github.com/yourusername/fast-file-deletion/internal/engine"
+	"github.com/yourusername/fast-file-deletion/internal/gitclean"
 	"github.com/yourusername/fast-file-deletion/internal/logger"
 	"github.com/yourusername/fast-file-deletion/internal/monitor"
+	"github.com/yourusername/fast-file-deletion/internal/privilege"
 	"github.com/yourusername/fast-file-deletion/internal/progress"
+	"github.com/yourusername/fast-file-deletion/internal/runlock"
 	"github.com/yourusername/fast-file-deletion/internal/safety"
+	"github.com/yourusername/fast-file-deletion/internal/sandbox"
 	"github.com/yourusername/fast-file-deletion/internal/scanner"
 )
 
@@ -38,12 +49,77 @@ type Config struct {
 	KeepDays       *int
 	Workers        int
 	BufferSize     int
-	DeletionMethod string // Deletion method: auto, fileinfo, deleteonclose, ntapi, deleteapi
-	Benchmark      bool   // Enable benchmarking mode
-	Monitor        bool   // Enable real-time system resource monitoring
+	DeletionMethod string        // Deletion method: auto, fileinfo, deleteonclose, ntapi, deleteapi
+	Benchmark      bool          // Enable benchmarking mode
+	Monitor        bool          // Enable real-time system resource monitoring
+	Revalidate     bool          // Re-check identity and age of each entry right before deletion
+	StrictScan     bool          // Delete nothing if the scan had to skip anything it could not read
+	Sandbox        bool          // Confine the process to the target directory (Landlock, Linux only)
+	RunAsOwner     bool          // Switch to the target directory's owner before deleting (root only)
+	WaitForLock    time.Duration // How long to wait for an overlapping run to finish (0 = fail immediately)
+	Include        []string      // Gitignore-style patterns selecting what to delete (empty = everything)
+	Exclude        []string      // Gitignore-style patterns that are never deleted
+	ExcludeFrom    []string      // Files holding additional exclude patterns
+	Where          string        // Filter expression selecting what to delete (see scanner.ParseWhere)
+	AgeBy          string        // Timestamp used for age filters: mtime, atime, ctime, btime
+	OlderThan      time.Duration // Only delete entries older than this (0 = no limit)
+	NewerThan      time.Duration // Only delete entries newer than this (0 = no limit)
+	ReferenceTime  time.Time     // Instant ages are measured from (zero = now)
+	AgeFromName    string        // Time layout or regex reading file ages from names ("" = disabled)
+	NameFallback   string        // Files without a timestamp in their name: keep, delete, timestamp
+	KeepMarker     string        // Sentinel file name that protects a directory's subtree ("" = disabled)
+	PolicyFile     string        // Per-directory retention policy file name ("" = disabled)
+	KeepNewest     int           // Keep this many newest files per directory or group (0 = no limit)
+	KeepGroups     []string      // Name globs splitting directories into KeepNewest groups
+	MaxTotalSize   int64         // Trim the tree to this many bytes, oldest first (0 = no limit)
+	TmpfilesConfig []string      // tmpfiles.d files whose cleanup rules replace --target-directory
+	PlanOut        string        // File the scan's deletion plan is written to ("" = none)
+	PlanIn         string        // Plan file to execute instead of scanning --target-directory
+	Estimate       bool          // Estimate the size and scan/delete duration instead of scanning
+	ContentsOnly   bool          // Empty the target directory but keep it
+	FilesOnly      bool          // Delete files only, keeping every directory
+	EmptyDirs      bool          // Only delete empty directories, including ones emptied bottom-up
+	BrokenSymlinks bool          // Only delete symbolic links whose target is missing
+	Sweep          bool          // Find build artifact directories and delete the ones chosen
+	SweepPresets   []string      // Presets --sweep looks for (empty = all)
+	PresetFiles    []string      // Files defining additional --sweep presets
+	GitClean       string        // Only delete what git clean would: ignored or untracked ("" = disabled)
+	Dedupe         bool          // Only delete the copies of duplicate files, keeping one of each
+	DedupeKeep     string        // Copy of each duplicate group that is kept: oldest, shortest-path
+	DedupePrefer   []string      // Directories whose copies are kept first
+	DedupeLink     bool          // Replace the copies with hard links instead of deleting them
+	Trash          bool          // Move entries into the FreeDesktop trash instead of deleting them
+
+	// RuleFilter selects what the tmpfiles.d rule being run cleans up. It is set
+	// for each rule by runTmpfilesMode, not by a flag.
+	RuleFilter scanner.Filter
+
+	// Subtrees are the artifact directories chosen for --sweep, relative to the
+	// target directory. They are set by chooseSweepTargets, not by a flag.
+	Subtrees []string
+}
+
+// stringList is a flag.Value that collects every occurrence of a repeatable flag.
+type stringList []string
+
+// String returns the collected values separated by commas.
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set appends one occurrence of the flag.
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
 func main() {
+	// Run as the helper that removes the target for a sandboxed run (see
+	// sandbox.StartRemover). Nothing may be printed before it takes over.
+	if len(os.Args) > 1 && os.Args[1] == sandbox.RemoverCommand {
+		os.Exit(sandbox.RunRemover(os.Args[2:], os.Stdin, os.Stdout))
+	}
+
 	// Set Go memory limit to 25% of system RAM for better performance
 	// This reduces GC pressure and improves deletion throughput
 	initializeMemoryLimit()
@@ -137,11 +213,55 @@ func parseArguments() (*Config, error) {
 	verbose := flag.Bool("verbose", false, "Enable detailed logging")
 	logFile := flag.String("log-file", "", "Write logs to specified file")
 	keepDays := flag.Int("keep-days", -1, "Only delete files older than N days")
-	workers := flag.Int("workers", 0, "Number of parallel workers (default: auto-detect)")
+	workers := flag.Int("workers", 0, "Number of parallel deletion and --dedupe hashing workers; the scan walk is sequential (default: auto-detect)")
 	bufferSize := flag.Int("buffer-size", 0, "Work queue buffer size (default: auto-detect)")
 	deletionMethod := flag.String("deletion-method", "auto", "Deletion method: auto, fileinfo, deleteonclose, ntapi, deleteapi")
 	benchmark := flag.Bool("benchmark", false, "Run comparative benchmarks of all deletion methods")
 	monitor := flag.Bool("monitor", false, "Enable real-time system resource monitoring and bottleneck detection")
+	revalidate := flag.Bool("revalidate", false, "Re-check each entry right before deletion and skip entries changed since the scan")
+	strictScan := flag.Bool("strict-scan", false, "Delete nothing if the scan skipped entries or directories it could not read")
+	sandboxFlag := flag.Bool("sandbox", false, "Confine the process to the target directory before deleting (Linux Landlock)")
+	runAsOwner := flag.Bool("run-as-owner", false, "Switch to the target directory's owner before deleting (when running as root)")
+	waitForLock := flag.Duration("wait-for-lock", 0, "Wait up to this long for an overlapping run to finish (e.g. 30s, 5m)")
+	var include, exclude, excludeFrom stringList
+	flag.Var(&include, "include", "Only delete entries matching this gitignore-style pattern (repeatable)")
+	flag.Var(&exclude, "exclude", "Never delete entries matching this gitignore-style pattern (repeatable)")
+	flag.Var(&excludeFrom, "exclude-from", "Read exclude patterns from a gitignore-style file (repeatable)")
+	where := flag.String("where", "", "Only delete entries matching this filter expression")
+	ageBy := flag.String("age-by", "mtime", "Timestamp used for age filters: mtime, atime, ctime, btime")
+	olderThan := flag.String("older-than", "", "Only delete entries older than this age (e.g. 36h, 7d, 2w)")
+	newerThan := flag.String("newer-than", "", "Only delete entries newer than this age (e.g. 36h, 7d, 2w)")
+	referenceTime := flag.String("reference-time", "", "Measure ages from this instant instead of now (e.g. 2024-01-31T00:00:00Z)")
+	ageFromName := flag.String("age-from-name", "", "Take file ages from a timestamp in their name: Go time layout or regex with named groups")
+	nameFallback := flag.String("age-from-name-fallback", "keep", "Files without a timestamp in their name: keep, delete, or timestamp (use --age-by)")
+	keepMarker := flag.String("keep-marker", scanner.DefaultKeepMarker, "Never delete directories holding a file with this name, or anything beneath them")
+	noKeepMarker := flag.Bool("no-keep-marker", false, "Ignore keep-markers")
+	policyFile := flag.String("policy-file", scanner.DefaultPolicyFile, "Per-directory file overriding the age settings for its subtree")
+	noPolicyFile := flag.Bool("no-policy-file", false, "Ignore policy files")
+	keepNewest := flag.Int("keep-newest", 0, "Keep the N newest files in each directory (or group, see --keep-newest-group)")
+	var keepGroups stringList
+	flag.Var(&keepGroups, "keep-newest-group", "Rank files whose name matches this glob separately for --keep-newest (repeatable)")
+	maxTotalSize := flag.String("max-total-size", "", "Delete the oldest files until the tree fits in this size (e.g. 200G)")
+	var tmpfilesConfig stringList
+	flag.Var(&tmpfilesConfig, "tmpfiles-config", "Run the age cleanup rules of these tmpfiles.d files instead of --target-directory (glob, repeatable)")
+	planOut := flag.String("plan-out", "", "Write the exact list of entries to delete, with their identities, to this plan file")
+	planIn := flag.String("plan-in", "", "Delete exactly the entries of this plan file instead of scanning --target-directory")
+	estimateFlag := flag.Bool("estimate", false, "Estimate the directory's size and the scan and deletion time without scanning it")
+	contentsOnly := flag.Bool("contents-only", false, "Empty the target directory but keep the directory itself")
+	filesOnly := flag.Bool("files-only", false, "Delete files only, keeping the whole directory structure")
+	emptyDirs := flag.Bool("empty-dirs", false, "Only delete empty directories, including directories left empty by the cleanup")
+	brokenSymlinks := flag.Bool("broken-symlinks", false, "Only delete symbolic links whose target does not exist")
+	sweepFlag := flag.Bool("sweep", false, "Find build artifact directories (node_modules, target, ...) and delete the ones chosen")
+	var sweepPresets, sweepPresetFiles stringList
+	flag.Var(&sweepPresets, "sweep-preset", "Only look for the artifacts of this --sweep preset (repeatable)")
+	flag.Var(&sweepPresetFiles, "sweep-presets", "Read additional --sweep presets from this file (repeatable)")
+	gitClean := flag.String("git-clean", "", "Only delete what git clean would in a working tree: ignored or untracked")
+	dedupeFlag := flag.Bool("dedupe", false, "Only delete the copies of duplicate files, keeping one copy of each")
+	dedupeKeep := flag.String("dedupe-keep", "oldest", "Copy of duplicate files that --dedupe keeps: oldest or shortest-path")
+	var dedupePrefer stringList
+	flag.Var(&dedupePrefer, "dedupe-prefer", "Keep the copies of duplicate files in this directory first (repeatable)")
+	dedupeLink := flag.Bool("dedupe-link", false, "Replace the copies of duplicate files with hard links instead of deleting them")
+	trash := flag.Bool("trash", false, "Move entries into the trash instead of deleting them, so they can be restored")
 
 	// Custom usage function
 	flag.Usage = printUsage
@@ -149,8 +269,26 @@ func parseArguments() (*Config, error) {
 	// Parse flags
 	flag.Parse()
 
-	// Check if target directory was provided
-	if *targetDir == "" {
+	// A shell-expanded --tmpfiles-config glob leaves the remaining files as positional arguments
+	if len(tmpfilesConfig) > 0 {
+		if *targetDir != "" {
+			return nil, fmt.Errorf("--tmpfiles-config and --target-directory flags cannot be used together\n" +
+				"   The directories to clean come from the tmpfiles.d rules")
+		}
+		for _, arg := range flag.Args() {
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("flag %s after the tmpfiles.d files\n"+
+					"   Options must come before the files of an unquoted --tmpfiles-config glob", arg)
+			}
+		}
+		tmpfilesConfig = append(tmpfilesConfig, flag.Args()...)
+	} else if *planIn != "" {
+		if *targetDir != "" {
+			return nil, fmt.Errorf("--plan-in and --target-directory flags cannot be used together\n" +
+				"   The directory comes from the plan")
+		}
+	} else if *targetDir == "" {
+		// Check if target directory was provided
 		// Check if user provided positional arguments (old syntax)
 		if flag.NArg() > 0 {
 			return nil, fmt.Errorf("positional arguments are not supported\n"+
@@ -162,7 +300,7 @@ func parseArguments() (*Config, error) {
 	}
 
 	// Check for unexpected positional arguments
-	if flag.NArg() > 0 {
+	if len(tmpfilesConfig) == 0 && flag.NArg() > 0 {
 		return nil, fmt.Errorf("unexpected positional arguments: %v\n"+
 			"   All options must be specified as flags", flag.Args())
 	}
@@ -172,6 +310,47 @@ func parseArguments() (*Config, error) {
 		return nil, fmt.Errorf("invalid --keep-days value: must be >= 0 (got %d)", *keepDays)
 	}
 
+	// Parse ages and reference time before building config
+	var olderThanAge, newerThanAge time.Duration
+	if *olderThan != "" {
+		age, err := scanner.ParseAge(*olderThan)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --older-than value: %w", err)
+		}
+		olderThanAge = age
+	}
+	if *newerThan != "" {
+		age, err := scanner.ParseAge(*newerThan)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --newer-than value: %w", err)
+		}
+		newerThanAge = age
+	}
+	var maxTotalBytes int64
+	if *maxTotalSize != "" {
+		size, err := scanner.ParseSize(*maxTotalSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-total-size value: %w", err)
+		}
+		maxTotalBytes = size
+	}
+	var reference time.Time
+	if *referenceTime != "" {
+		t, err := scanner.ParseTime(*referenceTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --reference-time value: %w", err)
+		}
+		reference = t
+	}
+
+	// Keep-markers and policy files are on unless turned off explicitly
+	if *noKeepMarker {
+		*keepMarker = ""
+	}
+	if *noPolicyFile {
+		*policyFile = ""
+	}
+
 	// Build config for validation
 	var keepDaysPtr *int
 	if *keepDays >= 0 {
@@ -190,6 +369,43 @@ func parseArguments() (*Config, error) {
 		DeletionMethod: *deletionMethod,
 		Benchmark:      *benchmark,
 		Monitor:        *monitor,
+		Revalidate:     *revalidate,
+		StrictScan:     *strictScan,
+		Sandbox:        *sandboxFlag,
+		RunAsOwner:     *runAsOwner,
+		WaitForLock:    *waitForLock,
+		Include:        include,
+		Exclude:        exclude,
+		ExcludeFrom:    excludeFrom,
+		Where:          *where,
+		AgeBy:          *ageBy,
+		OlderThan:      olderThanAge,
+		NewerThan:      newerThanAge,
+		ReferenceTime:  reference,
+		AgeFromName:    *ageFromName,
+		NameFallback:   *nameFallback,
+		KeepMarker:     *keepMarker,
+		PolicyFile:     *policyFile,
+		KeepNewest:     *keepNewest,
+		KeepGroups:     keepGroups,
+		MaxTotalSize:   maxTotalBytes,
+		TmpfilesConfig: tmpfilesConfig,
+		PlanOut:        *planOut,
+		PlanIn:         *planIn,
+		Estimate:       *estimateFlag,
+		ContentsOnly:   *contentsOnly,
+		FilesOnly:      *filesOnly,
+		EmptyDirs:      *emptyDirs,
+		BrokenSymlinks: *brokenSymlinks,
+		Sweep:          *sweepFlag,
+		SweepPresets:   sweepPresets,
+		PresetFiles:    sweepPresetFiles,
+		GitClean:       *gitClean,
+		Dedupe:         *dedupeFlag,
+		DedupeKeep:     *dedupeKeep,
+		DedupePrefer:   dedupePrefer,
+		DedupeLink:     *dedupeLink,
+		Trash:          *trash,
 	}
 
 	// Validate configuration
@@ -248,6 +464,189 @@ func validateConfig(config *Config) error {
 		}
 	}
 
+	if config.WaitForLock < 0 {
+		return fmt.Errorf("invalid --wait-for-lock value: must be >= 0 (got %s)", config.WaitForLock)
+	}
+
+	// Validate include/exclude patterns (files named by --exclude-from are read at scan time)
+	if _, err := scanner.NewMatcher(config.Include); err != nil {
+		return fmt.Errorf("invalid --include value: %w", err)
+	}
+	if _, err := scanner.NewMatcher(config.Exclude); err != nil {
+		return fmt.Errorf("invalid --exclude value: %w", err)
+	}
+
+	// Validate age options
+	if config.AgeBy != "" {
+		if _, err := scanner.ParseTimeField(config.AgeBy); err != nil {
+			return fmt.Errorf("invalid --age-by value: %w", err)
+		}
+	}
+	if config.KeepDays != nil && config.OlderThan > 0 {
+		return fmt.Errorf("--keep-days and --older-than flags cannot be used together")
+	}
+	if config.OlderThan > 0 && config.NewerThan > 0 && config.NewerThan <= config.OlderThan {
+		return fmt.Errorf("--newer-than (%s) must be greater than --older-than (%s), otherwise nothing can match",
+			config.NewerThan, config.OlderThan)
+	}
+
+	if config.AgeFromName != "" {
+		if _, err := scanner.ParseNameTime(config.AgeFromName); err != nil {
+			return fmt.Errorf("invalid --age-from-name value: %w", err)
+		}
+		if config.KeepDays == nil && config.OlderThan == 0 && config.NewerThan == 0 &&
+			config.KeepNewest == 0 && config.MaxTotalSize == 0 {
+			return fmt.Errorf("--age-from-name requires --keep-days, --older-than, --newer-than, --keep-newest or --max-total-size")
+		}
+	}
+	if config.NameFallback != "" {
+		if _, err := scanner.ParseNameFallback(config.NameFallback); err != nil {
+			return fmt.Errorf("invalid --age-from-name-fallback value: %w", err)
+		}
+		if config.NameFallback != "keep" && config.AgeFromName == "" {
+			return fmt.Errorf("--age-from-name-fallback requires --age-from-name")
+		}
+	}
+
+	if config.Where != "" {
+		if _, err := scanner.ParseWhere(config.Where, time.Now()); err != nil {
+			return fmt.Errorf("invalid --where value: %w", err)
+		}
+	}
+
+	// Validate count and size retention limits
+	if config.KeepNewest < 0 {
+		return fmt.Errorf("invalid --keep-newest value: must be >= 0 (got %d)", config.KeepNewest)
+	}
+	if len(config.KeepGroups) > 0 && config.KeepNewest == 0 {
+		return fmt.Errorf("--keep-newest-group requires --keep-newest")
+	}
+	for _, glob := range config.KeepGroups {
+		if _, err := scanner.NameGlob(glob); err != nil {
+			return fmt.Errorf("invalid --keep-newest-group value: %w", err)
+		}
+	}
+
+	// Keep-markers and policy files are looked up by name inside each directory
+	for flagName, name := range map[string]string{"keep-marker": config.KeepMarker, "policy-file": config.PolicyFile} {
+		if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("invalid --%s value: %q must be a file name, not a path", flagName, name)
+		}
+	}
+
+	// tmpfiles.d rules bring their own directories and ages, and run one after another
+	if len(config.TmpfilesConfig) > 0 {
+		switch {
+		case config.Benchmark:
+			return fmt.Errorf("--tmpfiles-config and --benchmark flags cannot be used together")
+		case config.Sandbox:
+			return fmt.Errorf("--tmpfiles-config and --sandbox flags cannot be used together (the sandbox can only confine one directory)")
+		case config.RunAsOwner:
+			return fmt.Errorf("--tmpfiles-config and --run-as-owner flags cannot be used together (root is needed for later rules)")
+		case config.KeepDays != nil || config.OlderThan > 0 || config.NewerThan > 0:
+			return fmt.Errorf("--tmpfiles-config cannot be combined with --keep-days, --older-than or --newer-than (ages come from the rules)")
+		}
+	}
+
+	// A plan lists exactly what to delete, so nothing may change the selection
+	if config.PlanIn != "" {
+		switch {
+		case config.Benchmark || len(config.TmpfilesConfig) > 0 || config.PlanOut != "":
+			return fmt.Errorf("--plan-in cannot be combined with --benchmark, --tmpfiles-config or --plan-out")
+		case config.KeepDays != nil || config.OlderThan > 0 || config.NewerThan > 0 || config.AgeFromName != "" ||
+			len(config.Include) > 0 || len(config.Exclude) > 0 || len(config.ExcludeFrom) > 0 || config.Where != "" ||
+			config.KeepNewest > 0 || config.MaxTotalSize > 0:
+			return fmt.Errorf("--plan-in cannot be combined with age, pattern, filter or retention options (the plan decides what is deleted)")
+		}
+	}
+	if config.PlanOut != "" && (config.Benchmark || len(config.TmpfilesConfig) > 0) {
+		return fmt.Errorf("--plan-out cannot be combined with --benchmark or --tmpfiles-config")
+	}
+
+	if config.ContentsOnly && config.FilesOnly {
+		return fmt.Errorf("--contents-only and --files-only flags cannot be used together (--files-only keeps the target too)")
+	}
+	if config.PlanIn != "" && (config.ContentsOnly || config.FilesOnly) {
+		return fmt.Errorf("--plan-in cannot be combined with --contents-only or --files-only (the plan decides what is deleted)")
+	}
+
+	// Janitorial modes select kinds of entries and never rank files
+	if config.EmptyDirs || config.BrokenSymlinks {
+		switch {
+		case config.EmptyDirs && config.FilesOnly:
+			return fmt.Errorf("--empty-dirs and --files-only flags cannot be used together (--files-only keeps every directory)")
+		case config.PlanIn != "":
+			return fmt.Errorf("--plan-in cannot be combined with --empty-dirs or --broken-symlinks (the plan decides what is deleted)")
+		case config.KeepNewest > 0 || config.MaxTotalSize > 0:
+			return fmt.Errorf("--empty-dirs and --broken-symlinks cannot be combined with --keep-newest or --max-total-size")
+		}
+	}
+
+	// An estimate neither scans nor deletes
+	if config.Estimate && (config.Benchmark || len(config.TmpfilesConfig) > 0 || config.PlanIn != "" || config.PlanOut != "") {
+		return fmt.Errorf("--estimate cannot be combined with --benchmark, --tmpfiles-config, --plan-in or --plan-out")
+	}
+
+	// A sweep chooses its directories below --target-directory before scanning
+	if config.Sweep {
+		switch {
+		case config.Benchmark || len(config.TmpfilesConfig) > 0 || config.PlanIn != "" || config.Estimate:
+			return fmt.Errorf("--sweep cannot be combined with --benchmark, --tmpfiles-config, --plan-in or --estimate")
+		case config.ContentsOnly || config.EmptyDirs || config.BrokenSymlinks:
+			return fmt.Errorf("--sweep cannot be combined with --contents-only, --empty-dirs or --broken-symlinks")
+		}
+	} else if len(config.SweepPresets) > 0 || len(config.PresetFiles) > 0 {
+		return fmt.Errorf("--sweep-preset and --sweep-presets flags require --sweep")
+	}
+
+	// A git cleanup selects from the working tree the target is in
+	if config.GitClean != "" {
+		if _, err := gitclean.ParseMode(config.GitClean); err != nil {
+			return fmt.Errorf("invalid --git-clean value: %w", err)
+		}
+		if config.Benchmark || len(config.TmpfilesConfig) > 0 || config.PlanIn != "" || config.Estimate {
+			return fmt.Errorf("--git-clean cannot be combined with --benchmark, --tmpfiles-config, --plan-in or --estimate")
+		}
+	}
+
+	// Duplicates are found by comparing every file the scan selects
+	if config.Dedupe {
+		if config.DedupeKeep != "" {
+			if _, err := dedupe.ParseRule(config.DedupeKeep); err != nil {
+				return fmt.Errorf("invalid --dedupe-keep value: %w", err)
+			}
+		}
+		switch {
+		case config.Benchmark || len(config.TmpfilesConfig) > 0 || config.PlanIn != "" || config.Estimate:
+			return fmt.Errorf("--dedupe cannot be combined with --benchmark, --tmpfiles-config, --plan-in or --estimate")
+		case config.Sweep || config.EmptyDirs || config.BrokenSymlinks:
+			return fmt.Errorf("--dedupe cannot be combined with --sweep, --empty-dirs or --broken-symlinks")
+		case config.PlanOut != "":
+			return fmt.Errorf("--dedupe and --plan-out flags cannot be used together (a plan does not record the copies that are kept)")
+		}
+	} else if config.DedupeLink || len(config.DedupePrefer) > 0 || (config.DedupeKeep != "" && config.DedupeKeep != "oldest") {
+		return fmt.Errorf("--dedupe-keep, --dedupe-prefer and --dedupe-link flags require --dedupe")
+	}
+
+	// The trash belongs to the user running the deletion, outside the target
+	if config.Trash {
+		switch {
+		case runtime.GOOS == "windows" || runtime.GOOS == "darwin":
+			return fmt.Errorf("--trash flag is only available on systems following the FreeDesktop trash specification (Linux, BSD)")
+		case config.Benchmark || config.DedupeLink:
+			return fmt.Errorf("--trash cannot be combined with --benchmark or --dedupe-link")
+		case config.Sandbox:
+			return fmt.Errorf("--trash and --sandbox flags cannot be used together (the trash is outside the target directory)")
+		case config.RunAsOwner:
+			return fmt.Errorf("--trash and --run-as-owner flags cannot be used together (the trash belongs to the user running the deletion)")
+		}
+	}
+
+	// Windows files are owned by SIDs, not uids
+	if config.RunAsOwner && runtime.GOOS == "windows" {
+		return fmt.Errorf("--run-as-owner flag is not available on Windows")
+	}
+
 	// Validate target directory exists (basic check)
 	// Note: We don't validate existence here as that's done in the safety validator
 	// But we check for obviously invalid paths
@@ -280,12 +679,106 @@ func printUsage() {
 	fmt.Println("  --verbose               Enable detailed logging")
 	fmt.Println("  --log-file PATH         Write logs to specified file")
 	fmt.Println("  --keep-days N           Only delete files older than N days")
+	fmt.Println("  --older-than AGE        Only delete entries older than AGE (e.g. 36h, 7d, 2w)")
+	fmt.Println("  --newer-than AGE        Only delete entries newer than AGE")
+	fmt.Println("  --age-by FIELD          Timestamp for age filters: mtime (default), atime, ctime, btime")
+	fmt.Println("  --reference-time TIME   Measure ages from TIME instead of now")
+	fmt.Println("                          (e.g. 2024-01-31, 2024-01-31T18:00:00, RFC 3339)")
+	fmt.Println("  --age-from-name SPEC    Take file ages from a timestamp in their name instead of --age-by,")
+	fmt.Println("                          for age limits, --keep-newest and --max-total-size. SPEC is a Go time")
+	fmt.Println("                          layout found anywhere in the name (e.g. 2006-01-02T1504, 20060102) or a")
+	fmt.Println("                          regex with named groups year, month, day, hour, minute, second, epoch")
+	fmt.Println("  --age-from-name-fallback POLICY")
+	fmt.Println("                          Files without a timestamp in their name: keep (default), delete, or")
+	fmt.Println("                          timestamp (use the --age-by timestamp)")
 	fmt.Println("  --workers N             Number of parallel workers (default: auto-detect)")
+	fmt.Println("                          (also the number of files hashed at once by --dedupe;")
+	fmt.Println("                          the scan itself walks the tree sequentially)")
 	fmt.Println("  --buffer-size N         Work queue buffer size (default: auto-detect)")
 	fmt.Println("  --deletion-method NAME  Deletion method (default: auto)")
 	fmt.Println("                          Options: auto, fileinfo, deleteonclose, ntapi, deleteapi")
 	fmt.Println("  --benchmark             Run comparative benchmarks of all deletion methods")
 	fmt.Println("  --monitor               Enable real-time system resource monitoring and bottleneck detection")
+	fmt.Println("  --revalidate            Re-check each entry right before deletion and skip entries")
+	fmt.Println("                          changed since the scan (identity, mtime, age filter)")
+	fmt.Println("  --strict-scan           Delete nothing if the scan skipped entries or directories it could")
+	fmt.Println("                          not read (by default they are left in place and the rest is deleted)")
+	fmt.Println("  --sandbox               Confine the process to the target directory before deleting")
+	fmt.Println("                          (Linux Landlock; reports enforced, partially enforced or unavailable)")
+	fmt.Println("  --run-as-owner          When running as root, switch to the target directory's owner before")
+	fmt.Println("                          deleting; entries owned by other users are left in place")
+	fmt.Println("  --wait-for-lock DURATION")
+	fmt.Println("                          Wait for an overlapping run to finish instead of refusing to start")
+	fmt.Println("                          (e.g. 30s, 5m; default: refuse immediately). On Unix, runs of")
+	fmt.Println("                          other users are seen once root has created /run/fast-file-deletion")
+	fmt.Println("  --include PATTERN       Only delete entries matching PATTERN (repeatable)")
+	fmt.Println("  --exclude PATTERN       Never delete or descend into entries matching PATTERN (repeatable)")
+	fmt.Println("  --exclude-from FILE     Read exclude patterns from FILE (repeatable)")
+	fmt.Println("                          Patterns use gitignore syntax: *.log, /build, **/cache, keep/, !keep.log")
+	fmt.Println("  --where EXPR            Only delete entries matching a filter expression, e.g.")
+	fmt.Println("                          \"size > 100M and mtime older than 7d and not name ~ '*.keep'\"")
+	fmt.Println("                          Fields: size, mtime, atime, ctime, btime, name, path, uid, gid,")
+	fmt.Println("                          type (f, d, l, p, s, c, b), depth")
+	fmt.Println("                          Operators: = != < <= > >=, ~ (glob), =~ (regex), older/newer than AGE,")
+	fmt.Println("                          and, or, not, parentheses")
+	fmt.Println("  --keep-newest N         Keep the N newest files in each directory, by the --age-by timestamp")
+	fmt.Println("  --keep-newest-group GLOB")
+	fmt.Println("                          Rank files whose name matches GLOB separately within each directory")
+	fmt.Println("                          (repeatable, e.g. 'app-*.log'; other files form one more group)")
+	fmt.Println("  --max-total-size SIZE   Delete the oldest files until the tree fits in SIZE (e.g. 500M, 200G);")
+	fmt.Println("                          with --age-by atime the least recently used files go first")
+	fmt.Println("  --keep-marker NAME      Never delete a directory holding a file named NAME, or anything")
+	fmt.Println("                          beneath it (default: .ffdkeep)")
+	fmt.Println("  --no-keep-marker        Ignore keep-markers")
+	fmt.Println("  --policy-file NAME      Per-directory file overriding age settings for its subtree, e.g.")
+	fmt.Println("                          \"keep-days = 3\" (default: .ffdpolicy)")
+	fmt.Println("  --no-policy-file        Ignore policy files")
+	fmt.Println("  --tmpfiles-config FILE  Instead of --target-directory, run the age cleanup of tmpfiles.d rules")
+	fmt.Println("                          (d, D, e, v, q, Q, C lines with an age; x/X lines exclude paths).")
+	fmt.Println("                          Repeatable and accepts globs; earlier files override later ones")
+	fmt.Println("                          of the same name, as /etc/tmpfiles.d overrides /usr/lib/tmpfiles.d")
+	fmt.Println("  --plan-out FILE         Write the entries to delete (paths, types, sizes, identities), the scan")
+	fmt.Println("                          options and a checksum to a plan file; with --dry-run nothing is deleted")
+	fmt.Println("  --plan-in FILE          Instead of --target-directory, delete exactly the entries of a plan file,")
+	fmt.Println("                          skipping any entry replaced or modified since the plan was made")
+	fmt.Println("  --contents-only         Empty the target directory but keep it, with its permissions and owner")
+	fmt.Println("  --files-only            Delete files only, keeping every directory with its permissions, owner")
+	fmt.Println("                          and ACLs")
+	fmt.Println("  --empty-dirs            Only delete empty directories, including directories that only hold")
+	fmt.Println("                          empty directories (like find -empty -delete); the target is kept")
+	fmt.Println("  --broken-symlinks       Only delete symbolic links whose target does not exist (like")
+	fmt.Println("                          find -xtype l -delete); with --empty-dirs, directories they leave")
+	fmt.Println("                          empty go too")
+	fmt.Println("  --sweep                 Find build artifact directories, such as node_modules beside a")
+	fmt.Println("                          package.json or target beside a Cargo.toml, list them by project")
+	fmt.Println("                          with their sizes, and delete the ones chosen (all with --force).")
+	fmt.Println("                          Presets: node, next, nuxt, rust, maven, gradle, python, venv,")
+	fmt.Println("                          pytools, dotnet, swift, elixir, haskell, dart, zig, terraform")
+	fmt.Println("  --sweep-preset NAME     Only look for the artifacts of this preset (repeatable)")
+	fmt.Println("  --sweep-presets FILE    Read additional presets, \"[name]\" sections with \"dirs = ...\" and")
+	fmt.Println("                          \"markers = ...\" lines, from FILE (repeatable; also read from")
+	fmt.Println("                          sweep-presets.conf in the user configuration directory)")
+	fmt.Println("  --git-clean MODE        Only delete what git clean would in the git working tree holding the")
+	fmt.Println("                          target: \"ignored\" files (git clean -Xd) or all \"untracked\" ones")
+	fmt.Println("                          (git clean -xd). Follows .gitignore files, info/exclude and")
+	fmt.Println("                          core.excludesFile; tracked files, .git, submodules and nested")
+	fmt.Println("                          repositories are never deleted")
+	fmt.Println("  --dedupe                Only delete the copies of duplicate files, keeping one copy of each.")
+	fmt.Println("                          Files are compared by size, then a hash of their first 64 KiB, then")
+	fmt.Println("                          a hash of their contents; hard links are not copies. Directories are")
+	fmt.Println("                          kept, and the groups and bytes reclaimable are listed before deleting")
+	fmt.Println("                          (a copy is skipped if the copy kept changed since the scan)")
+	fmt.Println("  --dedupe-keep RULE      Copy each group keeps: oldest (default) or shortest-path")
+	fmt.Println("  --dedupe-prefer DIR     Keep the copy in DIR, absolute or relative to the target, before")
+	fmt.Println("                          applying --dedupe-keep (repeatable, most preferred first)")
+	fmt.Println("  --dedupe-link           Replace the copies with hard links to the copy kept instead of")
+	fmt.Println("                          deleting them, so every path still exists (same file system only)")
+	fmt.Println("  --trash                 Move entries into the trash instead of deleting them, so they can be")
+	fmt.Println("                          restored from a desktop's trash can (FreeDesktop trash specification:")
+	fmt.Println("                          the home trash, or .Trash-$uid at the top of other file systems)")
+	fmt.Println("  --estimate              Estimate the number of entries, size, and scan and deletion time")
+	fmt.Println("                          without scanning or deleting (samples the tree, or reads file system")
+	fmt.Println("                          statistics for a mount point; rates come from previous runs)")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  fast-file-deletion -td C:\\temp\\old-logs")
@@ -296,6 +789,29 @@ func printUsage() {
 	fmt.Println("  fast-file-deletion -td C:\\temp\\cache --deletion-method fileinfo")
 	fmt.Println("  fast-file-deletion -td C:\\temp\\benchmark --benchmark --workers 16")
 	fmt.Println("  fast-file-deletion -td C:\\data\\large-dir --monitor  # Diagnose performance bottlenecks")
+	fmt.Println("  fast-file-deletion -td /var/log/app --keep-days 7 --revalidate --force")
+	fmt.Println("  fast-file-deletion -td /var/cache/app --older-than 36h --age-by atime  # Evict unused cache")
+	fmt.Println("  fast-file-deletion -td /srv/scratch --force --sandbox  # Unattended cron cleanup")
+	fmt.Println("  fast-file-deletion -td /mnt/share/build --force --strict-scan  # All or nothing")
+	fmt.Println("  fast-file-deletion -td /home/alice/scratch --force --run-as-owner  # Root cleanup job")
+	fmt.Println("  fast-file-deletion -td /srv/cache --force --wait-for-lock 10m  # Queue behind another run")
+	fmt.Println("  fast-file-deletion -td /srv/work --include '*.tmp' --include '*.log' --exclude '*.db' --exclude keep/")
+	fmt.Println("  fast-file-deletion -td /srv/uploads --where \"type = f and (size > 1G or atime older than 30d)\"")
+	fmt.Println("  fast-file-deletion -td /srv/builds --keep-days 30 --policy-file .retention  # Per-project retention")
+	fmt.Println("  fast-file-deletion -td /var/log/app --keep-newest 10 --keep-newest-group 'app-*.log'")
+	fmt.Println("  fast-file-deletion -td /srv/artifacts --max-total-size 200G --age-by atime  # LRU trim")
+	fmt.Println("  fast-file-deletion -td /backups/db --older-than 30d --age-from-name 2006-01-02T1504  # Copied backups")
+	fmt.Println("  fast-file-deletion --tmpfiles-config '/etc/tmpfiles.d/*.conf' --force  # systemd-tmpfiles --clean")
+	fmt.Println("  fast-file-deletion -td /srv/data --older-than 1y --dry-run --force --plan-out purge.plan  # For review")
+	fmt.Println("  fast-file-deletion --plan-in purge.plan  # Once approved")
+	fmt.Println("  fast-file-deletion -td /mnt/archive --estimate  # How long would it take?")
+	fmt.Println("  fast-file-deletion -td /srv/app/cache --contents-only --force  # Keep the mount point")
+	fmt.Println("  fast-file-deletion -td /srv/spool --files-only --older-than 7d  # Keep the layout")
+	fmt.Println("  fast-file-deletion -td /srv/share --empty-dirs --broken-symlinks  # Tidy up")
+	fmt.Println("  fast-file-deletion -td ~/code --sweep --sweep-preset node --sweep-preset rust  # Reclaim build space")
+	fmt.Println("  fast-file-deletion -td ~/src/monorepo --git-clean ignored  # Parallel git clean -Xd")
+	fmt.Println("  fast-file-deletion -td /srv/artifacts --dedupe --dedupe-prefer releases --dry-run  # Duplicate report")
+	fmt.Println("  fast-file-deletion -td ~/Downloads/old --trash  # Recoverable cleanup")
 }
 
 // run executes the main deletion workflow with the given configuration.
@@ -316,14 +832,63 @@ func run(config *Config) int {
 		return runBenchmarkMode(config)
 	}
 
-	// Validate path, scan directory, and get user confirmation
-	scanResult, exitCode := scanAndConfirm(config)
+	if len(config.TmpfilesConfig) > 0 {
+		return runTmpfilesMode(config)
+	}
+
+	if config.Estimate {
+		return runEstimateMode(config)
+	}
+
+	// Choose the artifact directories the scan is restricted to
+	if config.Sweep {
+		subtrees, exitCode := chooseSweepTargets(config)
+		if subtrees == nil {
+			return exitCode
+		}
+		config.Subtrees = subtrees
+	}
+
+	// Validate path, scan directory (or read the plan), and get user confirmation
+	scanResult, runLock, exitCode := scanAndConfirm(config)
 	if scanResult == nil {
 		return exitCode
 	}
+	defer runLock.Release()
+
+	// Give up root before anything is deleted
+	var owner *privilege.Owner
+	if config.RunAsOwner {
+		if owner = applyRunAsOwner(scanResult); owner == nil {
+			return 2
+		}
+	}
+
+	// Confine the process to the target before the engine starts
+	var remover *sandbox.Remover
+	if config.Sandbox {
+		var ok bool
+		if remover, ok = applySandbox(config, scanResult, owner); !ok {
+			return 2
+		}
+		if remover != nil {
+			defer remover.Close()
+		}
+	}
+
+	// Replace the duplicate copies with hard links instead of deleting them
+	if config.DedupeLink {
+		return runDedupeLink(config, scanResult)
+	}
 
 	// Initialize engine and backend
-	backendInstance, eng, reporter := createEngine(config, scanResult)
+	backendInstance, eng, reporter := createEngine(config, scanResult, owner, remover)
+	// Duplicate copies are only deleted while the copy kept is intact
+	if config.Revalidate || config.Dedupe {
+		eng.SetRevalidator(func(index int, _ string) bool {
+			return scanResult.Revalidate(index)
+		})
+	}
 
 	// Set up interrupt handler for graceful cancellation
 	ctx, cancel := engine.SetupInterruptHandler()
@@ -336,19 +901,23 @@ func run(config *Config) int {
 	fmt.Println()
 	if config.DryRun {
 		fmt.Println("Starting dry run (no files will be deleted)...")
+	} else if config.Trash {
+		fmt.Println("Moving entries into the trash...")
 	} else {
 		fmt.Println("Starting deletion...")
 	}
 
-	result, err := eng.DeleteWithUTF16(ctx, scanResult.Files, scanResult.FilesUTF16, scanResult.IsDirectory, config.DryRun)
+	meter := startSpaceMeter(config, scanResult)
+	result, err := eng.DeleteEntries(ctx, scanResult, config.DryRun)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\n❌ Error: Deletion failed: %v\n\n", err)
 		logger.Error("Deletion failed: %v", err)
 		return 2
 	}
+	recordRun(config, scanResult, result)
 
 	// Display results
-	return displayResults(config, result, backendInstance, scanResult, mon, reporter)
+	return displayResults(config, result, backendInstance, scanResult, mon, reporter, meter)
 }
 
 // setupLogging initializes logging and displays platform information.
@@ -359,7 +928,13 @@ func setupLogging(config *Config) error {
 	}
 
 	logger.Info("Fast File Deletion Tool v0.1.0")
-	logger.Info("Target directory: %s", config.TargetDir)
+	if len(config.TmpfilesConfig) > 0 {
+		logger.Info("tmpfiles.d configuration: %s", strings.Join(config.TmpfilesConfig, ", "))
+	} else if config.PlanIn != "" {
+		logger.Info("Deletion plan: %s", config.PlanIn)
+	} else {
+		logger.Info("Target directory: %s", config.TargetDir)
+	}
 
 	if runtime.GOOS != "windows" {
 		fmt.Println()
@@ -375,56 +950,576 @@ func setupLogging(config *Config) error {
 	return nil
 }
 
-// scanAndConfirm validates the target path, scans the directory, and obtains user confirmation.
-// Returns the scan result and exit code. A nil scan result means the caller should return the exit code.
-func scanAndConfirm(config *Config) (*scanner.ScanResult, int) {
+// scanAndConfirm validates the target path, locks it against overlapping runs, scans the
+// directory, writes the --plan-out plan, and obtains user confirmation. With --plan-in the
+// plan's entries replace the scan.
+// Returns the scan result, the run lock and exit code. A nil scan result means the caller
+// should return the exit code; otherwise the caller must release the lock when done.
+func scanAndConfirm(config *Config) (*scanner.ScanResult, *runlock.Lock, int) {
+	if config.PlanIn != "" {
+		return loadPlan(config)
+	}
+
+	scanResult, runLock, exitCode := scanTarget(config)
+	if scanResult == nil {
+		return nil, nil, exitCode
+	}
+	if config.PlanOut != "" && !writePlanFile(config, scanResult) {
+		runLock.Release()
+		return nil, nil, 2
+	}
+	if !confirmDeletion(config, scanResult) {
+		runLock.Release()
+		return nil, nil, 0
+	}
+	return scanResult, runLock, 0
+}
+
+// scanTarget validates the target path, locks it against overlapping runs, and scans
+// the directory with live progress, printing a summary of what was found. Ctrl+C
+// cancels the scan, which returns exit code 0 like a declined confirmation.
+// Returns the scan result, the run lock and exit code. A nil scan result means the caller
+// should return the exit code; otherwise the caller must release the lock when done.
+func scanTarget(config *Config) (scanResult *scanner.ScanResult, runLock *runlock.Lock, exitCode int) {
 	logger.Info("Validating target path safety...")
 	isSafe, reason := safety.IsSafePath(config.TargetDir)
 	if !isSafe {
 		fmt.Fprintf(os.Stderr, "\n❌ Error: Cannot delete this path\n")
 		fmt.Fprintf(os.Stderr, "   Reason: %s\n\n", reason)
 		logger.Error("Path validation failed: %s", reason)
-		return nil, 2
+		return nil, nil, 2
 	}
 
+	runLock = acquireRunLock(config)
+	if runLock == nil {
+		return nil, nil, 2
+	}
+	defer func() {
+		if scanResult == nil {
+			runLock.Release()
+		}
+	}()
+
 	logger.Info("Scanning directory...")
 	fmt.Println("\nScanning directory...")
 
-	s := scanner.NewScanner(config.TargetDir, config.KeepDays)
-	scanResult, err := s.Scan()
+	s, err := newScanner(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: %v\n\n", err)
+		logger.Error("Invalid scan options: %v", err)
+		return nil, nil, 2
+	}
+	s.SetProgress(progress.NewScanReporter().Update, 0)
+
+	// Ctrl+C stops the scan. Default handling is restored afterwards so that it
+	// still ends the process at the confirmation prompt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	scanResult, err = s.ScanContext(ctx)
+	stop()
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("\n\n❌ Scan cancelled.")
+		logger.Info("Scan cancelled by user")
+		return nil, nil, 0
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\n❌ Error: Failed to scan directory: %v\n\n", err)
 		logger.Error("Directory scan failed: %v", err)
-		return nil, 2
+		return nil, nil, 2
 	}
 
 	fmt.Printf("Found %d files and directories", scanResult.TotalScanned)
-	if config.KeepDays != nil {
-		fmt.Printf(" (%d to delete, %d to retain)", scanResult.TotalToDelete, scanResult.TotalRetained)
+	if config.KeepDays != nil || config.OlderThan > 0 || config.NewerThan > 0 ||
+		config.KeepNewest > 0 || config.MaxTotalSize > 0 || config.RuleFilter != nil || scanResult.TotalRetained > 0 {
+		fmt.Printf(" (%d to delete, %d to retain", scanResult.TotalToDelete, scanResult.TotalRetained)
+		if scanResult.TotalRetainedDirs > 0 {
+			fmt.Printf(", including %d directories", scanResult.TotalRetainedDirs)
+		}
+		fmt.Print(")")
 	}
 	fmt.Println()
 
 	logger.Info("Scan complete: %d total, %d to delete, %d to retain",
 		scanResult.TotalScanned, scanResult.TotalToDelete, scanResult.TotalRetained)
 
+	displayScanSpace(scanResult)
+	displayScanControls(config, scanResult)
+	if config.Dedupe {
+		displayDuplicates(config, scanResult)
+	}
+	displayScanWarnings(scanResult)
+
+	if config.StrictScan && len(scanResult.Warnings) > 0 {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: The scan was incomplete, nothing will be deleted (--strict-scan)\n\n")
+		logger.Error("Strict scan: refusing to delete after %d scan warnings", len(scanResult.Warnings))
+		return nil, nil, 2
+	}
+
+	return scanResult, runLock, 0
+}
+
+// confirmDeletion reports whether the entries found by the scan should be deleted:
+// false if there is nothing to delete or the user declines. The confirmation
+// screen, or the output of a forced dry run, breaks down the files to delete.
+func confirmDeletion(config *Config, scanResult *scanner.ScanResult) bool {
 	if scanResult.TotalToDelete == 0 {
 		fmt.Println("\n✓ No files to delete.")
 		logger.Info("No files to delete, exiting")
-		return nil, 0
+		return false
+	}
+
+	// Break down what is about to be deleted, on the confirmation screen or,
+	// when there is none, before a dry run
+	details := progress.FormatSummary(scanResult.Summary)
+	if config.Force && config.DryRun && details != "" {
+		fmt.Println("\nThe dry run covers:")
+		fmt.Print(details)
 	}
 
-	confirmed := safety.GetUserConfirmation(config.TargetDir, scanResult.TotalToDelete, config.DryRun, config.Force)
+	confirmed := safety.GetUserConfirmationWithDetails(config.TargetDir, scanResult.TotalToDelete, details, config.DryRun, config.Force)
 	if !confirmed {
 		fmt.Println("\n❌ Deletion cancelled by user.")
 		logger.Info("Deletion cancelled by user")
-		return nil, 0
+		return false
 	}
 
-	return scanResult, 0
+	return true
+}
+
+// keepDirs returns which directories the scan keeps for --contents-only and
+// --files-only.
+func keepDirs(contentsOnly, filesOnly bool) scanner.KeepDirs {
+	switch {
+	case filesOnly:
+		return scanner.KeepAllDirs
+	case contentsOnly:
+		return scanner.KeepRoot
+	default:
+		return scanner.KeepNoDirs
+	}
+}
+
+// selection returns the kinds of entries --empty-dirs and --broken-symlinks
+// restrict the scan to (0 for everything).
+func selection(emptyDirs, brokenSymlinks bool) scanner.Select {
+	var sel scanner.Select
+	if emptyDirs {
+		sel |= scanner.SelectEmptyDirs
+	}
+	if brokenSymlinks {
+		sel |= scanner.SelectBrokenSymlinks
+	}
+	return sel
+}
+
+// newScanner creates a scanner for the target directory configured with the
+// age, pattern, filter and revalidation options from config.
+func newScanner(config *Config) (*scanner.Scanner, error) {
+	s := scanner.NewScanner(config.TargetDir, config.KeepDays)
+	s.SetWorkers(config.Workers)
+	s.SetCompact(true)
+	s.SetRecordIdentity(config.Revalidate || config.PlanOut != "")
+	if !config.Force || config.DryRun {
+		// Shown on the confirmation screen and in dry-run output
+		s.SetSummary(scanner.DefaultSummaryTop)
+	}
+
+	if config.AgeBy != "" {
+		ageBy, err := scanner.ParseTimeField(config.AgeBy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --age-by value: %w", err)
+		}
+		s.SetAgeBy(ageBy)
+	}
+	s.SetOlderThan(config.OlderThan)
+	s.SetNewerThan(config.NewerThan)
+	s.SetReferenceTime(config.ReferenceTime)
+	if config.AgeFromName != "" {
+		nameTime, err := scanner.ParseNameTime(config.AgeFromName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --age-from-name value: %w", err)
+		}
+		fallback := scanner.NameFallbackKeep
+		if config.NameFallback != "" {
+			if fallback, err = scanner.ParseNameFallback(config.NameFallback); err != nil {
+				return nil, fmt.Errorf("invalid --age-from-name-fallback value: %w", err)
+			}
+		}
+		s.SetAgeFromName(nameTime, fallback)
+	}
+	reference := config.ReferenceTime
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+
+	include, err := scanner.NewMatcher(config.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --include value: %w", err)
+	}
+	s.SetInclude(include)
+
+	// Patterns from files come first so --exclude (including "!" negations) can override them
+	var excludeLines []string
+	for _, path := range config.ExcludeFrom {
+		lines, err := scanner.ReadPatternFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --exclude-from value: %w", err)
+		}
+		excludeLines = append(excludeLines, lines...)
+	}
+	excludeLines = append(excludeLines, config.Exclude...)
+
+	// The working tree's .git and submodules come last so that no negation re-includes them
+	var filters []scanner.Filter
+	if config.GitClean != "" {
+		mode, err := gitclean.ParseMode(config.GitClean)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --git-clean value: %w", err)
+		}
+		cleaner, err := gitclean.Open(config.TargetDir, mode)
+		if err != nil {
+			return nil, fmt.Errorf("cannot use --git-clean: %w", err)
+		}
+		logger.Info("Git working tree: %s (deleting %s files)", cleaner.Worktree(), mode)
+		excludeLines = append(excludeLines, cleaner.Excludes()...)
+		filters = append(filters, cleaner)
+	}
+	exclude, err := scanner.NewMatcher(excludeLines)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	s.SetExclude(exclude)
+
+	if config.RuleFilter != nil {
+		filters = append(filters, config.RuleFilter)
+	}
+	if config.Where != "" {
+		filter, err := scanner.ParseWhere(config.Where, reference)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --where value: %w", err)
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) > 0 {
+		s.SetFilter(scanner.And(filters...))
+	}
+
+	s.SetKeepMarker(config.KeepMarker)
+	s.SetPolicyFile(config.PolicyFile)
+	s.SetKeepDirs(keepDirs(config.ContentsOnly, config.FilesOnly))
+	s.SetSelect(selection(config.EmptyDirs, config.BrokenSymlinks))
+	s.SetSubtrees(config.Subtrees)
+
+	s.SetKeepNewest(config.KeepNewest)
+	if err := s.SetKeepNewestGroups(config.KeepGroups); err != nil {
+		return nil, fmt.Errorf("invalid --keep-newest-group value: %w", err)
+	}
+	s.SetMaxTotalSize(config.MaxTotalSize)
+
+	if config.Dedupe {
+		keep := dedupe.KeepOldest
+		if config.DedupeKeep != "" {
+			if keep, err = dedupe.ParseRule(config.DedupeKeep); err != nil {
+				return nil, fmt.Errorf("invalid --dedupe-keep value: %w", err)
+			}
+		}
+		s.SetDedupe(&dedupe.Options{Keep: keep, Prefer: config.DedupePrefer})
+	}
+
+	return s, nil
+}
+
+// maxListedControls caps how many protected directories, policies and warnings
+// the scan summary lists; the complete lists are written to the log.
+const maxListedControls = 10
+
+// displayScanControls notes the directories kept by --contents-only or
+// --files-only, the kinds of entries selected by --empty-dirs and
+// --broken-symlinks, the artifact directories chosen by --sweep or the files
+// selected by --git-clean, notes that --trash keeps entries recoverable, and
+// lists the subtrees protected by keep-markers and the directories whose
+// policy file overrides the age settings, so that a cleanup can be explained
+// from its summary.
+func displayScanControls(config *Config, scanResult *scanner.ScanResult) {
+	if config.FilesOnly {
+		fmt.Println("\n📁 Keeping every directory (--files-only)")
+	} else if config.ContentsOnly {
+		fmt.Println("\n📁 Keeping the target directory itself (--contents-only)")
+	}
+	switch {
+	case config.EmptyDirs && config.BrokenSymlinks:
+		fmt.Println("\n🧹 Only empty directories and broken symbolic links are deleted")
+	case config.EmptyDirs:
+		fmt.Println("\n🧹 Only empty directories are deleted")
+	case config.BrokenSymlinks:
+		fmt.Println("\n🧹 Only broken symbolic links are deleted")
+	case config.Sweep:
+		fmt.Printf("\n🧹 Only the %d build artifact directories chosen are deleted\n", len(config.Subtrees))
+	}
+	if config.Trash {
+		fmt.Println("\n🗑️  Entries are moved into the trash and can be restored (--trash); emptying it frees the space")
+	}
+	if mode, err := gitclean.ParseMode(config.GitClean); config.GitClean != "" && err == nil {
+		if mode == gitclean.ModeUntracked {
+			fmt.Println("\n🧹 Only files git does not track are deleted (like git clean -xd)")
+		} else {
+			fmt.Println("\n🧹 Only files git ignores are deleted (like git clean -Xd)")
+		}
+	}
+
+	if len(scanResult.Protected) > 0 {
+		fmt.Printf("\n🛡️  Protected subtrees: %d\n", len(scanResult.Protected))
+		for i, dir := range scanResult.Protected {
+			logger.Info("Protected subtree: %s", dir)
+			if i < maxListedControls {
+				fmt.Printf("   %s\n", dir)
+			}
+		}
+		if extra := len(scanResult.Protected) - maxListedControls; extra > 0 {
+			fmt.Printf("   ... and %d more (see log)\n", extra)
+		}
+	}
+
+	if len(scanResult.Policies) > 0 {
+		fmt.Printf("\n📋 Retention policies (%s): %d\n", config.PolicyFile, len(scanResult.Policies))
+		for i, dp := range scanResult.Policies {
+			if i < maxListedControls {
+				fmt.Printf("   %s: %s\n", dp.Dir, dp.Policy)
+			}
+		}
+		if extra := len(scanResult.Policies) - maxListedControls; extra > 0 {
+			fmt.Printf("   ... and %d more (see log)\n", extra)
+		}
+	}
+}
+
+// displayScanSpace shows how much disk space deleting the scanned entries will
+// free. Hardlinked files count once, and only if every link is deleted; sparse
+// and compressed files count the blocks they occupy rather than their length.
+func displayScanSpace(scanResult *scanner.ScanResult) {
+	space := scanResult.Space
+	if scanResult.TotalToDelete == 0 {
+		return
+	}
+	if !space.Exact {
+		fmt.Printf("Space to free: about %s\n", progress.FormatBytes(space.AllocatedBytes))
+		return
+	}
+
+	fmt.Printf("Space to free: %s on disk (%s of file data)\n",
+		progress.FormatBytes(space.AllocatedBytes), progress.FormatBytes(space.ApparentBytes))
+	if space.HardlinkedFiles > 0 {
+		fmt.Printf("   %s paths are hardlinks; each file is counted once\n", progress.FormatNumber(space.HardlinkedFiles))
+	}
+	if space.LinkedBytes > 0 {
+		fmt.Printf("   %s stays in use by links outside the deletion and will not be freed\n", progress.FormatBytes(space.LinkedBytes))
+	}
+}
+
+// startSpaceMeter records the free space of the target's file system before a
+// deletion, so displayResults can report what was actually reclaimed. It returns
+// nil for dry runs, under --trash, where the space stays in use until the trash
+// is emptied, and when free space cannot be measured.
+func startSpaceMeter(config *Config, scanResult *scanner.ScanResult) *diskspace.Meter {
+	if config.DryRun || config.Trash {
+		return nil
+	}
+	meter, err := diskspace.Start(scanResult.ScannedPath)
+	if err != nil {
+		logger.Warning("Cannot measure free space, the space reclaimed will not be reported: %v", err)
+		return nil
+	}
+	return meter
+}
+
+// displaySpaceReclaimed compares the growth of free space since meter was
+// started with the scan's estimate.
+func displaySpaceReclaimed(meter *diskspace.Meter, scanResult *scanner.ScanResult) {
+	if meter == nil {
+		return
+	}
+	reclaimed, err := meter.Reclaimed()
+	if err != nil {
+		logger.Warning("Cannot measure free space after deletion: %v", err)
+		return
+	}
+	logger.Info("Space reclaimed: %d bytes (estimated %d bytes)", reclaimed, scanResult.Space.AllocatedBytes)
+	fmt.Printf("Space reclaimed:        %s (estimated %s)\n",
+		progress.FormatBytes(int64(reclaimed)), progress.FormatBytes(scanResult.Space.AllocatedBytes))
+	fmt.Println()
+}
+
+// displayScanWarnings lists the entries the scan could not read or evaluate.
+// They and any directories above them are left in place.
+func displayScanWarnings(scanResult *scanner.ScanResult) {
+	if len(scanResult.Warnings) == 0 {
+		return
+	}
+
+	fmt.Printf("\n⚠️  Scan warnings: %d", len(scanResult.Warnings))
+	if scanResult.SkippedSubtrees > 0 {
+		fmt.Printf(" (%d directories not fully scanned)", scanResult.SkippedSubtrees)
+	}
+	fmt.Println(", these entries will be left in place")
+	for i, w := range scanResult.Warnings {
+		if i < maxListedControls {
+			fmt.Printf("   %s: cannot %s (%s)\n", w.Path, w.Op, w.Kind)
+		}
+	}
+	if extra := len(scanResult.Warnings) - maxListedControls; extra > 0 {
+		fmt.Printf("   ... and %d more (see log)\n", extra)
+	}
+}
+
+// acquireRunLock locks the target directory in the run registry so that no other
+// run works on the same, an enclosing, or a nested directory at the same time.
+// Waits up to config.WaitForLock for a conflicting run to finish.
+// Returns nil if the lock could not be obtained and the run must stop.
+func acquireRunLock(config *Config) *runlock.Lock {
+	registry := runlock.DefaultRegistry()
+
+	lock, err := registry.TryAcquire(config.TargetDir)
+	var conflict *runlock.ConflictError
+	if errors.As(err, &conflict) && config.WaitForLock > 0 {
+		fmt.Printf("\n⏳ Waiting up to %s for another run to finish: %s\n", config.WaitForLock, conflict.Holder)
+		logger.Info("Waiting up to %s for lock held by %s", config.WaitForLock, conflict.Holder)
+		lock, err = registry.Wait(config.TargetDir, config.WaitForLock)
+	}
+
+	if errors.As(err, &conflict) {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Another run is already working on this directory\n")
+		fmt.Fprintf(os.Stderr, "   Locked path: %s\n", conflict.Holder.Path)
+		fmt.Fprintf(os.Stderr, "   Held by: %s\n", conflict.Holder)
+		fmt.Fprintf(os.Stderr, "   Use --wait-for-lock DURATION to wait for it to finish\n\n")
+		logger.Error("Target overlaps a running deletion: %v", conflict)
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Failed to lock target directory: %v\n\n", err)
+		logger.Error("Failed to acquire run lock in %s: %v", registry.Dir(), err)
+		return nil
+	}
+
+	logger.Debug("Acquired run lock for %s", lock.Holder().Path)
+	return lock
+}
+
+// applySandbox restricts the process with Landlock so that only entries beneath
+// the validated target (and the log file) can be modified from here on.
+// The sandboxed process cannot remove the target itself, so when the target
+// is deleted too, a remover is started first to do that, running as owner if
+// it is non-nil. Returns the remover (nil if none is needed), and false if the
+// sandbox could not be applied and the run must stop. An unavailable sandbox
+// is reported but does not stop the run.
+func applySandbox(config *Config, scanResult *scanner.ScanResult, owner *privilege.Owner) (*sandbox.Remover, bool) {
+	policy := sandbox.Policy{
+		Targets: []string{scanResult.ScannedPath},
+	}
+	if config.LogFile != "" {
+		policy.WritableFiles = append(policy.WritableFiles, config.LogFile)
+	}
+
+	var remover *sandbox.Remover
+	if deletesRoot(scanResult) && runtime.GOOS == "linux" {
+		uid, gid := -1, -1
+		if owner != nil {
+			uid, gid = owner.UID, owner.GID
+		}
+		var err error
+		if remover, err = sandbox.StartRemover(policy.Targets, uid, gid); err != nil {
+			fmt.Fprintf(os.Stderr, "\n❌ Error: Failed to apply sandbox: %v\n\n", err)
+			logger.Error("Failed to start the remover for the sandbox: %v", err)
+			return nil, false
+		}
+	}
+
+	result, err := sandbox.Restrict(policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Failed to apply sandbox: %v\n\n", err)
+		logger.Error("Failed to apply sandbox: %v", err)
+		if remover != nil {
+			remover.Close()
+		}
+		return nil, false
+	}
+
+	switch result.Status {
+	case sandbox.StatusEnforced:
+		fmt.Printf("🔒 Sandbox: enforced (Landlock ABI %d)\n", result.ABI)
+		logger.Info("Sandbox enforced (Landlock ABI %d)", result.ABI)
+	case sandbox.StatusPartiallyEnforced:
+		fmt.Printf("🔒 Sandbox: partially enforced (%s)\n", result.Reason)
+		logger.Warning("Sandbox partially enforced: %s", result.Reason)
+	default:
+		fmt.Printf("⚠️  Sandbox: unavailable (%s)\n", result.Reason)
+		logger.Warning("Sandbox unavailable: %s", result.Reason)
+
+		// Nothing stops the process from removing the target itself
+		if remover != nil {
+			remover.Close()
+			remover = nil
+		}
+	}
+
+	return remover, true
+}
+
+// applyRunAsOwner switches the process to the owner of the scanned directory so
+// that deletion happens with that user's permissions instead of root's.
+// Returns the owner, or nil if the switch failed and the run must stop.
+func applyRunAsOwner(scanResult *scanner.ScanResult) *privilege.Owner {
+	owner, err := privilege.OwnerOf(scanResult.ScannedPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Cannot determine the owner of the target directory: %v\n\n", err)
+		logger.Error("Cannot determine target owner: %v", err)
+		return nil
+	}
+
+	method, err := privilege.DropTo(owner)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Failed to switch to the target owner (uid:gid %s): %v\n\n", owner, err)
+		logger.Error("Failed to switch to target owner %s: %v", owner, err)
+		return nil
+	}
+
+	if method == privilege.MethodNone {
+		fmt.Printf("👤 Running as target owner (uid:gid %s)\n", owner)
+		logger.Info("Already running as target owner %s", owner)
+	} else {
+		fmt.Printf("👤 Switched to target owner (uid:gid %s, %s)\n", owner, method)
+		logger.Info("Switched to target owner %s using %s", owner, method)
+	}
+
+	return &owner
+}
+
+// deletesRoot reports whether the scan result includes the scanned root directory itself.
+func deletesRoot(scanResult *scanner.ScanResult) bool {
+	n := scanResult.Len()
+	if n == 0 {
+		return false
+	}
+	last, err := filepath.Abs(scanResult.Path(n - 1))
+	if err != nil {
+		return false
+	}
+	return last == scanResult.ScannedPath
+}
+
+// deletedDirs returns the paths of the directories the scan result deletes.
+func deletedDirs(scanResult *scanner.ScanResult) map[string]bool {
+	dirs := make(map[string]bool)
+	for i := 0; i < scanResult.Len(); i++ {
+		if scanResult.IsDir(i) {
+			dirs[scanResult.Path(i)] = true
+		}
+	}
+	return dirs
 }
 
 // createEngine initializes the backend, deletion engine, and progress reporter.
-func createEngine(config *Config, scanResult *scanner.ScanResult) (backend.Backend, *engine.Engine, *progress.Reporter) {
+// If owner is non-nil, the backend refuses to delete entries owned by other users.
+// If remover is non-nil, the target directory itself is removed through it.
+func createEngine(config *Config, scanResult *scanner.ScanResult, owner *privilege.Owner, remover *sandbox.Remover) (backend.Backend, *engine.Engine, *progress.Reporter) {
 	workerCount := config.Workers
 	if workerCount == 0 {
 		workerCount = runtime.NumCPU() * engine.DefaultWorkerMultiplier
@@ -439,6 +1534,17 @@ func createEngine(config *Config, scanResult *scanner.ScanResult) (backend.Backe
 	logger.Debug("Engine configuration: workers=%d, buffer_size=%d", workerCount, bufferSize)
 
 	backendInstance := backend.NewBackend()
+	if config.Trash {
+		trash := backend.NewTrashBackend()
+		// A scan only deletes a directory with everything beneath it, so it can go
+		// into the trash in one piece. Not when entries are re-checked one by one,
+		// or with a plan, which may list a directory without its contents.
+		if !config.Revalidate && !config.Dedupe && config.PlanIn == "" {
+			trash.SetWholeDirs(deletedDirs(scanResult))
+		}
+		backendInstance = trash
+		logger.Info("Entries are moved into the trash instead of being deleted")
+	}
 
 	if config.DeletionMethod != "auto" {
 		if advBackend, ok := backendInstance.(backend.AdvancedBackend); ok {
@@ -462,6 +1568,14 @@ func createEngine(config *Config, scanResult *scanner.ScanResult) (backend.Backe
 		logger.Info("Using automatic deletion method selection")
 	}
 
+	if owner != nil {
+		backendInstance = privilege.NewOwnerGuardBackend(backendInstance, *owner)
+		logger.Info("Only entries owned by uid %d will be deleted", owner.UID)
+	}
+	if remover != nil {
+		backendInstance = sandbox.NewTargetBackend(backendInstance, remover)
+	}
+
 	reporter := progress.NewReporter(scanResult.TotalToDelete, scanResult.TotalSizeBytes)
 
 	eng := engine.NewEngineWithBufferSize(backendInstance, config.Workers, config.BufferSize, func(deletedCount int) {
@@ -497,11 +1611,19 @@ func startMonitor(config *Config, ctx context.Context, eng *engine.Engine) inter
 	return mon
 }
 
-// displayResults shows final statistics, monitoring report, and returns the appropriate exit code.
-func displayResults(config *Config, result *engine.DeletionResult, backendInstance backend.Backend, scanResult *scanner.ScanResult, mon interface{}, reporter *progress.Reporter) int {
+// displayResults shows final statistics, the space reclaimed (if meter is set)
+// or held in the trash, monitoring report, and returns the appropriate exit code.
+func displayResults(config *Config, result *engine.DeletionResult, backendInstance backend.Backend, scanResult *scanner.ScanResult, mon interface{}, reporter *progress.Reporter, meter *diskspace.Meter) int {
+	reporter.SetRetainedDirs(scanResult.TotalRetainedDirs)
 	reporter.Finish(result.DeletedCount, result.FailedCount, scanResult.TotalRetained)
 
 	displayCompletionReport(result, backendInstance)
+	displaySpaceReclaimed(meter, scanResult)
+	if config.Trash && !config.DryRun {
+		fmt.Printf("Space held in the trash: %s (estimated), freed once the trash is emptied\n",
+			progress.FormatBytes(scanResult.Space.AllocatedBytes))
+		fmt.Println()
+	}
 
 	if config.Monitor && mon != nil {
 		if winMon, ok := mon.(*monitor.WindowsMonitor); ok {
@@ -840,11 +1962,14 @@ func displayCompletionReport(result *engine.DeletionResult, backendInstance back
 	fmt.Println()
 
 	// Display basic statistics
-	fmt.Printf("Total files processed:  %s\n", progress.FormatNumber(result.DeletedCount+result.FailedCount))
+	fmt.Printf("Total files processed:  %s\n", progress.FormatNumber(result.DeletedCount+result.FailedCount+result.ChangedCount))
 	fmt.Printf("Successfully deleted:   %s files\n", progress.FormatNumber(result.DeletedCount))
 	if result.FailedCount > 0 {
 		fmt.Printf("Failed to delete:       %s files\n", progress.FormatNumber(result.FailedCount))
 	}
+	if result.ChangedCount > 0 {
+		fmt.Printf("Changed since scan:     %s files (skipped)\n", progress.FormatNumber(result.ChangedCount))
+	}
 	fmt.Println()
 
 	// Display timing and performance metrics
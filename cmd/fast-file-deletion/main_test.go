@@ -8,7 +8,9 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
 	"pgregory.net/rapid"
 )
 
@@ -2033,3 +2035,563 @@ func TestValidateConfigBoundaryValues(t *testing.T) {
 		})
 	}
 }
+
+// parseTestArgs runs parseArguments against the given command line (without the
+// program name) using a fresh flag set.
+func parseTestArgs(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet("fast-file-deletion", flag.ContinueOnError)
+	os.Args = append([]string{"fast-file-deletion"}, args...)
+
+	return parseArguments()
+}
+
+// TestRevalidateFlagParsing tests the --revalidate flag and its default.
+func TestRevalidateFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Revalidate {
+		t.Error("Expected Revalidate to default to false")
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--keep-days", "7", "--revalidate")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.Revalidate {
+		t.Error("Expected Revalidate to be true")
+	}
+}
+
+// TestSandboxFlagParsing tests the --sandbox flag and its default.
+func TestSandboxFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Sandbox {
+		t.Error("Expected Sandbox to default to false")
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--force", "--sandbox")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.Sandbox {
+		t.Error("Expected Sandbox to be true")
+	}
+}
+
+// TestRunAsOwnerFlagParsing tests parsing of the --run-as-owner flag.
+func TestRunAsOwnerFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.RunAsOwner {
+		t.Error("Expected RunAsOwner to default to false")
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--force", "--run-as-owner")
+	if runtime.GOOS == "windows" {
+		if err == nil {
+			t.Error("Expected --run-as-owner to be rejected on Windows")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.RunAsOwner {
+		t.Error("Expected RunAsOwner to be true")
+	}
+}
+
+// TestWaitForLockFlagParsing tests parsing and validation of the --wait-for-lock flag.
+func TestWaitForLockFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.WaitForLock != 0 {
+		t.Errorf("Expected WaitForLock to default to 0, got %s", config.WaitForLock)
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--wait-for-lock", "5m")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.WaitForLock != 5*time.Minute {
+		t.Errorf("Expected WaitForLock 5m, got %s", config.WaitForLock)
+	}
+
+	if _, err := parseTestArgs(t, "-td", "/tmp/test", "--wait-for-lock", "-1s"); err == nil {
+		t.Error("Expected error for negative --wait-for-lock")
+	}
+}
+
+// TestIncludeExcludeFlagParsing tests that the pattern flags are repeatable and
+// that --exclude-from files are merged before --exclude patterns.
+func TestIncludeExcludeFlagParsing(t *testing.T) {
+	patternFile := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(patternFile, []byte("*.db\n"), 0644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+
+	config, err := parseTestArgs(t, "-td", "/tmp/test",
+		"--include", "*.tmp", "--include", "*.log",
+		"--exclude", "keep/", "--exclude-from", patternFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(config.Include) != 2 || config.Include[0] != "*.tmp" || config.Include[1] != "*.log" {
+		t.Errorf("Expected two include patterns, got %q", config.Include)
+	}
+	if len(config.Exclude) != 1 || config.Exclude[0] != "keep/" {
+		t.Errorf("Expected one exclude pattern, got %q", config.Exclude)
+	}
+	if len(config.ExcludeFrom) != 1 || config.ExcludeFrom[0] != patternFile {
+		t.Errorf("Expected one exclude file, got %q", config.ExcludeFrom)
+	}
+
+	if _, err := newScanner(config); err != nil {
+		t.Errorf("Expected scanner to be created: %v", err)
+	}
+
+	config.ExcludeFrom = []string{filepath.Join(t.TempDir(), "missing.txt")}
+	if _, err := newScanner(config); err == nil {
+		t.Error("Expected error for missing --exclude-from file")
+	}
+}
+
+// TestWhereFlagParsing tests that --where expressions are validated at parse time.
+func TestWhereFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--where", "size > 100M and mtime older than 7d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Where != "size > 100M and mtime older than 7d" {
+		t.Errorf("Expected Where to be stored, got %q", config.Where)
+	}
+
+	if _, err := parseTestArgs(t, "-td", "/tmp/test", "--where", "size >"); err == nil {
+		t.Error("Expected error for incomplete --where expression")
+	}
+	if _, err := parseTestArgs(t, "-td", "/tmp/test", "--where", "colour = red"); err == nil {
+		t.Error("Expected error for unknown --where field")
+	}
+}
+
+// TestAgeFlagParsing tests parsing and validation of the age filter flags.
+func TestAgeFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test",
+		"--older-than", "36h", "--newer-than", "2w", "--age-by", "atime",
+		"--reference-time", "2024-01-31T00:00:00Z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.OlderThan != 36*time.Hour || config.NewerThan != 14*24*time.Hour {
+		t.Errorf("Expected ages 36h and 2w, got %s and %s", config.OlderThan, config.NewerThan)
+	}
+	if config.AgeBy != "atime" {
+		t.Errorf("Expected AgeBy atime, got %q", config.AgeBy)
+	}
+	if !config.ReferenceTime.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected reference time %v", config.ReferenceTime)
+	}
+
+	invalid := [][]string{
+		{"--older-than", "7"},
+		{"--newer-than", "soon"},
+		{"--age-by", "utime"},
+		{"--reference-time", "yesterday"},
+		{"--keep-days", "7", "--older-than", "7d"},
+		{"--older-than", "7d", "--newer-than", "1d"},
+	}
+	for _, args := range invalid {
+		if _, err := parseTestArgs(t, append([]string{"-td", "/tmp/test"}, args...)...); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestKeepMarkerFlagParsing tests the keep-marker and policy file defaults and validation.
+func TestKeepMarkerFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.KeepMarker != ".ffdkeep" || config.PolicyFile != ".ffdpolicy" {
+		t.Errorf("Expected default markers .ffdkeep and .ffdpolicy, got %q and %q", config.KeepMarker, config.PolicyFile)
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--keep-marker", "KEEP", "--no-policy-file")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.KeepMarker != "KEEP" || config.PolicyFile != "" {
+		t.Errorf("Expected KEEP and disabled policy file, got %q and %q", config.KeepMarker, config.PolicyFile)
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--no-keep-marker")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.KeepMarker != "" || config.PolicyFile != ".ffdpolicy" {
+		t.Errorf("Expected disabled keep-marker and .ffdpolicy, got %q and %q", config.KeepMarker, config.PolicyFile)
+	}
+
+	for _, bad := range []string{"sub/.keep", "..", `a\b`} {
+		if _, err := parseTestArgs(t, "-td", "/tmp/test", "--keep-marker", bad); err == nil {
+			t.Errorf("Expected error for --keep-marker %q", bad)
+		}
+	}
+}
+
+// TestRetentionLimitFlagParsing tests parsing and validation of --keep-newest,
+// --keep-newest-group and --max-total-size.
+func TestRetentionLimitFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test",
+		"--keep-newest", "10", "--keep-newest-group", "app-*.log", "--keep-newest-group", "db-*.log",
+		"--max-total-size", "200G")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.KeepNewest != 10 || len(config.KeepGroups) != 2 || config.KeepGroups[1] != "db-*.log" {
+		t.Errorf("Expected keep-newest 10 with two groups, got %d %v", config.KeepNewest, config.KeepGroups)
+	}
+	if config.MaxTotalSize != 200<<30 {
+		t.Errorf("Expected 200 GiB size limit, got %d", config.MaxTotalSize)
+	}
+
+	invalid := [][]string{
+		{"--keep-newest", "-1"},
+		{"--keep-newest-group", "*.log"},
+		{"--keep-newest", "3", "--keep-newest-group", `bad\`},
+		{"--max-total-size", "lots"},
+	}
+	for _, args := range invalid {
+		if _, err := parseTestArgs(t, append([]string{"-td", "/tmp/test"}, args...)...); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestTmpfilesConfigFlagParsing tests that --tmpfiles-config replaces the target
+// directory, collects shell-expanded files, and rejects conflicting flags.
+func TestTmpfilesConfigFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "--tmpfiles-config", "/etc/tmpfiles.d/a.conf", "/etc/tmpfiles.d/b.conf",
+		"--tmpfiles-config", "/usr/lib/tmpfiles.d/*.conf")
+	if err == nil {
+		t.Errorf("Expected an error for a flag after the expanded files, got %v", config.TmpfilesConfig)
+	}
+
+	config, err = parseTestArgs(t, "--force", "--tmpfiles-config", "/etc/tmpfiles.d/a.conf", "/etc/tmpfiles.d/b.conf")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.Join(config.TmpfilesConfig, " "); got != "/etc/tmpfiles.d/a.conf /etc/tmpfiles.d/b.conf" {
+		t.Errorf("Expected both files, got %q", got)
+	}
+	if config.TargetDir != "" {
+		t.Errorf("Expected no target directory, got %q", config.TargetDir)
+	}
+
+	invalid := [][]string{
+		{"-td", "/tmp/test"},
+		{"--sandbox"},
+		{"--run-as-owner"},
+		{"--keep-days", "3"},
+		{"--older-than", "2d"},
+	}
+	for _, args := range invalid {
+		if _, err := parseTestArgs(t, append(args, "--tmpfiles-config", "/etc/tmpfiles.d/a.conf")...); err == nil {
+			t.Errorf("Expected error for %v with --tmpfiles-config", args)
+		}
+	}
+}
+
+// TestAgeFromNameFlagParsing tests the --age-from-name flags and their validation.
+func TestAgeFromNameFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--older-than", "30d",
+		"--age-from-name", "2006-01-02T1504", "--age-from-name-fallback", "timestamp")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.AgeFromName != "2006-01-02T1504" || config.NameFallback != "timestamp" {
+		t.Errorf("Expected layout and timestamp fallback, got %q and %q", config.AgeFromName, config.NameFallback)
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--keep-newest", "5",
+		"--age-from-name", `(?P<year>\d{4})(?P<month>\d\d)(?P<day>\d\d)`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.NameFallback != "keep" {
+		t.Errorf("Expected the fallback to default to keep, got %q", config.NameFallback)
+	}
+
+	invalid := [][]string{
+		{"--age-from-name", "2006-01-02"},                            // No age or retention limit
+		{"--older-than", "1d", "--age-from-name", "Jan 2 2006"},      // Variable width
+		{"--older-than", "1d", "--age-from-name", `(?P<week>\d\d)`},  // Unknown group
+		{"--older-than", "1d", "--age-from-name-fallback", "delete"}, // Fallback without names
+		{"--older-than", "1d", "--age-from-name", "20060102", "--age-from-name-fallback", "mtime"},
+	}
+	for _, args := range invalid {
+		if _, err := parseTestArgs(t, append([]string{"-td", "/tmp/test"}, args...)...); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestStrictScanFlagParsing tests parsing of the --strict-scan flag.
+func TestStrictScanFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.StrictScan {
+		t.Error("Expected StrictScan to default to false")
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--force", "--strict-scan")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.StrictScan {
+		t.Error("Expected StrictScan to be true")
+	}
+}
+
+// TestPlanFlagParsing tests --plan-out and --plan-in and the options --plan-in
+// cannot be combined with.
+func TestPlanFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--dry-run", "--plan-out", "review.plan")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.PlanOut != "review.plan" || config.PlanIn != "" {
+		t.Errorf("Expected PlanOut review.plan, got %+v", config)
+	}
+
+	config, err = parseTestArgs(t, "--plan-in", "review.plan", "--force")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config == nil || config.PlanIn != "review.plan" || config.TargetDir != "" {
+		t.Errorf("Expected PlanIn review.plan without a target, got %+v", config)
+	}
+
+	for _, args := range [][]string{
+		{"--plan-in", "review.plan", "-td", "/tmp/test"},
+		{"--plan-in", "review.plan", "--keep-days", "3"},
+		{"--plan-in", "review.plan", "--exclude", "*.db"},
+		{"--plan-in", "review.plan", "--plan-out", "other.plan"},
+		{"--tmpfiles-config", "a.conf", "--plan-out", "review.plan"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// TestEstimateFlagParsing tests the --estimate flag and the modes it cannot be
+// combined with.
+func TestEstimateFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--estimate", "--older-than", "7d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.Estimate {
+		t.Errorf("Expected Estimate to be set, got %+v", config)
+	}
+
+	for _, args := range [][]string{
+		{"--plan-in", "review.plan", "--estimate"},
+		{"-td", "/tmp/test", "--estimate", "--plan-out", "review.plan"},
+		{"--tmpfiles-config", "a.conf", "--estimate"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// TestKeepDirsFlagParsing tests --contents-only and --files-only and the
+// options they cannot be combined with.
+func TestKeepDirsFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--contents-only")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.ContentsOnly || config.FilesOnly || keepDirs(config.ContentsOnly, config.FilesOnly) != scanner.KeepRoot {
+		t.Errorf("Expected ContentsOnly to keep the root, got %+v", config)
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--files-only", "--older-than", "7d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.FilesOnly || keepDirs(config.ContentsOnly, config.FilesOnly) != scanner.KeepAllDirs {
+		t.Errorf("Expected FilesOnly to keep all directories, got %+v", config)
+	}
+
+	for _, args := range [][]string{
+		{"-td", "/tmp/test", "--contents-only", "--files-only"},
+		{"--plan-in", "review.plan", "--contents-only"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// TestSelectionFlagParsing tests --empty-dirs and --broken-symlinks and the
+// options they cannot be combined with.
+func TestSelectionFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--empty-dirs", "--broken-symlinks", "--older-than", "30d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := scanner.SelectEmptyDirs | scanner.SelectBrokenSymlinks; selection(config.EmptyDirs, config.BrokenSymlinks) != want {
+		t.Errorf("Expected both kinds to be selected, got %+v", config)
+	}
+	if selection(false, false) != 0 {
+		t.Errorf("Expected no selection without the flags")
+	}
+
+	for _, args := range [][]string{
+		{"-td", "/tmp/test", "--empty-dirs", "--files-only"},
+		{"-td", "/tmp/test", "--broken-symlinks", "--keep-newest", "3"},
+		{"--plan-in", "review.plan", "--empty-dirs"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// TestSweepFlagParsing tests the --sweep flags and the options they cannot be
+// combined with.
+func TestSweepFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--sweep", "--sweep-preset", "node",
+		"--sweep-preset", "rust", "--sweep-presets", "extra.conf", "--older-than", "30d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.Sweep || len(config.SweepPresets) != 2 || config.SweepPresets[1] != "rust" ||
+		len(config.PresetFiles) != 1 || config.PresetFiles[0] != "extra.conf" {
+		t.Errorf("Expected the sweep settings to be parsed, got %+v", config)
+	}
+
+	for _, args := range [][]string{
+		{"-td", "/tmp/test", "--sweep-preset", "node"},
+		{"-td", "/tmp/test", "--sweep", "--estimate"},
+		{"-td", "/tmp/test", "--sweep", "--empty-dirs"},
+		{"-td", "/tmp/test", "--sweep", "--contents-only"},
+		{"--plan-in", "review.plan", "--sweep"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// TestGitCleanFlagParsing tests the --git-clean modes and the options it
+// cannot be combined with.
+func TestGitCleanFlagParsing(t *testing.T) {
+	for _, mode := range []string{"ignored", "untracked"} {
+		config, err := parseTestArgs(t, "-td", "/tmp/test", "--git-clean", mode, "--older-than", "7d")
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", mode, err)
+		}
+		if config.GitClean != mode {
+			t.Errorf("Expected GitClean %q, got %q", mode, config.GitClean)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-td", "/tmp/test", "--git-clean", "everything"},
+		{"-td", "/tmp/test", "--git-clean", "ignored", "--estimate"},
+		{"--plan-in", "review.plan", "--git-clean", "ignored"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// TestDedupeFlagParsing tests that --dedupe and its options are parsed, and
+// that the options require --dedupe.
+func TestDedupeFlagParsing(t *testing.T) {
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--dedupe", "--dedupe-keep", "shortest-path",
+		"--dedupe-prefer", "releases", "--dedupe-prefer", "/srv/master", "--dedupe-link", "--older-than", "7d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.Dedupe || config.DedupeKeep != "shortest-path" || !config.DedupeLink {
+		t.Errorf("Expected --dedupe with shortest-path and links, got %+v", config)
+	}
+	if want := []string{"releases", "/srv/master"}; strings.Join(config.DedupePrefer, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected DedupePrefer %v, got %v", want, config.DedupePrefer)
+	}
+
+	config, err = parseTestArgs(t, "-td", "/tmp/test", "--dedupe")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.DedupeKeep != "oldest" {
+		t.Errorf("Expected DedupeKeep oldest by default, got %q", config.DedupeKeep)
+	}
+
+	for _, args := range [][]string{
+		{"-td", "/tmp/test", "--dedupe", "--dedupe-keep", "newest"},
+		{"-td", "/tmp/test", "--dedupe-link"},
+		{"-td", "/tmp/test", "--dedupe-prefer", "releases"},
+		{"-td", "/tmp/test", "--dedupe", "--estimate"},
+		{"-td", "/tmp/test", "--dedupe", "--empty-dirs"},
+		{"-td", "/tmp/test", "--dedupe", "--dedupe-link", "--plan-out", "dedupe.plan"},
+		{"-td", "/tmp/test", "--dedupe", "--plan-out", "dedupe.plan"},
+		{"--plan-in", "review.plan", "--dedupe"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// TestTrashFlagParsing tests that --trash is parsed where the FreeDesktop
+// trash is available and refuses options that conflict with it.
+func TestTrashFlagParsing(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		if _, err := parseTestArgs(t, "-td", "/tmp/test", "--trash"); err == nil {
+			t.Errorf("Expected --trash to be refused on %s", runtime.GOOS)
+		}
+		return
+	}
+
+	config, err := parseTestArgs(t, "-td", "/tmp/test", "--trash", "--older-than", "7d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.Trash {
+		t.Error("Expected Trash to be set")
+	}
+
+	for _, args := range [][]string{
+		{"-td", "/tmp/test", "--trash", "--sandbox"},
+		{"-td", "/tmp/test", "--trash", "--run-as-owner"},
+		{"-td", "/tmp/test", "--trash", "--dedupe", "--dedupe-link"},
+	} {
+		if _, err := parseTestArgs(t, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
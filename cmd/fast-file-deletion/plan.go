@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+	"github.com/yourusername/fast-file-deletion/internal/progress"
+	"github.com/yourusername/fast-file-deletion/internal/runlock"
+	"github.com/yourusername/fast-file-deletion/internal/safety"
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// writePlanFile writes the scan result to the --plan-out file. Returns false if
+// the plan could not be written, in which case nothing may be deleted: whoever
+// reviews the plan must see what was deleted.
+func writePlanFile(config *Config, scanResult *scanner.ScanResult) bool {
+	err := writePlan(config.PlanOut, scanResult, planSettings())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Failed to write the deletion plan: %v\n\n", err)
+		logger.Error("Failed to write deletion plan %s: %v", config.PlanOut, err)
+		return false
+	}
+
+	fmt.Printf("\n📝 Deletion plan written to %s (%s entries, %s)\n",
+		config.PlanOut, progress.FormatNumber(scanResult.TotalToDelete), progress.FormatBytes(scanResult.TotalSizeBytes))
+	fmt.Printf("   Execute it with: fast-file-deletion --plan-in %s\n", config.PlanOut)
+	logger.Info("Deletion plan written to %s: %d entries", config.PlanOut, scanResult.TotalToDelete)
+	return true
+}
+
+// writePlan writes a plan file, removing it again if it could not be completed.
+func writePlan(path string, scanResult *scanner.ScanResult, settings map[string]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = scanner.WritePlan(file, scanResult, settings)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// planSettings returns the command-line options that were set, for the plan's
+// reviewers. Options that only affect how the plan is written are left out.
+func planSettings() map[string]string {
+	settings := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "plan-out" {
+			settings[f.Name] = f.Value.String()
+		}
+	})
+	return settings
+}
+
+// loadPlan reads the --plan-in plan, validates and locks its root directory, checks
+// that the root is still the directory that was scanned, and obtains user
+// confirmation. Entries are revalidated before deletion, so any entry replaced or
+// modified since the plan was made is skipped.
+// Returns the plan's entries, the run lock and exit code like scanAndConfirm.
+func loadPlan(config *Config) (*scanner.ScanResult, *runlock.Lock, int) {
+	file, err := os.Open(config.PlanIn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Cannot open the deletion plan: %v\n\n", err)
+		logger.Error("Cannot open deletion plan: %v", err)
+		return nil, nil, 2
+	}
+	plan, err := scanner.ReadPlan(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Cannot read the deletion plan %s: %v\n\n", config.PlanIn, err)
+		logger.Error("Cannot read deletion plan %s: %v", config.PlanIn, err)
+		return nil, nil, 2
+	}
+	displayPlan(config.PlanIn, plan.Header)
+
+	// The plan decides the target, and its identities are always checked
+	config.TargetDir = plan.Header.Root
+	config.Revalidate = true
+
+	isSafe, reason := safety.IsSafePath(config.TargetDir)
+	if !isSafe {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Cannot delete this path\n")
+		fmt.Fprintf(os.Stderr, "   Reason: %s\n\n", reason)
+		logger.Error("Path validation failed: %s", reason)
+		return nil, nil, 2
+	}
+
+	runLock := acquireRunLock(config)
+	if runLock == nil {
+		return nil, nil, 2
+	}
+
+	if err := plan.CheckRoot(); err != nil {
+		runLock.Release()
+		fmt.Fprintf(os.Stderr, "\n❌ Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "   Nothing will be deleted; scan the directory again to make a new plan\n\n")
+		logger.Error("Refusing deletion plan %s: %v", config.PlanIn, err)
+		return nil, nil, 2
+	}
+
+	if !confirmDeletion(config, plan.Result) {
+		runLock.Release()
+		return nil, nil, 0
+	}
+	return plan.Result, runLock, 0
+}
+
+// displayPlan describes a deletion plan: where and when it was made, with which
+// options, and what it deletes.
+func displayPlan(path string, header scanner.PlanHeader) {
+	fmt.Printf("\nDeletion plan: %s\n", path)
+	fmt.Printf("   Directory: %s\n", header.Root)
+	fmt.Printf("   Created:   %s\n", header.Created.Local().Format(time.RFC1123))
+	fmt.Printf("   Entries:   %s (%s, %s on disk)\n", progress.FormatNumber(header.Entries),
+		progress.FormatBytes(header.Bytes), progress.FormatBytes(header.AllocatedBytes))
+	if header.Warnings > 0 {
+		fmt.Printf("   The scan had %d warnings; the entries concerned are not in the plan\n", header.Warnings)
+	}
+	if len(header.Settings) > 0 {
+		names := make([]string, 0, len(header.Settings))
+		for name := range header.Settings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("   Scan options:")
+		for _, name := range names {
+			fmt.Printf("     --%s=%s\n", name, header.Settings[name])
+		}
+	}
+	logger.Info("Deletion plan %s: %d entries in %s, created %s", path, header.Entries, header.Root, header.Created)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+	"github.com/yourusername/fast-file-deletion/internal/progress"
+	"github.com/yourusername/fast-file-deletion/internal/safety"
+	"github.com/yourusername/fast-file-deletion/internal/sweep"
+)
+
+// chooseSweepTargets finds the build artifact directories below the target
+// directory, lists them by project with their sizes, and asks which to delete
+// (all of them with --force). Returns the chosen directories relative to the
+// target, for the scan to be restricted to, and an exit code. A nil list means
+// the caller should return the exit code: 0 when nothing was found or chosen,
+// 2 on error.
+func chooseSweepTargets(config *Config) ([]string, int) {
+	isSafe, reason := safety.IsSafePath(config.TargetDir)
+	if !isSafe {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Cannot sweep this path\n")
+		fmt.Fprintf(os.Stderr, "   Reason: %s\n\n", reason)
+		logger.Error("Path validation failed: %s", reason)
+		return nil, 2
+	}
+
+	presets, err := sweepPresets(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: %v\n\n", err)
+		logger.Error("Invalid sweep presets: %v", err)
+		return nil, 2
+	}
+
+	fmt.Printf("\nSearching %s for build artifacts...\n", config.TargetDir)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	result, err := sweep.Find(ctx, config.TargetDir, presets)
+	stop()
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("\n❌ Search cancelled.")
+		logger.Info("Sweep search cancelled by user")
+		return nil, 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Failed to search directory: %v\n\n", err)
+		logger.Error("Sweep search failed: %v", err)
+		return nil, 2
+	}
+
+	artifacts := result.Artifacts()
+	if result.Unreadable > 0 {
+		fmt.Printf("\n⚠️  %d directories could not be read and were not searched\n", result.Unreadable)
+	}
+	if len(artifacts) == 0 {
+		fmt.Println("\n✓ No build artifacts found.")
+		logger.Info("No build artifacts found, exiting")
+		return nil, 0
+	}
+	displaySweepArtifacts(config, result)
+
+	var chosen []int
+	if config.Force {
+		chosen, _ = sweep.ParseSelection("all", len(artifacts))
+	} else {
+		chosen, err = promptSweepSelection(len(artifacts))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n❌ Error: %v\n\n", err)
+			logger.Error("Invalid sweep selection: %v", err)
+			return nil, 2
+		}
+	}
+	if len(chosen) == 0 {
+		fmt.Println("\n❌ Nothing selected.")
+		logger.Info("No build artifacts selected, exiting")
+		return nil, 0
+	}
+
+	subtrees := make([]string, len(chosen))
+	for i, index := range chosen {
+		subtrees[i] = artifacts[index].Rel
+		logger.Info("Sweeping %s (%s, %s)", artifacts[index].Path, artifacts[index].Preset, progress.FormatBytes(artifacts[index].Bytes))
+	}
+	return subtrees, 0
+}
+
+// sweepPresets returns the presets chosen with --sweep-preset, or all of them,
+// from the built-in presets, the user's preset file and the --sweep-presets
+// files.
+func sweepPresets(config *Config) ([]sweep.Preset, error) {
+	presets := sweep.Builtin()
+	var err error
+	if file := sweep.DefaultPresetFile(); file != "" {
+		if presets, err = sweep.LoadPresets(presets, []string{file}, true); err != nil {
+			return nil, err
+		}
+	}
+	if presets, err = sweep.LoadPresets(presets, config.PresetFiles, false); err != nil {
+		return nil, err
+	}
+	if len(config.SweepPresets) == 0 {
+		return presets, nil
+	}
+	return sweep.Choose(presets, config.SweepPresets)
+}
+
+// displaySweepArtifacts lists the artifacts found, numbered for selection and
+// grouped by project, largest first.
+func displaySweepArtifacts(config *Config, result *sweep.Result) {
+	artifacts := result.Artifacts()
+	fmt.Printf("\nFound %d build artifact directories in %d projects (%s):\n",
+		len(artifacts), len(result.Projects), progress.FormatBytes(result.Bytes()))
+
+	number := 1
+	for _, project := range result.Projects {
+		dir, err := filepath.Rel(config.TargetDir, project.Dir)
+		if err != nil {
+			dir = project.Dir
+		}
+		fmt.Printf("\n  %s  %s\n", dir, progress.FormatBytes(project.Bytes))
+		for _, a := range project.Artifacts {
+			fmt.Printf("   %3d. %-16s %10s  %8s entries  (%s, %s)\n", number, filepath.Base(a.Path),
+				progress.FormatBytes(a.Bytes), progress.FormatNumber(a.Entries), a.Preset, a.Marker)
+			number++
+		}
+	}
+}
+
+// promptSweepSelection asks which of the n artifacts to delete.
+func promptSweepSelection(n int) ([]int, error) {
+	fmt.Println()
+	fmt.Printf("Select the directories to delete: all, none, or numbers and ranges (e.g. 1,3,5-%d):\n", max(n, 5))
+	fmt.Print("> ")
+	line, err := readLine()
+	if err != nil {
+		logger.Warning("Failed to read user input: %v", err)
+		return nil, nil
+	}
+	return sweep.ParseSelection(line, n)
+}
+
+// readLine reads one line from standard input without buffering past it, so
+// that the confirmation prompt that follows reads its own line.
+func readLine() (string, error) {
+	var line strings.Builder
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				return line.String(), nil
+			}
+			line.WriteByte(b[0])
+		}
+		if err != nil {
+			if line.Len() > 0 {
+				return line.String(), nil
+			}
+			return "", err
+		}
+	}
+}
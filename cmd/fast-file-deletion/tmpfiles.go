@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/engine"
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+	"github.com/yourusername/fast-file-deletion/internal/tmpfiles"
+)
+
+// ruleOutcome is what one tmpfiles.d rule did to one of its directories.
+type ruleOutcome struct {
+	rule     tmpfiles.Rule
+	dir      string // Empty when the rule matched no directory
+	scanned  int
+	deleted  int
+	failed   int
+	retained int
+	note     string // Why nothing was deleted, if nothing was
+	err      bool   // The rule could not be run
+}
+
+// runTmpfilesMode runs the age cleanup rules of the tmpfiles.d files named by
+// --tmpfiles-config, one directory at a time, with the usual scan, confirmation
+// and deletion of each directory, then reports the outcome per rule.
+//
+// Returns an exit code: 0 for success, 1 if any rule failed, 2 if the
+// configuration could not be read.
+func runTmpfilesMode(config *Config) int {
+	tmpConfig, err := tmpfiles.ReadFiles(config.TmpfilesConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: %v\n\n", err)
+		logger.Error("Failed to read tmpfiles.d configuration: %v", err)
+		return 2
+	}
+
+	fmt.Println()
+	for _, warning := range tmpConfig.Warnings {
+		fmt.Printf("⚠️  Skipping %s\n", warning)
+		logger.Warning("Skipping tmpfiles.d line %s", warning)
+	}
+	fmt.Printf("Read %d cleanup rules and %d exclusions from tmpfiles.d\n", len(tmpConfig.Rules), len(tmpConfig.Exclusions))
+	logger.Info("tmpfiles.d: %d cleanup rules, %d exclusions, %d lines skipped",
+		len(tmpConfig.Rules), len(tmpConfig.Exclusions), len(tmpConfig.Warnings))
+
+	// Every rule measures ages from the same instant, as systemd-tmpfiles does
+	reference := config.ReferenceTime
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+
+	ctx, cancel := engine.SetupInterruptHandler()
+	defer cancel()
+
+	var outcomes []ruleOutcome
+	for _, rule := range tmpConfig.Rules {
+		dirs, err := rule.Expand()
+		if err != nil {
+			logger.Error("%v", err)
+			outcomes = append(outcomes, ruleOutcome{rule: rule, note: err.Error(), err: true})
+			continue
+		}
+		if len(dirs) == 0 {
+			logger.Info("Skipping %s (%s): no such directory", rule, rule.Source)
+			outcomes = append(outcomes, ruleOutcome{rule: rule, note: "no such directory"})
+			continue
+		}
+		for _, dir := range dirs {
+			if ctx.Err() != nil {
+				outcomes = append(outcomes, ruleOutcome{rule: rule, dir: dir, note: "interrupted"})
+				continue
+			}
+			outcomes = append(outcomes, runTmpfilesRule(ctx, config, rule, dir, tmpConfig.Exclusions, reference))
+		}
+	}
+
+	return displayTmpfilesSummary(outcomes)
+}
+
+// runTmpfilesRule cleans one directory of a tmpfiles.d rule.
+func runTmpfilesRule(ctx context.Context, config *Config, rule tmpfiles.Rule, dir string, exclusions []tmpfiles.Exclusion, reference time.Time) ruleOutcome {
+	fmt.Printf("\n── %s (%s)\n", rule, rule.Source)
+	logger.Info("Running %s (%s) on %s", rule, rule.Source, dir)
+
+	ruleConfig := *config
+	ruleConfig.TargetDir = dir
+	ruleConfig.RuleFilter = rule.Filter(reference, exclusions)
+	outcome := ruleOutcome{rule: rule, dir: dir}
+
+	scanResult, runLock, exitCode := scanTarget(&ruleConfig)
+	if scanResult == nil {
+		outcome.note, outcome.err = "could not be scanned", true
+		if exitCode == 0 {
+			outcome.note, outcome.err = "interrupted", false
+		}
+		return outcome
+	}
+	defer runLock.Release()
+	outcome.scanned, outcome.retained = scanResult.TotalScanned, scanResult.TotalRetained
+
+	if !confirmDeletion(&ruleConfig, scanResult) {
+		outcome.note = "cancelled"
+		if scanResult.TotalToDelete == 0 {
+			outcome.note = "nothing old enough"
+		}
+		return outcome
+	}
+
+	backendInstance, eng, reporter := createEngine(&ruleConfig, scanResult, nil, nil)
+	if ruleConfig.Revalidate {
+		eng.SetRevalidator(func(index int, _ string) bool {
+			return scanResult.Revalidate(index)
+		})
+	}
+
+	// Stop this rule's monitor once its deletion is done
+	ruleCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	mon := startMonitor(&ruleConfig, ruleCtx, eng)
+
+	fmt.Println()
+	if ruleConfig.DryRun {
+		fmt.Println("Starting dry run (no files will be deleted)...")
+	} else {
+		fmt.Println("Starting deletion...")
+	}
+
+	meter := startSpaceMeter(&ruleConfig, scanResult)
+	result, err := eng.DeleteEntries(ruleCtx, scanResult, ruleConfig.DryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Error: Deletion failed: %v\n\n", err)
+		logger.Error("Deletion failed for %s: %v", dir, err)
+		outcome.note, outcome.err = "deletion failed", true
+		return outcome
+	}
+	recordRun(&ruleConfig, scanResult, result)
+
+	displayResults(&ruleConfig, result, backendInstance, scanResult, mon, reporter, meter)
+	outcome.deleted, outcome.failed = result.DeletedCount, result.FailedCount
+	if ctx.Err() != nil {
+		outcome.note = "interrupted"
+	}
+	return outcome
+}
+
+// displayTmpfilesSummary prints one line per rule and directory and returns the
+// exit code of the whole run.
+func displayTmpfilesSummary(outcomes []ruleOutcome) int {
+	fmt.Println()
+	fmt.Println("═══════════════════════════════════════════════════════════════════════════")
+	fmt.Println("                         TMPFILES.D CLEANUP")
+	fmt.Println("═══════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-40s %9s %9s %7s %9s\n", "Rule / Directory", "Scanned", "Deleted", "Failed", "Retained")
+	fmt.Println("───────────────────────────────────────────────────────────────────────────")
+
+	exitCode := 0
+	for _, o := range outcomes {
+		if o.err || o.failed > 0 {
+			exitCode = 1
+		}
+		fmt.Printf("%s  (%s)\n", o.rule, o.rule.Source)
+		dir := o.dir
+		if dir == "" {
+			dir = "-"
+		}
+		line := fmt.Sprintf("  %-38s %9d %9d %7d %9d", dir, o.scanned, o.deleted, o.failed, o.retained)
+		if o.note != "" {
+			line += "  " + o.note
+		}
+		fmt.Println(line)
+		logger.Info("tmpfiles.d %s on %s: %d scanned, %d deleted, %d failed, %d retained",
+			o.rule, dir, o.scanned, o.deleted, o.failed, o.retained)
+	}
+	fmt.Println("═══════════════════════════════════════════════════════════════════════════")
+
+	if exitCode != 0 {
+		fmt.Println("⚠️  Warning: some rules did not complete; see above for details")
+	}
+	return exitCode
+}
@@ -2,21 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"github.com/yourusername/fast-file-deletion/internal/backend"
+	"github.com/yourusername/fast-file-deletion/internal/dedupe"
+	"github.com/yourusername/fast-file-deletion/internal/diskspace"
 	"github.com/yourusername/fast-file-deletion/internal/engine"
+	"github.com/yourusername/fast-file-deletion/internal/gitclean"
 	"github.com/yourusername/fast-file-deletion/internal/logger"
 	"github.com/yourusername/fast-file-deletion/internal/monitor"
 	"github.com/yourusername/fast-file-deletion/internal/progress"
+	"github.com/yourusername/fast-file-deletion/internal/runlock"
 	"github.com/yourusername/fast-file-deletion/internal/safety"
 	"github.com/yourusername/fast-file-deletion/internal/scanner"
 )
@@ -28,6 +35,7 @@ type App struct {
 	engine             *engine.Engine
 	monitor            interface{}
 	cancelFunc         context.CancelFunc
+	scanCancel         context.CancelFunc // Cancels a running ScanDirectory
 	backendInst        backend.Backend
 	reporter           *progress.Reporter
 	lastScanResult     *scanner.ScanResult
@@ -48,6 +56,30 @@ type Config struct {
 	DeletionMethod string  `json:"deletionMethod"`
 	Benchmark      bool    `json:"benchmark"`
 	Monitor        bool    `json:"monitor"`
+	Revalidate     bool    `json:"revalidate"`
+	StrictScan     bool    `json:"strictScan"`
+	Include        []string `json:"include"`
+	Exclude        []string `json:"exclude"`
+	Where          string   `json:"where"`
+	AgeBy          string   `json:"ageBy"`
+	OlderThan      string   `json:"olderThan"`
+	NewerThan      string   `json:"newerThan"`
+	KeepMarker     string   `json:"keepMarker"`
+	PolicyFile     string   `json:"policyFile"`
+	KeepNewest     int      `json:"keepNewest"`
+	KeepGroups     []string `json:"keepNewestGroups"`
+	MaxTotalSize   string   `json:"maxTotalSize"`
+	AgeFromName    string   `json:"ageFromName"`
+	NameFallback   string   `json:"ageFromNameFallback"`
+	ContentsOnly   bool     `json:"contentsOnly"`   // Keep the target directory itself
+	FilesOnly      bool     `json:"filesOnly"`      // Keep every directory, deleting files only
+	EmptyDirs      bool     `json:"emptyDirs"`      // Only delete empty directories
+	BrokenSymlinks bool     `json:"brokenSymlinks"` // Only delete symbolic links whose target is missing
+	GitClean       string   `json:"gitClean"`       // Only delete ignored or untracked git files ("" = disabled)
+	Dedupe         bool     `json:"dedupe"`         // Only delete the copies of duplicate files
+	DedupeKeep     string   `json:"dedupeKeep"`     // Copy of duplicate files that is kept: oldest, shortest-path
+	DedupePrefer   []string `json:"dedupePrefer"`   // Directories whose copies are kept first
+	Trash          bool     `json:"trash"`          // Move entries into the trash instead of deleting them
 }
 
 // ValidationResult holds the result of path validation
@@ -61,7 +93,56 @@ type ScanResult struct {
 	TotalScanned  int   `json:"totalScanned"`
 	TotalToDelete int   `json:"totalToDelete"`
 	TotalRetained int   `json:"totalRetained"`
+	TotalRetainedDirs int `json:"totalRetainedDirs"`
+	Protected     []string `json:"protected"`
 	TotalSizeBytes int64 `json:"totalSizeBytes"`
+	Warnings        []string `json:"warnings"`
+	SkippedSubtrees int      `json:"skippedSubtrees"`
+	ApparentBytes   int64    `json:"apparentBytes"`   // File sizes, hardlinked files counted once
+	AllocatedBytes  int64    `json:"allocatedBytes"`  // Disk blocks the deletion releases
+	LinkedBytes     int64    `json:"linkedBytes"`     // Blocks kept by links outside the deletion
+	HardlinkedFiles int      `json:"hardlinkedFiles"`
+	SpaceExact      bool     `json:"spaceExact"`
+	DuplicateGroups  int     `json:"duplicateGroups"`  // Groups of identical files found by dedupe
+	ReclaimableBytes int64   `json:"reclaimableBytes"` // Bytes removing their copies reclaims
+	Summary         *ScanSummary `json:"summary,omitempty"`
+}
+
+// ScanSummary breaks down the files to delete for the review screen
+type ScanSummary struct {
+	Subtrees          []SummaryGroup `json:"subtrees"`
+	SubtreesOmitted   int            `json:"subtreesOmitted"`
+	Extensions        []SummaryGroup `json:"extensions"`
+	ExtensionsOmitted int            `json:"extensionsOmitted"`
+	Owners            []SummaryGroup `json:"owners"`
+	OwnersOmitted     int            `json:"ownersOmitted"`
+	Ages              []AgeBucket    `json:"ages"`
+}
+
+// SummaryGroup is the number and size of the files to delete in one group
+type SummaryGroup struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// AgeBucket is one bar of the age histogram
+type AgeBucket struct {
+	Label string `json:"label"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// ScanProgress holds live scan progress, emitted as "scan:progress" events
+type ScanProgress struct {
+	Entries     int     `json:"entries"`
+	Dirs        int     `json:"dirs"`
+	DirsQueued  int     `json:"dirsQueued"`
+	Bytes       int64   `json:"bytes"`
+	CurrentPath string  `json:"currentPath"`
+	ElapsedMs   int64   `json:"elapsedMs"`
+	Rate        float64 `json:"rate"`
+	Done        bool    `json:"done"`
 }
 
 // LiveMetrics holds real-time deletion metrics
@@ -87,13 +168,17 @@ type SystemMetrics struct {
 type DeletionResult struct {
 	DeletedCount   int     `json:"deletedCount"`
 	FailedCount    int     `json:"failedCount"`
+	ChangedCount   int     `json:"changedCount"`
 	RetainedCount  int     `json:"retainedCount"`
+	RetainedDirs   int     `json:"retainedDirs"`
 	DurationMs     int64   `json:"durationMs"`
 	AverageRate    float64 `json:"averageRate"`
 	PeakRate       float64 `json:"peakRate"`
 	MethodStats    *MethodStats `json:"methodStats,omitempty"`
 	BottleneckReport string `json:"bottleneckReport,omitempty"`
 	Errors         []string `json:"errors,omitempty"`
+	ReclaimedBytes *int64   `json:"reclaimedBytes,omitempty"` // Growth of free space, when it could be measured
+	EstimatedBytes int64    `json:"estimatedBytes"`           // Space the scan expected to release
 }
 
 // MethodStats holds statistics about deletion methods used
@@ -106,8 +191,10 @@ type MethodStats struct {
 
 const (
 	// MaxScanResults limits the number of files that can be scanned
-	// to prevent memory exhaustion attacks
-	MaxScanResults = 10_000_000 // 10 million files
+	// to prevent memory exhaustion attacks. Scans are compact (an entry
+	// takes about 12 bytes plus its name), which allows more than the
+	// 10 million entries a flat list of paths did.
+	MaxScanResults = 50_000_000 // 50 million files
 )
 
 var (
@@ -139,8 +226,12 @@ func (a *App) OnStartup(ctx context.Context, options application.ServiceOptions)
 func (a *App) OnShutdown() error {
 	a.mu.Lock()
 	cancel := a.cancelFunc
+	scanCancel := a.scanCancel
 	a.mu.Unlock()
 
+	if scanCancel != nil {
+		scanCancel() // Cancel ongoing scan
+	}
 	if cancel != nil {
 		cancel() // Cancel ongoing deletion
 		// Give goroutine time to clean up
@@ -191,7 +282,106 @@ func (a *App) ScanDirectory(config Config) (ScanResult, error) {
 
 	// Scan directory
 	s := scanner.NewScanner(config.TargetDir, config.KeepDays)
-	scanResult, err := s.Scan()
+	s.SetWorkers(config.Workers)
+	s.SetCompact(true)
+	s.SetRecordIdentity(config.Revalidate)
+	s.SetSummary(scanner.DefaultSummaryTop)
+	include, err := scanner.NewMatcher(config.Include)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	excludeLines := config.Exclude
+	var filters []scanner.Filter
+	if config.GitClean != "" {
+		mode, err := gitclean.ParseMode(config.GitClean)
+		if err != nil {
+			return ScanResult{}, fmt.Errorf("invalid git-clean mode: %w", err)
+		}
+		cleaner, err := gitclean.Open(config.TargetDir, mode)
+		if err != nil {
+			return ScanResult{}, err
+		}
+		excludeLines = append(slices.Clone(excludeLines), cleaner.Excludes()...)
+		filters = append(filters, cleaner)
+	}
+	exclude, err := scanner.NewMatcher(excludeLines)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	s.SetInclude(include)
+	s.SetExclude(exclude)
+	if err := applyAgeOptions(s, config); err != nil {
+		return ScanResult{}, err
+	}
+	if config.Where != "" {
+		filter, err := scanner.ParseWhere(config.Where, time.Now())
+		if err != nil {
+			return ScanResult{}, fmt.Errorf("invalid filter expression: %w", err)
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) > 0 {
+		s.SetFilter(scanner.And(filters...))
+	}
+	for _, name := range []string{config.KeepMarker, config.PolicyFile} {
+		if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return ScanResult{}, fmt.Errorf("invalid marker file name %q: must be a file name, not a path", name)
+		}
+	}
+	s.SetKeepMarker(config.KeepMarker)
+	s.SetPolicyFile(config.PolicyFile)
+	switch {
+	case config.FilesOnly:
+		s.SetKeepDirs(scanner.KeepAllDirs)
+	case config.ContentsOnly:
+		s.SetKeepDirs(scanner.KeepRoot)
+	}
+	var sel scanner.Select
+	if config.EmptyDirs {
+		sel |= scanner.SelectEmptyDirs
+	}
+	if config.BrokenSymlinks {
+		sel |= scanner.SelectBrokenSymlinks
+	}
+	s.SetSelect(sel)
+	if config.Dedupe {
+		keep := dedupe.KeepOldest
+		if config.DedupeKeep != "" {
+			if keep, err = dedupe.ParseRule(config.DedupeKeep); err != nil {
+				return ScanResult{}, fmt.Errorf("invalid dedupe-keep rule: %w", err)
+			}
+		}
+		s.SetDedupe(&dedupe.Options{Keep: keep, Prefer: config.DedupePrefer})
+	}
+	s.SetProgress(func(p scanner.ScanProgress) {
+		if a.app == nil {
+			return
+		}
+		a.app.Event.Emit("scan:progress", ScanProgress{
+			Entries:     p.Entries,
+			Dirs:        p.Dirs,
+			DirsQueued:  p.DirsQueued,
+			Bytes:       p.Bytes,
+			CurrentPath: p.CurrentPath,
+			ElapsedMs:   p.Elapsed.Milliseconds(),
+			Rate:        p.Rate,
+			Done:        p.Done,
+		})
+	}, 0)
+
+	// Let CancelDeletion abort the scan
+	ctx, cancel := context.WithCancel(context.Background())
+	a.mu.Lock()
+	a.scanCancel = cancel
+	a.mu.Unlock()
+	scanResult, err := s.ScanContext(ctx)
+	a.mu.Lock()
+	a.scanCancel = nil
+	a.mu.Unlock()
+	cancel()
+	if errors.Is(err, context.Canceled) {
+		return ScanResult{}, fmt.Errorf("scan cancelled")
+	}
 	if err != nil {
 		return ScanResult{}, fmt.Errorf("failed to scan directory: %w", err)
 	}
@@ -202,19 +392,127 @@ func (a *App) ScanDirectory(config Config) (ScanResult, error) {
 			scanResult.TotalScanned, MaxScanResults)
 	}
 
+	// Entries that could not be read are left in place; strict mode refuses
+	// to delete anything then
+	warnings := make([]string, len(scanResult.Warnings))
+	for i, w := range scanResult.Warnings {
+		warnings[i] = w.String()
+	}
+	if config.StrictScan && len(warnings) > 0 {
+		return ScanResult{}, fmt.Errorf("scan incomplete, nothing will be deleted (strict scan): %s", strings.Join(warnings, "; "))
+	}
+
 	// Store scan result for later use
 	a.mu.Lock()
 	a.lastScanResult = scanResult
 	a.mu.Unlock()
 
+	var reclaimable int64
+	for _, g := range scanResult.Duplicates {
+		reclaimable += g.Reclaimable()
+	}
+
 	return ScanResult{
 		TotalScanned:   scanResult.TotalScanned,
 		TotalToDelete:  scanResult.TotalToDelete,
 		TotalRetained:  scanResult.TotalRetained,
+		TotalRetainedDirs: scanResult.TotalRetainedDirs,
+		Protected:      scanResult.Protected,
 		TotalSizeBytes: scanResult.TotalSizeBytes,
+		Warnings:        warnings,
+		SkippedSubtrees: scanResult.SkippedSubtrees,
+		ApparentBytes:   scanResult.Space.ApparentBytes,
+		AllocatedBytes:  scanResult.Space.AllocatedBytes,
+		LinkedBytes:     scanResult.Space.LinkedBytes,
+		HardlinkedFiles: scanResult.Space.HardlinkedFiles,
+		SpaceExact:      scanResult.Space.Exact,
+		DuplicateGroups:  len(scanResult.Duplicates),
+		ReclaimableBytes: reclaimable,
+		Summary:         newScanSummary(scanResult.Summary),
 	}, nil
 }
 
+// newScanSummary converts the scanner's summary for the frontend.
+func newScanSummary(summary *scanner.Summary) *ScanSummary {
+	if summary == nil {
+		return nil
+	}
+	groups := func(in []scanner.SummaryGroup) []SummaryGroup {
+		out := make([]SummaryGroup, len(in))
+		for i, g := range in {
+			out[i] = SummaryGroup{Name: g.Name, Files: g.Files, Bytes: g.Bytes}
+		}
+		return out
+	}
+	ages := make([]AgeBucket, len(summary.Ages))
+	for i, a := range summary.Ages {
+		ages[i] = AgeBucket{Label: a.Label, Files: a.Files, Bytes: a.Bytes}
+	}
+	return &ScanSummary{
+		Subtrees:          groups(summary.Subtrees),
+		SubtreesOmitted:   summary.SubtreesOmitted,
+		Extensions:        groups(summary.Extensions),
+		ExtensionsOmitted: summary.ExtensionsOmitted,
+		Owners:            groups(summary.Owners),
+		OwnersOmitted:     summary.OwnersOmitted,
+		Ages:              ages,
+	}
+}
+
+// applyAgeOptions configures the age field, older/newer limits, name
+// timestamps and the keep-newest and total size retention limits from config.
+func applyAgeOptions(s *scanner.Scanner, config Config) error {
+	if config.AgeBy != "" {
+		field, err := scanner.ParseTimeField(config.AgeBy)
+		if err != nil {
+			return err
+		}
+		s.SetAgeBy(field)
+	}
+	if config.OlderThan != "" {
+		age, err := scanner.ParseAge(config.OlderThan)
+		if err != nil {
+			return fmt.Errorf("invalid older-than age: %w", err)
+		}
+		s.SetOlderThan(age)
+	}
+	if config.NewerThan != "" {
+		age, err := scanner.ParseAge(config.NewerThan)
+		if err != nil {
+			return fmt.Errorf("invalid newer-than age: %w", err)
+		}
+		s.SetNewerThan(age)
+	}
+	if config.KeepNewest < 0 {
+		return fmt.Errorf("invalid keep-newest count: must be >= 0")
+	}
+	s.SetKeepNewest(config.KeepNewest)
+	if err := s.SetKeepNewestGroups(config.KeepGroups); err != nil {
+		return fmt.Errorf("invalid keep-newest group: %w", err)
+	}
+	if config.MaxTotalSize != "" {
+		size, err := scanner.ParseSize(config.MaxTotalSize)
+		if err != nil {
+			return fmt.Errorf("invalid max total size: %w", err)
+		}
+		s.SetMaxTotalSize(size)
+	}
+	if config.AgeFromName != "" {
+		nameTime, err := scanner.ParseNameTime(config.AgeFromName)
+		if err != nil {
+			return fmt.Errorf("invalid name timestamp: %w", err)
+		}
+		fallback := scanner.NameFallbackKeep
+		if config.NameFallback != "" {
+			if fallback, err = scanner.ParseNameFallback(config.NameFallback); err != nil {
+				return fmt.Errorf("invalid name timestamp fallback: %w", err)
+			}
+		}
+		s.SetAgeFromName(nameTime, fallback)
+	}
+	return nil
+}
+
 // StartDeletion begins the deletion process
 func (a *App) StartDeletion(config Config) error {
 	// Security Fix #2: Atomic check-and-set to prevent concurrent deletions
@@ -258,6 +556,17 @@ func (a *App) StartDeletion(config Config) error {
 		return fmt.Errorf("path no longer safe: %s", reason)
 	}
 
+	// Refuse targets that overlap a CLI or GUI run already in progress
+	runLock, err := runlock.DefaultRegistry().TryAcquire(scanResult.ScannedPath)
+	if err != nil {
+		a.deletionInProgress.Store(false)
+		var conflict *runlock.ConflictError
+		if errors.As(err, &conflict) {
+			return fmt.Errorf("another run is already working on %s: %s", conflict.Holder.Path, conflict.Holder)
+		}
+		return fmt.Errorf("cannot lock target directory: %w", err)
+	}
+
 	// Initialize engine and backend
 	workerCount := config.Workers
 	if workerCount == 0 {
@@ -270,6 +579,9 @@ func (a *App) StartDeletion(config Config) error {
 	}
 
 	backendInstance := backend.NewBackend()
+	if config.Trash {
+		backendInstance = backend.NewTrashBackend()
+	}
 
 	// Set deletion method if specified
 	if config.DeletionMethod != "auto" {
@@ -305,6 +617,13 @@ func (a *App) StartDeletion(config Config) error {
 		}
 	})
 
+	// Duplicate copies are only deleted while the copy kept is intact
+	if config.Revalidate || config.Dedupe {
+		eng.SetRevalidator(func(index int, _ string) bool {
+			return scanResult.Revalidate(index)
+		})
+	}
+
 	a.mu.Lock()
 	a.engine = eng
 	a.backendInst = backendInstance
@@ -342,6 +661,7 @@ func (a *App) StartDeletion(config Config) error {
 		// Security Fix #3: Deferred cleanup handler (resource leak protection)
 		defer func() {
 			cancel() // Cancel context to stop monitor
+			runLock.Release()
 
 			a.mu.Lock()
 			a.cancelFunc = nil
@@ -354,9 +674,20 @@ func (a *App) StartDeletion(config Config) error {
 			a.deletionInProgress.Store(false) // Allow new deletions
 		}()
 
+		// Measure free space around the deletion to report what it reclaimed.
+		// Entries moved into the trash free nothing until it is emptied
+		var meter *diskspace.Meter
+		if !config.DryRun && !config.Trash {
+			m, err := diskspace.Start(scanResult.ScannedPath)
+			if err != nil {
+				logger.Warning("Cannot measure free space: %v", err)
+			}
+			meter = m
+		}
+
 		startTime = time.Now()
 
-		result, err := eng.DeleteWithUTF16(ctx, scanResult.Files, scanResult.FilesUTF16, scanResult.IsDirectory, config.DryRun)
+		result, err := eng.DeleteEntries(ctx, scanResult, config.DryRun)
 
 		duration := time.Since(startTime)
 
@@ -371,8 +702,19 @@ func (a *App) StartDeletion(config Config) error {
 		finalResult := DeletionResult{
 			DeletedCount:  result.DeletedCount,
 			FailedCount:   result.FailedCount,
+			ChangedCount:  result.ChangedCount,
 			RetainedCount: scanResult.TotalRetained,
+			RetainedDirs:  scanResult.TotalRetainedDirs,
 			DurationMs:    duration.Milliseconds(),
+			EstimatedBytes: scanResult.Space.AllocatedBytes,
+		}
+		if meter != nil {
+			if reclaimed, err := meter.Reclaimed(); err == nil {
+				n := int64(reclaimed)
+				finalResult.ReclaimedBytes = &n
+			} else {
+				logger.Warning("Cannot measure free space after deletion: %v", err)
+			}
 		}
 
 		// Calculate rates
@@ -412,12 +754,17 @@ func (a *App) StartDeletion(config Config) error {
 	return nil
 }
 
-// CancelDeletion cancels an ongoing deletion
+// CancelDeletion cancels an ongoing scan or deletion
 func (a *App) CancelDeletion() error {
 	a.mu.Lock()
 	cancel := a.cancelFunc
+	scanCancel := a.scanCancel
 	a.mu.Unlock()
 
+	if scanCancel != nil {
+		scanCancel()
+		return nil
+	}
 	if cancel == nil {
 		return fmt.Errorf("no deletion in progress")
 	}
@@ -514,6 +861,39 @@ func (a *App) validateConfig(config Config) error {
 		return fmt.Errorf("benchmark mode is only available on Windows")
 	}
 
+	if config.ContentsOnly && config.FilesOnly {
+		return fmt.Errorf("contents-only and files-only cannot be used together")
+	}
+
+	if config.EmptyDirs && config.FilesOnly {
+		return fmt.Errorf("empty-dirs and files-only cannot be used together")
+	}
+
+	if (config.EmptyDirs || config.BrokenSymlinks) && (config.KeepNewest > 0 || config.MaxTotalSize != "") {
+		return fmt.Errorf("empty-dirs and broken-symlinks cannot be combined with keep-newest or max-total-size")
+	}
+
+	if config.GitClean != "" {
+		if _, err := gitclean.ParseMode(config.GitClean); err != nil {
+			return fmt.Errorf("invalid git-clean mode: %w", err)
+		}
+	}
+
+	if config.Dedupe {
+		if config.DedupeKeep != "" {
+			if _, err := dedupe.ParseRule(config.DedupeKeep); err != nil {
+				return fmt.Errorf("invalid dedupe-keep rule: %w", err)
+			}
+		}
+		if config.EmptyDirs || config.BrokenSymlinks {
+			return fmt.Errorf("dedupe cannot be combined with empty-dirs or broken-symlinks")
+		}
+	}
+
+	if config.Trash && (runtime.GOOS == "windows" || runtime.GOOS == "darwin") {
+		return fmt.Errorf("trash is only available on systems following the FreeDesktop trash specification (Linux, BSD)")
+	}
+
 	return nil
 }
 
@@ -22,8 +22,9 @@ import { useWailsEvent } from './hooks/useWailsEvent';
 import { ConfigurationForm } from './components/ConfigurationForm';
 import { ProgressView } from './components/ProgressView';
 import { ResultsView } from './components/ResultsView';
-import { DeletionResult } from './types/backend';
-import { formatNumber, formatBytes } from './utils/config';
+import { ScanSummaryView } from './components/ScanSummaryView';
+import { DeletionResult, ScanProgress } from './types/backend';
+import { formatNumber, formatBytes, formatDuration } from './utils/config';
 
 const useStyles = makeStyles({
   app: {
@@ -49,6 +50,19 @@ function AppContent() {
   const classes = useStyles();
   const { state, dispatch } = useAppContext();
   const [confirmDialogOpen, setConfirmDialogOpen] = useState(false);
+  const [scanProgress, setScanProgress] = useState<ScanProgress | null>(null);
+
+  // Listen for scan progress events
+  useWailsEvent<ScanProgress>('scan:progress', (data) => {
+    setScanProgress(data);
+  });
+
+  // Clear the previous scan's progress when a new scan starts
+  useEffect(() => {
+    if (state.stage === 'scanning') {
+      setScanProgress(null);
+    }
+  }, [state.stage]);
 
   // Listen for deletion complete event
   useWailsEvent<DeletionResult>('deletion:complete', (result) => {
@@ -78,6 +92,14 @@ function AppContent() {
     }
   };
 
+  const handleCancelScan = async () => {
+    try {
+      await (window as any).ffd.CancelDeletion();
+    } catch (err) {
+      console.error('Failed to cancel scan:', err);
+    }
+  };
+
   const handleCancelConfirmation = () => {
     setConfirmDialogOpen(false);
     dispatch({ type: 'RESET' });
@@ -93,7 +115,27 @@ function AppContent() {
         return (
           <div className={classes.loadingContainer}>
             <Spinner size="huge" label="Scanning directory..." />
-            <p>Please wait while we scan the target directory...</p>
+            {scanProgress ? (
+              <div style={{ textAlign: 'center' }}>
+                <p>
+                  {formatNumber(scanProgress.entries)} entries ({formatNumber(scanProgress.dirs)} dirs,{' '}
+                  {formatNumber(scanProgress.dirsQueued)} queued) · {formatBytes(scanProgress.bytes)} to delete
+                </p>
+                <p>
+                  {formatNumber(Math.round(scanProgress.rate))} entries/sec · {formatDuration(scanProgress.elapsedMs / 1000)}
+                </p>
+                {scanProgress.currentPath && (
+                  <p style={{ color: tokens.colorNeutralForeground3, wordBreak: 'break-all' }}>
+                    {scanProgress.currentPath}
+                  </p>
+                )}
+              </div>
+            ) : (
+              <p>Please wait while we scan the target directory...</p>
+            )}
+            <Button appearance="secondary" onClick={handleCancelScan}>
+              Cancel Scan
+            </Button>
           </div>
         );
 
@@ -160,10 +202,49 @@ function AppContent() {
                     </p>
                   )}
 
+                  {scanResult.totalRetainedDirs > 0 && (
+                    <p style={{ marginBottom: tokens.spacingVerticalM }}>
+                      <strong>Directories to Keep:</strong> {formatNumber(scanResult.totalRetainedDirs)} (still hold retained files)
+                    </p>
+                  )}
+
+                  {scanResult.protected && scanResult.protected.length > 0 && (
+                    <p style={{ marginBottom: tokens.spacingVerticalM }}>
+                      <strong>Protected Subtrees:</strong> {scanResult.protected.join(', ')}
+                    </p>
+                  )}
+
                   <p style={{ marginBottom: tokens.spacingVerticalM }}>
                     <strong>Total Size:</strong> {formatBytes(scanResult.totalSizeBytes)}
                   </p>
 
+                  <p style={{ marginBottom: tokens.spacingVerticalM }}>
+                    <strong>Space to Free:</strong>{' '}
+                    {scanResult.spaceExact
+                      ? `${formatBytes(scanResult.allocatedBytes)} on disk (${formatBytes(scanResult.apparentBytes)} of file data)`
+                      : `about ${formatBytes(scanResult.allocatedBytes)}`}
+                    {scanResult.hardlinkedFiles > 0 &&
+                      `; ${formatNumber(scanResult.hardlinkedFiles)} hardlinks counted once`}
+                    {scanResult.linkedBytes > 0 &&
+                      `; ${formatBytes(scanResult.linkedBytes)} stays in use by links outside the deletion`}
+                  </p>
+
+                  {scanResult.summary && <ScanSummaryView summary={scanResult.summary} />}
+
+                  {scanResult.warnings && scanResult.warnings.length > 0 && (
+                    <MessageBar intent="warning" style={{ marginBottom: tokens.spacingVerticalM }}>
+                      <MessageBarBody>
+                        <MessageBarTitle>
+                          {formatNumber(scanResult.warnings.length)} entries could not be scanned
+                          {scanResult.skippedSubtrees > 0 &&
+                            ` (${formatNumber(scanResult.skippedSubtrees)} directories not fully scanned)`}
+                        </MessageBarTitle>
+                        They will be left in place. {scanResult.warnings.slice(0, 5).join('; ')}
+                        {scanResult.warnings.length > 5 && ` ... and ${scanResult.warnings.length - 5} more`}
+                      </MessageBarBody>
+                    </MessageBar>
+                  )}
+
                   {isDryRun ? (
                     <MessageBar intent="info" style={{ marginTop: tokens.spacingVerticalL }}>
                       <MessageBarBody>
@@ -5,6 +5,7 @@ import {
   Checkbox,
   Label,
   SpinButton,
+  Textarea,
   Dropdown,
   Option,
   MessageBar,
@@ -52,6 +53,10 @@ const useStyles = makeStyles({
   },
 });
 
+// Lists are edited one entry per line
+const toLines = (list: string[]) => list.join('\n');
+const fromLines = (text: string) => text.split('\n');
+
 export function ConfigurationForm() {
   const classes = useStyles();
   const { state, dispatch } = useAppContext();
@@ -112,14 +117,27 @@ export function ConfigurationForm() {
       return;
     }
 
-    dispatch({ type: 'SET_CONFIG', payload: config });
+    const nonEmpty = (list: string[]) => list.filter((line) => line.trim() !== '');
+    const scanConfig: Config = {
+      ...config,
+      include: nonEmpty(config.include),
+      exclude: nonEmpty(config.exclude),
+      keepNewestGroups: nonEmpty(config.keepNewestGroups),
+      dedupePrefer: nonEmpty(config.dedupePrefer),
+    };
+
+    dispatch({ type: 'SET_CONFIG', payload: scanConfig });
     dispatch({ type: 'START_SCAN' });
 
     try {
-      const result = await (window as any).ffd.ScanDirectory(config);
+      const result = await (window as any).ffd.ScanDirectory(scanConfig);
       dispatch({ type: 'SCAN_COMPLETE', payload: result });
     } catch (err: any) {
-      dispatch({ type: 'SCAN_ERROR', payload: err.toString() });
+      if (err.toString().includes('scan cancelled')) {
+        dispatch({ type: 'SCAN_CANCELLED' });
+      } else {
+        dispatch({ type: 'SCAN_ERROR', payload: err.toString() });
+      }
     }
   };
 
@@ -207,8 +225,378 @@ export function ConfigurationForm() {
         </div>
       </div>
 
-      {/* Advanced Options */}
+      <div className={classes.field}>
+        <Checkbox
+          label="Move to Trash"
+          checked={config.trash}
+          onChange={(e, data) => setConfig({ ...config, trash: data.checked === true })}
+          disabled={disabled}
+        />
+        <div className={classes.helpText}>
+          Move entries into the trash so they can be restored; the space is only freed once the trash is emptied (Linux and BSD only)
+        </div>
+      </div>
+
+      <div className={classes.field}>
+        <Checkbox
+          label="Re-check Before Deleting"
+          checked={config.revalidate}
+          onChange={(e, data) => setConfig({ ...config, revalidate: data.checked === true })}
+          disabled={disabled}
+        />
+        <div className={classes.helpText}>
+          Skip entries that changed since the scan
+        </div>
+      </div>
+
+      <div className={classes.field}>
+        <Checkbox
+          label="Strict Scan"
+          checked={config.strictScan}
+          onChange={(e, data) => setConfig({ ...config, strictScan: data.checked === true })}
+          disabled={disabled}
+        />
+        <div className={classes.helpText}>
+          Delete nothing if the scan could not read some entries or directories
+        </div>
+      </div>
+
+      {/* Selection and Advanced Options */}
       <Accordion collapsible>
+        <AccordionItem value="selection">
+          <AccordionHeader>Selection</AccordionHeader>
+          <AccordionPanel>
+            {/* Include Patterns */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="include">
+                Include Patterns
+              </Label>
+              <Textarea
+                id="include"
+                value={toLines(config.include)}
+                onChange={(e, data) => setConfig({ ...config, include: fromLines(data.value) })}
+                placeholder="*.log"
+                resize="vertical"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Only delete entries matching one of these gitignore-style patterns, one per line
+              </div>
+            </div>
+
+            {/* Exclude Patterns */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="exclude">
+                Exclude Patterns
+              </Label>
+              <Textarea
+                id="exclude"
+                value={toLines(config.exclude)}
+                onChange={(e, data) => setConfig({ ...config, exclude: fromLines(data.value) })}
+                placeholder="*.keep"
+                resize="vertical"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Never delete entries matching one of these gitignore-style patterns, one per line
+              </div>
+            </div>
+
+            {/* Filter Expression */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="where">
+                Filter Expression
+              </Label>
+              <Input
+                id="where"
+                value={config.where}
+                onChange={(e) => setConfig({ ...config, where: e.target.value })}
+                placeholder="size > 10M and name ~ *.tmp"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Only delete entries matching this expression
+              </div>
+            </div>
+
+            {/* Age By */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="ageBy">
+                Age By
+              </Label>
+              <Dropdown
+                id="ageBy"
+                value={config.ageBy}
+                onOptionSelect={(e, data) => {
+                  setConfig({ ...config, ageBy: data.optionValue as any });
+                }}
+                disabled={disabled}
+              >
+                <Option value="mtime">Modification time (mtime)</Option>
+                <Option value="atime">Access time (atime)</Option>
+                <Option value="ctime">Change time (ctime)</Option>
+                <Option value="btime">Creation time (btime)</Option>
+              </Dropdown>
+              <div className={classes.helpText}>
+                Timestamp used for the age filters
+              </div>
+            </div>
+
+            {/* Older Than */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="olderThan">
+                Older Than
+              </Label>
+              <Input
+                id="olderThan"
+                value={config.olderThan}
+                onChange={(e) => setConfig({ ...config, olderThan: e.target.value })}
+                placeholder="7d"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Only delete entries older than this age (e.g. 36h, 7d, 2w)
+              </div>
+            </div>
+
+            {/* Newer Than */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="newerThan">
+                Newer Than
+              </Label>
+              <Input
+                id="newerThan"
+                value={config.newerThan}
+                onChange={(e) => setConfig({ ...config, newerThan: e.target.value })}
+                placeholder="36h"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Only delete entries newer than this age (e.g. 36h, 7d, 2w)
+              </div>
+            </div>
+
+            {/* Age From Name */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="ageFromName">
+                Age From Name
+              </Label>
+              <Input
+                id="ageFromName"
+                value={config.ageFromName}
+                onChange={(e) => setConfig({ ...config, ageFromName: e.target.value })}
+                placeholder="2006-01-02"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Take file ages from a timestamp in their name: Go time layout or regex with named groups
+              </div>
+            </div>
+
+            {/* Files Without a Name Timestamp */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="ageFromNameFallback">
+                Files Without a Name Timestamp
+              </Label>
+              <Dropdown
+                id="ageFromNameFallback"
+                value={config.ageFromNameFallback}
+                onOptionSelect={(e, data) => {
+                  setConfig({ ...config, ageFromNameFallback: data.optionValue as any });
+                }}
+                disabled={disabled}
+              >
+                <Option value="keep">Keep</Option>
+                <Option value="delete">Delete</Option>
+                <Option value="timestamp">Use the Age By timestamp</Option>
+              </Dropdown>
+              <div className={classes.helpText}>
+                What to do with files whose name holds no timestamp
+              </div>
+            </div>
+
+            {/* Keep Newest */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="keepNewest">
+                Keep Newest
+              </Label>
+              <SpinButton
+                id="keepNewest"
+                value={config.keepNewest || undefined}
+                onChange={(e, data) => {
+                  setConfig({ ...config, keepNewest: data.value ?? 0 });
+                }}
+                min={0}
+                max={100000}
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Keep the N newest files in each directory (0 = disabled)
+              </div>
+            </div>
+
+            {/* Keep Newest Groups */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="keepNewestGroups">
+                Keep Newest Groups
+              </Label>
+              <Textarea
+                id="keepNewestGroups"
+                value={toLines(config.keepNewestGroups)}
+                onChange={(e, data) => setConfig({ ...config, keepNewestGroups: fromLines(data.value) })}
+                placeholder="*.gz"
+                resize="vertical"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Rank files whose name matches one of these globs separately for Keep Newest, one per line
+              </div>
+            </div>
+
+            {/* Max Total Size */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="maxTotalSize">
+                Max Total Size
+              </Label>
+              <Input
+                id="maxTotalSize"
+                value={config.maxTotalSize}
+                onChange={(e) => setConfig({ ...config, maxTotalSize: e.target.value })}
+                placeholder="200G"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Delete the oldest files until the tree fits in this size
+              </div>
+            </div>
+
+            {/* Git Clean */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="gitClean">
+                Git Clean
+              </Label>
+              <Dropdown
+                id="gitClean"
+                value={config.gitClean}
+                onOptionSelect={(e, data) => {
+                  setConfig({ ...config, gitClean: data.optionValue as any });
+                }}
+                disabled={disabled}
+              >
+                <Option value="">Disabled</Option>
+                <Option value="ignored">Ignored files</Option>
+                <Option value="untracked">Untracked files</Option>
+              </Dropdown>
+              <div className={classes.helpText}>
+                Only delete what git clean would in a working tree
+              </div>
+            </div>
+
+            {/* Contents Only */}
+            <div className={classes.field}>
+              <Checkbox
+                label="Contents Only"
+                checked={config.contentsOnly}
+                onChange={(e, data) => setConfig({ ...config, contentsOnly: data.checked === true })}
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Empty the target directory but keep the directory itself
+              </div>
+            </div>
+
+            {/* Files Only */}
+            <div className={classes.field}>
+              <Checkbox
+                label="Files Only"
+                checked={config.filesOnly}
+                onChange={(e, data) => setConfig({ ...config, filesOnly: data.checked === true })}
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Delete files only, keeping the whole directory structure
+              </div>
+            </div>
+
+            {/* Empty Directories Only */}
+            <div className={classes.field}>
+              <Checkbox
+                label="Empty Directories Only"
+                checked={config.emptyDirs}
+                onChange={(e, data) => setConfig({ ...config, emptyDirs: data.checked === true })}
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Only delete empty directories, including directories left empty by the cleanup
+              </div>
+            </div>
+
+            {/* Broken Symlinks Only */}
+            <div className={classes.field}>
+              <Checkbox
+                label="Broken Symlinks Only"
+                checked={config.brokenSymlinks}
+                onChange={(e, data) => setConfig({ ...config, brokenSymlinks: data.checked === true })}
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Only delete symbolic links whose target does not exist
+              </div>
+            </div>
+
+            {/* Duplicates Only */}
+            <div className={classes.field}>
+              <Checkbox
+                label="Duplicates Only"
+                checked={config.dedupe}
+                onChange={(e, data) => setConfig({ ...config, dedupe: data.checked === true })}
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Only delete the copies of duplicate files, keeping one copy of each
+              </div>
+            </div>
+
+            {/* Duplicate Kept */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="dedupeKeep">
+                Duplicate Kept
+              </Label>
+              <Dropdown
+                id="dedupeKeep"
+                value={config.dedupeKeep}
+                onOptionSelect={(e, data) => {
+                  setConfig({ ...config, dedupeKeep: data.optionValue as any });
+                }}
+                disabled={disabled}
+              >
+                <Option value="oldest">Oldest</Option>
+                <Option value="shortest-path">Shortest path</Option>
+              </Dropdown>
+              <div className={classes.helpText}>
+                Copy of duplicate files that is kept
+              </div>
+            </div>
+
+            {/* Preferred Directories */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="dedupePrefer">
+                Preferred Directories
+              </Label>
+              <Textarea
+                id="dedupePrefer"
+                value={toLines(config.dedupePrefer)}
+                onChange={(e, data) => setConfig({ ...config, dedupePrefer: fromLines(data.value) })}
+                placeholder="/data/originals"
+                resize="vertical"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Keep the copies of duplicate files in these directories first, one per line
+              </div>
+            </div>
+          </AccordionPanel>
+        </AccordionItem>
         <AccordionItem value="advanced">
           <AccordionHeader>Advanced Options</AccordionHeader>
           <AccordionPanel>
@@ -233,6 +621,40 @@ export function ConfigurationForm() {
               </div>
             </div>
 
+            {/* Keep Marker */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="keepMarker">
+                Keep Marker
+              </Label>
+              <Input
+                id="keepMarker"
+                value={config.keepMarker}
+                onChange={(e) => setConfig({ ...config, keepMarker: e.target.value })}
+                placeholder="(ignore keep-markers)"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Never delete a directory holding a file with this name, or anything beneath it (empty to ignore)
+              </div>
+            </div>
+
+            {/* Policy File */}
+            <div className={classes.field}>
+              <Label className={classes.label} htmlFor="policyFile">
+                Policy File
+              </Label>
+              <Input
+                id="policyFile"
+                value={config.policyFile}
+                onChange={(e) => setConfig({ ...config, policyFile: e.target.value })}
+                placeholder="(ignore policy files)"
+                disabled={disabled}
+              />
+              <div className={classes.helpText}>
+                Per-directory file overriding the age settings for its subtree (empty to ignore)
+              </div>
+            </div>
+
             {/* Workers */}
             <div className={classes.field}>
               <Label className={classes.label} htmlFor="workers">
@@ -14,7 +14,7 @@ import {
 } from '@fluentui/react-components';
 import { CheckmarkCircleRegular, ErrorCircleRegular } from '@fluentui/react-icons';
 import { useAppContext } from '../context/AppContext';
-import { formatNumber, formatRate, formatDuration } from '../utils/config';
+import { formatNumber, formatRate, formatDuration, formatBytes } from '../utils/config';
 
 const useStyles = makeStyles({
   container: {
@@ -165,6 +165,15 @@ export function ResultsView() {
           </Card>
         )}
 
+        {result.retainedDirs > 0 && (
+          <Card className={classes.statCard}>
+            <div className={classes.statLabel}>Retained Directories</div>
+            <div className={classes.statValue} style={{ color: tokens.colorNeutralForeground3 }}>
+              {formatNumber(result.retainedDirs)}
+            </div>
+          </Card>
+        )}
+
         <Card className={classes.statCard}>
           <div className={classes.statLabel}>Duration</div>
           <div className={classes.statValue}>{formatDuration(duration)}</div>
@@ -179,6 +188,22 @@ export function ResultsView() {
           <div className={classes.statLabel}>Peak Rate</div>
           <div className={classes.statValue}>{formatRate(result.peakRate)}</div>
         </Card>
+
+        {result.reclaimedBytes !== undefined && (
+          <Card className={classes.statCard}>
+            <div className={classes.statLabel}>Space Reclaimed</div>
+            <div className={classes.statValue}>{formatBytes(result.reclaimedBytes)}</div>
+            <div className={classes.statLabel}>estimated {formatBytes(result.estimatedBytes)}</div>
+          </Card>
+        )}
+
+        {config.trash && !config.dryRun && (
+          <Card className={classes.statCard}>
+            <div className={classes.statLabel}>Space Held in Trash</div>
+            <div className={classes.statValue}>{formatBytes(result.estimatedBytes)}</div>
+            <div className={classes.statLabel}>freed once the trash is emptied</div>
+          </Card>
+        )}
       </div>
 
       {/* Method Statistics */}
@@ -0,0 +1,121 @@
+import { makeStyles, tokens } from '@fluentui/react-components';
+import { ScanSummary, SummaryGroup } from '../types/backend';
+import { formatNumber, formatBytes } from '../utils/config';
+
+const useStyles = makeStyles({
+  grid: {
+    display: 'grid',
+    gridTemplateColumns: 'repeat(auto-fit, minmax(260px, 1fr))',
+    gap: tokens.spacingHorizontalL,
+    marginBottom: tokens.spacingVerticalM,
+  },
+  table: {
+    width: '100%',
+    borderCollapse: 'collapse',
+    fontSize: tokens.fontSizeBase200,
+    '& th': {
+      textAlign: 'left',
+      padding: tokens.spacingVerticalXS,
+      borderBottom: `1px solid ${tokens.colorNeutralStroke1}`,
+      fontWeight: tokens.fontWeightSemibold,
+    },
+    '& td': {
+      padding: tokens.spacingVerticalXS,
+      borderBottom: `1px solid ${tokens.colorNeutralStroke2}`,
+      overflowWrap: 'anywhere',
+    },
+  },
+  bar: {
+    height: '10px',
+    backgroundColor: tokens.colorBrandBackground,
+    borderRadius: tokens.borderRadiusSmall,
+  },
+});
+
+interface GroupTableProps {
+  title: string;
+  groups: SummaryGroup[] | null;
+  omitted: number;
+  label: (name: string) => string;
+}
+
+function GroupTable({ title, groups, omitted, label }: GroupTableProps) {
+  const classes = useStyles();
+  if (!groups || groups.length === 0) {
+    return null;
+  }
+  return (
+    <table className={classes.table}>
+      <thead>
+        <tr>
+          <th>{title}</th>
+          <th>Size</th>
+          <th>Files</th>
+        </tr>
+      </thead>
+      <tbody>
+        {groups.map((g) => (
+          <tr key={g.name}>
+            <td>{label(g.name)}</td>
+            <td>{formatBytes(g.bytes)}</td>
+            <td>{formatNumber(g.files)}</td>
+          </tr>
+        ))}
+        {omitted > 0 && (
+          <tr>
+            <td colSpan={3}>... and {formatNumber(omitted)} more</td>
+          </tr>
+        )}
+      </tbody>
+    </table>
+  );
+}
+
+/**
+ * Breakdown of the files a scan marked for deletion: largest subtrees,
+ * extensions and owners, and an age histogram.
+ */
+export function ScanSummaryView({ summary }: { summary: ScanSummary }) {
+  const classes = useStyles();
+  const most = Math.max(0, ...summary.ages.map((a) => a.files));
+
+  return (
+    <div className={classes.grid}>
+      <GroupTable
+        title="Subtree"
+        groups={summary.subtrees}
+        omitted={summary.subtreesOmitted}
+        label={(name) => (name === '.' ? '(top level)' : `${name}/`)}
+      />
+      <GroupTable
+        title="Extension"
+        groups={summary.extensions}
+        omitted={summary.extensionsOmitted}
+        label={(ext) => ext || '(none)'}
+      />
+      <GroupTable title="Owner" groups={summary.owners} omitted={summary.ownersOmitted} label={(name) => name} />
+      {most > 0 && (
+        <table className={classes.table}>
+          <thead>
+            <tr>
+              <th>Age</th>
+              <th style={{ width: '40%' }}></th>
+              <th>Files</th>
+            </tr>
+          </thead>
+          <tbody>
+            {summary.ages.map((a) => (
+              <tr key={a.label} title={formatBytes(a.bytes)}>
+                <td>{a.label}</td>
+                <td>
+                  <div className={classes.bar} style={{ width: `${(a.files / most) * 100}%` }} />
+                </td>
+                <td>{formatNumber(a.files)}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </div>
+  );
+}
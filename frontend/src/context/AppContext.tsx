@@ -8,6 +8,7 @@ type AppAction =
   | { type: 'START_SCAN' }
   | { type: 'SCAN_COMPLETE'; payload: ScanResult }
   | { type: 'SCAN_ERROR'; payload: string }
+  | { type: 'SCAN_CANCELLED' }
   | { type: 'CONFIRM_DELETION' }
   | { type: 'UPDATE_METRICS'; payload: LiveMetrics }
   | { type: 'DELETION_COMPLETE'; payload: DeletionResult }
@@ -51,6 +52,12 @@ function appReducer(state: AppState, action: AppAction): AppState {
         error: action.payload,
       };
 
+    case 'SCAN_CANCELLED':
+      return {
+        ...state,
+        stage: 'config',
+      };
+
     case 'CONFIRM_DELETION':
       return {
         ...state,
@@ -13,6 +13,30 @@ export interface Config {
   deletionMethod: 'auto' | 'fileinfo' | 'deleteonclose' | 'ntapi' | 'deleteapi';
   benchmark: boolean;
   monitor: boolean;
+  revalidate: boolean;
+  strictScan: boolean;
+  include: string[];
+  exclude: string[];
+  where: string;
+  ageBy: 'mtime' | 'atime' | 'ctime' | 'btime';
+  olderThan: string;
+  newerThan: string;
+  keepMarker: string;
+  policyFile: string;
+  keepNewest: number;
+  keepNewestGroups: string[];
+  maxTotalSize: string;
+  ageFromName: string;
+  ageFromNameFallback: 'keep' | 'delete' | 'timestamp';
+  contentsOnly: boolean;
+  filesOnly: boolean;
+  emptyDirs: boolean;
+  brokenSymlinks: boolean;
+  gitClean: '' | 'ignored' | 'untracked';
+  dedupe: boolean;
+  dedupeKeep: 'oldest' | 'shortest-path';
+  dedupePrefer: string[];
+  trash: boolean;
 }
 
 export interface ValidationResult {
@@ -24,7 +48,52 @@ export interface ScanResult {
   totalScanned: number;
   totalToDelete: number;
   totalRetained: number;
+  totalRetainedDirs: number;
+  protected: string[] | null;
   totalSizeBytes: number;
+  warnings: string[] | null;
+  skippedSubtrees: number;
+  apparentBytes: number;
+  allocatedBytes: number;
+  linkedBytes: number;
+  hardlinkedFiles: number;
+  spaceExact: boolean;
+  duplicateGroups: number;
+  reclaimableBytes: number;
+  summary?: ScanSummary;
+}
+
+export interface SummaryGroup {
+  name: string;
+  files: number;
+  bytes: number;
+}
+
+export interface AgeBucket {
+  label: string;
+  files: number;
+  bytes: number;
+}
+
+export interface ScanSummary {
+  subtrees: SummaryGroup[] | null;
+  subtreesOmitted: number;
+  extensions: SummaryGroup[] | null;
+  extensionsOmitted: number;
+  owners: SummaryGroup[] | null;
+  ownersOmitted: number;
+  ages: AgeBucket[];
+}
+
+export interface ScanProgress {
+  entries: number;
+  dirs: number;
+  dirsQueued: number;
+  bytes: number;
+  currentPath: string;
+  elapsedMs: number;
+  rate: number;
+  done: boolean;
 }
 
 export interface LiveMetrics {
@@ -47,13 +116,17 @@ export interface SystemMetrics {
 export interface DeletionResult {
   deletedCount: number;
   failedCount: number;
+  changedCount: number;
   retainedCount: number;
+  retainedDirs: number;
   durationMs: number;
   averageRate: number;
   peakRate: number;
   methodStats?: MethodStats;
   bottleneckReport?: string;
   errors?: string[];
+  reclaimedBytes?: number;
+  estimatedBytes: number;
 }
 
 export interface MethodStats {
@@ -12,6 +12,30 @@ export const defaultConfig: Config = {
   deletionMethod: 'auto',
   benchmark: false,
   monitor: false,
+  revalidate: false,
+  strictScan: false,
+  include: [],
+  exclude: [],
+  where: '',
+  ageBy: 'mtime',
+  olderThan: '',
+  newerThan: '',
+  keepMarker: '.ffdkeep',
+  policyFile: '.ffdpolicy',
+  keepNewest: 0,
+  keepNewestGroups: [],
+  maxTotalSize: '',
+  ageFromName: '',
+  ageFromNameFallback: 'keep',
+  contentsOnly: false,
+  filesOnly: false,
+  emptyDirs: false,
+  brokenSymlinks: false,
+  gitClean: '',
+  dedupe: false,
+  dedupeKeep: 'oldest',
+  dedupePrefer: [],
+  trash: false,
 };
 
 export function formatNumber(num: number): string {
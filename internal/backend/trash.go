@@ -0,0 +1,435 @@
+//go:build !windows
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+)
+
+// TrashBackend moves entries into the trash, following the FreeDesktop.org
+// Trash specification, instead of deleting them, so that they can be
+// restored from the trash of any desktop that follows it.
+//
+// Entries on the file system of the home trash ($XDG_DATA_HOME/Trash) go
+// there. Entries on other file systems go to the trash at the top of their
+// mount: $topdir/.Trash/$uid if an administrator prepared $topdir/.Trash
+// (a sticky directory, not a symbolic link), or else $topdir/.Trash-$uid,
+// which is created when needed. A trash directory that already exists is
+// only used if it belongs to the user, since anyone who can write to the
+// top of a mount could have prepared it. Entries are never copied across file
+// systems: if no trash can be used on an entry's file system, it is left in
+// place and reported as a failure.
+//
+// Entries are trashed in the engine's bottom-up order: files first, then
+// each directory once it is empty. Each gets a .trashinfo file recording its
+// original path and the time it was trashed. Directories set with
+// SetWholeDirs are instead trashed at once with everything beneath them.
+type TrashBackend struct {
+	uid   int
+	whole map[string]bool // Directories trashed with their contents (nil = none)
+
+	mu      sync.Mutex
+	home    *trashDir            // Home trash, once located
+	homeErr error                // Why the home trash cannot be used
+	homeDev uint64               // Device of the home trash
+	byDev   map[uint64]*trashDir // Trash of each file system seen
+}
+
+// trashDir is a trash directory, holding files/ and info/.
+type trashDir struct {
+	path   string
+	topdir string // Mount the trash belongs to, which recorded paths are relative to ("" = the home trash)
+	err    error  // Why the trash cannot be used
+
+	mu   sync.Mutex
+	next map[string]int // Number to try first for each name, past those already taken
+}
+
+// NewTrashBackend creates a backend that moves entries into the trash of the
+// current user. Trash directories are located and created on first use.
+func NewTrashBackend() *TrashBackend {
+	return &TrashBackend{
+		uid:   os.Getuid(),
+		byDev: make(map[uint64]*trashDir),
+	}
+}
+
+// SetWholeDirs sets the directories that are deleted together with
+// everything beneath them, keyed by the paths the engine passes. Each is
+// moved into the trash in one piece, with a single .trashinfo file, so that
+// restoring it brings back the whole tree. Entries beneath one of them are
+// left in place for it to carry along; if it cannot be moved in one piece,
+// its entries are trashed one by one instead.
+//
+// Must be called before the first entry is deleted.
+func (b *TrashBackend) SetWholeDirs(dirs map[string]bool) {
+	b.whole = dirs
+}
+
+// carried reports whether path is beneath a directory set with SetWholeDirs,
+// which takes it into the trash.
+func (b *TrashBackend) carried(path string) bool {
+	return b.whole[filepath.Dir(path)]
+}
+
+// DeleteFile moves a file, or any other non-directory, into the trash.
+// Directories are refused, so that they are only trashed once empty.
+func (b *TrashBackend) DeleteFile(path string) error {
+	if b.carried(path) {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to trash file %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("failed to trash file %s: %w", path, syscall.EISDIR)
+	}
+	return b.trash(path, info)
+}
+
+// DeleteDirectory moves an empty directory into the trash. Returns an error
+// if the directory still holds entries, which would go with it, unless it
+// was set with SetWholeDirs.
+func (b *TrashBackend) DeleteDirectory(path string) error {
+	if b.carried(path) {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to trash directory %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("failed to trash directory %s: %w", path, syscall.ENOTDIR)
+	}
+	if b.whole[path] {
+		return b.trashTree(path, info)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to trash directory %s: %w", path, err)
+	}
+	names, err := f.Readdirnames(1)
+	f.Close()
+	if len(names) > 0 {
+		return fmt.Errorf("failed to trash directory %s: %w", path, syscall.ENOTEMPTY)
+	}
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to trash directory %s: %w", path, err)
+	}
+	return b.trash(path, info)
+}
+
+// trash moves the entry into the trash of its file system.
+func (b *TrashBackend) trash(path string, info os.FileInfo) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to trash %s: %w", path, err)
+	}
+	dev, ok := deviceOf(info)
+	if !ok {
+		return fmt.Errorf("failed to trash %s: cannot determine its file system", path)
+	}
+	td := b.trashFor(abs, dev)
+	if td.err != nil {
+		return fmt.Errorf("failed to trash %s: %w", path, td.err)
+	}
+	if err := td.move(abs, time.Now()); err != nil {
+		logger.Debug("Moving to the trash failed for: %s (error: %v)", path, err)
+		return fmt.Errorf("failed to trash %s: %w", path, err)
+	}
+	return nil
+}
+
+// trashTree moves the directory at path into the trash with everything
+// beneath it. If it cannot be moved in one piece, the entries beneath it,
+// which were left in place for it, are trashed one by one, bottom-up.
+func (b *TrashBackend) trashTree(path string, info os.FileInfo) error {
+	err := b.trash(path, info)
+	if err == nil {
+		return nil
+	}
+	logger.Debug("Trashing %s in one piece failed, trashing its entries one by one: %v", path, err)
+
+	var dirs []string
+	walkErr := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return b.trash(p, info)
+	})
+	if walkErr != nil {
+		return fmt.Errorf("failed to trash directory %s: %w", path, walkErr)
+	}
+	// Walk order lists a directory before its descendants
+	for i := len(dirs) - 1; i >= 0; i-- {
+		info, err := os.Lstat(dirs[i])
+		if err == nil {
+			err = b.trash(dirs[i], info)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to trash directory %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// trashFor returns the trash for entries on the device dev, abs being one of
+// them, locating or creating it on first use.
+func (b *TrashBackend) trashFor(abs string, dev uint64) *trashDir {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.home == nil && b.homeErr == nil {
+		b.home, b.homeDev, b.homeErr = locateHomeTrash()
+	}
+	if b.homeErr != nil {
+		// Without it, entries on its file system cannot be told apart
+		return &trashDir{err: b.homeErr}
+	}
+	if dev == b.homeDev {
+		if _, ok := b.byDev[dev]; !ok {
+			b.home.err = makeTrashDirs(b.home.path, b.uid)
+			b.byDev[dev] = b.home
+		}
+		return b.home
+	}
+
+	td, ok := b.byDev[dev]
+	if !ok {
+		td = topdirTrash(mountPoint(abs, dev), b.uid)
+		if td.err == nil {
+			logger.Info("Trash for %s: %s", td.topdir, td.path)
+		}
+		b.byDev[dev] = td
+	}
+	return td
+}
+
+// locateHomeTrash returns the home trash and the device it is, or will be,
+// created on.
+func locateHomeTrash() (*trashDir, uint64, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" || !filepath.IsAbs(dataHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, 0, fmt.Errorf("cannot locate the home trash: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	td := &trashDir{path: filepath.Join(dataHome, "Trash")}
+
+	// The trash may not exist yet; it will be created where its closest
+	// existing ancestor is
+	for dir := td.path; ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil {
+			dev, ok := deviceOf(info)
+			if !ok {
+				return nil, 0, fmt.Errorf("cannot locate the home trash: unknown file system of %s", dir)
+			}
+			return td, dev, nil
+		}
+		if dir == filepath.Dir(dir) {
+			return nil, 0, fmt.Errorf("cannot locate the home trash %s", td.path)
+		}
+	}
+}
+
+// topdirTrash returns the trash of the user uid at the top of a mount:
+// the administrator's $topdir/.Trash/$uid if $topdir/.Trash is a sticky
+// directory, or else $topdir/.Trash-$uid.
+func topdirTrash(topdir string, uid int) *trashDir {
+	id := strconv.Itoa(uid)
+	shared := filepath.Join(topdir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil {
+		switch {
+		case !info.IsDir():
+			logger.Warning("Not using the shared trash %s: it is not a directory", shared)
+		case info.Mode()&os.ModeSticky == 0:
+			// Without the sticky bit, other users could replace the user's trash
+			if info.Mode().Perm()&0002 != 0 {
+				logger.Warning("Not using the shared trash %s: it is world-writable without the sticky bit", shared)
+			}
+		default:
+			td := &trashDir{path: filepath.Join(shared, id), topdir: topdir}
+			if td.err = makeTrashDirs(td.path, uid); td.err == nil {
+				return td
+			}
+			logger.Warning("Cannot use the shared trash %s: %v", shared, td.err)
+		}
+	}
+
+	td := &trashDir{path: filepath.Join(topdir, ".Trash-"+id), topdir: topdir}
+	if err := makeTrashDirs(td.path, uid); err != nil {
+		td.err = fmt.Errorf("no usable trash on the file system mounted at %s: %w", topdir, err)
+	}
+	return td
+}
+
+// makeTrashDirs creates the trash directory dir with its files and info
+// directories, readable by the user only, and checks that none of them is
+// a symbolic link and that all of them belong to the user uid.
+func makeTrashDirs(dir string, uid int) error {
+	for _, d := range []string{dir, filepath.Join(dir, "files"), filepath.Join(dir, "info")} {
+		if err := os.Mkdir(d, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+			if errors.Is(err, os.ErrNotExist) && d == dir {
+				err = os.MkdirAll(d, 0700)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		info, err := os.Lstat(d)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", d)
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != uid {
+			return fmt.Errorf("%s does not belong to uid %d", d, uid)
+		}
+	}
+	return nil
+}
+
+// mountPoint returns the top directory of the mount holding abs, whose
+// device is dev: the highest ancestor on the same device.
+func mountPoint(abs string, dev uint64) string {
+	dir := filepath.Dir(abs)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		info, err := os.Stat(parent)
+		if err != nil {
+			return dir
+		}
+		if d, ok := deviceOf(info); !ok || d != dev {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// move writes the .trashinfo file for the entry at abs, trashed at now,
+// then moves the entry under the name the info file reserved. The info
+// file is removed again if the entry cannot be moved.
+func (td *trashDir) move(abs string, now time.Time) error {
+	if abs == td.path || strings.HasPrefix(abs, td.path+string(filepath.Separator)) {
+		return fmt.Errorf("it is in the trash %s", td.path)
+	}
+
+	recorded := abs
+	if td.topdir != "" {
+		rel, err := filepath.Rel(td.topdir, abs)
+		if err != nil {
+			return err
+		}
+		recorded = rel
+	}
+	content := "[Trash Info]\nPath=" + escapeTrashPath(recorded) + "\nDeletionDate=" + now.Format("2006-01-02T15:04:05") + "\n"
+
+	name, infoPath, err := td.reserve(filepath.Base(abs), content)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(abs, filepath.Join(td.path, "files", name)); err != nil {
+		os.Remove(infoPath)
+		return err
+	}
+	return nil
+}
+
+// reserve creates the info file of a trashed entry under a name that is
+// free in both files/ and info/, starting with base. Creating the info file
+// exclusively claims the name, as the specification requires.
+func (td *trashDir) reserve(base, content string) (name, infoPath string, err error) {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if stem == "" {
+		stem, ext = base, ""
+	}
+
+	// Names already taken are not tried again: a tree holds thousands of
+	// files named index.js
+	td.mu.Lock()
+	n := max(td.next[base], 1)
+	td.mu.Unlock()
+	for ; ; n++ {
+		name = base
+		if n > 1 {
+			name = stem + "." + strconv.Itoa(n) + ext
+		}
+		infoPath = filepath.Join(td.path, "info", name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := os.Lstat(filepath.Join(td.path, "files", name)); err == nil {
+			// Left behind without its info file
+			f.Close()
+			os.Remove(infoPath)
+			continue
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(infoPath)
+			return "", "", err
+		}
+		td.mu.Lock()
+		if td.next == nil {
+			td.next = make(map[string]int)
+		}
+		td.next[base] = max(td.next[base], n+1)
+		td.mu.Unlock()
+		return name, infoPath, nil
+	}
+}
+
+// escapeTrashPath escapes a path for a .trashinfo file, as in URLs, keeping
+// the separators.
+func escapeTrashPath(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// deviceOf returns the device of the file system holding a file.
+func deviceOf(info os.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
@@ -0,0 +1,295 @@
+//go:build !windows
+
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTrash returns a TrashBackend whose home trash is in a temporary
+// directory on the same file system as t.TempDir.
+func newTestTrash(t *testing.T) (*TrashBackend, string) {
+	t.Helper()
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	return NewTrashBackend(), filepath.Join(dataHome, "Trash")
+}
+
+// readTrashInfo returns the contents of the info file of a trashed entry.
+func readTrashInfo(t *testing.T, trash, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(trash, "info", name+".trashinfo"))
+	if err != nil {
+		t.Fatalf("Failed to read info file of %s: %v", name, err)
+	}
+	return string(content)
+}
+
+// TestTrashBackend_HomeTrash tests that files and empty directories are moved
+// into the home trash with their info files, and that names are not reused.
+func TestTrashBackend_HomeTrash(t *testing.T) {
+	b, trash := newTestTrash(t)
+	root := t.TempDir()
+	dir := filepath.Join(root, "old reports")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	first := filepath.Join(dir, "report.pdf")
+	second := filepath.Join(root, "report.pdf")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	before := time.Now().Truncate(time.Second)
+	for _, path := range []string{first, second} {
+		if err := b.DeleteFile(path); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+	}
+	if err := b.DeleteDirectory(dir); err != nil {
+		t.Fatalf("DeleteDirectory failed: %v", err)
+	}
+
+	for _, path := range []string{first, second, dir} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone, got %v", path, err)
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(trash, "files", "report.2.pdf")); err != nil || string(content) != second {
+		t.Errorf("Expected the second report as report.2.pdf, got %q, %v", content, err)
+	}
+	if info, err := os.Stat(filepath.Join(trash, "files", "old reports")); err != nil || !info.IsDir() {
+		t.Errorf("Expected the directory in the trash, got %v", err)
+	}
+
+	info := readTrashInfo(t, trash, "old reports")
+	want := regexp.MustCompile(`^\[Trash Info\]\nPath=(.*)\nDeletionDate=(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d)\n$`)
+	m := want.FindStringSubmatch(info)
+	if m == nil {
+		t.Fatalf("Unexpected info file:\n%s", info)
+	}
+	if escaped := strings.ReplaceAll(dir, " ", "%20"); m[1] != escaped {
+		t.Errorf("Expected Path=%s, got %s", escaped, m[1])
+	}
+	deleted, err := time.ParseInLocation("2006-01-02T15:04:05", m[2], time.Local)
+	if err != nil || deleted.Before(before) || deleted.After(time.Now()) {
+		t.Errorf("Unexpected DeletionDate %s (%v)", m[2], err)
+	}
+	if !strings.Contains(readTrashInfo(t, trash, "report.2.pdf"), "Path="+second+"\n") {
+		t.Errorf("Expected the info file of report.2.pdf to record %s", second)
+	}
+}
+
+// TestTrashBackend_WholeDirs tests that a directory set with SetWholeDirs
+// goes into the trash in one piece, with a single info file, when the engine
+// reaches it after the entries beneath it.
+func TestTrashBackend_WholeDirs(t *testing.T) {
+	b, trash := newTestTrash(t)
+	root := t.TempDir()
+	dir := filepath.Join(root, "node_modules")
+	sub := filepath.Join(dir, "lib")
+	kept := filepath.Join(root, "package.json")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, path := range []string{filepath.Join(dir, "index.js"), filepath.Join(sub, "index.js"), kept} {
+		if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	b.SetWholeDirs(map[string]bool{dir: true, sub: true})
+
+	// Bottom-up, as the engine deletes them
+	for _, path := range []string{filepath.Join(sub, "index.js"), filepath.Join(dir, "index.js"), kept} {
+		if err := b.DeleteFile(path); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+	}
+	for _, path := range []string{sub, dir} {
+		if err := b.DeleteDirectory(path); err != nil {
+			t.Fatalf("DeleteDirectory failed: %v", err)
+		}
+	}
+
+	if _, err := os.Lstat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be gone, got %v", dir, err)
+	}
+	content, err := os.ReadFile(filepath.Join(trash, "files", "node_modules", "lib", "index.js"))
+	if err != nil || string(content) != filepath.Join(sub, "index.js") {
+		t.Errorf("Expected the tree in the trash, got %q, %v", content, err)
+	}
+	infos, err := os.ReadDir(filepath.Join(trash, "info"))
+	if err != nil {
+		t.Fatalf("Failed to read info directory: %v", err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	if strings.Join(names, ",") != "node_modules.trashinfo,package.json.trashinfo" {
+		t.Errorf("Expected one info file for the tree and one for the file, got %v", names)
+	}
+}
+
+// TestTrashBackend_Refusals tests that directories are only trashed once
+// empty, that DeleteFile refuses directories, and that the trash itself is
+// never trashed.
+func TestTrashBackend_Refusals(t *testing.T) {
+	b, trash := newTestTrash(t)
+	root := t.TempDir()
+	dir := filepath.Join(root, "dir")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if err := b.DeleteDirectory(dir); err == nil {
+		t.Error("Expected an error for a directory that is not empty")
+	}
+	if err := b.DeleteFile(dir); err == nil {
+		t.Error("Expected DeleteFile to refuse a directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file")); err != nil {
+		t.Errorf("Expected the directory to be left in place: %v", err)
+	}
+
+	// Trashing a file creates the trash; its own entries are then refused
+	if err := b.DeleteFile(filepath.Join(dir, "file")); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	if err := b.DeleteFile(filepath.Join(trash, "info", "file.trashinfo")); err == nil {
+		t.Error("Expected an error for an entry of the trash")
+	}
+}
+
+// TestTopdirTrash tests the choice between the administrator's shared
+// .Trash directory and the per-user .Trash-$uid directory, and that paths
+// in a mount's trash are recorded relative to the mount.
+func TestTopdirTrash(t *testing.T) {
+	topdir := t.TempDir()
+	uid := os.Getuid()
+	own := filepath.Join(topdir, ".Trash-"+strconv.Itoa(uid))
+	td := topdirTrash(topdir, uid)
+	if td.err != nil || td.path != own {
+		t.Fatalf("Expected %s, got %s (%v)", own, td.path, td.err)
+	}
+	info, err := os.Stat(filepath.Join(td.path, "files"))
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected files/ with mode 0700, got %v", err)
+	}
+
+	// Without the sticky bit the shared trash must not be used
+	shared := filepath.Join(topdir, ".Trash")
+	if err := os.Mkdir(shared, 0777); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Chmod(shared, 0777); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	if td := topdirTrash(topdir, uid); td.path != own {
+		t.Errorf("Expected %s without the sticky bit, got %s", own, td.path)
+	}
+	if _, err := os.Lstat(filepath.Join(shared, strconv.Itoa(uid))); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be created in a shared trash without the sticky bit, got %v", err)
+	}
+	if err := os.Chmod(shared, 0777|os.ModeSticky); err != nil {
+		t.Fatalf("Failed to set the sticky bit: %v", err)
+	}
+	td = topdirTrash(topdir, uid)
+	if td.err != nil || td.path != filepath.Join(shared, strconv.Itoa(uid)) {
+		t.Fatalf("Expected .Trash/%d, got %s (%v)", uid, td.path, td.err)
+	}
+
+	file := filepath.Join(topdir, "media", "clip #1.mp4")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := td.move(file, time.Now()); err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if content := readTrashInfo(t, td.path, "clip #1.mp4"); !strings.Contains(content, "\nPath=media/clip%20%231.mp4\n") {
+		t.Errorf("Expected a relative, escaped path, got:\n%s", content)
+	}
+}
+
+// TestTopdirTrash_Ownership tests that trash directories prepared by another
+// user, in the shared .Trash or as .Trash-$uid, are never used.
+func TestTopdirTrash_Ownership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("preparing another user's directories requires root")
+	}
+	const uid, other = 1234, 4321
+	topdir := t.TempDir()
+	shared := filepath.Join(topdir, ".Trash")
+	own := filepath.Join(topdir, ".Trash-1234")
+	for _, dir := range []string{filepath.Join(shared, "1234"), own} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.Chown(dir, other, other); err != nil {
+			t.Fatalf("Failed to change owner: %v", err)
+		}
+	}
+	if err := os.Chmod(shared, 0777|os.ModeSticky); err != nil {
+		t.Fatalf("Failed to set the sticky bit: %v", err)
+	}
+
+	if td := topdirTrash(topdir, uid); td.err == nil {
+		t.Errorf("Expected trash directories of uid %d to be refused, got %s", other, td.path)
+	}
+
+	// Once .Trash-1234 belongs to the user, it is used instead of .Trash/1234
+	for _, dir := range []string{own, filepath.Join(own, "files"), filepath.Join(own, "info")} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.Chown(dir, uid, uid); err != nil {
+			t.Fatalf("Failed to change owner: %v", err)
+		}
+	}
+	if td := topdirTrash(topdir, uid); td.err != nil || td.path != own {
+		t.Errorf("Expected %s, got %s (%v)", own, td.path, td.err)
+	}
+}
+
+// TestTrashDirReserve tests that names taken in the trash, by an info file
+// or by an entry left behind without one, are skipped, and that repeated
+// names are numbered in turn.
+func TestTrashDirReserve(t *testing.T) {
+	td := topdirTrash(t.TempDir(), os.Getuid())
+	if td.err != nil {
+		t.Fatalf("topdirTrash failed: %v", td.err)
+	}
+	if err := os.WriteFile(filepath.Join(td.path, "info", "index.js.trashinfo"), nil, 0600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(td.path, "files", "index.2.js"), nil, 0600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	for _, want := range []string{"index.3.js", "index.4.js", "index.5.js"} {
+		name, _, err := td.reserve("index.js", "[Trash Info]\n")
+		if err != nil {
+			t.Fatalf("reserve failed: %v", err)
+		}
+		if name != want {
+			t.Errorf("Expected %s, got %s", want, name)
+		}
+	}
+	if name, _, err := td.reserve("package.json", "[Trash Info]\n"); err != nil || name != "package.json" {
+		t.Errorf("Expected package.json, got %s (%v)", name, err)
+	}
+}
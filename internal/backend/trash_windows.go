@@ -0,0 +1,31 @@
+//go:build windows
+
+package backend
+
+import "errors"
+
+// errTrashUnavailable is returned by TrashBackend on Windows, whose Recycle
+// Bin does not follow the FreeDesktop.org Trash specification.
+var errTrashUnavailable = errors.New("the FreeDesktop trash is not available on Windows")
+
+// TrashBackend moves entries into the FreeDesktop.org trash on other
+// platforms. On Windows it refuses every entry, leaving it in place.
+type TrashBackend struct{}
+
+// NewTrashBackend creates a backend that refuses every entry.
+func NewTrashBackend() *TrashBackend {
+	return &TrashBackend{}
+}
+
+// SetWholeDirs does nothing; every entry is refused anyway.
+func (b *TrashBackend) SetWholeDirs(dirs map[string]bool) {}
+
+// DeleteFile returns an error; the file is left in place.
+func (b *TrashBackend) DeleteFile(path string) error {
+	return errTrashUnavailable
+}
+
+// DeleteDirectory returns an error; the directory is left in place.
+func (b *TrashBackend) DeleteDirectory(path string) error {
+	return errTrashUnavailable
+}
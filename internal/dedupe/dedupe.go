@@ -0,0 +1,292 @@
+// Package dedupe finds byte-identical files and chooses the copy of each
+// group that survives. Candidates are narrowed down in three passes, each
+// only over the files the previous one left in a group: by size, by a hash of
+// their first bytes, and by a hash of their whole content. Files are hashed
+// in parallel.
+package dedupe
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PartialSize is how many leading bytes the second pass hashes. Files no
+// larger than this are fully hashed by it.
+const PartialSize = 64 * 1024
+
+// File is a candidate file.
+type File struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Dev     uint64 // Device and inode identify the file's data, shared by
+	Ino     uint64 // hard links; both zero if unknown
+}
+
+// sameData reports whether a and b are hard links to the same data.
+func (a File) sameData(b File) bool {
+	if a.Dev == 0 && a.Ino == 0 {
+		return a.Path == b.Path
+	}
+	return a.Dev == b.Dev && a.Ino == b.Ino
+}
+
+// Rule chooses the survivor among copies in the same preferred directory.
+type Rule int
+
+const (
+	// KeepOldest keeps the copy modified longest ago, usually the original.
+	KeepOldest Rule = iota
+	// KeepShortestPath keeps the copy with the shortest path.
+	KeepShortestPath
+)
+
+// ParseRule parses "oldest" or "shortest-path".
+func ParseRule(s string) (Rule, error) {
+	switch strings.ToLower(s) {
+	case "oldest":
+		return KeepOldest, nil
+	case "shortest-path":
+		return KeepShortestPath, nil
+	}
+	return 0, fmt.Errorf("unknown rule %q (expected oldest or shortest-path)", s)
+}
+
+// String returns the name ParseRule accepts.
+func (r Rule) String() string {
+	if r == KeepShortestPath {
+		return "shortest-path"
+	}
+	return "oldest"
+}
+
+// Options controls how duplicates are found and which copy survives.
+type Options struct {
+	Keep    Rule     // Chooses the survivor among the copies in the most preferred directory
+	Prefer  []string // Directories whose copies survive, most preferred first
+	Workers int      // Files hashed at once (0 = one per CPU; the scanner passes its worker count)
+}
+
+// Group is a set of identical files.
+type Group struct {
+	Size     int64  // Size of each file
+	Hash     string // Hex SHA-256 of the content
+	Survivor File   // The copy that is kept
+	Copies   []File // The other copies, hard links to the survivor excluded
+}
+
+// Reclaimable returns the bytes freed by removing the copies, counting data
+// shared by hard links among them once.
+func (g Group) Reclaimable() int64 {
+	var seen []File
+	for _, c := range g.Copies {
+		if !slices.ContainsFunc(seen, c.sameData) {
+			seen = append(seen, c)
+		}
+	}
+	return int64(len(seen)) * g.Size
+}
+
+// HashError is a file that could not be read. It is left out of its group.
+type HashError struct {
+	Path string
+	Err  error
+}
+
+// Find groups the identical files among files, largest first. Empty files
+// and files without a copy are left out. Files that cannot be read are
+// returned as failures; the error is ctx's if it is cancelled.
+func Find(ctx context.Context, files []File, opts Options) ([]Group, []HashError, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	bySize := make(map[int64][]File)
+	for _, f := range files {
+		if f.Size > 0 {
+			bySize[f.Size] = append(bySize[f.Size], f)
+		}
+	}
+	var candidates []candidate
+	for _, same := range bySize {
+		if distinctData(same) {
+			candidates = append(candidates, candidate{files: same})
+		}
+	}
+
+	var failed []HashError
+	var err error
+	if candidates, failed, err = refine(ctx, candidates, PartialSize, workers, failed); err != nil {
+		return nil, nil, err
+	}
+	var small, large []candidate
+	for _, c := range candidates {
+		if c.files[0].Size <= PartialSize {
+			small = append(small, c) // Already fully hashed
+		} else {
+			large = append(large, c)
+		}
+	}
+	if large, failed, err = refine(ctx, large, -1, workers, failed); err != nil {
+		return nil, nil, err
+	}
+
+	var groups []Group
+	for _, c := range append(small, large...) {
+		survivor := slices.MinFunc(c.files, opts.compare)
+		g := Group{Size: survivor.Size, Hash: c.hash, Survivor: survivor}
+		for _, f := range c.files {
+			if !f.sameData(survivor) {
+				g.Copies = append(g.Copies, f)
+			}
+		}
+		slices.SortFunc(g.Copies, func(a, b File) int { return strings.Compare(a.Path, b.Path) })
+		groups = append(groups, g)
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		return cmp.Or(cmp.Compare(b.Reclaimable(), a.Reclaimable()), strings.Compare(a.Survivor.Path, b.Survivor.Path))
+	})
+	return groups, failed, nil
+}
+
+// candidate is a set of files that may be identical, with the hash they
+// share so far.
+type candidate struct {
+	hash  string
+	files []File
+}
+
+// refine splits each candidate by a hash of the first limit bytes of its
+// files (the whole file if limit < 0), keeping the subsets that still hold
+// distinct data.
+func refine(ctx context.Context, candidates []candidate, limit int64, workers int, failed []HashError) ([]candidate, []HashError, error) {
+	var paths []string
+	for _, c := range candidates {
+		for _, f := range c.files {
+			paths = append(paths, f.Path)
+		}
+	}
+	sums, errs := hashAll(ctx, paths, limit, workers)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var refined []candidate
+	i := 0
+	for _, c := range candidates {
+		byHash := make(map[string][]File)
+		var order []string
+		for _, f := range c.files {
+			if errs[i] != nil {
+				failed = append(failed, HashError{Path: f.Path, Err: errs[i]})
+			} else {
+				if _, ok := byHash[sums[i]]; !ok {
+					order = append(order, sums[i])
+				}
+				byHash[sums[i]] = append(byHash[sums[i]], f)
+			}
+			i++
+		}
+		for _, sum := range order {
+			if distinctData(byHash[sum]) {
+				refined = append(refined, candidate{hash: sum, files: byHash[sum]})
+			}
+		}
+	}
+	return refined, failed, nil
+}
+
+// hashAll hashes the files with a pool of workers.
+func hashAll(ctx context.Context, paths []string, limit int64, workers int) ([]string, []error) {
+	sums := make([]string, len(paths))
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				sums[i], errs[i] = hashFile(paths[i], limit)
+			}
+		}()
+	}
+	for i := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return sums, errs
+}
+
+// hashFile returns the hex SHA-256 of the first limit bytes of the file, or
+// of all of it if limit < 0.
+func hashFile(path string, limit int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// distinctData reports whether files hold at least two copies of their data,
+// rather than hard links to one.
+func distinctData(files []File) bool {
+	for _, f := range files[1:] {
+		if !f.sameData(files[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare orders files by how much they deserve to survive: copies in the
+// most preferred directory first, then by the rule, then by path.
+func (o Options) compare(a, b File) int {
+	if c := cmp.Compare(o.preference(a.Path), o.preference(b.Path)); c != 0 {
+		return c
+	}
+	byAge := a.ModTime.Compare(b.ModTime)
+	byLength := cmp.Compare(len(a.Path), len(b.Path))
+	if o.Keep == KeepShortestPath {
+		return cmp.Or(byLength, byAge, strings.Compare(a.Path, b.Path))
+	}
+	return cmp.Or(byAge, byLength, strings.Compare(a.Path, b.Path))
+}
+
+// preference returns the index of the first preferred directory holding
+// path, or len(o.Prefer) if none does.
+func (o Options) preference(path string) int {
+	for i, dir := range o.Prefer {
+		dir = filepath.Clean(dir)
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return len(o.Prefer)
+}
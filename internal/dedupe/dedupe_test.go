@@ -0,0 +1,253 @@
+package dedupe
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// base is the modification time of the oldest test file.
+var base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// writeFile creates the file, relative to root and slash-separated, with the
+// given content, modified age hours after base, and returns it as a File.
+func writeFile(t *testing.T, root, name string, content []byte, age int) File {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to create %s: %v", name, err)
+	}
+	mtime := base.Add(time.Duration(age) * time.Hour)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("Failed to set times of %s: %v", name, err)
+	}
+	return File{Path: path, Size: int64(len(content)), ModTime: mtime}
+}
+
+// paths returns the paths of the files, relative to root.
+func paths(t *testing.T, root string, files []File) []string {
+	t.Helper()
+	rels := make([]string, len(files))
+	for i, f := range files {
+		rel, err := filepath.Rel(root, f.Path)
+		if err != nil {
+			t.Fatalf("Failed to relativize %s: %v", f.Path, err)
+		}
+		rels[i] = filepath.ToSlash(rel)
+	}
+	return rels
+}
+
+// TestFind tests that only identical files are grouped, including large files
+// that share their first bytes but differ further on, and that the oldest
+// copy survives by default.
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	large := bytes.Repeat([]byte("x"), PartialSize+100)
+	largeOther := bytes.Clone(large)
+	largeOther[PartialSize+50] = 'y'
+
+	files := []File{
+		writeFile(t, root, "a/report.pdf", []byte("report"), 2),
+		writeFile(t, root, "b/report.pdf", []byte("report"), 1),
+		writeFile(t, root, "c/copy.pdf", []byte("report"), 3),
+		writeFile(t, root, "a/other.txt", []byte("resort"), 0), // Same size, other content
+		writeFile(t, root, "a/empty", nil, 0),
+		writeFile(t, root, "b/empty", nil, 0),
+		writeFile(t, root, "a/big.iso", large, 5),
+		writeFile(t, root, "b/big.iso", large, 4),
+		writeFile(t, root, "c/big.iso", largeOther, 0),
+	}
+
+	groups, failed, err := Find(context.Background(), files, Options{Workers: 2})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(failed) != 0 {
+		t.Fatalf("Expected no failures, got %v", failed)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d: %v", len(groups), groups)
+	}
+
+	// Largest reclaimable first
+	if got := paths(t, root, []File{groups[0].Survivor}); got[0] != "b/big.iso" {
+		t.Errorf("Expected b/big.iso to survive, got %s", got[0])
+	}
+	if got, want := paths(t, root, groups[0].Copies), []string{"a/big.iso"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected copies %v, got %v", want, got)
+	}
+	if got := paths(t, root, []File{groups[1].Survivor}); got[0] != "b/report.pdf" {
+		t.Errorf("Expected b/report.pdf to survive, got %s", got[0])
+	}
+	if got, want := paths(t, root, groups[1].Copies), []string{"a/report.pdf", "c/copy.pdf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected copies %v, got %v", want, got)
+	}
+	if got := groups[1].Reclaimable(); got != 12 {
+		t.Errorf("Expected 12 bytes reclaimable, got %d", got)
+	}
+}
+
+// TestFind_Survivor tests the rules and preferred directories.
+func TestFind_Survivor(t *testing.T) {
+	root := t.TempDir()
+	files := []File{
+		writeFile(t, root, "incoming/2024/photo.jpg", []byte("photo"), 0),
+		writeFile(t, root, "archive/photo.jpg", []byte("photo"), 2),
+		writeFile(t, root, "x.jpg", []byte("photo"), 1),
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"oldest", Options{}, "incoming/2024/photo.jpg"},
+		{"shortest path", Options{Keep: KeepShortestPath}, "x.jpg"},
+		{"preferred", Options{Prefer: []string{filepath.Join(root, "archive")}}, "archive/photo.jpg"},
+		{"preferred order", Options{Prefer: []string{filepath.Join(root, "incoming"), filepath.Join(root, "archive")}},
+			"incoming/2024/photo.jpg"},
+		{"not a path prefix", Options{Prefer: []string{filepath.Join(root, "arch")}}, "incoming/2024/photo.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, _, err := Find(context.Background(), files, tt.opts)
+			if err != nil {
+				t.Fatalf("Find failed: %v", err)
+			}
+			if len(groups) != 1 {
+				t.Fatalf("Expected 1 group, got %d", len(groups))
+			}
+			if got := paths(t, root, []File{groups[0].Survivor})[0]; got != tt.want {
+				t.Errorf("Expected %s to survive, got %s", tt.want, got)
+			}
+			if len(groups[0].Copies) != 2 {
+				t.Errorf("Expected 2 copies, got %d", len(groups[0].Copies))
+			}
+		})
+	}
+}
+
+// TestFind_Hardlinks tests that hard links to the same data are not copies of
+// each other, and are counted once in the bytes reclaimable.
+func TestFind_Hardlinks(t *testing.T) {
+	root := t.TempDir()
+	a := writeFile(t, root, "a", []byte("data"), 0)
+	b := writeFile(t, root, "b", []byte("data"), 1)
+	c := writeFile(t, root, "c", []byte("data"), 2)
+	a.Dev, a.Ino = 1, 10
+	b.Dev, b.Ino = 1, 10 // Same data as a
+	c.Dev, c.Ino = 1, 20
+
+	groups, _, err := Find(context.Background(), []File{a, b}, Options{})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("Expected hard links not to be duplicates, got %v", groups)
+	}
+
+	// b is a link to the survivor, so only c is a copy
+	groups, _, err = Find(context.Background(), []File{a, b, c}, Options{})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Copies) != 1 || groups[0].Copies[0].Path != c.Path {
+		t.Fatalf("Expected c to be the only copy, got %v", groups)
+	}
+
+	// Two links to one copy release its data once
+	g := Group{Size: 4, Copies: []File{b, a, c}}
+	if got := g.Reclaimable(); got != 8 {
+		t.Errorf("Expected 8 bytes reclaimable, got %d", got)
+	}
+}
+
+// TestFind_Unreadable tests that a file that cannot be read is reported and
+// left out, while its readable copies are still grouped.
+func TestFind_Unreadable(t *testing.T) {
+	root := t.TempDir()
+	files := []File{
+		writeFile(t, root, "a", []byte("data"), 0),
+		writeFile(t, root, "b", []byte("data"), 1),
+		{Path: filepath.Join(root, "missing"), Size: 4},
+	}
+	groups, failed, err := Find(context.Background(), files, Options{})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Path != files[2].Path {
+		t.Errorf("Expected the missing file to fail, got %v", failed)
+	}
+	if len(groups) != 1 || len(groups[0].Copies) != 1 {
+		t.Errorf("Expected one group with one copy, got %v", groups)
+	}
+}
+
+// TestFind_Cancelled tests that a cancelled search returns the context's error.
+func TestFind_Cancelled(t *testing.T) {
+	root := t.TempDir()
+	files := []File{
+		writeFile(t, root, "a", []byte("data"), 0),
+		writeFile(t, root, "b", []byte("data"), 1),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := Find(ctx, files, Options{}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestLink tests that a copy is replaced by a link to the survivor, and that
+// a copy changed since it was found is left alone.
+func TestLink(t *testing.T) {
+	root := t.TempDir()
+	survivor := writeFile(t, root, "a/data.bin", []byte("payload"), 0)
+	copy := writeFile(t, root, "b/data.bin", []byte("payload"), 1)
+
+	if err := Link(survivor, copy); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	si, _ := os.Stat(survivor.Path)
+	ci, err := os.Stat(copy.Path)
+	if err != nil {
+		t.Fatalf("Copy is gone: %v", err)
+	}
+	if !os.SameFile(si, ci) {
+		t.Error("Expected the copy to be a link to the survivor")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(copy.Path))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %d entries", len(entries))
+	}
+
+	changed := writeFile(t, root, "c/data.bin", []byte("payload"), 2)
+	if err := os.WriteFile(changed.Path, []byte("PAYLOAD"), 0644); err != nil {
+		t.Fatalf("Failed to change file: %v", err)
+	}
+	if err := Link(survivor, changed); err == nil {
+		t.Error("Expected an error for a changed copy")
+	}
+	if content, _ := os.ReadFile(changed.Path); string(content) != "PAYLOAD" {
+		t.Errorf("Expected the changed copy to be left alone, got %q", content)
+	}
+}
+
+// TestParseRule tests the rule names.
+func TestParseRule(t *testing.T) {
+	for _, rule := range []Rule{KeepOldest, KeepShortestPath} {
+		if got, err := ParseRule(rule.String()); err != nil || got != rule {
+			t.Errorf("ParseRule(%q) = %v, %v", rule, got, err)
+		}
+	}
+	if _, err := ParseRule("newest"); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+}
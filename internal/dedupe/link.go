@@ -0,0 +1,37 @@
+package dedupe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Link replaces copy with a hard link to survivor, so that both paths share
+// the survivor's data and the copy's is released. The copy is only replaced
+// once the link exists, so a failure leaves it as it was. Both files must
+// still have the size and modification time they were found with; links
+// cannot cross file systems.
+func Link(survivor, copy File) error {
+	for _, f := range []File{survivor, copy} {
+		info, err := os.Lstat(f.Path)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || info.Size() != f.Size || !info.ModTime().Equal(f.ModTime) {
+			return fmt.Errorf("%s changed since it was scanned", f.Path)
+		}
+	}
+
+	tmp := filepath.Join(filepath.Dir(copy.Path),
+		".ffd-link-"+strconv.FormatInt(time.Now().UnixNano(), 36)+"-"+filepath.Base(copy.Path))
+	if err := os.Link(survivor.Path, tmp); err != nil {
+		return fmt.Errorf("cannot link %s to %s: %w", copy.Path, survivor.Path, err)
+	}
+	if err := os.Rename(tmp, copy.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cannot replace %s: %w", copy.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Package diskspace measures the free space of the file system holding a path,
+// so that the space a deletion actually reclaimed can be compared with what the
+// scan estimated it would release. It also reports how much of a file system is
+// in use, which sizes a tree without walking it when the tree is a file system
+// of its own.
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Free returns the number of free bytes on the file system holding path,
+// including blocks reserved for the superuser: a deletion frees blocks whoever
+// may use them afterwards. If path does not exist (for example because it was
+// just deleted), its nearest existing ancestor is measured instead.
+func Free(path string) (uint64, error) {
+	dir, err := existingAncestor(path)
+	if err != nil {
+		return 0, err
+	}
+	return freeBytes(dir)
+}
+
+// Usage is the space and number of inodes in use on a file system.
+type Usage struct {
+	UsedBytes  uint64 // Allocated bytes, including metadata
+	UsedInodes uint64 // 0 if the file system does not count inodes (e.g. btrfs, NTFS)
+}
+
+// Used returns the usage of the file system holding path.
+func Used(path string) (Usage, error) {
+	return usage(path)
+}
+
+// IsMountPoint reports whether path is the root of a file system (a mount
+// point, or a volume root on Windows), so that the file system's usage is the
+// usage of the tree below path.
+func IsMountPoint(path string) (bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, fmt.Errorf("cannot resolve %s: %w", path, err)
+	}
+	return isMountPoint(abs)
+}
+
+// existingAncestor returns path or, if it does not exist, the nearest of its
+// parents that does.
+func existingAncestor(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %s: %w", path, err)
+	}
+	for {
+		_, err := os.Lstat(abs)
+		if err == nil {
+			return abs, nil
+		}
+		parent := filepath.Dir(abs)
+		if !errors.Is(err, fs.ErrNotExist) || parent == abs {
+			return "", fmt.Errorf("cannot measure free space of %s: %w", path, err)
+		}
+		abs = parent
+	}
+}
+
+// Meter measures the space a deletion reclaims on one file system.
+type Meter struct {
+	path   string
+	before uint64
+}
+
+// Start records the free space of the file system holding path before a
+// deletion.
+func Start(path string) (*Meter, error) {
+	before, err := Free(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Meter{path: path, before: before}, nil
+}
+
+// Reclaimed returns how much the free space grew since Start. Other writers on
+// the same file system skew the figure, and space freed on file systems
+// mounted below the path is not seen; a shrinking file system yields 0.
+func (m *Meter) Reclaimed() (uint64, error) {
+	after, err := Free(m.path)
+	if err != nil {
+		return 0, err
+	}
+	if after < m.before {
+		return 0, nil
+	}
+	return after - m.before, nil
+}
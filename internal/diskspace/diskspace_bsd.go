@@ -0,0 +1,32 @@
+//go:build darwin || freebsd
+
+package diskspace
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// freeBytes returns the free blocks of the file system holding path, in bytes.
+func freeBytes(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("statfs failed for %s: %w", path, err)
+	}
+	return st.Bfree * uint64(st.Bsize), nil
+}
+
+// usage returns the used blocks, in bytes, and used inodes of the file system
+// holding path.
+func usage(path string) (Usage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return Usage{}, fmt.Errorf("statfs failed for %s: %w", path, err)
+	}
+	u := Usage{UsedBytes: (st.Blocks - st.Bfree) * uint64(st.Bsize)}
+	if free := uint64(st.Ffree); st.Files > 0 && st.Files >= free {
+		u.UsedInodes = st.Files - free
+	}
+	return u, nil
+}
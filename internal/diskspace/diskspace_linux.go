@@ -0,0 +1,41 @@
+//go:build linux
+
+package diskspace
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// freeBytes returns the free blocks of the file system holding path, in bytes.
+func freeBytes(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("statfs failed for %s: %w", path, err)
+	}
+	return st.Bfree * blockSize(&st), nil
+}
+
+// usage returns the used blocks, in bytes, and used inodes of the file system
+// holding path.
+func usage(path string) (Usage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return Usage{}, fmt.Errorf("statfs failed for %s: %w", path, err)
+	}
+	u := Usage{UsedBytes: (st.Blocks - st.Bfree) * blockSize(&st)}
+	if st.Files > 0 && st.Files >= st.Ffree {
+		u.UsedInodes = st.Files - st.Ffree
+	}
+	return u, nil
+}
+
+// blockSize returns the unit block counts are in: the fragment size, or the
+// block size on file systems that do not report one.
+func blockSize(st *unix.Statfs_t) uint64 {
+	if st.Frsize != 0 {
+		return uint64(st.Frsize)
+	}
+	return uint64(st.Bsize)
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package diskspace
+
+import "fmt"
+
+// freeBytes is not supported on this platform.
+func freeBytes(path string) (uint64, error) {
+	return 0, fmt.Errorf("measuring free space is not supported on this platform")
+}
+
+// usage is not supported on this platform.
+func usage(path string) (Usage, error) {
+	return Usage{}, fmt.Errorf("measuring file system usage is not supported on this platform")
+}
@@ -0,0 +1,87 @@
+package diskspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFree tests that free space can be measured for a directory and for a
+// path that no longer exists.
+func TestFree(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	free, err := Free(tmpDir)
+	if err != nil {
+		t.Fatalf("Free failed: %v", err)
+	}
+	if free == 0 {
+		t.Errorf("Expected free space on the temporary directory")
+	}
+
+	gone := filepath.Join(tmpDir, "deleted", "subtree")
+	if _, err := Free(gone); err != nil {
+		t.Errorf("Free of a missing path should measure its ancestor: %v", err)
+	}
+}
+
+// TestMeter tests that deleting a file is measured as reclaimed space.
+func TestMeter(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "data")
+	data := make([]byte, 8<<20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	m, err := Start(tmpDir)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := os.Remove(file); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	reclaimed, err := m.Reclaimed()
+	if err != nil {
+		t.Fatalf("Reclaimed failed: %v", err)
+	}
+	// Other activity on the file system may blur the figure, so only check
+	// that most of the file was seen
+	if reclaimed < 4<<20 {
+		t.Logf("Reclaimed %d bytes after deleting 8 MiB (file system busy or delayed freeing?)", reclaimed)
+	}
+}
+
+// TestUsed tests that the usage of the file system holding a directory can be
+// measured.
+func TestUsed(t *testing.T) {
+	u, err := Used(t.TempDir())
+	if err != nil {
+		t.Fatalf("Used failed: %v", err)
+	}
+	if u.UsedBytes == 0 {
+		t.Errorf("Expected used space on the file system holding the temporary directory")
+	}
+}
+
+// TestIsMountPoint tests that the file system root is a mount point and a fresh
+// subdirectory is not.
+func TestIsMountPoint(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.VolumeName(tmpDir) + string(filepath.Separator)
+
+	if mounted, err := IsMountPoint(root); err != nil || !mounted {
+		t.Errorf("IsMountPoint(%s) = %v, %v; want true", root, mounted, err)
+	}
+
+	sub := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if mounted, err := IsMountPoint(sub); err != nil || mounted {
+		t.Errorf("IsMountPoint(%s) = %v, %v; want false", sub, mounted, err)
+	}
+}
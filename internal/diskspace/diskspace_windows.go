@@ -0,0 +1,52 @@
+//go:build windows
+
+package diskspace
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// freeBytes returns the free bytes of the volume holding path, ignoring any
+// per-user quota.
+func freeBytes(path string) (uint64, error) {
+	pathUTF16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert path to UTF-16: %w", err)
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(pathUTF16, &available, &total, &free); err != nil {
+		return 0, fmt.Errorf("GetDiskFreeSpaceEx failed for %s: %w", path, err)
+	}
+	return free, nil
+}
+
+// usage returns the used bytes of the volume holding path. NTFS does not
+// report a file count.
+func usage(path string) (Usage, error) {
+	pathUTF16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to convert path to UTF-16: %w", err)
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(pathUTF16, &available, &total, &free); err != nil {
+		return Usage{}, fmt.Errorf("GetDiskFreeSpaceEx failed for %s: %w", path, err)
+	}
+	return Usage{UsedBytes: total - free}, nil
+}
+
+// isMountPoint reports whether path is the root of its volume.
+func isMountPoint(path string) (bool, error) {
+	pathUTF16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to convert path to UTF-16: %w", err)
+	}
+	buf := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathUTF16, &buf[0], uint32(len(buf))); err != nil {
+		return false, fmt.Errorf("GetVolumePathName failed for %s: %w", path, err)
+	}
+	volume := strings.TrimRight(windows.UTF16ToString(buf), `\`)
+	return strings.EqualFold(volume, strings.TrimRight(path, `\`)), nil
+}
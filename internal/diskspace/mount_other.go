@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package diskspace
+
+import "fmt"
+
+// isMountPoint is not supported on this platform.
+func isMountPoint(path string) (bool, error) {
+	return false, fmt.Errorf("detecting mount points is not supported on this platform")
+}
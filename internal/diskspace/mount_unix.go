@@ -0,0 +1,26 @@
+//go:build unix
+
+package diskspace
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// isMountPoint reports whether the absolute path is on another device than its
+// parent, or is the root directory.
+func isMountPoint(path string) (bool, error) {
+	var st, parent syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return false, fmt.Errorf("cannot stat %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	if dir == path {
+		return true, nil
+	}
+	if err := syscall.Lstat(dir, &parent); err != nil {
+		return false, fmt.Errorf("cannot stat %s: %w", dir, err)
+	}
+	return st.Dev != parent.Dev, nil
+}
@@ -45,17 +45,58 @@ type Engine struct {
 	workers          int
 	bufferSize       int // Custom buffer size (0 = auto-detect)
 	progressCallback func(int)
+	revalidator      Revalidator // Optional pre-deletion check (nil = disabled)
 
 	// Live counters accessible during deletion for external monitoring.
 	liveCounters atomicCounters
 	startTime    atomic.Value // stores time.Time
 }
 
+// Revalidator re-checks an entry immediately before it is deleted.
+// It receives the entry's index in the files list passed to Delete and its path,
+// and returns false if the entry changed since the scan and must be skipped.
+// Revalidators are called concurrently from multiple workers.
+type Revalidator func(index int, path string) bool
+
 // workItem represents a file or directory to delete with optional UTF-16 path.
 type workItem struct {
-	pathUTF8    string   // UTF-8 path (always present)
-	pathUTF16   *uint16  // Optional pre-converted UTF-16 path
-	isDirectory bool     // True if this is a directory (skip DeleteFile attempt)
+	index       int     // Index in the files list (used for revalidation)
+	pathUTF8    string  // UTF-8 path (always present)
+	pathUTF16   *uint16 // Optional pre-converted UTF-16 path
+	isDirectory bool    // True if this is a directory (skip DeleteFile attempt)
+}
+
+// Entries is an index-addressed list of entries to delete, such as a
+// scanner.ScanResult. See DeleteEntries.
+type Entries interface {
+	Len() int
+	Path(i int) string // Path of entry i
+	IsDir(i int) bool  // Whether entry i is a directory
+	Depth(i int) int   // Depth in the tree: entries are deleted after every deeper entry
+}
+
+// utf16Entries is implemented by Entries that carry pre-converted UTF-16 paths.
+type utf16Entries interface {
+	UTF16(i int) *uint16
+}
+
+// pathList adapts the slices passed to DeleteWithUTF16 to Entries.
+type pathList struct {
+	files       []string
+	filesUTF16  []*uint16 // Optional
+	isDirectory []bool    // Optional
+}
+
+func (l pathList) Len() int          { return len(l.files) }
+func (l pathList) Path(i int) string { return l.files[i] }
+func (l pathList) IsDir(i int) bool  { return i < len(l.isDirectory) && l.isDirectory[i] }
+func (l pathList) Depth(i int) int   { return countPathSeparators(l.files[i]) }
+
+func (l pathList) UTF16(i int) *uint16 {
+	if i < len(l.filesUTF16) {
+		return l.filesUTF16[i]
+	}
+	return nil
 }
 
 // atomicCounters provides lock-free counters for deletion statistics.
@@ -64,6 +105,13 @@ type workItem struct {
 type atomicCounters struct {
 	deleted atomic.Int64 // Number of files successfully deleted
 	failed  atomic.Int64 // Number of files that failed to delete
+	changed atomic.Int64 // Number of files skipped because they changed since the scan
+}
+
+// processed returns the number of work items that have been handled, whether
+// they were deleted, failed, or skipped by revalidation.
+func (c *atomicCounters) processed() int64 {
+	return c.deleted.Load() + c.failed.Load() + c.changed.Load()
 }
 
 // DeletionResult contains statistics and errors from a deletion operation.
@@ -72,6 +120,7 @@ type atomicCounters struct {
 type DeletionResult struct {
 	DeletedCount    int         // Number of files successfully deleted
 	FailedCount     int         // Number of files that failed to delete
+	ChangedCount    int         // Number of files skipped because they changed since the scan
 	Errors          []FileError // List of errors encountered during deletion
 	DurationSeconds float64     // Total time taken for deletion
 	PeakRate        float64     // Peak deletion rate in files/sec
@@ -139,6 +188,13 @@ func NewEngineWithBufferSize(backend backend.Backend, workers int, bufferSize in
 	}
 }
 
+// SetRevalidator enables revalidation mode. Each entry is passed to fn right
+// before deletion; entries for which fn returns false are skipped and counted
+// in DeletionResult.ChangedCount instead of being deleted. Pass nil to disable.
+func (e *Engine) SetRevalidator(fn Revalidator) {
+	e.revalidator = fn
+}
+
 // FilesDeleted returns the current count of successfully deleted files.
 // This is safe to call concurrently during deletion for live monitoring.
 func (e *Engine) FilesDeleted() int {
@@ -209,30 +265,45 @@ func (e *Engine) Delete(ctx context.Context, files []string, dryRun bool) (*Dele
 //
 // Validates Requirements: 4.5, 5.2, 5.3, 5.5
 func (e *Engine) DeleteWithUTF16(ctx context.Context, files []string, filesUTF16 []*uint16, isDirectory []bool, dryRun bool) (*DeletionResult, error) {
+	// Validate that filesUTF16 matches files length if provided
+	if filesUTF16 != nil && len(filesUTF16) != len(files) {
+		return nil, fmt.Errorf("filesUTF16 length (%d) does not match files length (%d)", len(filesUTF16), len(files))
+	}
+
+	// Validate that isDirectory matches files length if provided
+	if isDirectory != nil && len(isDirectory) != len(files) {
+		return nil, fmt.Errorf("isDirectory length (%d) does not match files length (%d)", len(isDirectory), len(files))
+	}
+
+	return e.DeleteEntries(ctx, pathList{files: files, filesUTF16: filesUTF16, isDirectory: isDirectory}, dryRun)
+}
+
+// DeleteEntries deletes entries like DeleteWithUTF16, reading them by index.
+// Each path is requested from entries when the entry is handed to a worker, so
+// a compact list such as a scanner.ScanResult scanned with SetCompact never has
+// to hold every path at once. If entries also has a UTF16(i int) *uint16
+// method, its pre-converted UTF-16 paths are used.
+//
+// Revalidator indices refer to the positions in entries.
+func (e *Engine) DeleteEntries(ctx context.Context, entries Entries, dryRun bool) (*DeletionResult, error) {
 	startTime := time.Now()
 	e.startTime.Store(startTime)
 	// Reset live counters for this deletion run
 	e.liveCounters.deleted.Store(0)
 	e.liveCounters.failed.Store(0)
+	e.liveCounters.changed.Store(0)
 
-	logger.Info("Starting deletion of %d files with %d workers", len(files), e.workers)
+	logger.Info("Starting deletion of %d files with %d workers", entries.Len(), e.workers)
 	if dryRun {
 		logger.Info("Running in DRY-RUN mode - no files will be deleted")
 	}
-
-	// Validate that filesUTF16 matches files length if provided
-	if filesUTF16 != nil && len(filesUTF16) != len(files) {
-		return nil, fmt.Errorf("filesUTF16 length (%d) does not match files length (%d)", len(filesUTF16), len(files))
-	}
-
-	// Validate that isDirectory matches files length if provided
-	if isDirectory != nil && len(isDirectory) != len(files) {
-		return nil, fmt.Errorf("isDirectory length (%d) does not match files length (%d)", len(isDirectory), len(files))
+	if e.revalidator != nil {
+		logger.Info("Revalidation enabled: entries changed since the scan will be skipped")
 	}
 
 	// Check if backend supports UTF-16 optimization
 	utf16Backend, supportsUTF16 := e.backend.(backend.UTF16Backend)
-	if filesUTF16 != nil && supportsUTF16 {
+	if u, ok := entries.(utf16Entries); ok && supportsUTF16 && entries.Len() > 0 && u.UTF16(0) != nil {
 		logger.Debug("Using UTF-16 pre-converted paths for deletion")
 	}
 
@@ -252,7 +323,7 @@ func (e *Engine) DeleteWithUTF16(ctx context.Context, files []string, filesUTF16
 	// Validates Requirements: 4.3, 5.4, 11.2
 	bufferSize := e.bufferSize
 	if bufferSize <= 0 {
-		bufferSize = len(files)
+		bufferSize = entries.Len()
 		if bufferSize > MaxAutoBufferSize {
 			bufferSize = MaxAutoBufferSize
 		}
@@ -281,7 +352,7 @@ func (e *Engine) DeleteWithUTF16(ctx context.Context, files []string, filesUTF16
 	// For very large file sets (millions of files), processing in batches allows
 	// memory from completed batches to be released before processing subsequent batches
 	// Validates Requirements: 5.5
-	err := e.processBatches(ctx, files, filesUTF16, isDirectory, workChan, counters)
+	err := e.processBatches(ctx, entries, workChan, counters)
 	if err != nil {
 		close(workChan)
 		wg.Wait()
@@ -297,6 +368,7 @@ func (e *Engine) DeleteWithUTF16(ctx context.Context, files []string, filesUTF16
 	// Copy atomic counter values to result
 	result.DeletedCount = int(counters.deleted.Load())
 	result.FailedCount = int(counters.failed.Load())
+	result.ChangedCount = int(counters.changed.Load())
 
 	// Calculate duration and rates
 	result.DurationSeconds = time.Since(startTime).Seconds()
@@ -317,6 +389,9 @@ func (e *Engine) DeleteWithUTF16(ctx context.Context, files []string, filesUTF16
 
 	logger.Info("Deletion completed: %d succeeded, %d failed in %.2f seconds",
 		result.DeletedCount, result.FailedCount, result.DurationSeconds)
+	if result.ChangedCount > 0 {
+		logger.Info("Skipped %d entries that changed since the scan", result.ChangedCount)
+	}
 
 	return result, nil
 }
@@ -326,21 +401,21 @@ func (e *Engine) DeleteWithUTF16(ctx context.Context, files []string, filesUTF16
 // each depth level is processed in batches to limit memory usage.
 //
 // Validates Requirements: 5.5
-func (e *Engine) processBatches(ctx context.Context, files []string, filesUTF16 []*uint16, isDirectory []bool, workChan chan<- workItem, counters *atomicCounters) error {
+func (e *Engine) processBatches(ctx context.Context, entries Entries, workChan chan<- workItem, counters *atomicCounters) error {
 
 	// Build the depth map once: group file indices by directory depth
-	depthMap := make(map[int][]int) // depth -> list of file indices
+	depthMap := make(map[int][]int32) // depth -> list of file indices
 	maxDepth := 0
 
-	for i, file := range files {
-		depth := countPathSeparators(file)
-		depthMap[depth] = append(depthMap[depth], i)
+	totalFiles := entries.Len()
+	for i := 0; i < totalFiles; i++ {
+		depth := entries.Depth(i)
+		depthMap[depth] = append(depthMap[depth], int32(i))
 		if depth > maxDepth {
 			maxDepth = depth
 		}
 	}
 
-	totalFiles := len(files)
 	useBatching := totalFiles >= BatchThreshold
 
 	if useBatching {
@@ -360,14 +435,14 @@ func (e *Engine) processBatches(ctx context.Context, files []string, filesUTF16
 
 		if useBatching {
 			// Process in batches for large file sets
-			if err := e.processIndicesInBatches(ctx, files, filesUTF16, isDirectory, indices, BatchSize, workChan, counters); err != nil {
+			if err := e.processIndicesInBatches(ctx, entries, indices, BatchSize, workChan, counters); err != nil {
 				return err
 			}
 			runtime.GC()
 			logger.Debug("Released memory after processing depth %d", depth)
 		} else {
 			// Send all items at this depth, then wait for completion
-			if err := e.processIndicesAndWait(ctx, files, filesUTF16, isDirectory, indices, workChan, counters); err != nil {
+			if err := e.processIndicesAndWait(ctx, entries, indices, workChan, counters); err != nil {
 				return err
 			}
 		}
@@ -376,14 +451,11 @@ func (e *Engine) processBatches(ctx context.Context, files []string, filesUTF16
 	return nil
 }
 
-// makeWorkItem creates a workItem from the file arrays at the given index.
-func makeWorkItem(files []string, filesUTF16 []*uint16, isDirectory []bool, i int) workItem {
-	item := workItem{pathUTF8: files[i]}
-	if filesUTF16 != nil && i < len(filesUTF16) {
-		item.pathUTF16 = filesUTF16[i]
-	}
-	if isDirectory != nil && i < len(isDirectory) {
-		item.isDirectory = isDirectory[i]
+// makeWorkItem creates a workItem for the entry at the given index, building its path.
+func makeWorkItem(entries Entries, i int32) workItem {
+	item := workItem{index: int(i), pathUTF8: entries.Path(int(i)), isDirectory: entries.IsDir(int(i))}
+	if u, ok := entries.(utf16Entries); ok {
+		item.pathUTF16 = u.UTF16(int(i))
 	}
 	return item
 }
@@ -392,7 +464,7 @@ func makeWorkItem(files []string, filesUTF16 []*uint16, isDirectory []bool, i in
 // window approach where the next batch starts after 80% of the current batch completes.
 //
 // Validates Requirements: 5.5
-func (e *Engine) processIndicesInBatches(ctx context.Context, files []string, filesUTF16 []*uint16, isDirectory []bool, indices []int, batchSize int, workChan chan<- workItem, counters *atomicCounters) error {
+func (e *Engine) processIndicesInBatches(ctx context.Context, entries Entries, indices []int32, batchSize int, workChan chan<- workItem, counters *atomicCounters) error {
 	for start := 0; start < len(indices); start += batchSize {
 		end := start + batchSize
 		if end > len(indices) {
@@ -400,12 +472,12 @@ func (e *Engine) processIndicesInBatches(ctx context.Context, files []string, fi
 		}
 
 		batchIndices := indices[start:end]
-		countBefore := counters.deleted.Load() + counters.failed.Load()
+		countBefore := counters.processed()
 
 		// Send all items in the batch
 		for _, i := range batchIndices {
 			select {
-			case workChan <- makeWorkItem(files, filesUTF16, isDirectory, i):
+			case workChan <- makeWorkItem(entries, i):
 			case <-ctx.Done():
 				return fmt.Errorf("deletion interrupted by user")
 			}
@@ -418,7 +490,7 @@ func (e *Engine) processIndicesInBatches(ctx context.Context, files []string, fi
 			case <-ctx.Done():
 				return fmt.Errorf("deletion interrupted by user")
 			default:
-				processed := (counters.deleted.Load() + counters.failed.Load()) - countBefore
+				processed := counters.processed() - countBefore
 				if processed >= threshold {
 					time.Sleep(2 * time.Millisecond)
 					goto nextBatch
@@ -433,12 +505,12 @@ func (e *Engine) processIndicesInBatches(ctx context.Context, files []string, fi
 
 // processIndicesAndWait sends all items at the given indices to workers and waits
 // for them all to be processed before returning. Used for smaller file sets.
-func (e *Engine) processIndicesAndWait(ctx context.Context, files []string, filesUTF16 []*uint16, isDirectory []bool, indices []int, workChan chan<- workItem, counters *atomicCounters) error {
-	countBefore := counters.deleted.Load() + counters.failed.Load()
+func (e *Engine) processIndicesAndWait(ctx context.Context, entries Entries, indices []int32, workChan chan<- workItem, counters *atomicCounters) error {
+	countBefore := counters.processed()
 
 	for _, i := range indices {
 		select {
-		case workChan <- makeWorkItem(files, filesUTF16, isDirectory, i):
+		case workChan <- makeWorkItem(entries, i):
 		case <-ctx.Done():
 			return fmt.Errorf("deletion interrupted by user")
 		}
@@ -451,7 +523,7 @@ func (e *Engine) processIndicesAndWait(ctx context.Context, files []string, file
 		case <-ctx.Done():
 			return fmt.Errorf("deletion interrupted by user")
 		default:
-			if counters.deleted.Load()+counters.failed.Load() >= expected {
+			if counters.processed() >= expected {
 				return nil
 			}
 			time.Sleep(time.Millisecond)
@@ -492,6 +564,13 @@ func (e *Engine) workerWithUTF16(ctx context.Context, workChan <-chan workItem,
 
 			// Process this file
 			logger.Debug("Processing: %s", item.pathUTF8)
+
+			// In revalidation mode, skip entries that changed since the scan
+			if e.revalidator != nil && !e.revalidator(item.index, item.pathUTF8) {
+				counters.changed.Add(1)
+				logger.Info("Skipping (changed since scan): %s", item.pathUTF8)
+				continue
+			}
 			
 			var err error
 			if dryRun {
@@ -1254,3 +1254,46 @@ func TestBufferSizeCalculationBoundaries(t *testing.T) {
 		})
 	}
 }
+
+// TestRevalidatorSkipsChangedEntries tests that entries rejected by the
+// revalidator are skipped, left on disk, and counted as changed since scan.
+func TestRevalidatorSkipsChangedEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := make([]string, 0, 6)
+	for i := 0; i < 6; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("file_%d.txt", i))
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		files = append(files, path)
+	}
+
+	eng := NewEngine(backend.NewBackend(), 2, nil)
+	eng.SetRevalidator(func(index int, path string) bool {
+		if files[index] != path {
+			t.Errorf("Revalidator index %d does not match path %s", index, path)
+		}
+		return index%2 == 0
+	})
+
+	result, err := eng.Delete(context.Background(), files, false)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if result.DeletedCount != 3 || result.ChangedCount != 3 || result.FailedCount != 0 {
+		t.Errorf("Expected 3 deleted, 3 changed, 0 failed; got %d deleted, %d changed, %d failed",
+			result.DeletedCount, result.ChangedCount, result.FailedCount)
+	}
+
+	for i, path := range files {
+		_, err := os.Stat(path)
+		if i%2 == 0 && !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted", path)
+		}
+		if i%2 == 1 && err != nil {
+			t.Errorf("Expected changed entry %s to be kept, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package estimate predicts how large a directory tree is and how long
+// scanning and deleting it will take, in a fraction of the time a scan needs.
+//
+// The size comes from the file system's usage when the tree is a file system
+// of its own, and otherwise from random descents through the tree (see
+// scanner.SampleTree). Durations divide the size by the scan and deletion
+// rates of previous runs, recorded in a history file, falling back to the
+// rate seen while sampling or to conservative defaults.
+package estimate
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/diskspace"
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// Method is how the size of a tree was estimated.
+type Method string
+
+const (
+	// MethodFileSystem counts the inodes and blocks in use on the file system
+	// the tree is the root of.
+	MethodFileSystem Method = "file system statistics"
+	// MethodSampling extrapolates from random descents through the tree.
+	MethodSampling Method = "sampling"
+)
+
+// RateSource is where a rate comes from.
+type RateSource string
+
+const (
+	RateFromHistory RateSource = "previous runs" // Median of the runs recorded in the history
+	RateFromSample  RateSource = "sampling"      // Speed at which the sample read the tree
+	RateDefault     RateSource = "default"       // No measurement available
+)
+
+// Rate is a throughput in entries per second, with its plausible range.
+type Rate struct {
+	Value  float64
+	Low    float64
+	High   float64
+	Runs   int // Runs the rate was measured from (RateFromHistory only)
+	Source RateSource
+}
+
+// Default rates, used without history. They are rough figures for local
+// disks and deliberately wide.
+var (
+	defaultScanRate   = Rate{Value: 20000, Low: 5000, High: 80000, Source: RateDefault}
+	defaultDeleteRate = Rate{Value: 5000, Low: 1000, High: 20000, Source: RateDefault}
+)
+
+// DurationRange is an estimated duration with its bounds.
+type DurationRange struct {
+	Value time.Duration
+	Low   time.Duration
+	High  time.Duration
+}
+
+// Result is the estimate for a tree.
+type Result struct {
+	Root    string
+	Method  Method
+	Entries scanner.EstimateRange // Files and directories below the root
+	Bytes   scanner.EstimateRange // File sizes (sampling) or allocated blocks (file system statistics)
+	Sample  *scanner.TreeSample   // nil with MethodFileSystem
+
+	ScanRate   Rate
+	DeleteRate Rate
+	Scan       DurationRange
+	Delete     DurationRange
+}
+
+// Estimate estimates the size of the tree below root and the time scanning
+// and deleting it takes, using the rates recorded in history (which may be
+// nil). File system statistics are used when root is a mount point whose file
+// system counts inodes; they include hardlinked files once and exclude file
+// systems mounted further down. Otherwise the tree is sampled with probes
+// descents (0 for scanner.DefaultSampleProbes). ctx stops the sampling.
+func Estimate(ctx context.Context, root string, probes int, history *History) (*Result, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %w", root, err)
+	}
+	result := &Result{Root: abs}
+
+	if usage, ok := fileSystemUsage(abs); ok {
+		entries := int64(usage.UsedInodes - 1) // The root directory's own inode
+		bytes := int64(usage.UsedBytes)
+		result.Method = MethodFileSystem
+		result.Entries = scanner.EstimateRange{Value: entries, Low: entries, High: entries}
+		result.Bytes = scanner.EstimateRange{Value: bytes, Low: bytes, High: bytes}
+		result.ScanRate = defaultScanRate
+	} else {
+		rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0))
+		sample, err := scanner.SampleTree(ctx, abs, probes, rng)
+		if err != nil {
+			return nil, err
+		}
+		result.Method = MethodSampling
+		result.Entries = sample.Entries
+		result.Bytes = sample.Bytes
+		result.Sample = sample
+		result.ScanRate = sampleRate(sample)
+	}
+	logger.Info("Estimate of %s by %s: %d entries (%d-%d), %d bytes (%d-%d)", abs, result.Method,
+		result.Entries.Value, result.Entries.Low, result.Entries.High,
+		result.Bytes.Value, result.Bytes.Low, result.Bytes.High)
+
+	result.DeleteRate = defaultDeleteRate
+	if history != nil {
+		if rate, ok := history.ScanRate(abs); ok {
+			result.ScanRate = rate
+		}
+		if rate, ok := history.DeleteRate(abs); ok {
+			result.DeleteRate = rate
+		}
+	}
+	result.Scan = durationOf(result.Entries, result.ScanRate)
+	result.Delete = durationOf(result.Entries, result.DeleteRate)
+	return result, nil
+}
+
+// fileSystemUsage returns the usage of the file system root is the mount
+// point of, if it counts inodes.
+func fileSystemUsage(root string) (diskspace.Usage, bool) {
+	mounted, err := diskspace.IsMountPoint(root)
+	if err != nil || !mounted {
+		return diskspace.Usage{}, false
+	}
+	usage, err := diskspace.Used(root)
+	if err != nil {
+		logger.Debug("File system usage unavailable for %s: %v", root, err)
+		return diskspace.Usage{}, false
+	}
+	if usage.UsedInodes == 0 {
+		logger.Debug("File system of %s does not count inodes, sampling instead", root)
+		return diskspace.Usage{}, false
+	}
+	return usage, true
+}
+
+// sampleRate returns the rate at which the sample read entries, which reads
+// directories the way a scan does, with bounds as wide as a single
+// measurement deserves. Samples too small to time fall back to the default.
+func sampleRate(sample *scanner.TreeSample) Rate {
+	seconds := sample.Elapsed.Seconds()
+	if sample.EntriesRead < minRunEntries || seconds <= 0 {
+		return defaultScanRate
+	}
+	rate := float64(sample.EntriesRead) / seconds
+	return Rate{Value: rate, Low: rate / 2, High: rate * 2, Source: RateFromSample}
+}
+
+// durationOf divides an entry count by a rate. The shortest duration pairs
+// the fewest entries with the fastest rate, the longest the most entries with
+// the slowest.
+func durationOf(entries scanner.EstimateRange, rate Rate) DurationRange {
+	seconds := func(n int64, perSecond float64) time.Duration {
+		return time.Duration(float64(n) / perSecond * float64(time.Second))
+	}
+	return DurationRange{
+		Value: seconds(entries.Value, rate.Value),
+		Low:   seconds(entries.Low, rate.High),
+		High:  seconds(entries.High, rate.Low),
+	}
+}
@@ -0,0 +1,129 @@
+package estimate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestHistory_RoundTrip tests that recorded runs are written and read back,
+// and that only the most recent runs are kept.
+func TestHistory_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "history.json")
+
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory of a missing file failed: %v", err)
+	}
+	if len(h.Runs) != 0 {
+		t.Fatalf("Expected an empty history, got %d runs", len(h.Runs))
+	}
+
+	for i := 0; i < maxHistoryRuns+5; i++ {
+		if err := h.Record(Run{Root: "/data", Scanned: i}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	h, err = LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(h.Runs) != maxHistoryRuns {
+		t.Fatalf("Expected %d runs, got %d", maxHistoryRuns, len(h.Runs))
+	}
+	if first := h.Runs[0].Scanned; first != 5 {
+		t.Errorf("Expected the oldest runs to be dropped, first run scanned %d", first)
+	}
+}
+
+// TestHistory_Invalid tests that a damaged history file is reported.
+func TestHistory_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := LoadHistory(path); err == nil {
+		t.Error("Expected an error for a damaged history")
+	}
+}
+
+// TestHistory_Rates tests that rates come from the runs on the same root when
+// there are any, ignore runs too small to measure, and have bounds around the
+// median.
+func TestHistory_Rates(t *testing.T) {
+	h := &History{Runs: []Run{
+		{Root: "/a", Scanned: 10000, ScanSeconds: 1, Deleted: 10000, DeleteSeconds: 4},
+		{Root: "/a", Scanned: 20000, ScanSeconds: 1},
+		{Root: "/a", Scanned: 30000, ScanSeconds: 1},
+		{Root: "/b", Scanned: 100000, ScanSeconds: 1},
+		{Root: "/b", Scanned: 10, ScanSeconds: 0.001}, // Too small
+	}}
+
+	rate, ok := h.ScanRate("/a")
+	if !ok || rate.Value != 20000 || rate.Runs != 3 || rate.Source != RateFromHistory {
+		t.Errorf("ScanRate(/a) = %+v, %v; want the median 20000 of 3 runs", rate, ok)
+	}
+	if rate.Low > 10000 || rate.High < 30000 {
+		t.Errorf("ScanRate(/a) bounds %v-%v should cover the runs", rate.Low, rate.High)
+	}
+
+	rate, ok = h.ScanRate("/c")
+	if !ok || rate.Runs != 4 || rate.Value != 25000 {
+		t.Errorf("ScanRate(/c) = %+v, %v; want the median 25000 of all 4 runs", rate, ok)
+	}
+
+	rate, ok = h.DeleteRate("/b")
+	if !ok || rate.Value != 2500 {
+		t.Errorf("DeleteRate(/b) = %+v, %v; want 2500 from the only deleting run", rate, ok)
+	}
+	if rate.Low >= 2500 || rate.High <= 2500 {
+		t.Errorf("DeleteRate(/b) bounds %v-%v should widen around a single run", rate.Low, rate.High)
+	}
+
+	if _, ok := (&History{}).DeleteRate("/a"); ok {
+		t.Error("Expected no rate from an empty history")
+	}
+}
+
+// TestEstimate tests an estimate of a small tree, which sampling counts
+// exactly, with durations from the history.
+func TestEstimate(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 10; i++ {
+		dir := filepath.Join(root, "dir"+strconv.Itoa(i))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	history := &History{Runs: []Run{{Root: root, Scanned: 2000, ScanSeconds: 1, Deleted: 1000, DeleteSeconds: 1}}}
+
+	result, err := Estimate(context.Background(), root, 0, history)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if result.Method != MethodSampling || !result.Sample.Exact {
+		t.Fatalf("Expected an exact sample, got %s (exact %v)", result.Method, result.Sample != nil && result.Sample.Exact)
+	}
+	if result.Entries.Value != 20 || result.Bytes.Value != 40 {
+		t.Errorf("Estimated %d entries and %d bytes, want 20 and 40", result.Entries.Value, result.Bytes.Value)
+	}
+	if result.ScanRate.Source != RateFromHistory || result.DeleteRate.Source != RateFromHistory {
+		t.Errorf("Expected rates from the history, got %s and %s", result.ScanRate.Source, result.DeleteRate.Source)
+	}
+	if want := 10 * time.Millisecond; result.Scan.Value != want {
+		t.Errorf("Scan duration = %s, want %s", result.Scan.Value, want)
+	}
+	if want := 20 * time.Millisecond; result.Delete.Value != want {
+		t.Errorf("Delete duration = %s, want %s", result.Delete.Value, want)
+	}
+	if result.Delete.Low > result.Delete.Value || result.Delete.High < result.Delete.Value {
+		t.Errorf("Delete bounds %s-%s should surround %s", result.Delete.Low, result.Delete.High, result.Delete.Value)
+	}
+}
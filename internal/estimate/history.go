@@ -0,0 +1,150 @@
+package estimate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// maxHistoryRuns is how many runs a history keeps, newest last.
+const maxHistoryRuns = 50
+
+// minRunEntries is the smallest run whose rates are used: below it, start-up
+// costs dominate and the rate says little about larger trees.
+const minRunEntries = 1000
+
+// Run records the throughput of one run.
+type Run struct {
+	Time          time.Time `json:"time"`
+	Root          string    `json:"root"` // Absolute path of the target directory
+	Scanned       int       `json:"scanned"`
+	ScanSeconds   float64   `json:"scanSeconds"`
+	Deleted       int       `json:"deleted,omitempty"` // 0 for dry runs
+	DeleteSeconds float64   `json:"deleteSeconds,omitempty"`
+}
+
+// History holds the runs recorded in a history file.
+type History struct {
+	path string
+	Runs []Run
+}
+
+// DefaultHistoryPath returns the history file shared by the runs of the
+// current user, in the user's cache directory.
+func DefaultHistoryPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "fast-file-deletion", "history.json")
+}
+
+// LoadHistory reads the history file at path. A missing file is an empty
+// history. On error the history returned is empty, and recording into it
+// replaces the file.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("cannot read run history: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.Runs); err != nil {
+		h.Runs = nil
+		return h, fmt.Errorf("invalid run history %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// Record adds a run to the history and writes it back, keeping the most
+// recent runs only. The file is replaced atomically, so a concurrent run can
+// at worst lose the other's record.
+func (h *History) Record(run Run) error {
+	h.Runs = append(h.Runs, run)
+	if len(h.Runs) > maxHistoryRuns {
+		h.Runs = slices.Clone(h.Runs[len(h.Runs)-maxHistoryRuns:])
+	}
+
+	data, err := json.MarshalIndent(h.Runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode run history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("cannot create run history directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), ".history-*.json")
+	if err != nil {
+		return fmt.Errorf("cannot write run history: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), h.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cannot write run history: %w", err)
+	}
+	return nil
+}
+
+// ScanRate returns the scan rate, in entries per second, of the recorded runs
+// (see rateOf). ok is false if no run was large enough to measure it.
+func (h *History) ScanRate(root string) (rate Rate, ok bool) {
+	return h.rateOf(root, func(r Run) (int, float64) { return r.Scanned, r.ScanSeconds })
+}
+
+// DeleteRate returns the deletion rate, in entries per second, of the
+// recorded runs that deleted (see rateOf). ok is false if no run was large
+// enough to measure it.
+func (h *History) DeleteRate(root string) (rate Rate, ok bool) {
+	return h.rateOf(root, func(r Run) (int, float64) { return r.Deleted, r.DeleteSeconds })
+}
+
+// rateOf returns the median rate of the runs on root or, if none of them was
+// large enough, of all runs. The bounds are the slowest and fastest of those
+// runs, but at least a third below and half above the median: rates vary
+// with caches and load more than a few runs show.
+func (h *History) rateOf(root string, measure func(Run) (int, float64)) (Rate, bool) {
+	var same, all []float64
+	for _, r := range h.Runs {
+		n, seconds := measure(r)
+		if n < minRunEntries || seconds <= 0 {
+			continue
+		}
+		rate := float64(n) / seconds
+		all = append(all, rate)
+		if r.Root == root {
+			same = append(same, rate)
+		}
+	}
+	rates := same
+	if len(rates) == 0 {
+		rates = all
+	}
+	if len(rates) == 0 {
+		return Rate{}, false
+	}
+
+	slices.Sort(rates)
+	median := rates[len(rates)/2]
+	if len(rates)%2 == 0 {
+		median = (rates[len(rates)/2-1] + rates[len(rates)/2]) / 2
+	}
+	return Rate{
+		Value:  median,
+		Low:    min(rates[0], median/1.5),
+		High:   max(rates[len(rates)-1], median*1.5),
+		Runs:   len(rates),
+		Source: RateFromHistory,
+	}, true
+}
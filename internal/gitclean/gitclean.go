@@ -0,0 +1,359 @@
+// Package gitclean selects what git clean would remove from a working tree:
+// the files git ignores (git clean -X -d) or every untracked file (git clean
+// -x -d), so that the scanner and engine can delete large ignored build
+// outputs in parallel.
+//
+// Tracked files are read from the index and are never selected, nor are the
+// .git directory, submodules and untracked nested repositories. Ignore rules
+// come from the .gitignore file of every directory, read as the scan reaches
+// it, from $GIT_DIR/info/exclude and from the core.excludesFile of the git
+// configuration (by default $XDG_CONFIG_HOME/git/ignore).
+package gitclean
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// Mode selects what a Cleaner matches.
+type Mode int
+
+const (
+	// ModeIgnored matches ignored files and directories, like git clean -X -d.
+	ModeIgnored Mode = iota
+	// ModeUntracked matches every untracked file and directory, ignored or
+	// not, like git clean -x -d.
+	ModeUntracked
+)
+
+// ParseMode parses "ignored" or "untracked".
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "ignored":
+		return ModeIgnored, nil
+	case "untracked":
+		return ModeUntracked, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q (expected ignored or untracked)", s)
+}
+
+// String returns the name ParseMode accepts.
+func (m Mode) String() string {
+	if m == ModeUntracked {
+		return "untracked"
+	}
+	return "ignored"
+}
+
+// Cleaner is a scanner.Filter matching what git clean would remove below a
+// directory of a working tree. Paths are kept relative to the working tree,
+// slash-separated, with "" for its root. It is safe for concurrent use, so
+// the engine can use it to revalidate entries.
+type Cleaner struct {
+	mode     Mode
+	worktree string // Root of the working tree
+	prefix   string // Scan root relative to the working tree ("" = the root)
+
+	tracked     map[string]bool // Files and submodules in the index
+	trackedDirs map[string]bool // Directories holding tracked entries
+	submodules  []string
+
+	mu          sync.Mutex
+	excludes    []gitignore.Pattern            // core.excludesFile, then info/exclude
+	dirPatterns map[string][]gitignore.Pattern // .gitignore patterns by directory
+	ignoredDirs map[string]bool                // Ignore decisions for directories
+	nested      map[string]bool                // Submodules and untracked nested repositories
+}
+
+// Open prepares a Cleaner for root, which must be in a git working tree
+// (at its top or below it), but not in its .git directory.
+func Open(root string, mode Mode) (*Cleaner, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainOpenWithOptions(abs, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("%s is not in a git working tree", root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the git repository of %s: %w", root, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the git working tree of %s: %w", root, err)
+	}
+
+	c := &Cleaner{
+		mode:        mode,
+		worktree:    wt.Filesystem.Root(),
+		tracked:     make(map[string]bool),
+		trackedDirs: make(map[string]bool),
+		dirPatterns: make(map[string][]gitignore.Pattern),
+		ignoredDirs: make(map[string]bool),
+		nested:      make(map[string]bool),
+	}
+	if c.prefix, err = relativeTo(c.worktree, abs); err != nil {
+		return nil, err
+	}
+	if isGitPath(c.prefix) {
+		return nil, fmt.Errorf("%s is in a .git directory", root)
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the git index: %w", err)
+	}
+	for _, e := range idx.Entries {
+		c.tracked[e.Name] = true
+		if e.Mode == filemode.Submodule {
+			c.nested[e.Name] = true
+			c.submodules = append(c.submodules, e.Name)
+		}
+		for dir := path.Dir(e.Name); dir != "."; dir = path.Dir(dir) {
+			c.trackedDirs[dir] = true
+		}
+	}
+
+	if file := excludesFile(repo); file != "" {
+		if c.excludes, err = readPatterns(file, nil); err != nil {
+			return nil, err
+		}
+	}
+	if st, ok := repo.Storer.(*filesystem.Storage); ok {
+		info, err := readPatterns(filepath.Join(st.Filesystem().Root(), "info", "exclude"), nil)
+		if err != nil {
+			return nil, err
+		}
+		c.excludes = append(c.excludes, info...)
+	}
+	return c, nil
+}
+
+// Worktree returns the root of the working tree.
+func (c *Cleaner) Worktree() string {
+	return c.worktree
+}
+
+// Excludes returns gitignore-style patterns, relative to the scan root, for
+// the subtrees that must not even be scanned: .git directories and files, and
+// the submodules.
+func (c *Cleaner) Excludes() []string {
+	patterns := []string{".git"}
+	for _, sub := range c.submodules {
+		rel := sub
+		if c.prefix != "" {
+			var ok bool
+			if rel, ok = strings.CutPrefix(sub, c.prefix+"/"); !ok {
+				continue
+			}
+		}
+		patterns = append(patterns, "/"+escapePattern(rel)+"/")
+	}
+	return patterns
+}
+
+// Match reports whether git clean would remove the entry, in the Cleaner's
+// mode.
+func (c *Cleaner) Match(e *scanner.Entry) (bool, error) {
+	rel := e.RelPath
+	if c.prefix != "" {
+		rel = c.prefix + "/" + rel
+	}
+	if isGitPath(rel) || c.tracked[rel] || c.trackedDirs[rel] {
+		return false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := rel; p != "."; p = path.Dir(p) {
+		if c.nested[p] {
+			return false, nil
+		}
+	}
+	if e.IsDir() && isRepository(e.Path) {
+		// git clean leaves untracked repositories alone unless forced twice
+		c.nested[rel] = true
+		return false, nil
+	}
+	if c.mode == ModeUntracked {
+		return true, nil
+	}
+	return c.ignored(rel, e.IsDir()), nil
+}
+
+// ignored reports whether the path is ignored: matched by the ignore rules,
+// or in an ignored directory, since git never looks inside those.
+func (c *Cleaner) ignored(rel string, isDir bool) bool {
+	if dir := path.Dir(rel); dir != "." && c.ignoredDir(dir) {
+		return true
+	}
+	return c.matches(rel, isDir)
+}
+
+// ignoredDir is ignored for directories, remembering the decisions.
+func (c *Cleaner) ignoredDir(dir string) bool {
+	ignored, ok := c.ignoredDirs[dir]
+	if !ok {
+		ignored = c.ignored(dir, true)
+		c.ignoredDirs[dir] = ignored
+	}
+	return ignored
+}
+
+// matches applies the ignore rules to the path: those of the .gitignore
+// files from its directory up, then the excludes. The first rule that
+// matches, reading each file from the bottom, decides.
+func (c *Cleaner) matches(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	for depth := len(parts) - 1; depth >= 0; depth-- {
+		if result := lastMatch(c.patternsOf(parts[:depth]), parts, isDir); result != gitignore.NoMatch {
+			return result == gitignore.Exclude
+		}
+	}
+	return lastMatch(c.excludes, parts, isDir) == gitignore.Exclude
+}
+
+// patternsOf returns the patterns of the .gitignore file in the directory
+// whose path components are given, reading it on first use. An unreadable
+// file ignores nothing, which only keeps more.
+func (c *Cleaner) patternsOf(dir []string) []gitignore.Pattern {
+	key := strings.Join(dir, "/")
+	patterns, ok := c.dirPatterns[key]
+	if !ok {
+		file := filepath.Join(c.worktree, filepath.FromSlash(key), ".gitignore")
+		patterns, _ = readPatterns(file, dir)
+		c.dirPatterns[key] = patterns
+	}
+	return patterns
+}
+
+// lastMatch returns the result of the last pattern matching the path.
+func lastMatch(patterns []gitignore.Pattern, parts []string, isDir bool) gitignore.MatchResult {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if result := patterns[i].Match(parts, isDir); result != gitignore.NoMatch {
+			return result
+		}
+	}
+	return gitignore.NoMatch
+}
+
+// readPatterns reads an ignore file whose patterns apply below domain. A
+// missing file has no patterns.
+func readPatterns(file string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read ignore rules: %w", err)
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read ignore rules %s: %w", file, err)
+	}
+	return patterns, nil
+}
+
+// excludesFile returns the core.excludesFile in effect for the repository:
+// from its own configuration, the user's or the system's, in that order, or
+// git's default in the user's configuration directory.
+func excludesFile(repo *git.Repository) string {
+	var file string
+	if cfg, err := repo.Config(); err == nil {
+		file = cfg.Raw.Section("core").Option("excludesfile")
+	}
+	for _, scope := range []config.Scope{config.GlobalScope, config.SystemScope} {
+		if file != "" {
+			break
+		}
+		if cfg, err := config.LoadConfig(scope); err == nil {
+			file = cfg.Raw.Section("core").Option("excludesfile")
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	switch {
+	case file == "" && os.Getenv("XDG_CONFIG_HOME") != "":
+		return filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "git", "ignore")
+	case file == "" && home != "":
+		return filepath.Join(home, ".config", "git", "ignore")
+	case strings.HasPrefix(file, "~/") && home != "":
+		return filepath.Join(home, file[2:])
+	}
+	return file
+}
+
+// relativeTo returns target relative to root, slash-separated, with "" for
+// root itself, resolving symbolic links so that both are compared as the
+// same kind of path.
+func relativeTo(root, target string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the working tree %s", target, root)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// isGitPath reports whether a slash-separated path is, or is in, a .git
+// directory or file.
+func isGitPath(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// isRepository reports whether dir holds a .git directory or file.
+func isRepository(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// escapePattern escapes the characters that are special in gitignore-style
+// patterns, so that a path matches only itself.
+func escapePattern(rel string) string {
+	var sb strings.Builder
+	for _, r := range rel {
+		if strings.ContainsRune(`\*?[ `, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,176 @@
+package gitclean
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// makeRepo creates a working tree with tracked, ignored and untracked files,
+// a submodule, an untracked nested repository, and user-wide excludes. The
+// user's own git configuration is hidden from the test.
+func makeRepo(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	files := map[string]string{
+		".gitignore":         "*.o\nbuild/\n!keep.o\n",
+		"main.c":             "",
+		"main.o":             "",
+		"keep.o":             "",
+		"notes.txt":          "",
+		"src/.gitignore":     "gen/\n*.tmp\n",
+		"src/lib.c":          "",
+		"src/lib.o":          "",
+		"src/gen/out.c":      "",
+		"src/scratch.tmp":    "",
+		"build/app":          "",
+		"build/README":       "",
+		"vendor/x/.git/HEAD": "",
+		"vendor/x/data.o":    "",
+		"sub/.git":           "gitdir: ../.git/modules/sub",
+		"sub/code.o":         "",
+		"cache/big.bin":      "",
+		"home-excluded.swp":  "",
+		".git/info/exclude":  "cache/\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	ignore := filepath.Join(home, "config", "git", "ignore")
+	if err := os.MkdirAll(filepath.Dir(ignore), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(ignore, []byte("*.swp\n"), 0644); err != nil {
+		t.Fatalf("Failed to create global excludes: %v", err)
+	}
+
+	// Track sources, build/README despite build/ being ignored, and the submodule
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to open worktree: %v", err)
+	}
+	for _, name := range []string{".gitignore", "main.c", "src/.gitignore", "src/lib.c"} {
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	idx.Entries = append(idx.Entries,
+		&index.Entry{Name: "build/README", Mode: filemode.Regular, Hash: plumbing.ZeroHash},
+		&index.Entry{Name: "sub", Mode: filemode.Submodule, Hash: plumbing.ZeroHash},
+	)
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	return root
+}
+
+// scanClean scans dir, below the working tree root, the way the CLI does and
+// returns the relative paths selected for deletion.
+func scanClean(t *testing.T, dir string, mode Mode) []string {
+	t.Helper()
+	c, err := Open(dir, mode)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	exclude, err := scanner.NewMatcher(c.Excludes())
+	if err != nil {
+		t.Fatalf("Invalid excludes %v: %v", c.Excludes(), err)
+	}
+	s := scanner.NewScanner(dir, nil)
+	s.SetExclude(exclude)
+	s.SetFilter(c)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var deleted []string
+	for _, path := range result.Files {
+		rel, _ := filepath.Rel(dir, path)
+		deleted = append(deleted, filepath.ToSlash(rel))
+	}
+	slices.Sort(deleted)
+	return deleted
+}
+
+// TestCleaner_Ignored tests that only ignored files are selected, following
+// nested .gitignore files, info/exclude and the user's excludes, and that
+// tracked files, submodules and nested repositories are left alone.
+func TestCleaner_Ignored(t *testing.T) {
+	root := makeRepo(t)
+	got := scanClean(t, root, ModeIgnored)
+	want := []string{
+		"build/app", "cache", "cache/big.bin", "home-excluded.swp", "main.o",
+		"src/gen", "src/gen/out.c", "src/lib.o", "src/scratch.tmp",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// TestCleaner_Untracked tests that every untracked entry is selected, ignored
+// or not, but not tracked files, submodules or nested repositories.
+func TestCleaner_Untracked(t *testing.T) {
+	root := makeRepo(t)
+	got := scanClean(t, root, ModeUntracked)
+	want := []string{
+		"build/app", "cache", "cache/big.bin", "home-excluded.swp", "keep.o", "main.o", "notes.txt",
+		"src/gen", "src/gen/out.c", "src/lib.o", "src/scratch.tmp",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// TestCleaner_Subdirectory tests a scan rooted below the top of the working
+// tree, where the rules of the directories above still apply.
+func TestCleaner_Subdirectory(t *testing.T) {
+	root := makeRepo(t)
+	got := scanClean(t, filepath.Join(root, "src"), ModeIgnored)
+	want := []string{"gen", "gen/out.c", "lib.o", "scratch.tmp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// TestOpen_Errors tests that directories outside a working tree, or in its
+// .git directory, are refused.
+func TestOpen_Errors(t *testing.T) {
+	if _, err := Open(t.TempDir(), ModeIgnored); err == nil {
+		t.Error("Expected an error outside a working tree")
+	}
+	root := makeRepo(t)
+	if _, err := Open(filepath.Join(root, ".git", "info"), ModeIgnored); err == nil {
+		t.Error("Expected an error in the .git directory")
+	}
+	if _, err := ParseMode("everything"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
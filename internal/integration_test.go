@@ -1465,3 +1465,62 @@ func TestCombinedAgeFilteringAndDryRun(t *testing.T) {
 	
 	t.Logf("Combined test: dry-run with age filtering preserved all files correctly")
 }
+
+// Integration Test: Compact Scan Deletion
+// Tests that a compact scan result is deleted by index, with revalidation,
+// leaving exactly the retained entries
+func TestCompactScanDeletion(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetDir := filepath.Join(tmpDir, "target")
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	for _, name := range []string{"a/b/c/old.log", "a/b/old.log", "a/new.log", "d/old.log"} {
+		path := filepath.Join(targetDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if filepath.Base(name) == "old.log" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatalf("Failed to set file time: %v", err)
+			}
+		}
+	}
+
+	keepDays := 5
+	s := scanner.NewScanner(targetDir, &keepDays)
+	s.SetCompact(true)
+	s.SetRecordIdentity(true)
+	scanResult, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if scanResult.Files != nil || scanResult.Len() != scanResult.TotalToDelete {
+		t.Fatalf("Expected a compact result with %d entries, got %d (flat: %v)",
+			scanResult.TotalToDelete, scanResult.Len(), scanResult.Files != nil)
+	}
+
+	eng := engine.NewEngine(backend.NewBackend(), 4, nil)
+	eng.SetRevalidator(func(index int, path string) bool { return scanResult.Revalidate(index) })
+	result, err := eng.DeleteEntries(context.Background(), scanResult, false)
+	if err != nil {
+		t.Fatalf("Deletion failed: %v", err)
+	}
+	if result.FailedCount > 0 || result.ChangedCount > 0 || result.DeletedCount != scanResult.TotalToDelete {
+		t.Errorf("Expected %d deletions, got %d (failed %d, changed %d): %v",
+			scanResult.TotalToDelete, result.DeletedCount, result.FailedCount, result.ChangedCount, result.Errors)
+	}
+
+	var remaining []string
+	filepath.WalkDir(targetDir, func(path string, d os.DirEntry, err error) error {
+		if err == nil {
+			rel, _ := filepath.Rel(targetDir, path)
+			remaining = append(remaining, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if got, want := fmt.Sprint(remaining), "[. a a/new.log]"; got != want {
+		t.Errorf("Remaining entries %s, want %s", got, want)
+	}
+}
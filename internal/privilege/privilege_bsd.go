@@ -0,0 +1,23 @@
+//go:build !linux && !windows
+
+package privilege
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// dropTo permanently switches all ids of the process to owner.
+// setfsuid does not exist outside Linux, so the switch cannot be undone.
+func dropTo(owner Owner) (Method, error) {
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return MethodNone, fmt.Errorf("failed to clear supplementary groups: %w", err)
+	}
+	if err := syscall.Setgid(owner.GID); err != nil {
+		return MethodNone, fmt.Errorf("setgid(%d) failed: %w", owner.GID, err)
+	}
+	if err := syscall.Setuid(owner.UID); err != nil {
+		return MethodNone, fmt.Errorf("setuid(%d) failed: %w", owner.UID, err)
+	}
+	return MethodSetUID, nil
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+package privilege
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// dropTo switches the filesystem uid and gid of every thread to owner.
+//
+// setfsuid/setfsgid are per-thread on Linux, so they are issued through
+// AllThreadsSyscall. That is not possible in binaries built with cgo; in that
+// case the real, effective and saved ids are switched permanently instead,
+// which Go's syscall package applies to all threads.
+func dropTo(owner Owner) (Method, error) {
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return MethodNone, fmt.Errorf("failed to clear supplementary groups: %w", err)
+	}
+
+	// setfsgid/setfsuid return the previous id rather than an error, so the
+	// switch is verified by calling them again with an invalid id (-1).
+	_, _, errno := syscall.AllThreadsSyscall(syscall.SYS_SETFSGID, uintptr(owner.GID), 0, 0)
+	if errno == syscall.ENOTSUP {
+		return dropToSetUID(owner)
+	}
+	if errno != 0 {
+		return MethodNone, fmt.Errorf("setfsgid(%d) failed: %w", owner.GID, errno)
+	}
+	if _, _, errno = syscall.AllThreadsSyscall(syscall.SYS_SETFSUID, uintptr(owner.UID), 0, 0); errno != 0 {
+		return MethodNone, fmt.Errorf("setfsuid(%d) failed: %w", owner.UID, errno)
+	}
+
+	fsgid, _, _ := syscall.RawSyscall(syscall.SYS_SETFSGID, ^uintptr(0), 0, 0)
+	fsuid, _, _ := syscall.RawSyscall(syscall.SYS_SETFSUID, ^uintptr(0), 0, 0)
+	if int(fsuid) != owner.UID || int(fsgid) != owner.GID {
+		return MethodNone, fmt.Errorf("filesystem credentials not switched (fsuid=%d, fsgid=%d)", fsuid, fsgid)
+	}
+
+	return MethodFSUID, nil
+}
+
+// dropToSetUID permanently switches all ids of the process to owner.
+func dropToSetUID(owner Owner) (Method, error) {
+	if err := syscall.Setgid(owner.GID); err != nil {
+		return MethodNone, fmt.Errorf("setgid(%d) failed: %w", owner.GID, err)
+	}
+	if err := syscall.Setuid(owner.UID); err != nil {
+		return MethodNone, fmt.Errorf("setuid(%d) failed: %w", owner.UID, err)
+	}
+	return MethodSetUID, nil
+}
@@ -0,0 +1,95 @@
+//go:build linux
+
+package privilege
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dropHelperEnv marks the re-executed test binary that drops privileges.
+// Once dropped, the test process cannot regain root, so DropTo must never
+// switch users in the main test process.
+const dropHelperEnv = "FFD_PRIVILEGE_HELPER"
+
+// nobody is the uid/gid the helper process switches to.
+const nobody = 65534
+
+// TestDropHelperProcess is not a real test: it is executed in a child process
+// by TestDropToRestrictsRemoval, switches to nobody, and reports what it could remove.
+func TestDropHelperProcess(t *testing.T) {
+	if os.Getenv(dropHelperEnv) != "1" {
+		t.Skip("helper process only")
+	}
+
+	method, err := DropTo(Owner{UID: nobody, GID: nobody})
+	if err != nil {
+		t.Fatalf("DropTo failed: %v", err)
+	}
+	os.Stdout.WriteString("METHOD " + method.String() + "\n")
+
+	if err := os.Remove(os.Getenv("FFD_PRIVILEGE_OWNED")); err != nil {
+		os.Stdout.WriteString("OWNED-FAILED " + err.Error() + "\n")
+	} else {
+		os.Stdout.WriteString("OWNED-REMOVED\n")
+	}
+	if err := os.Remove(os.Getenv("FFD_PRIVILEGE_FOREIGN")); err != nil {
+		os.Stdout.WriteString("FOREIGN-DENIED\n")
+	} else {
+		os.Stdout.WriteString("FOREIGN-REMOVED\n")
+	}
+}
+
+// TestDropToRestrictsRemoval tests that after switching to an unprivileged owner,
+// the process can only remove entries from directories that owner may write.
+func TestDropToRestrictsRemoval(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users requires root")
+	}
+
+	tmpDir := t.TempDir()
+	// t.TempDir creates private directories; nobody must be able to traverse them.
+	for _, dir := range []string{filepath.Dir(tmpDir), tmpDir} {
+		if err := os.Chmod(dir, 0755); err != nil {
+			t.Fatalf("Failed to chmod: %v", err)
+		}
+	}
+
+	ownedDir := filepath.Join(tmpDir, "owned")
+	foreignDir := filepath.Join(tmpDir, "foreign")
+	for _, dir := range []string{ownedDir, foreignDir} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	if err := os.Chown(ownedDir, nobody, nobody); err != nil {
+		t.Fatalf("Failed to change owner: %v", err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDropHelperProcess$", "-test.v")
+	cmd.Env = append(os.Environ(),
+		dropHelperEnv+"=1",
+		"FFD_PRIVILEGE_OWNED="+filepath.Join(ownedDir, "file.txt"),
+		"FFD_PRIVILEGE_FOREIGN="+filepath.Join(foreignDir, "file.txt"),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Helper process failed: %v\n%s", err, out)
+	}
+
+	output := string(out)
+	for _, want := range []string{"METHOD setfsuid", "OWNED-REMOVED", "FOREIGN-DENIED"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected helper output to contain %s, got:\n%s", want, output)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(foreignDir, "file.txt")); err != nil {
+		t.Errorf("File in root-owned directory was removed: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package privilege
+
+import "testing"
+
+// TestMethodString tests the human-readable names of the switch methods.
+func TestMethodString(t *testing.T) {
+	tests := []struct {
+		method Method
+		want   string
+	}{
+		{MethodNone, "none"},
+		{MethodFSUID, "setfsuid"},
+		{MethodSetUID, "setuid"},
+		{Method(99), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.method.String(); got != tt.want {
+			t.Errorf("Method(%d).String() = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
+
+// TestOwnerString tests the uid:gid formatting of an owner.
+func TestOwnerString(t *testing.T) {
+	if got := (Owner{UID: 1000, GID: 100}).String(); got != "1000:100" {
+		t.Errorf("Owner.String() = %q, want %q", got, "1000:100")
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package privilege
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// OwnerOf returns the owner of path, following symlinks.
+func OwnerOf(path string) (Owner, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Owner{}, fmt.Errorf("cannot stat %s: %w", path, err)
+	}
+	return ownerFromInfo(path, info)
+}
+
+// ownerOfLink returns the owner of path itself, without following symlinks.
+func ownerOfLink(path string) (Owner, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Owner{}, fmt.Errorf("cannot stat %s: %w", path, err)
+	}
+	return ownerFromInfo(path, info)
+}
+
+// ownerFromInfo extracts the owner from the stat data held in info.
+func ownerFromInfo(path string, info os.FileInfo) (Owner, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return Owner{}, fmt.Errorf("no ownership information available for %s", path)
+	}
+	return Owner{UID: int(st.Uid), GID: int(st.Gid)}, nil
+}
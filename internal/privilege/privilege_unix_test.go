@@ -0,0 +1,94 @@
+//go:build !windows
+
+package privilege
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/fast-file-deletion/internal/backend"
+)
+
+// TestOwnerOf tests that the owner of a freshly created file is the current user.
+func TestOwnerOf(t *testing.T) {
+	owner, err := OwnerOf(t.TempDir())
+	if err != nil {
+		t.Fatalf("OwnerOf failed: %v", err)
+	}
+	if owner.UID != os.Geteuid() || owner.GID != os.Getegid() {
+		t.Errorf("Expected owner %d:%d, got %s", os.Geteuid(), os.Getegid(), owner)
+	}
+
+	if _, err := OwnerOf(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing path")
+	}
+}
+
+// TestDropToCurrentOwner tests that switching to the current user is a no-op.
+func TestDropToCurrentOwner(t *testing.T) {
+	method, err := DropTo(Owner{UID: os.Geteuid(), GID: os.Getegid()})
+	if err != nil {
+		t.Fatalf("DropTo failed: %v", err)
+	}
+	if method != MethodNone {
+		t.Errorf("Expected MethodNone, got %s", method)
+	}
+}
+
+// TestDropToRequiresRoot tests that a non-root process cannot switch to another user.
+func TestDropToRequiresRoot(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("running as root")
+	}
+	if _, err := DropTo(Owner{UID: os.Geteuid() + 1, GID: os.Getegid()}); err == nil {
+		t.Error("Expected error when switching users without root")
+	}
+}
+
+// TestOwnerGuardBackend tests that entries owned by another user are refused
+// while entries owned by the expected user are deleted.
+func TestOwnerGuardBackend(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing file ownership requires root")
+	}
+
+	tmpDir := t.TempDir()
+	mine := filepath.Join(tmpDir, "mine.txt")
+	theirs := filepath.Join(tmpDir, "theirs.txt")
+	theirDir := filepath.Join(tmpDir, "their-dir")
+	for _, path := range []string{mine, theirs} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	if err := os.Mkdir(theirDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, path := range []string{theirs, theirDir} {
+		if err := os.Lchown(path, 65534, 65534); err != nil {
+			t.Fatalf("Failed to change owner: %v", err)
+		}
+	}
+
+	guard := NewOwnerGuardBackend(backend.NewBackend(), Owner{UID: 0, GID: 0})
+
+	if err := guard.DeleteFile(mine); err != nil {
+		t.Errorf("Expected file owned by uid 0 to be deleted: %v", err)
+	}
+	if err := guard.DeleteFile(theirs); err == nil {
+		t.Error("Expected file owned by another user to be refused")
+	}
+	if err := guard.DeleteDirectory(theirDir); err == nil {
+		t.Error("Expected directory owned by another user to be refused")
+	}
+
+	if _, err := os.Lstat(mine); !os.IsNotExist(err) {
+		t.Error("Expected mine.txt to be removed")
+	}
+	for _, path := range []string{theirs, theirDir} {
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", filepath.Base(path), err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package privilege
+
+import "fmt"
+
+// errUnsupported is returned by every operation on Windows, where files are
+// owned by SIDs and there is no equivalent of switching to a uid.
+var errUnsupported = fmt.Errorf("running as the directory owner is not supported on Windows")
+
+// OwnerOf is not supported on Windows.
+func OwnerOf(path string) (Owner, error) {
+	return Owner{}, errUnsupported
+}
+
+// ownerOfLink is not supported on Windows.
+func ownerOfLink(path string) (Owner, error) {
+	return Owner{}, errUnsupported
+}
+
+// dropTo is not supported on Windows.
+func dropTo(owner Owner) (Method, error) {
+	return MethodNone, errUnsupported
+}
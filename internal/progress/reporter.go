@@ -13,9 +13,10 @@ import (
 // It tracks deletion progress and calculates statistics like deletion rate,
 // elapsed time, and estimated time remaining (ETA).
 type Reporter struct {
-	totalFiles int       // Total number of files to delete
-	totalBytes int64     // Total size of files to delete
-	startTime  time.Time // When deletion started
+	totalFiles   int       // Total number of files to delete
+	totalBytes   int64     // Total size of files to delete
+	startTime    time.Time // When deletion started
+	retainedDirs int       // Retained entries that are directories (reported by Finish)
 }
 
 // NewReporter creates a new Reporter with the specified total counts.
@@ -130,19 +131,27 @@ func FormatNumber(n int) string {
 	return result
 }
 
+// SetRetainedDirs records how many of the retained entries passed to Finish are
+// directories, so that they are reported separately from retained files.
+func (r *Reporter) SetRetainedDirs(count int) {
+	r.retainedDirs = count
+}
+
 // Finish displays final statistics after deletion completes.
 // This method should be called once at the end of the deletion operation.
 //
 // Parameters:
 //   - deletedCount: Number of files successfully deleted
 //   - failedCount: Number of files that failed to delete
-//   - retainedCount: Number of files retained due to age filtering (0 if no filtering)
+//   - retainedCount: Number of entries retained by age, filters, keep-markers or
+//     retention limits (0 if nothing was retained), including the directories
+//     set with SetRetainedDirs
 //
 // The final statistics include:
 //   - Total time taken
 //   - Average deletion rate
 //   - Success/failure counts
-//   - Retention statistics (if age filtering was used)
+//   - Retention statistics (if any entries were retained)
 func (r *Reporter) Finish(deletedCount int, failedCount int, retainedCount int) {
 	// Print newline to move past the progress line
 	fmt.Println()
@@ -161,9 +170,12 @@ func (r *Reporter) Finish(deletedCount int, failedCount int, retainedCount int)
 		fmt.Printf("Failed to delete: %s files\n", FormatNumber(failedCount))
 	}
 
-	// Display retention statistics if age filtering was used
-	if retainedCount > 0 {
-		fmt.Printf("Retained (due to age): %s files\n", FormatNumber(retainedCount))
+	// Display retention statistics if any entries were retained
+	if retainedFiles := retainedCount - r.retainedDirs; retainedFiles > 0 {
+		fmt.Printf("Retained: %s files\n", FormatNumber(retainedFiles))
+	}
+	if r.retainedDirs > 0 {
+		fmt.Printf("Retained directories: %s\n", FormatNumber(r.retainedDirs))
 	}
 
 	fmt.Println()
@@ -0,0 +1,83 @@
+package progress
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// maxScanPathWidth is how much of the current path the scan progress line shows.
+const maxScanPathWidth = 40
+
+// ScanReporter displays live progress of the scan phase on a single line,
+// using \r to overwrite it like Reporter.Update does during deletion.
+type ScanReporter struct {
+	lastWidth int // Length of the previous line, so a shorter one can blank it out
+}
+
+// NewScanReporter creates a ScanReporter. Pass its Update method to
+// scanner.Scanner.SetProgress.
+func NewScanReporter() *ScanReporter {
+	return &ScanReporter{}
+}
+
+// Update displays a progress report:
+//   - Entries visited, directories visited and directories still being walked
+//   - Bytes marked for deletion so far
+//   - Scan rate (entries/second) and elapsed time
+//   - The tail of the path being visited
+//
+// The final report (p.Done) ends the line.
+func (r *ScanReporter) Update(p scanner.ScanProgress) {
+	line := fmt.Sprintf("Scanning: %s entries (%s dirs, %s queued) | %s to delete | Rate: %s entries/sec | Elapsed: %s",
+		FormatNumber(p.Entries),
+		FormatNumber(p.Dirs),
+		FormatNumber(p.DirsQueued),
+		FormatBytes(p.Bytes),
+		FormatNumber(int(p.Rate)),
+		FormatDuration(p.Elapsed),
+	)
+	if p.CurrentPath != "" {
+		line += " | " + shortenPath(p.CurrentPath, maxScanPathWidth)
+	}
+
+	width := utf8.RuneCountInString(line)
+	padding := ""
+	if width < r.lastWidth {
+		padding = strings.Repeat(" ", r.lastWidth-width)
+	}
+	r.lastWidth = width
+
+	fmt.Printf("\r%s%s", line, padding)
+	if p.Done {
+		fmt.Println()
+		r.lastWidth = 0
+	}
+}
+
+// shortenPath keeps the last max characters of path, prefixed with "...".
+func shortenPath(path string, max int) string {
+	runes := []rune(path)
+	if len(runes) <= max {
+		return path
+	}
+	return "..." + string(runes[len(runes)-max+3:])
+}
+
+// FormatBytes formats a byte count with binary units, e.g. "1.50 GB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	units := []string{"KB", "MB", "GB", "TB", "PB", "EB"}
+	value := float64(n) / unit
+	i := 0
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
@@ -0,0 +1,35 @@
+package progress
+
+import (
+	"testing"
+)
+
+// TestFormatBytes tests byte counts formatted with binary units.
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.00 KB"},
+		{1536 * 1024, "1.50 MB"},
+		{5 << 40, "5.00 TB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.bytes); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
+
+// TestShortenPath tests that long paths keep their tail.
+func TestShortenPath(t *testing.T) {
+	if got := shortenPath("/short/path", 40); got != "/short/path" {
+		t.Errorf("Short path changed to %q", got)
+	}
+	got := shortenPath("/a/very/long/path/that/does/not/fit/in/the/line/file.txt", 20)
+	if want := "...the/line/file.txt"; got != want {
+		t.Errorf("shortenPath = %q, want %q", got, want)
+	}
+}
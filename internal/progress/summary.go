@@ -0,0 +1,71 @@
+package progress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// summaryBarWidth is the width of the longest bar of the age histogram.
+const summaryBarWidth = 20
+
+// FormatSummary formats a scan summary as indented text for the confirmation
+// screen and dry-run output: the largest subtrees, extensions and owners, then
+// the age histogram. Returns "" for a nil summary.
+func FormatSummary(summary *scanner.Summary) string {
+	if summary == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	writeGroups(&b, "Largest subtrees", summary.Subtrees, summary.SubtreesOmitted, func(name string) string {
+		if name == "." {
+			return "(top level)"
+		}
+		return name + "/"
+	})
+	writeGroups(&b, "Extensions", summary.Extensions, summary.ExtensionsOmitted, func(ext string) string {
+		if ext == "" {
+			return "(none)"
+		}
+		return ext
+	})
+	writeGroups(&b, "Owners", summary.Owners, summary.OwnersOmitted, func(name string) string { return name })
+
+	most := 0
+	for _, a := range summary.Ages {
+		most = max(most, a.Files)
+	}
+	if most > 0 {
+		b.WriteString("   Age:\n")
+		for _, a := range summary.Ages {
+			bar := strings.Repeat("#", (a.Files*summaryBarWidth+most-1)/most)
+			fmt.Fprintf(&b, "     %-12s %-*s %10s files %12s\n",
+				a.Label, summaryBarWidth, bar, FormatNumber(a.Files), FormatBytes(a.Bytes))
+		}
+	}
+	return b.String()
+}
+
+// writeGroups writes a titled list of summary groups, if there are any.
+func writeGroups(b *strings.Builder, title string, groups []scanner.SummaryGroup, omitted int, label func(string) string) {
+	if len(groups) == 0 {
+		return
+	}
+
+	width := 0
+	for _, g := range groups {
+		width = max(width, len([]rune(label(g.Name))))
+	}
+	width = min(width, maxScanPathWidth)
+
+	fmt.Fprintf(b, "   %s:\n", title)
+	for _, g := range groups {
+		fmt.Fprintf(b, "     %-*s %12s %10s files\n",
+			width, shortenPath(label(g.Name), maxScanPathWidth), FormatBytes(g.Bytes), FormatNumber(g.Files))
+	}
+	if omitted > 0 {
+		fmt.Fprintf(b, "     ... and %d more\n", omitted)
+	}
+}
@@ -0,0 +1,39 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// TestFormatSummary tests that each part of a summary is listed and that
+// empty parts are left out.
+func TestFormatSummary(t *testing.T) {
+	if got := FormatSummary(nil); got != "" {
+		t.Errorf("FormatSummary(nil) = %q, want empty", got)
+	}
+
+	summary := &scanner.Summary{
+		Subtrees:        []scanner.SummaryGroup{{Name: "build", Files: 3, Bytes: 2048}, {Name: ".", Files: 1, Bytes: 5}},
+		SubtreesOmitted: 4,
+		Extensions:      []scanner.SummaryGroup{{Name: ".o", Files: 3, Bytes: 2048}, {Name: "", Files: 1, Bytes: 5}},
+		Ages: []scanner.AgeBucket{
+			{Label: "under 1 day", Files: 4, Bytes: 2053},
+			{Label: "over 1 year", Files: 0},
+		},
+	}
+	got := FormatSummary(summary)
+	for _, want := range []string{
+		"Largest subtrees:", "build/", "2.00 KB", "(top level)", "... and 4 more",
+		"Extensions:", ".o", "(none)",
+		"Age:", "under 1 day  " + strings.Repeat("#", summaryBarWidth),
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Summary does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Owners:") {
+		t.Errorf("Expected no owners section without owners:\n%s", got)
+	}
+}
@@ -0,0 +1,484 @@
+// Package runlock provides an advisory lock registry that prevents two
+// fast-file-deletion runs (CLI or GUI) from working on the same or nested
+// directories at the same time.
+//
+// Each run holds an OS file lock (flock on Unix, LockFileEx on Windows) on a
+// file in a registry directory. The file is named after a hash of the
+// canonical target path and records who holds it. Locks are released by the
+// operating system when a process exits, so a crashed run never blocks later
+// runs; its leftover file is removed by the next run that notices it.
+//
+// On Unix the default registry is shared by all users: /run/fast-file-deletion,
+// which root creates writable by everyone with the sticky bit set, so that
+// root's cron jobs and users' sessions see each other's locks. Each lock file
+// is named after the user who created it and is refused unless it belongs to
+// that user; other users' files are only read. Until root has created the
+// shared registry (or if it cannot be trusted), a user's runs fall back to a
+// registry of their own ($XDG_RUNTIME_DIR) and only see their own locks. Lock
+// files are never followed through symbolic links, and a file is only written
+// to by the run that created it.
+package runlock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// registryLockName is the file that serializes registry scans and acquisitions.
+const registryLockName = "registry.lock"
+
+// lockFileSuffix identifies per-target lock files in the registry directory.
+const lockFileSuffix = ".lock"
+
+// sharedMode is the mode of a shared registry directory.
+const sharedMode = os.ModePerm | os.ModeSticky
+
+// PollInterval is how often Wait retries while another run holds an overlapping lock.
+var PollInterval = 500 * time.Millisecond
+
+// Holder describes the run holding a lock.
+type Holder struct {
+	Path    string    `json:"path"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+	Command string    `json:"command"`
+}
+
+// String describes the holder for error messages.
+func (h Holder) String() string {
+	return fmt.Sprintf("PID %d on %s since %s (%s)",
+		h.PID, h.Host, h.Started.Format("2006-01-02 15:04:05"), h.Command)
+}
+
+// ConflictError is returned when the target overlaps a directory locked by a live run.
+type ConflictError struct {
+	Target string
+	Holder Holder
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	relation := "is locked by another run"
+	if !pathsEqual(e.Target, e.Holder.Path) {
+		relation = fmt.Sprintf("overlaps %s, which is locked by another run", e.Holder.Path)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Target, relation, e.Holder)
+}
+
+// Registry manages run locks stored in a directory.
+type Registry struct {
+	dir    string
+	shared bool // Holds the lock files of every user, each named after its owner
+}
+
+// NewRegistry returns a registry that keeps its lock files in dir, which
+// belongs to the user and nobody else can write to.
+// The directory is created when the first lock is acquired.
+func NewRegistry(dir string) *Registry {
+	return &Registry{dir: dir}
+}
+
+// NewSharedRegistry returns a registry that keeps the lock files of all users
+// in dir, which is writable by everyone with the sticky bit set so that no
+// user can remove or replace the files of another.
+// The directory is created when the first lock is acquired.
+func NewSharedRegistry(dir string) *Registry {
+	return &Registry{dir: dir, shared: true}
+}
+
+// DefaultRegistry returns the registry shared by all runs on this machine
+// (see the package documentation for the Unix fallback).
+func DefaultRegistry() *Registry {
+	dir, shared := defaultDir()
+	return &Registry{dir: dir, shared: shared}
+}
+
+// Dir returns the directory holding the registry's lock files.
+func (r *Registry) Dir() string {
+	return r.dir
+}
+
+// Lock is a held run lock. Release it when the run finishes.
+type Lock struct {
+	registry *Registry
+	file     *os.File
+	holder   Holder
+}
+
+// Holder returns the metadata recorded for this lock.
+func (l *Lock) Holder() Holder {
+	return l.holder
+}
+
+// TryAcquire locks target for this process. If a live run holds a lock on the
+// same directory, an ancestor, or a descendant, a *ConflictError is returned.
+func (r *Registry) TryAcquire(target string) (*Lock, error) {
+	canonical := canonicalPath(target)
+
+	if err := r.ensureDir(); err != nil {
+		return nil, err
+	}
+
+	registryLock, err := r.openLockFile(filepath.Join(r.dir, registryLockName))
+	if err != nil {
+		return nil, err
+	}
+	defer registryLock.Close()
+	if err := lockFile(registryLock, true); err != nil {
+		return nil, fmt.Errorf("failed to lock run registry: %w", err)
+	}
+	defer unlockFile(registryLock)
+
+	if conflict := r.findConflict(canonical); conflict != nil {
+		return nil, conflict
+	}
+
+	// Unheld lock files were removed above, so the target's lock file is
+	// created afresh and nobody else has written to it.
+	file, err := r.createLockFile(r.lockPath(canonical))
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file, false); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", canonical, err)
+	}
+
+	hostname, _ := os.Hostname()
+	holder := Holder{
+		Path:    canonical,
+		PID:     os.Getpid(),
+		Host:    hostname,
+		Started: time.Now(),
+		Command: strings.Join(os.Args, " "),
+	}
+	if err := writeHolder(file, holder); err != nil {
+		unlockFile(file)
+		file.Close()
+		return nil, fmt.Errorf("failed to record lock holder: %w", err)
+	}
+
+	return &Lock{registry: r, file: file, holder: holder}, nil
+}
+
+// Wait retries TryAcquire until the lock is obtained or timeout elapses.
+// On timeout the last *ConflictError is returned.
+func (r *Registry) Wait(target string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		lock, err := r.TryAcquire(target)
+		var conflict *ConflictError
+		if err == nil || !errors.As(err, &conflict) {
+			return lock, err
+		}
+		if time.Now().Add(PollInterval).After(deadline) {
+			return nil, err
+		}
+		time.Sleep(PollInterval)
+	}
+}
+
+// Release unlocks the run lock and removes its file. Releasing always frees the
+// lock; removing the file is best effort (a sandboxed or unprivileged process
+// may not be allowed to) and leftovers are cleaned up by later runs.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	path := l.file.Name()
+
+	// Hold the registry lock so no other run opens the file while it is removed.
+	registryLock, err := l.registry.openLockFile(filepath.Join(l.registry.dir, registryLockName))
+	if err == nil {
+		defer registryLock.Close()
+		if lockFile(registryLock, true) == nil {
+			defer unlockFile(registryLock)
+		}
+	}
+
+	unlockFile(l.file)
+	err = l.file.Close()
+	l.file = nil
+	if err == nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// findConflict returns a ConflictError for the first live lock overlapping
+// canonical. Lock files that are no longer held are removed.
+// Must be called with the registry lock held.
+func (r *Registry) findConflict(canonical string) *ConflictError {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == registryLockName || !strings.HasSuffix(name, lockFileSuffix) {
+			continue
+		}
+		path := filepath.Join(r.dir, name)
+
+		// In a shared registry, a file must belong to the user it is named
+		// after, so that nobody can hold or block another user's lock file
+		uid := os.Geteuid()
+		if r.shared {
+			var ok bool
+			if uid, ok = lockOwner(name); !ok {
+				continue
+			}
+		}
+		file, err := r.openExisting(path, uid)
+		if err != nil {
+			continue
+		}
+		if lockFile(file, false) == nil {
+			// Nobody holds it: the run that created it has exited. In a
+			// shared registry only its owner (or root) can remove it
+			unlockFile(file)
+			file.Close()
+			os.Remove(path)
+			continue
+		}
+
+		holder, err := readHolder(file)
+		file.Close()
+		if err != nil {
+			continue
+		}
+		if overlaps(canonical, holder.Path) {
+			return &ConflictError{Target: canonical, Holder: holder}
+		}
+	}
+
+	return nil
+}
+
+// ensureDir creates the registry directory, readable by the user only, and
+// refuses it unless it belongs to the user and nobody else can write to it:
+// otherwise another user could plant lock files in it. A shared registry is
+// prepared by ensureSharedDir instead.
+func (r *Registry) ensureDir() error {
+	if r.shared {
+		return r.ensureSharedDir()
+	}
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create run lock directory: %w", err)
+	}
+	info, err := os.Lstat(r.dir)
+	if err != nil {
+		return fmt.Errorf("failed to check run lock directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("run lock directory %s is not a directory", r.dir)
+	}
+	if err := checkPrivate(info); err != nil {
+		return fmt.Errorf("refusing run lock directory %s: %w", r.dir, err)
+	}
+	return nil
+}
+
+// ensureSharedDir creates the shared registry directory, writable by
+// everyone with the sticky bit set, and refuses it unless it is such a
+// directory belonging to root or the user. The owner also opens up a
+// directory created private, by the umask or an earlier version.
+func (r *Registry) ensureSharedDir() error {
+	if err := os.MkdirAll(filepath.Dir(r.dir), 0755); err != nil {
+		return fmt.Errorf("failed to create run lock directory: %w", err)
+	}
+	if err := os.Mkdir(r.dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed to create run lock directory: %w", err)
+	}
+	info, err := os.Lstat(r.dir)
+	if err != nil {
+		return fmt.Errorf("failed to check run lock directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("run lock directory %s is not a directory", r.dir)
+	}
+	if checkOwner(info) == nil && info.Mode()&sharedMode != sharedMode {
+		if err := os.Chmod(r.dir, sharedMode); err != nil {
+			return fmt.Errorf("failed to share run lock directory: %w", err)
+		}
+		if info, err = os.Lstat(r.dir); err != nil {
+			return fmt.Errorf("failed to check run lock directory: %w", err)
+		}
+	}
+	if err := checkShared(info); err != nil {
+		return fmt.Errorf("refusing run lock directory %s: %w", r.dir, err)
+	}
+	return nil
+}
+
+// lockPath returns the lock file for a canonical target path. In a shared
+// registry the name ends with the user's id (see lockOwner).
+func (r *Registry) lockPath(canonical string) string {
+	key := canonical
+	if runtime.GOOS == "windows" {
+		key = strings.ToLower(key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:8])
+	if r.shared {
+		name += "-" + strconv.Itoa(os.Geteuid())
+	}
+	return filepath.Join(r.dir, name+lockFileSuffix)
+}
+
+// lockOwner returns the id of the user a lock file of a shared registry is
+// named after.
+func lockOwner(name string) (int, bool) {
+	base := strings.TrimSuffix(name, lockFileSuffix)
+	i := strings.LastIndexByte(base, '-')
+	if i < 0 {
+		return 0, false
+	}
+	uid, err := strconv.Atoi(base[i+1:])
+	return uid, err == nil && uid >= 0
+}
+
+// openLockFile opens the lock file at path, creating it if it does not exist
+// yet. Its contents are left as they are. In a shared registry the file may
+// belong to anyone, since it is only locked, never written.
+func (r *Registry) openLockFile(path string) (*os.File, error) {
+	file, err := r.createLockFile(path)
+	if errors.Is(err, os.ErrExist) {
+		uid := os.Geteuid()
+		if r.shared {
+			uid = -1
+		}
+		file, err = r.openExisting(path, uid)
+		if err == nil && r.shared {
+			// Its owner opens up a file created private by an earlier version
+			file.Chmod(0644)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	return file, nil
+}
+
+// createLockFile creates a new lock file at path, readable by the user only,
+// or by everyone in a shared registry so that other users' runs can read who
+// holds it. Returns an error if anything, even a symbolic link, is already there.
+func (r *Registry) createLockFile(path string) (*os.File, error) {
+	perm := os.FileMode(0600)
+	if r.shared {
+		perm = 0644
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL|noFollow, perm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock file: %w", err)
+	}
+	if r.shared {
+		// The umask may have narrowed the mode
+		if err := file.Chmod(perm); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+	}
+	return file, nil
+}
+
+// openExisting opens an existing lock file without following symbolic links,
+// and refuses it unless it is a regular file belonging to the user uid (or
+// anyone, if uid is -1). In a shared registry files are opened read-only,
+// which is enough to lock and read them, and without blocking on a FIFO.
+func (r *Registry) openExisting(path string, uid int) (*os.File, error) {
+	flag := os.O_RDWR
+	if r.shared {
+		flag = os.O_RDONLY | noBlock
+	}
+	file, err := os.OpenFile(path, flag|noFollow, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err == nil && !info.Mode().IsRegular() {
+		err = fmt.Errorf("%s is not a regular file", path)
+	}
+	if err == nil && uid != -1 {
+		err = checkOwnedBy(info, uid)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeHolder records holder in a lock file the caller has just created.
+func writeHolder(file *os.File, holder Holder) error {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// readHolder reads the holder recorded in a lock file.
+func readHolder(file *os.File) (Holder, error) {
+	var holder Holder
+	data := make([]byte, 64*1024)
+	n, err := file.ReadAt(data, 0)
+	if n == 0 && err != nil {
+		return holder, err
+	}
+	if err := json.Unmarshal(data[:n], &holder); err != nil {
+		return holder, err
+	}
+	return holder, nil
+}
+
+// canonicalPath returns the absolute path of target with symlinks resolved,
+// so that different spellings of the same directory share a lock.
+func canonicalPath(target string) string {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		abs = target
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return filepath.Clean(abs)
+}
+
+// overlaps reports whether a and b are the same directory or one contains the other.
+func overlaps(a, b string) bool {
+	return pathsEqual(a, b) || isWithin(a, b) || isWithin(b, a)
+}
+
+// isWithin reports whether child is beneath parent.
+func isWithin(child, parent string) bool {
+	if !strings.HasSuffix(parent, string(filepath.Separator)) {
+		parent += string(filepath.Separator)
+	}
+	if runtime.GOOS == "windows" {
+		return strings.HasPrefix(strings.ToLower(child), strings.ToLower(parent))
+	}
+	return strings.HasPrefix(child, parent)
+}
+
+// pathsEqual compares paths case-insensitively on Windows and exactly elsewhere.
+func pathsEqual(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
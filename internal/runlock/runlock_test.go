@@ -0,0 +1,160 @@
+package runlock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTargets creates a registry and a nested target layout in a temp directory.
+func newTestTargets(t *testing.T) (*Registry, string, string, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	parent := filepath.Join(tmpDir, "data")
+	child := filepath.Join(parent, "cache")
+	sibling := filepath.Join(tmpDir, "other")
+	for _, dir := range []string{child, sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	return NewRegistry(filepath.Join(tmpDir, "locks")), parent, child, sibling
+}
+
+// TestTryAcquireRejectsOverlappingTargets tests that the same directory, an
+// ancestor and a descendant of a locked target are refused, while a sibling is not.
+func TestTryAcquireRejectsOverlappingTargets(t *testing.T) {
+	registry, parent, child, sibling := newTestTargets(t)
+
+	lock, err := registry.TryAcquire(child)
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+	defer lock.Release()
+
+	for _, target := range []string{child, parent, filepath.Join(child, "sub")} {
+		_, err := registry.TryAcquire(target)
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) {
+			t.Errorf("Expected ConflictError for %s, got %v", target, err)
+			continue
+		}
+		if conflict.Holder.PID != os.Getpid() {
+			t.Errorf("Expected holder PID %d, got %d", os.Getpid(), conflict.Holder.PID)
+		}
+		if !strings.Contains(conflict.Error(), "PID") {
+			t.Errorf("Expected error to describe the holder, got %q", conflict.Error())
+		}
+	}
+
+	siblingLock, err := registry.TryAcquire(sibling)
+	if err != nil {
+		t.Fatalf("Expected sibling directory to be lockable: %v", err)
+	}
+	siblingLock.Release()
+}
+
+// TestReleaseAllowsReacquire tests that a released lock can be taken again and
+// that its file is removed.
+func TestReleaseAllowsReacquire(t *testing.T) {
+	registry, parent, _, _ := newTestTargets(t)
+
+	lock, err := registry.TryAcquire(parent)
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+	lockFile := registry.lockPath(lock.Holder().Path)
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file to be removed, got %v", err)
+	}
+
+	lock, err = registry.TryAcquire(parent)
+	if err != nil {
+		t.Fatalf("Expected reacquire to succeed: %v", err)
+	}
+	lock.Release()
+}
+
+// TestStaleLockFileIsIgnored tests that a lock file left behind by a run that
+// exited does not block new runs and is cleaned up.
+func TestStaleLockFileIsIgnored(t *testing.T) {
+	registry, parent, _, _ := newTestTargets(t)
+	if err := registry.ensureDir(); err != nil {
+		t.Fatalf("ensureDir failed: %v", err)
+	}
+
+	stale := registry.lockPath(canonicalPath(parent)) + ".old" + lockFileSuffix
+	data := `{"path":"` + filepath.ToSlash(canonicalPath(parent)) + `","pid":1}`
+	if err := os.WriteFile(stale, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write stale lock: %v", err)
+	}
+
+	lock, err := registry.TryAcquire(parent)
+	if err != nil {
+		t.Fatalf("Expected stale lock to be ignored: %v", err)
+	}
+	defer lock.Release()
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected stale lock file to be removed, got %v", err)
+	}
+}
+
+// TestWaitAcquiresAfterRelease tests that Wait obtains the lock once the
+// holder releases it, and times out while it is held.
+func TestWaitAcquiresAfterRelease(t *testing.T) {
+	registry, parent, child, _ := newTestTargets(t)
+
+	oldInterval := PollInterval
+	PollInterval = 10 * time.Millisecond
+	defer func() { PollInterval = oldInterval }()
+
+	lock, err := registry.TryAcquire(parent)
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+
+	_, err = registry.Wait(child, 50*time.Millisecond)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected ConflictError after timeout, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.Release()
+	}()
+
+	waited, err := registry.Wait(child, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected Wait to succeed after release: %v", err)
+	}
+	waited.Release()
+}
+
+// TestOverlaps tests the path overlap rules.
+func TestOverlaps(t *testing.T) {
+	sep := string(filepath.Separator)
+	base := sep + "data"
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{base, base, true},
+		{base, base + sep + "cache", true},
+		{base + sep + "cache", base, true},
+		{base, base + "-old", false},
+		{base + sep + "a", base + sep + "b", false},
+	}
+	for _, tt := range tests {
+		if got := overlaps(tt.a, tt.b); got != tt.want {
+			t.Errorf("overlaps(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+//go:build !windows
+
+package runlock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// noFollow makes opening a lock file fail if it is a symbolic link.
+const noFollow = syscall.O_NOFOLLOW
+
+// noBlock keeps opening a FIFO planted in a shared registry from blocking.
+const noBlock = syscall.O_NONBLOCK
+
+// sharedDir is the registry shared by all users. Only root can create it in
+// /run, which root's runs do, as can an administrator (mode 1777, e.g. with
+// systemd-tmpfiles).
+const sharedDir = "/run/fast-file-deletion"
+
+// defaultDir returns the shared registry for root, and for other users once
+// root has created it. Otherwise it returns a directory belonging to the user:
+// the runtime directory of the session or, without one, a per-user directory
+// in /tmp, which ensureDir refuses if someone else created it.
+func defaultDir() (string, bool) {
+	if os.Geteuid() == 0 {
+		return sharedDir, true
+	}
+	if info, err := os.Lstat(sharedDir); err == nil && info.IsDir() && checkShared(info) == nil {
+		return sharedDir, true
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "fast-file-deletion"), false
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("fast-file-deletion-locks-%d", os.Geteuid())), false
+}
+
+// checkPrivate returns an error unless the registry directory belongs to the
+// user and neither its group nor others can write to it.
+func checkPrivate(info os.FileInfo) error {
+	if err := checkOwner(info); err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("it is writable by other users (mode %s)", info.Mode().Perm())
+	}
+	return nil
+}
+
+// checkShared returns an error unless the shared registry directory belongs
+// to root or the user and is writable by everyone with the sticky bit set, so
+// that every user can add lock files but nobody can remove or replace those
+// of others.
+func checkShared(info os.FileInfo) error {
+	if checkOwnedBy(info, 0) != nil && checkOwner(info) != nil {
+		return fmt.Errorf("it belongs to neither root nor user %d", os.Geteuid())
+	}
+	if info.Mode()&sharedMode != sharedMode {
+		return fmt.Errorf("it is not writable by everyone with the sticky bit set (mode %s)", info.Mode())
+	}
+	return nil
+}
+
+// checkOwner returns an error unless the file belongs to the user.
+func checkOwner(info os.FileInfo) error {
+	return checkOwnedBy(info, os.Geteuid())
+}
+
+// checkOwnedBy returns an error unless the file belongs to the user uid.
+func checkOwnedBy(info os.FileInfo, uid int) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot determine the owner of %s", info.Name())
+	}
+	if int(st.Uid) != uid {
+		return fmt.Errorf("%s belongs to user %d, not %d", info.Name(), st.Uid, uid)
+	}
+	return nil
+}
+
+// lockFile takes an exclusive flock on file. If wait is false and another open
+// file holds the lock, an error is returned immediately.
+func lockFile(file *os.File, wait bool) error {
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	for {
+		err := syscall.Flock(int(file.Fd()), how)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// unlockFile releases the flock held on file.
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,140 @@
+//go:build !windows
+
+package runlock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestRegistryRefusesSharedDirectory tests that a private registry directory other
+// users can write to is refused, even with the sticky bit.
+func TestRegistryRefusesSharedDirectory(t *testing.T) {
+	registry, parent, _, _ := newTestTargets(t)
+	if err := os.Mkdir(registry.Dir(), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Chmod(registry.Dir(), 0777|os.ModeSticky); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+
+	if lock, err := registry.TryAcquire(parent); err == nil {
+		lock.Release()
+		t.Fatal("Expected a world-writable registry to be refused")
+	}
+}
+
+// TestLockFileSymlinkIsNotFollowed tests that lock files planted as symbolic
+// links are neither written through nor taken over.
+func TestLockFileSymlinkIsNotFollowed(t *testing.T) {
+	registry, parent, _, _ := newTestTargets(t)
+	if err := registry.ensureDir(); err != nil {
+		t.Fatalf("ensureDir failed: %v", err)
+	}
+	victim := filepath.Join(t.TempDir(), "victim")
+	if err := os.WriteFile(victim, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	for _, name := range []string{registryLockName, filepath.Base(registry.lockPath(canonicalPath(parent)))} {
+		link := filepath.Join(registry.Dir(), name)
+		if err := os.Symlink(victim, link); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		if lock, err := registry.TryAcquire(parent); err == nil {
+			lock.Release()
+			t.Errorf("Expected a symlinked %s to be refused", name)
+		}
+		os.Remove(link)
+	}
+
+	content, err := os.ReadFile(victim)
+	if err != nil || string(content) != "secret" {
+		t.Errorf("Expected the link target to be untouched, got %q, %v", content, err)
+	}
+	if info, err := os.Stat(victim); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the link target to keep mode 0600, got %v", err)
+	}
+}
+
+// TestSharedRegistry tests that a shared registry is opened up to everyone
+// with the sticky bit, even if it was created private, and that its lock
+// files are named after their owner and readable by other users.
+func TestSharedRegistry(t *testing.T) {
+	_, parent, child, _ := newTestTargets(t)
+	registry := NewSharedRegistry(filepath.Join(t.TempDir(), "shared"))
+	if err := os.Mkdir(registry.Dir(), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	lock, err := registry.TryAcquire(parent)
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+	defer lock.Release()
+
+	info, err := os.Lstat(registry.Dir())
+	if err != nil || info.Mode()&sharedMode != sharedMode {
+		t.Errorf("Expected the registry to have mode %s, got %v (%v)", sharedMode, info.Mode(), err)
+	}
+	path := lock.file.Name()
+	if uid, ok := lockOwner(filepath.Base(path)); !ok || uid != os.Geteuid() {
+		t.Errorf("Expected %s to be named after user %d", path, os.Geteuid())
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("Expected %s to have mode 0644, got %v", path, err)
+	}
+
+	var conflict *ConflictError
+	if _, err := registry.TryAcquire(child); !errors.As(err, &conflict) {
+		t.Errorf("Expected a conflict for %s, got %v", child, err)
+	}
+}
+
+// TestSharedRegistryChecksLockOwners tests that a held lock file is ignored
+// unless it belongs to the user it is named after.
+func TestSharedRegistryChecksLockOwners(t *testing.T) {
+	_, parent, _, _ := newTestTargets(t)
+	registry := NewSharedRegistry(filepath.Join(t.TempDir(), "shared"))
+	if err := registry.ensureDir(); err != nil {
+		t.Fatalf("ensureDir failed: %v", err)
+	}
+
+	// Held, but named after another user
+	otherUID := os.Geteuid() + 1000
+	name := filepath.Base(NewRegistry(registry.Dir()).lockPath(canonicalPath(parent)))
+	planted := filepath.Join(registry.Dir(), strings.TrimSuffix(name, lockFileSuffix)+"-"+strconv.Itoa(otherUID)+lockFileSuffix)
+	data := `{"path":"` + filepath.ToSlash(canonicalPath(parent)) + `","pid":1}`
+	if err := os.WriteFile(planted, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+	holder, err := os.Open(planted)
+	if err != nil {
+		t.Fatalf("Failed to open lock file: %v", err)
+	}
+	defer holder.Close()
+	if err := lockFile(holder, false); err != nil {
+		t.Fatalf("Failed to lock file: %v", err)
+	}
+
+	lock, err := registry.TryAcquire(parent)
+	if err != nil {
+		t.Fatalf("Expected a lock file of the wrong owner to be ignored: %v", err)
+	}
+	lock.Release()
+
+	if os.Geteuid() != 0 {
+		t.Skip("Giving the lock file to another user requires root")
+	}
+	if err := os.Chown(planted, otherUID, -1); err != nil {
+		t.Fatalf("Failed to change owner: %v", err)
+	}
+	var conflict *ConflictError
+	if _, err := registry.TryAcquire(parent); !errors.As(err, &conflict) {
+		t.Errorf("Expected the lock of user %d to conflict, got %v", otherUID, err)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build windows
+
+package runlock
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffsetHigh places the locked byte far beyond the holder metadata.
+// Windows byte-range locks are mandatory, so locking the metadata itself would
+// stop other runs from reading who holds the lock.
+const lockOffsetHigh = 0x7FFFFFFF
+
+// defaultDir uses ProgramData so that runs under different users (a scheduled
+// task and a desktop session) share the registry; %TEMP% is per-user. The
+// registry is used like a private one, relying on ProgramData's ACL.
+func defaultDir() (string, bool) {
+	if programData := os.Getenv("ProgramData"); programData != "" {
+		return filepath.Join(programData, "fast-file-deletion", "locks"), false
+	}
+	return filepath.Join(os.TempDir(), "fast-file-deletion-locks"), false
+}
+
+// noFollow is not needed on Windows, where creating symbolic links requires
+// a privilege.
+const noFollow = 0
+
+// noBlock is not needed on Windows, where opening a named pipe needs its
+// \\.\pipe\ path.
+const noBlock = 0
+
+// checkPrivate accepts the registry directory: ProgramData's inherited ACL
+// already lets only administrators change files created by other users.
+func checkPrivate(info os.FileInfo) error {
+	return nil
+}
+
+// checkShared accepts the registry directory, for the same reason as checkPrivate.
+func checkShared(info os.FileInfo) error {
+	return nil
+}
+
+// checkOwner accepts any lock file, for the same reason as checkPrivate.
+func checkOwner(info os.FileInfo) error {
+	return nil
+}
+
+// checkOwnedBy accepts any lock file, for the same reason as checkPrivate.
+func checkOwnedBy(info os.FileInfo, uid int) error {
+	return nil
+}
+
+// lockFile takes an exclusive LockFileEx lock on file. If wait is false and
+// another handle holds the lock, an error is returned immediately.
+func lockFile(file *os.File, wait bool) error {
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK)
+	if !wait {
+		flags |= windows.LOCKFILE_FAIL_IMMEDIATELY
+	}
+	overlapped := windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	return windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, &overlapped)
+}
+
+// unlockFile releases the LockFileEx lock held on file.
+func unlockFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -196,6 +196,13 @@ func hasWritePermission(path string) bool {
 //
 // Returns true if user confirms, false otherwise.
 func GetUserConfirmation(path string, fileCount int, dryRun bool, force bool) bool {
+	return GetUserConfirmationWithDetails(path, fileCount, "", dryRun, force)
+}
+
+// GetUserConfirmationWithDetails is GetUserConfirmation with details, such as a
+// breakdown of what is about to be deleted, shown below the file count.
+// details is printed as is and should end with a newline.
+func GetUserConfirmationWithDetails(path string, fileCount int, details string, dryRun bool, force bool) bool {
 	// Skip confirmation if force flag is enabled
 	if force {
 		logger.Info("Force flag enabled, skipping confirmation")
@@ -226,6 +233,7 @@ func GetUserConfirmation(path string, fileCount int, dryRun bool, force bool) bo
 	if fileCount > 0 {
 		fmt.Printf("   Files: %d files and directories\n", fileCount)
 	}
+	fmt.Print(details)
 	fmt.Println()
 
 	// Special warning for drive roots
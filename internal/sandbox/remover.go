@@ -0,0 +1,224 @@
+package sandbox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/yourusername/fast-file-deletion/internal/backend"
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+)
+
+// RemoverCommand is the first argument StartRemover runs the program with.
+// A program that uses StartRemover must check for it before anything else
+// and then hand over to RunRemover:
+//
+//	if len(os.Args) > 1 && os.Args[1] == sandbox.RemoverCommand {
+//		os.Exit(sandbox.RunRemover(os.Args[2:], os.Stdin, os.Stdout))
+//	}
+const RemoverCommand = "__sandbox-remover"
+
+// Remover removes the target directories themselves once the confined
+// process has emptied them. Landlock checks the removal of a directory
+// against its parent, and a rule for the parent would let the process remove
+// any empty directory next to the target. Instead, the targets are removed
+// by a helper process started before the sandbox is applied, and so not
+// confined by it, which removes those directories and nothing else: each
+// only if it is still the directory it was when the helper started, and
+// only once it is empty.
+type Remover struct {
+	targets []string // Absolute paths, in the order the helper was given them
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// StartRemover starts the helper for targets by running the program again
+// with RemoverCommand. It must be called before Restrict. If uid is not -1,
+// the helper runs as uid and gid, so that it removes the targets with the
+// same permissions as the rest of the run.
+func StartRemover(targets []string, uid, gid int) (*Remover, error) {
+	r := &Remover{}
+	for _, target := range targets {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve %s: %w", target, err)
+		}
+		r.targets = append(r.targets, abs)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate the program to start the remover: %w", err)
+	}
+	r.cmd = exec.Command(exe, append([]string{RemoverCommand}, r.targets...)...)
+	r.cmd.Stderr = os.Stderr
+	if uid != -1 {
+		r.cmd.SysProcAttr = removerCredential(uid, gid)
+	}
+	if r.in, err = r.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	out, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	r.out = bufio.NewReader(out)
+	if err := r.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("cannot start the remover: %w", err)
+	}
+
+	// The helper records the targets before it answers, so they are known
+	// before the sandbox is applied
+	if line, err := r.out.ReadString('\n'); err != nil || line != "ready\n" {
+		r.Close()
+		return nil, fmt.Errorf("remover did not start: %q (%v)", line, err)
+	}
+	logger.Debug("Started remover (PID %d) for %s", r.cmd.Process.Pid, strings.Join(r.targets, ", "))
+	return r, nil
+}
+
+// IsTarget reports whether path is one of the targets.
+func (r *Remover) IsTarget(path string) bool {
+	return r.index(path) >= 0
+}
+
+// index returns the position of path among the targets, or -1.
+func (r *Remover) index(path string) int {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return -1
+	}
+	for i, target := range r.targets {
+		if target == abs {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove has the helper remove the target directory at path, which must be
+// empty.
+func (r *Remover) Remove(path string) error {
+	i := r.index(path)
+	if i < 0 {
+		return fmt.Errorf("failed to delete directory %s: not a target of the sandbox", path)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.in == nil {
+		return fmt.Errorf("failed to delete directory %s: remover is closed", path)
+	}
+	if _, err := fmt.Fprintf(r.in, "%d\n", i); err != nil {
+		return fmt.Errorf("failed to delete directory %s: %w", path, err)
+	}
+	line, err := r.out.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to delete directory %s: remover stopped: %w", path, err)
+	}
+	if reply := strings.TrimSuffix(line, "\n"); reply != "ok" {
+		return fmt.Errorf("failed to delete directory %s: %s", path, strings.TrimPrefix(reply, "error "))
+	}
+	return nil
+}
+
+// Close stops the helper.
+func (r *Remover) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.in == nil {
+		return nil
+	}
+	r.in.Close()
+	r.in = nil
+	return r.cmd.Wait()
+}
+
+// RunRemover is the helper started by StartRemover: it records the
+// directories given as args, then removes the one whose index it reads from
+// in, replying "ok" or "error" and the reason on out, until in is closed. It
+// returns the exit code of the helper.
+func RunRemover(args []string, in io.Reader, out io.Writer) int {
+	infos := make([]os.FileInfo, len(args))
+	errs := make([]error, len(args))
+	for i, path := range args {
+		infos[i], errs[i] = os.Lstat(path)
+		if errs[i] == nil && !infos[i].IsDir() {
+			errs[i] = fmt.Errorf("%s is not a directory", path)
+		}
+	}
+	fmt.Fprintln(out, "ready")
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		i, err := strconv.Atoi(scanner.Text())
+		if err != nil || i < 0 || i >= len(args) {
+			fmt.Fprintln(out, "error unknown target")
+			continue
+		}
+		if err := removeTarget(args[i], infos[i], errs[i]); err != nil {
+			fmt.Fprintln(out, "error "+strings.ReplaceAll(err.Error(), "\n", " "))
+			continue
+		}
+		fmt.Fprintln(out, "ok")
+	}
+	return 0
+}
+
+// removeTarget removes the empty directory at path if it is still the
+// directory recorded as info.
+func removeTarget(path string, info os.FileInfo, recordErr error) error {
+	if recordErr != nil {
+		return recordErr
+	}
+	current, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !current.IsDir() || !os.SameFile(info, current) {
+		return errors.New("the directory was replaced")
+	}
+	// Rmdir, unlike os.Remove, never removes anything but an empty directory
+	return syscall.Rmdir(path)
+}
+
+// TargetBackend wraps a Backend and removes the target directories
+// themselves through a Remover, since the sandboxed process cannot.
+type TargetBackend struct {
+	inner   backend.Backend
+	remover *Remover
+}
+
+// NewTargetBackend returns a backend that deletes through inner, except for
+// the targets of remover.
+func NewTargetBackend(inner backend.Backend, remover *Remover) *TargetBackend {
+	return &TargetBackend{
+		inner:   inner,
+		remover: remover,
+	}
+}
+
+// DeleteFile deletes the file through the wrapped backend.
+func (b *TargetBackend) DeleteFile(path string) error {
+	return b.inner.DeleteFile(path)
+}
+
+// DeleteDirectory deletes the empty directory, through the remover if it is
+// a target.
+func (b *TargetBackend) DeleteDirectory(path string) error {
+	if b.remover.IsTarget(path) {
+		return b.remover.Remove(path)
+	}
+	return b.inner.DeleteDirectory(path)
+}
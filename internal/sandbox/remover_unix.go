@@ -0,0 +1,19 @@
+//go:build !windows
+
+package sandbox
+
+import (
+	"os"
+	"syscall"
+)
+
+// removerCredential runs the helper as uid and gid, unless the process
+// already runs as them.
+func removerCredential(uid, gid int) *syscall.SysProcAttr {
+	if uid == os.Getuid() && gid == os.Getgid() {
+		return nil
+	}
+	return &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)},
+	}
+}
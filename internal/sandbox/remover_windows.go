@@ -0,0 +1,11 @@
+//go:build windows
+
+package sandbox
+
+import "syscall"
+
+// removerCredential returns nil; the helper runs as the current user, since
+// changing owner is not supported on Windows.
+func removerCredential(uid, gid int) *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package sandbox confines the process to the directories it is about to clean.
+// It is a last line of defence for unattended runs: once the target has been
+// validated, the process gives up the right to modify anything outside of it,
+// so a bug or a bad path cannot remove unrelated files.
+//
+// On Linux the sandbox is implemented with the Landlock LSM. On other platforms,
+// and on kernels without Landlock, Restrict reports the sandbox as unavailable.
+package sandbox
+
+// Status describes how much of the requested sandbox the kernel enforces.
+type Status int
+
+const (
+	// StatusUnavailable means no restriction is in place (unsupported platform,
+	// kernel without Landlock, or Landlock disabled at boot).
+	StatusUnavailable Status = iota
+
+	// StatusPartiallyEnforced means the sandbox is active, but the kernel's
+	// Landlock ABI cannot restrict every access right ffd asks to restrict.
+	StatusPartiallyEnforced
+
+	// StatusEnforced means every requested restriction is active.
+	StatusEnforced
+)
+
+// String returns the human-readable name of the status.
+func (s Status) String() string {
+	switch s {
+	case StatusUnavailable:
+		return "unavailable"
+	case StatusPartiallyEnforced:
+		return "partially enforced"
+	case StatusEnforced:
+		return "enforced"
+	default:
+		return "unknown"
+	}
+}
+
+// Policy describes what the process still needs to modify once it is confined.
+// Everything not listed here becomes read-only for the rest of the process lifetime.
+type Policy struct {
+	// Targets are the validated directories whose contents may be removed.
+	// The targets themselves cannot be: Landlock checks that against their
+	// parent, so use a Remover for them.
+	Targets []string
+
+	// WritableFiles are files that may still be opened for writing, such as the log file.
+	WritableFiles []string
+}
+
+// Result describes the outcome of Restrict.
+type Result struct {
+	Status Status // How much of the policy is enforced
+	ABI    int    // Landlock ABI version reported by the kernel (0 if unavailable)
+	Reason string // Why the sandbox is not fully enforced (empty when enforced)
+}
+
+// Restrict confines the current process according to policy. The restriction
+// applies to all threads and cannot be lifted afterwards.
+//
+// An unavailable sandbox is not an error: the returned Result reports it so the
+// caller can decide whether to continue. An error is returned only when the
+// kernel supports the sandbox but the policy could not be applied.
+func Restrict(policy Policy) (Result, error) {
+	return restrict(policy)
+}
@@ -0,0 +1,151 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+)
+
+// Landlock access rights grouped by the ABI version that introduced them.
+const (
+	// accessWriteV1 covers every ABI 1 right that modifies the filesystem.
+	accessWriteV1 = unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+
+	// accessRemove is what the engine needs beneath each target.
+	accessRemove = unix.LANDLOCK_ACCESS_FS_REMOVE_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE
+
+	// accessWriteFile is what an already existing writable file needs.
+	accessWriteFile = unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// restrict applies the policy with Landlock.
+//
+// Only write-type rights are handled: reading stays unrestricted because the scan
+// has already completed and the engine only removes entries. Rights the running
+// kernel does not know about (REFER before ABI 2, TRUNCATE before ABI 3) cannot
+// be restricted, which is reported as StatusPartiallyEnforced.
+func restrict(policy Policy) (Result, error) {
+	abi, err := landlockABI()
+	if err != nil {
+		return Result{Status: StatusUnavailable, Reason: err.Error()}, nil
+	}
+
+	handled := uint64(accessWriteV1)
+	var missing []string
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	} else {
+		missing = append(missing, "truncate")
+	}
+
+	// Only the filesystem field is passed; the kernel treats the rest as zero.
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	rulesetFd, _, errno := unix.Syscall(
+		unix.SYS_LANDLOCK_CREATE_RULESET,
+		uintptr(unsafe.Pointer(&attr)),
+		unsafe.Sizeof(attr.Access_fs),
+		0,
+	)
+	if errno != 0 {
+		return Result{ABI: abi}, fmt.Errorf("landlock_create_ruleset failed: %w", errno)
+	}
+	defer unix.Close(int(rulesetFd))
+
+	for _, target := range policy.Targets {
+		if err := addPathRule(int(rulesetFd), target, accessRemove&handled); err != nil {
+			return Result{ABI: abi}, err
+		}
+	}
+
+	for _, file := range policy.WritableFiles {
+		if err := addPathRule(int(rulesetFd), file, accessWriteFile&handled); err != nil {
+			return Result{ABI: abi}, err
+		}
+	}
+
+	// Landlock requires no_new_privs unless the caller has CAP_SYS_ADMIN.
+	// Both calls must reach every OS thread of the Go runtime, not just this one.
+	if _, _, errno := syscall.AllThreadsSyscall6(syscall.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0, 0); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return Result{
+				Status: StatusUnavailable,
+				ABI:    abi,
+				Reason: "sandboxing all threads requires a build with CGO_ENABLED=0",
+			}, nil
+		}
+		return Result{ABI: abi}, fmt.Errorf("failed to set no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, rulesetFd, 0, 0); errno != 0 {
+		return Result{ABI: abi}, fmt.Errorf("landlock_restrict_self failed: %w", errno)
+	}
+
+	result := Result{Status: StatusEnforced, ABI: abi}
+	if len(missing) > 0 {
+		result.Status = StatusPartiallyEnforced
+		result.Reason = fmt.Sprintf("Landlock ABI %d cannot restrict: %v", abi, missing)
+	}
+
+	logger.Debug("Landlock ruleset applied (ABI %d, handled access 0x%x)", abi, handled)
+	return result, nil
+}
+
+// landlockABI returns the Landlock ABI version supported by the running kernel,
+// or an error describing why Landlock cannot be used.
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		switch {
+		case errors.Is(errno, unix.ENOSYS):
+			return 0, fmt.Errorf("kernel does not support Landlock")
+		case errors.Is(errno, unix.EOPNOTSUPP):
+			return 0, fmt.Errorf("Landlock is disabled on this kernel")
+		default:
+			return 0, fmt.Errorf("cannot query Landlock ABI: %w", errno)
+		}
+	}
+	return int(abi), nil
+}
+
+// addPathRule allows access to path and everything beneath it.
+func addPathRule(rulesetFd int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("cannot open %s for sandbox rule: %w", path, err)
+	}
+	defer unix.Close(fd)
+
+	rule := unix.LandlockPathBeneathAttr{
+		Allowed_access: access,
+		Parent_fd:      int32(fd),
+	}
+	_, _, errno := unix.Syscall6(
+		unix.SYS_LANDLOCK_ADD_RULE,
+		uintptr(rulesetFd),
+		unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&rule)),
+		0, 0, 0,
+	)
+	if errno != 0 {
+		return fmt.Errorf("cannot add sandbox rule for %s: %w", path, errno)
+	}
+	return nil
+}
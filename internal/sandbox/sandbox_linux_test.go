@@ -0,0 +1,127 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sandboxHelperEnv marks the re-executed test binary that applies the sandbox.
+// Restrict cannot be undone, so it must never run in the main test process.
+const sandboxHelperEnv = "FFD_SANDBOX_HELPER"
+
+// TestSandboxHelperProcess is not a real test: it is executed in a child process
+// by TestRestrictConfinesRemoval, applies the sandbox, and reports what it could remove.
+func TestSandboxHelperProcess(t *testing.T) {
+	if os.Getenv(sandboxHelperEnv) != "1" {
+		t.Skip("helper process only")
+	}
+
+	target := os.Getenv("FFD_SANDBOX_TARGET")
+	outside := os.Getenv("FFD_SANDBOX_OUTSIDE")
+	sibling := os.Getenv("FFD_SANDBOX_SIBLING")
+
+	remover, err := StartRemover([]string{target}, -1, -1)
+	if err != nil {
+		t.Fatalf("StartRemover failed: %v", err)
+	}
+	defer remover.Close()
+
+	result, err := Restrict(Policy{Targets: []string{target}})
+	if err != nil {
+		t.Fatalf("Restrict failed: %v", err)
+	}
+	if result.Status == StatusUnavailable {
+		os.Stdout.WriteString("UNAVAILABLE " + result.Reason + "\n")
+		return
+	}
+
+	if err := os.Remove(filepath.Join(target, "inside.txt")); err != nil {
+		os.Stdout.WriteString("INSIDE-FAILED " + err.Error() + "\n")
+	} else {
+		os.Stdout.WriteString("INSIDE-REMOVED\n")
+	}
+	if err := os.Remove(filepath.Join(outside, "outside.txt")); err != nil {
+		os.Stdout.WriteString("OUTSIDE-DENIED\n")
+	} else {
+		os.Stdout.WriteString("OUTSIDE-REMOVED\n")
+	}
+	if err := os.Remove(sibling); err != nil {
+		os.Stdout.WriteString("SIBLING-KEPT\n")
+	} else {
+		os.Stdout.WriteString("SIBLING-REMOVED\n")
+	}
+	if err := remover.Remove(sibling); err != nil {
+		os.Stdout.WriteString("SIBLING-REFUSED\n")
+	} else {
+		os.Stdout.WriteString("SIBLING-REMOVED-BY-REMOVER\n")
+	}
+	if err := os.Remove(target); err != nil {
+		os.Stdout.WriteString("TARGET-KEPT\n")
+	} else {
+		os.Stdout.WriteString("TARGET-REMOVED\n")
+	}
+	if err := remover.Remove(target); err != nil {
+		os.Stdout.WriteString("REMOVER-FAILED " + err.Error() + "\n")
+	} else {
+		os.Stdout.WriteString("REMOVER-REMOVED\n")
+	}
+}
+
+// TestRestrictConfinesRemoval tests that once sandboxed, the process can remove
+// entries beneath the target but nothing outside of it: neither the target
+// itself nor an empty directory next to it. Only the Remover, started before
+// the sandbox, removes the target, and nothing else.
+func TestRestrictConfinesRemoval(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "target")
+	outside := filepath.Join(tmpDir, "outside")
+	sibling := filepath.Join(tmpDir, "sibling")
+	for _, dir := range []string{target, outside, sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(target, "inside.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "outside.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSandboxHelperProcess$", "-test.v")
+	cmd.Env = append(os.Environ(),
+		sandboxHelperEnv+"=1",
+		"FFD_SANDBOX_TARGET="+target,
+		"FFD_SANDBOX_OUTSIDE="+outside,
+		"FFD_SANDBOX_SIBLING="+sibling,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Helper process failed: %v\n%s", err, out)
+	}
+
+	output := string(out)
+	if strings.Contains(output, "UNAVAILABLE") {
+		t.Skipf("Landlock not available: %s", output)
+	}
+
+	for _, want := range []string{"INSIDE-REMOVED", "OUTSIDE-DENIED", "SIBLING-KEPT", "SIBLING-REFUSED", "TARGET-KEPT", "REMOVER-REMOVED"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected helper output to contain %s, got:\n%s", want, output)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "outside.txt")); err != nil {
+		t.Errorf("File outside the sandbox was removed: %v", err)
+	}
+	if _, err := os.Stat(sibling); err != nil {
+		t.Errorf("Empty directory next to the target was removed: %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected the remover to remove the target, got %v", err)
+	}
+}
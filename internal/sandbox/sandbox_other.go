@@ -0,0 +1,11 @@
+//go:build !linux
+
+package sandbox
+
+// restrict reports the sandbox as unavailable; Landlock only exists on Linux.
+func restrict(policy Policy) (Result, error) {
+	return Result{
+		Status: StatusUnavailable,
+		Reason: "Landlock is only available on Linux",
+	}, nil
+}
@@ -0,0 +1,98 @@
+package sandbox
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the test binary as the remover when StartRemover starts it,
+// as the program's main does.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == RemoverCommand {
+		os.Exit(RunRemover(os.Args[2:], os.Stdin, os.Stdout))
+	}
+	os.Exit(m.Run())
+}
+
+// TestStatusString tests the human-readable status names.
+func TestStatusString(t *testing.T) {
+	cases := map[Status]string{
+		StatusUnavailable:       "unavailable",
+		StatusPartiallyEnforced: "partially enforced",
+		StatusEnforced:          "enforced",
+		Status(99):              "unknown",
+	}
+	for status, want := range cases {
+		if got := status.String(); got != want {
+			t.Errorf("Status(%d).String() = %q, want %q", status, got, want)
+		}
+	}
+}
+
+// TestRunRemoverRemovesOnlyRecordedEmptyDirectories tests that the remover
+// helper refuses a target that is not empty, has been replaced since it
+// started, or is not one of its targets.
+func TestRunRemoverRemovesOnlyRecordedEmptyDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	empty := filepath.Join(tmpDir, "empty")
+	full := filepath.Join(tmpDir, "full")
+	replaced := filepath.Join(tmpDir, "replaced")
+	for _, dir := range []string{empty, full, replaced, replaced + ".new"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(full, "file.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// Replace the directory once the helper has recorded it
+	in := &replacingReader{
+		Reader: strings.NewReader("1\n2\n3\n0\n"),
+		replace: func() {
+			os.Remove(replaced)
+			os.Rename(replaced+".new", replaced)
+		},
+	}
+	var out bytes.Buffer
+	RunRemover([]string{empty, full, replaced}, in, &out)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 || lines[0] != "ready" {
+		t.Fatalf("Unexpected remover output:\n%s", out.String())
+	}
+	for i, name := range []string{"full", "replaced", "unknown"} {
+		if !strings.HasPrefix(lines[i+1], "error ") {
+			t.Errorf("Expected the %s target to be refused, got %q", name, lines[i+1])
+		}
+	}
+	if lines[4] != "ok" {
+		t.Errorf("Expected the empty target to be removed, got %q", lines[4])
+	}
+
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", empty, err)
+	}
+	for _, dir := range []string{full, replaced} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("Expected %s to be kept: %v", dir, err)
+		}
+	}
+}
+
+// replacingReader calls replace before its first read.
+type replacingReader struct {
+	*strings.Reader
+	replace func()
+}
+
+func (r *replacingReader) Read(p []byte) (int, error) {
+	if r.replace != nil {
+		r.replace()
+		r.replace = nil
+	}
+	return r.Reader.Read(p)
+}
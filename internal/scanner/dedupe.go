@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/yourusername/fast-file-deletion/internal/dedupe"
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+)
+
+// SetDedupe restricts deletion to duplicate files: once the tree is
+// inventoried, the regular files that pass the patterns, filter and age
+// limits are compared by content, and of each group of identical files all
+// but the copy opts chooses are deleted. The groups are reported in
+// ScanResult.Duplicates. Files that are retained still take part, and may
+// be the copy that survives. Directories are kept. The preferred directories
+// of opts are absolute or relative to the root. Files are hashed by the
+// scanner's workers (see SetWorkers; a ParallelScanner uses its own), unless
+// opts.Workers sets a number. A nil opts disables it.
+//
+// ScanResult.Revalidate refuses a copy whose surviving copy was removed,
+// replaced or modified since the scan, so callers deleting the copies must
+// revalidate them even without SetRecordIdentity.
+func (o *scanOptions) SetDedupe(opts *dedupe.Options) {
+	o.dedupe = opts
+}
+
+// findDuplicates groups the identical files of the inventory and returns the
+// copies to delete: those that are deletable and not kept by the count and
+// size limits. The groups are recorded in result with only those copies, each
+// copy's survivor is recorded for Revalidate, and files that could not be
+// read are reported as warnings. The error is ctx's
+// if it is cancelled.
+func (s *Scanner) findDuplicates(ctx context.Context, inventory []inventoryFile, keep []bool, result *ScanResult) (map[string]bool, error) {
+	files := make([]dedupe.File, len(inventory))
+	deletable := make(map[string]bool)
+	for i, f := range inventory {
+		files[i] = dedupe.File{Path: f.path, Size: f.size, ModTime: f.modTime}
+		if f.spaceOK {
+			files[i].Dev, files[i].Ino = f.space.dev, f.space.ino
+		} else if f.identity != (FileIdentity{}) {
+			files[i].Dev, files[i].Ino = f.identity.Dev, f.identity.Ino
+		}
+		if f.deletable && !keep[i] {
+			deletable[f.path] = true
+		}
+	}
+
+	// Preferred directories must be spelled like the paths the walk produces
+	opts := *s.dedupe
+	if opts.Workers <= 0 {
+		opts.Workers = s.workers
+	}
+	opts.Prefer = make([]string, len(s.dedupe.Prefer))
+	for i, dir := range s.dedupe.Prefer {
+		if filepath.IsAbs(dir) {
+			if rel, err := filepath.Rel(result.ScannedPath, dir); err == nil {
+				dir = rel
+			}
+		}
+		opts.Prefer[i] = filepath.Join(s.rootPath, dir)
+	}
+
+	logger.Info("Comparing %d files for duplicates", len(files))
+	groups, failed, err := dedupe.Find(ctx, files, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range failed {
+		result.warn(f.Path, "compare contents", f.Err, false)
+	}
+
+	copies := make(map[string]bool)
+	result.survivors = make(map[string]dedupe.File)
+	for _, g := range groups {
+		deleted := g.Copies[:0]
+		for _, c := range g.Copies {
+			if deletable[c.Path] {
+				deleted = append(deleted, c)
+				copies[c.Path] = true
+				result.survivors[c.Path] = g.Survivor
+			}
+		}
+		if len(deleted) > 0 {
+			g.Copies = deleted
+			result.Duplicates = append(result.Duplicates, g)
+		}
+	}
+	// Copies that are kept no longer count, so the largest may have changed
+	slices.SortStableFunc(result.Duplicates, func(a, b dedupe.Group) int {
+		return cmp.Compare(b.Reclaimable(), a.Reclaimable())
+	})
+	logger.Info("Duplicates: %d groups, %d copies to delete", len(result.Duplicates), len(copies))
+	return copies, nil
+}
+
+// survivorIntact reports whether the copy of path's duplicate group that is
+// kept still holds the content it was compared with: the same file, with the
+// size and modification time it was found with. Paths that are not duplicate
+// copies are always intact.
+func (r *ScanResult) survivorIntact(path string) bool {
+	survivor, ok := r.survivors[path]
+	if !ok {
+		return true
+	}
+	info, err := os.Lstat(survivor.Path)
+	if err != nil {
+		logger.Debug("Revalidation failed, kept copy is gone: %s (kept copy %s: %v)", path, survivor.Path, err)
+		return false
+	}
+	if !info.Mode().IsRegular() || info.Size() != survivor.Size || !info.ModTime().Equal(survivor.ModTime) {
+		logger.Debug("Revalidation failed, kept copy modified since scan: %s (kept copy %s)", path, survivor.Path)
+		return false
+	}
+	if survivor.Dev != 0 || survivor.Ino != 0 {
+		id, err := identityOf(survivor.Path, info)
+		if err != nil || id.Dev != survivor.Dev || id.Ino != survivor.Ino {
+			logger.Debug("Revalidation failed, kept copy replaced since scan: %s (kept copy %s)", path, survivor.Path)
+			return false
+		}
+	}
+	return true
+}
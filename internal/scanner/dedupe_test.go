@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/dedupe"
+)
+
+// TestScanner_Dedupe tests that only the copies of duplicate files are
+// deleted, that directories and unique files are kept, and that excluded
+// files are left out of the comparison.
+func TestScanner_Dedupe(t *testing.T) {
+	root := t.TempDir()
+	files := []struct {
+		name, content string
+		age           time.Duration
+	}{
+		{"archive/photo.jpg", "photo", 3 * time.Hour},
+		{"incoming/photo.jpg", "photo", time.Hour},
+		{"incoming/photo (1).jpg", "photo", 2 * time.Hour},
+		{"incoming/unique.jpg", "other", time.Hour},
+		{"incoming/notes.txt", "notes", time.Hour},
+		{"backup/notes.txt", "notes", 2 * time.Hour}, // Excluded
+	}
+	now := time.Now()
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", f.name, err)
+		}
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatalf("Failed to set times of %s: %v", f.name, err)
+		}
+	}
+	exclude, err := NewMatcher([]string{"backup/"})
+	if err != nil {
+		t.Fatalf("Invalid pattern: %v", err)
+	}
+
+	s := NewScanner(root, nil)
+	s.SetExclude(exclude)
+	s.SetDedupe(&dedupe.Options{Keep: dedupe.KeepOldest})
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var deleted []string
+	for _, path := range result.Files {
+		rel, _ := filepath.Rel(root, path)
+		deleted = append(deleted, filepath.ToSlash(rel))
+	}
+	slices.Sort(deleted)
+	want := []string{"incoming/photo (1).jpg", "incoming/photo.jpg"}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("Expected %v to be deleted, got %v", want, deleted)
+	}
+
+	if len(result.Duplicates) != 1 {
+		t.Fatalf("Expected 1 group of duplicates, got %v", result.Duplicates)
+	}
+	g := result.Duplicates[0]
+	if g.Survivor.Path != filepath.Join(root, "archive", "photo.jpg") || len(g.Copies) != 2 || g.Reclaimable() != 10 {
+		t.Errorf("Expected archive/photo.jpg to survive with 2 copies of 10 bytes, got %+v", g)
+	}
+	if result.TotalSizeBytes != 10 {
+		t.Errorf("Expected 10 bytes to delete, got %d", result.TotalSizeBytes)
+	}
+}
+
+// TestScanner_DedupeAgeLimit tests that copies too new to be deleted are
+// retained, while still counting as copies of the survivor.
+func TestScanner_DedupeAgeLimit(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"a": 72 * time.Hour, "b": 48 * time.Hour, "c": time.Hour} {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte("same"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("Failed to set times of %s: %v", name, err)
+		}
+	}
+
+	s := NewScanner(root, nil)
+	s.SetOlderThan(24 * time.Hour)
+	s.SetDedupe(&dedupe.Options{Keep: dedupe.KeepShortestPath})
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	// The paths are equally long, so the oldest, a, survives; c is too new
+	if len(result.Files) != 1 || result.Files[0] != filepath.Join(root, "b") {
+		t.Errorf("Expected only b to be deleted, got %v", result.Files)
+	}
+	if result.TotalRetained != 2 {
+		t.Errorf("Expected 2 files retained, got %d", result.TotalRetained)
+	}
+}
+
+// TestScanner_DedupePrefer tests that preferred directories may be given
+// relative to the root or as absolute paths.
+func TestScanner_DedupePrefer(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a/data", "b/data", "c/data"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("same"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	for _, prefer := range []string{"b", filepath.Join(root, "b")} {
+		s := NewScanner(root, nil)
+		s.SetDedupe(&dedupe.Options{Prefer: []string{prefer}})
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if len(result.Duplicates) != 1 || result.Duplicates[0].Survivor.Path != filepath.Join(root, "b", "data") {
+			t.Errorf("Prefer %s: expected b/data to survive, got %+v", prefer, result.Duplicates)
+		}
+	}
+}
+
+// TestScanResult_RevalidateSurvivor tests that a copy is no longer deleted
+// once the copy its group keeps is modified, replaced or removed, even
+// without recorded identities.
+func TestScanResult_RevalidateSurvivor(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(path string) error
+	}{
+		{"modified", func(path string) error { return os.WriteFile(path, []byte("edited"), 0644) }},
+		{"removed", os.Remove},
+		{"replaced", func(path string) error {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			// Same content and times, but another file
+			if err := os.WriteFile(path+".new", []byte("same"), 0644); err != nil {
+				return err
+			}
+			if err := os.Chtimes(path+".new", info.ModTime(), info.ModTime()); err != nil {
+				return err
+			}
+			return os.Rename(path+".new", path)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			now := time.Now()
+			for name, age := range map[string]time.Duration{"original": 2 * time.Hour, "copy": time.Hour} {
+				path := filepath.Join(root, name)
+				if err := os.WriteFile(path, []byte("same"), 0644); err != nil {
+					t.Fatalf("Failed to create %s: %v", name, err)
+				}
+				if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+					t.Fatalf("Failed to set times of %s: %v", name, err)
+				}
+			}
+
+			s := NewScanner(root, nil)
+			s.SetDedupe(&dedupe.Options{})
+			result, err := s.Scan()
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			if result.Len() != 1 || result.Path(0) != filepath.Join(root, "copy") {
+				t.Fatalf("Expected only the copy to be deleted, got %v", result.Files)
+			}
+			if !result.Revalidate(0) {
+				t.Fatal("Expected the copy to revalidate while the original is intact")
+			}
+
+			if err := tt.change(filepath.Join(root, "original")); err != nil {
+				t.Fatalf("Failed to change the original: %v", err)
+			}
+			if result.Revalidate(0) {
+				t.Error("Expected the copy to be skipped once the original changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultSampleProbes is how many random descents SampleTree makes unless
+// told otherwise.
+const DefaultSampleProbes = 256
+
+// maxSampleDepth bounds a descent, in case a bind mount loops the tree.
+const maxSampleDepth = 4096
+
+// EstimateRange is an estimate with its approximate 95% confidence bounds.
+type EstimateRange struct {
+	Value int64
+	Low   int64
+	High  int64
+}
+
+// TreeSample estimates the size of a directory tree from random descents.
+type TreeSample struct {
+	Entries     EstimateRange // Files and directories below the root, like ScanResult.TotalScanned
+	Bytes       EstimateRange // Total size of the files
+	Probes      int           // Descents made
+	DirsRead    int           // Distinct directories enumerated
+	EntriesRead int           // Entries enumerated in them
+	Unreadable  int           // Directories that could not be read (counted as empty)
+	Exact       bool          // Every directory was read, so the figures are exact
+	Elapsed     time.Duration
+}
+
+// sampledDir is what a directory held when it was read.
+type sampledDir struct {
+	entries int
+	bytes   int64
+	subdirs []string
+}
+
+// SampleTree estimates how many entries and bytes the tree below root holds
+// without walking all of it. Each probe descends from root through randomly
+// chosen subdirectories until it reaches a directory without any, and
+// extrapolates from the directories it passed: a directory's entries count
+// once for every sibling it stands for at each level above it (Knuth's
+// estimator). Probes are averaged, and their spread gives the bounds.
+//
+// Directories are read as the scan reads them, without following symbolic
+// links, and each one only once; on a small tree the probes end up reading
+// all of it and the figures are exact. Filters are not applied. ctx stops the
+// sampling early, with an error wrapping ctx.Err().
+func SampleTree(ctx context.Context, root string, probes int, rng *rand.Rand) (*TreeSample, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	if probes <= 0 {
+		probes = DefaultSampleProbes
+	}
+
+	start := time.Now()
+	sample := &TreeSample{}
+	dirs := make(map[string]*sampledDir)
+	read := func(path string) *sampledDir {
+		if d, ok := dirs[path]; ok {
+			return d
+		}
+		d, err := readSampleDir(path)
+		if err != nil {
+			sample.Unreadable++
+		}
+		dirs[path] = d
+		sample.DirsRead++
+		sample.EntriesRead += d.entries
+		return d
+	}
+
+	entries := make([]float64, 0, probes)
+	bytes := make([]float64, 0, probes)
+	for sample.Probes < probes {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("sampling cancelled: %w", err)
+		}
+
+		var n, size float64
+		weight := 1.0
+		path := root
+		for depth := 0; depth < maxSampleDepth; depth++ {
+			d := read(path)
+			n += weight * float64(d.entries)
+			size += weight * float64(d.bytes)
+			if len(d.subdirs) == 0 {
+				break
+			}
+			weight *= float64(len(d.subdirs))
+			path = d.subdirs[rng.IntN(len(d.subdirs))]
+		}
+		entries = append(entries, n)
+		bytes = append(bytes, size)
+		sample.Probes++
+
+		if _, _, complete := sampledTotals(dirs, root); complete {
+			break
+		}
+	}
+
+	if n, size, complete := sampledTotals(dirs, root); complete {
+		sample.Entries = EstimateRange{Value: n, Low: n, High: n}
+		sample.Bytes = EstimateRange{Value: size, Low: size, High: size}
+		sample.Exact = true
+	} else {
+		var seen int64
+		for _, d := range dirs {
+			seen += d.bytes
+		}
+		sample.Entries = rangeOf(entries, int64(sample.EntriesRead))
+		sample.Bytes = rangeOf(bytes, seen)
+	}
+	sample.Elapsed = time.Since(start)
+	return sample, nil
+}
+
+// readSampleDir reads a directory's entries, their total size and its
+// subdirectories. A directory that cannot be read is returned empty with the
+// error.
+func readSampleDir(path string) (*sampledDir, error) {
+	list, err := os.ReadDir(path)
+	d := &sampledDir{entries: len(list)}
+	for _, entry := range list {
+		if entry.IsDir() {
+			d.subdirs = append(d.subdirs, filepath.Join(path, entry.Name()))
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			d.bytes += fileSize(info)
+		}
+	}
+	return d, err
+}
+
+// sampledTotals adds up the entries and bytes below path if every directory
+// in it has been read; complete is false otherwise.
+func sampledTotals(dirs map[string]*sampledDir, path string) (entries, bytes int64, complete bool) {
+	d, ok := dirs[path]
+	if !ok {
+		return 0, 0, false
+	}
+	entries, bytes = int64(d.entries), d.bytes
+	for _, sub := range d.subdirs {
+		n, size, ok := sampledTotals(dirs, sub)
+		if !ok {
+			return 0, 0, false
+		}
+		entries += n
+		bytes += size
+	}
+	return entries, bytes, true
+}
+
+// rangeOf returns the mean of the probes' estimates with the bounds of a 95%
+// normal confidence interval around it. The lower bound is at least seen, what
+// the probes actually found.
+func rangeOf(estimates []float64, seen int64) EstimateRange {
+	var sum float64
+	for _, e := range estimates {
+		sum += e
+	}
+	mean := sum / float64(len(estimates))
+
+	var margin float64
+	if len(estimates) > 1 {
+		var squares float64
+		for _, e := range estimates {
+			squares += (e - mean) * (e - mean)
+		}
+		stderr := math.Sqrt(squares/float64(len(estimates)-1)) / math.Sqrt(float64(len(estimates)))
+		margin = 1.96 * stderr
+	}
+
+	r := EstimateRange{
+		Value: int64(math.Round(mean)),
+		Low:   int64(math.Max(0, math.Floor(mean-margin))),
+		High:  int64(math.Ceil(mean + margin)),
+	}
+	r.Low = max(r.Low, seen)
+	r.Value = max(r.Value, r.Low)
+	r.High = max(r.High, r.Value)
+	return r
+}
@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// makeUniformTree creates depth levels of fanout subdirectories below root,
+// each directory holding two 10-byte files. Returns the number of entries and
+// bytes below root.
+func makeUniformTree(t *testing.T, root string, fanout, depth int) (int64, int64) {
+	t.Helper()
+	var entries, bytes int64
+	var fill func(dir string, level int)
+	fill = func(dir string, level int) {
+		for i := 0; i < 2; i++ {
+			if err := os.WriteFile(filepath.Join(dir, "file"+strconv.Itoa(i)), []byte("0123456789"), 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+			entries++
+			bytes += 10
+		}
+		if level == depth {
+			return
+		}
+		for i := 0; i < fanout; i++ {
+			sub := filepath.Join(dir, "dir"+strconv.Itoa(i))
+			if err := os.Mkdir(sub, 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			entries++
+			fill(sub, level+1)
+		}
+	}
+	fill(root, 0)
+	return entries, bytes
+}
+
+// TestSampleTree_Exact tests that a tree small enough to be read completely by
+// the probes is counted exactly.
+func TestSampleTree_Exact(t *testing.T) {
+	root := t.TempDir()
+	entries, bytes := makeUniformTree(t, root, 2, 2)
+
+	sample, err := SampleTree(context.Background(), root, 0, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatalf("SampleTree failed: %v", err)
+	}
+	if !sample.Exact {
+		t.Fatalf("Expected an exact count after %d probes of a %d-entry tree", sample.Probes, entries)
+	}
+	want := EstimateRange{Value: entries, Low: entries, High: entries}
+	if sample.Entries != want {
+		t.Errorf("Entries = %+v, want %+v", sample.Entries, want)
+	}
+	if sample.Bytes.Value != bytes {
+		t.Errorf("Bytes = %d, want %d", sample.Bytes.Value, bytes)
+	}
+	if sample.DirsRead != 7 {
+		t.Errorf("DirsRead = %d, want 7", sample.DirsRead)
+	}
+}
+
+// TestSampleTree_Extrapolates tests that a few probes of a uniform tree, which
+// every descent represents perfectly, extrapolate to its exact size.
+func TestSampleTree_Extrapolates(t *testing.T) {
+	root := t.TempDir()
+	entries, bytes := makeUniformTree(t, root, 4, 3)
+
+	sample, err := SampleTree(context.Background(), root, 3, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatalf("SampleTree failed: %v", err)
+	}
+	if sample.Exact {
+		t.Fatalf("Three probes cannot read all %d directories", 1+4+16+64)
+	}
+	if sample.Probes != 3 {
+		t.Errorf("Probes = %d, want 3", sample.Probes)
+	}
+	if sample.Entries.Value != entries || sample.Entries.Low > entries || sample.Entries.High < entries {
+		t.Errorf("Entries = %+v, want %d", sample.Entries, entries)
+	}
+	if sample.Bytes.Value != bytes {
+		t.Errorf("Bytes = %+v, want %d", sample.Bytes, bytes)
+	}
+	if sample.EntriesRead >= int(entries) {
+		t.Errorf("EntriesRead = %d, expected only part of the %d entries to be read", sample.EntriesRead, entries)
+	}
+}
+
+// TestSampleTree_NotADirectory tests that sampling a file fails.
+func TestSampleTree_NotADirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if _, err := SampleTree(context.Background(), file, 0, rand.New(rand.NewPCG(1, 2))); err == nil {
+		t.Error("Expected an error for a file")
+	}
+}
+
+// TestRangeOf tests the bounds derived from the probes' estimates.
+func TestRangeOf(t *testing.T) {
+	r := rangeOf([]float64{100, 200, 300}, 0)
+	if r.Value != 200 || r.Low >= 200 || r.High <= 200 {
+		t.Errorf("rangeOf = %+v, want bounds around 200", r)
+	}
+	if r := rangeOf([]float64{100, 200, 300}, 250); r.Low != 250 || r.Value != 250 {
+		t.Errorf("rangeOf with 250 seen = %+v, want Low and Value raised to 250", r)
+	}
+}
@@ -0,0 +1,354 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Filter decides whether a scanned entry is selected for deletion.
+// Entries a filter rejects are retained; rejected directories are still traversed.
+// Filters compose with And, Or and Not.
+type Filter interface {
+	Match(e *Entry) (bool, error)
+}
+
+// FilterFunc adapts an ordinary function to the Filter interface.
+type FilterFunc func(e *Entry) (bool, error)
+
+// Match calls f(e).
+func (f FilterFunc) Match(e *Entry) (bool, error) {
+	return f(e)
+}
+
+// Entry is the view of a scanned entry that filters evaluate. Name, type,
+// path and depth come from the directory listing; size, times and ownership
+// need a stat call, which is made on first use and cached, so filters that
+// only look at names and types never stat.
+type Entry struct {
+	Path    string // Path as produced by the walk
+	RelPath string // Slash-separated path relative to the scan root
+	Depth   int    // 1 for direct children of the scan root
+
+	d       fs.DirEntry
+	info    fs.FileInfo
+	infoErr error
+	loaded  bool
+}
+
+// newEntry creates an Entry for a directory entry found at the given depth.
+func newEntry(path, relPath string, depth int, d fs.DirEntry) *Entry {
+	return &Entry{Path: path, RelPath: relPath, Depth: depth, d: d}
+}
+
+// Name returns the base name of the entry.
+func (e *Entry) Name() string {
+	return e.d.Name()
+}
+
+// IsDir reports whether the entry is a directory.
+func (e *Entry) IsDir() bool {
+	return e.d.IsDir()
+}
+
+// Type returns the type bits of the entry, without a stat call.
+func (e *Entry) Type() fs.FileMode {
+	return e.d.Type()
+}
+
+// Info returns the entry's file info (lstat), loading it on first use.
+func (e *Entry) Info() (fs.FileInfo, error) {
+	if !e.loaded {
+		e.info, e.infoErr = e.d.Info()
+		e.loaded = true
+	}
+	return e.info, e.infoErr
+}
+
+// Time returns the requested timestamp of the entry.
+func (e *Entry) Time(field TimeField) (time.Time, error) {
+	info, err := e.Info()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return timeOfField(e.Path, info, field)
+}
+
+// timeOfField returns the given timestamp of the file at path described by info.
+// Only the modification time is always available from info; the others may
+// require the platform's stat call.
+func timeOfField(path string, info fs.FileInfo, field TimeField) (time.Time, error) {
+	if field == TimeMTime {
+		return info.ModTime(), nil
+	}
+	return timeOfInfo(path, info, field)
+}
+
+// Owner returns the numeric user and group ids that own the entry.
+func (e *Entry) Owner() (uid, gid int, err error) {
+	info, err := e.Info()
+	if err != nil {
+		return 0, 0, err
+	}
+	return ownerOfInfo(e.Path, info)
+}
+
+// dirEntry returns a DirEntry for the entry that reuses any loaded file info,
+// so later size and age checks do not stat again.
+func (e *Entry) dirEntry() fs.DirEntry {
+	if e.loaded && e.infoErr == nil {
+		return fs.FileInfoToDirEntry(e.info)
+	}
+	return e.d
+}
+
+// TimeField selects which timestamp of an entry is used for age comparisons.
+type TimeField int
+
+const (
+	TimeMTime TimeField = iota // Last modification
+	TimeATime                  // Last access
+	TimeCTime                  // Last status change (not available on Windows)
+	TimeBTime                  // Creation (birth); needs statx on Linux
+)
+
+// String returns the name of the time field as used on the command line.
+func (f TimeField) String() string {
+	switch f {
+	case TimeMTime:
+		return "mtime"
+	case TimeATime:
+		return "atime"
+	case TimeCTime:
+		return "ctime"
+	case TimeBTime:
+		return "btime"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTimeField parses "mtime", "atime", "ctime" or "btime".
+func ParseTimeField(s string) (TimeField, error) {
+	switch strings.ToLower(s) {
+	case "mtime":
+		return TimeMTime, nil
+	case "atime":
+		return TimeATime, nil
+	case "ctime":
+		return TimeCTime, nil
+	case "btime":
+		return TimeBTime, nil
+	default:
+		return TimeMTime, fmt.Errorf("unknown time field %q (expected mtime, atime, ctime or btime)", s)
+	}
+}
+
+// CompareOp is a comparison operator used by numeric and time filters.
+type CompareOp int
+
+const (
+	OpEqual CompareOp = iota
+	OpNotEqual
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+)
+
+// String returns the operator symbol.
+func (op CompareOp) String() string {
+	return [...]string{"=", "!=", "<", "<=", ">", ">="}[op]
+}
+
+// compare applies the operator to a three-way comparison result (-1, 0, 1).
+func (op CompareOp) compare(cmp int) bool {
+	switch op {
+	case OpEqual:
+		return cmp == 0
+	case OpNotEqual:
+		return cmp != 0
+	case OpLess:
+		return cmp < 0
+	case OpLessEqual:
+		return cmp <= 0
+	case OpGreater:
+		return cmp > 0
+	case OpGreaterEqual:
+		return cmp >= 0
+	}
+	return false
+}
+
+// compareInt64 returns -1, 0 or 1.
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// And matches entries matched by every filter. Evaluation stops at the first
+// filter that does not match, so cheap filters should come first.
+func And(filters ...Filter) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		for _, f := range filters {
+			ok, err := f.Match(e)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// Or matches entries matched by any filter. Evaluation stops at the first match.
+func Or(filters ...Filter) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		for _, f := range filters {
+			ok, err := f.Match(e)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// Not matches entries the filter does not match.
+func Not(f Filter) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		ok, err := f.Match(e)
+		return !ok && err == nil, err
+	})
+}
+
+// SizeFilter compares the entry's size in bytes with size.
+func SizeFilter(op CompareOp, size int64) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		info, err := e.Info()
+		if err != nil {
+			return false, err
+		}
+		return op.compare(compareInt64(info.Size(), size)), nil
+	})
+}
+
+// TimeFilter compares the entry's timestamp with t ("<" means before t).
+func TimeFilter(field TimeField, op CompareOp, t time.Time) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		et, err := e.Time(field)
+		if err != nil {
+			return false, err
+		}
+		return op.compare(et.Compare(t)), nil
+	})
+}
+
+// OlderThan matches entries whose timestamp is more than age before reference.
+func OlderThan(field TimeField, age time.Duration, reference time.Time) Filter {
+	return TimeFilter(field, OpLess, reference.Add(-age))
+}
+
+// NewerThan matches entries whose timestamp is less than age before reference.
+func NewerThan(field TimeField, age time.Duration, reference time.Time) Filter {
+	return TimeFilter(field, OpGreater, reference.Add(-age))
+}
+
+// UIDFilter compares the numeric owner user id with uid.
+func UIDFilter(op CompareOp, uid int) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		u, _, err := e.Owner()
+		if err != nil {
+			return false, err
+		}
+		return op.compare(compareInt64(int64(u), int64(uid))), nil
+	})
+}
+
+// GIDFilter compares the numeric owner group id with gid.
+func GIDFilter(op CompareOp, gid int) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		_, g, err := e.Owner()
+		if err != nil {
+			return false, err
+		}
+		return op.compare(compareInt64(int64(g), int64(gid))), nil
+	})
+}
+
+// DepthFilter compares the entry's depth below the scan root with depth.
+func DepthFilter(op CompareOp, depth int) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		return op.compare(compareInt64(int64(e.Depth), int64(depth))), nil
+	})
+}
+
+// TypeFilter matches entries of the given type: 0 for regular files, or one of
+// fs.ModeDir, fs.ModeSymlink, fs.ModeNamedPipe, fs.ModeSocket, fs.ModeDevice
+// and fs.ModeDevice|fs.ModeCharDevice.
+func TypeFilter(mode fs.FileMode) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		return e.Type()&fs.ModeType == mode, nil
+	})
+}
+
+// NameGlob matches the entry's base name against a glob ("*", "?", "[...]").
+func NameGlob(glob string) (Filter, error) {
+	re, err := compileGlob(glob)
+	if err != nil {
+		return nil, err
+	}
+	return FilterFunc(func(e *Entry) (bool, error) {
+		return re.MatchString(e.Name()), nil
+	}), nil
+}
+
+// PathGlob matches the entry's path relative to the scan root against a glob;
+// "**" matches across directories.
+func PathGlob(glob string) (Filter, error) {
+	re, err := compileGlob(strings.TrimPrefix(filepath.ToSlash(glob), "/"))
+	if err != nil {
+		return nil, err
+	}
+	return FilterFunc(func(e *Entry) (bool, error) {
+		return re.MatchString(e.RelPath), nil
+	}), nil
+}
+
+// NameRegexp matches the entry's base name against a regular expression.
+func NameRegexp(re *regexp.Regexp) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		return re.MatchString(e.Name()), nil
+	})
+}
+
+// PathRegexp matches the entry's relative path against a regular expression.
+func PathRegexp(re *regexp.Regexp) Filter {
+	return FilterFunc(func(e *Entry) (bool, error) {
+		return re.MatchString(e.RelPath), nil
+	})
+}
+
+// compileGlob compiles a glob to an anchored regular expression, case-insensitive on Windows.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	body, err := globToRegexp(glob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+	expr := "^" + body + "$"
+	if runtime.GOOS == "windows" {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingDirEntry wraps a DirEntry and counts calls to Info (stat calls).
+type countingDirEntry struct {
+	fs.DirEntry
+	infoCalls int
+}
+
+func (c *countingDirEntry) Info() (fs.FileInfo, error) {
+	c.infoCalls++
+	return c.DirEntry.Info()
+}
+
+// newTestEntry creates a file and returns an Entry for it with a stat counter.
+func newTestEntry(t *testing.T, name string, size int, modTime time.Time) (*Entry, *countingDirEntry) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	counting := &countingDirEntry{DirEntry: entries[0]}
+	return newEntry(path, name, 1, counting), counting
+}
+
+// TestFilterStatsOnlyWhenNeeded tests that name, type and depth filters are
+// evaluated without a stat call, and that stat data is loaded once and reused.
+func TestFilterStatsOnlyWhenNeeded(t *testing.T) {
+	entry, counting := newTestEntry(t, "report.log", 10, time.Now())
+
+	nameGlob, err := NameGlob("*.log")
+	if err != nil {
+		t.Fatalf("NameGlob failed: %v", err)
+	}
+	cheap := And(nameGlob, TypeFilter(0), DepthFilter(OpEqual, 1))
+	if ok, err := cheap.Match(entry); err != nil || !ok {
+		t.Fatalf("Expected cheap filter to match, got %v, %v", ok, err)
+	}
+	if counting.infoCalls != 0 {
+		t.Errorf("Expected no stat calls for name/type/depth filters, got %d", counting.infoCalls)
+	}
+
+	sized := And(SizeFilter(OpGreaterEqual, 10), OlderThan(TimeMTime, -time.Hour, time.Now()))
+	if ok, err := sized.Match(entry); err != nil || !ok {
+		t.Fatalf("Expected size/age filter to match, got %v, %v", ok, err)
+	}
+	if counting.infoCalls != 1 {
+		t.Errorf("Expected exactly one stat call, got %d", counting.infoCalls)
+	}
+}
+
+// TestFilterCombinators tests And, Or and Not, including short-circuiting.
+func TestFilterCombinators(t *testing.T) {
+	entry, _ := newTestEntry(t, "a.txt", 1, time.Now())
+	yes := FilterFunc(func(*Entry) (bool, error) { return true, nil })
+	no := FilterFunc(func(*Entry) (bool, error) { return false, nil })
+	mustNotRun := FilterFunc(func(*Entry) (bool, error) {
+		t.Error("Filter evaluated after short-circuit")
+		return false, nil
+	})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"and true", And(yes, yes), true},
+		{"and short-circuits", And(no, mustNotRun), false},
+		{"or short-circuits", Or(yes, mustNotRun), true},
+		{"or false", Or(no, no), false},
+		{"not", Not(no), true},
+	}
+	for _, tt := range tests {
+		got, err := tt.filter.Match(entry)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestFilterTimeFields tests that access and change times can be read and that
+// older/newer comparisons use the reference time.
+func TestFilterTimeFields(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	entry, _ := newTestEntry(t, "old.dat", 1, old)
+
+	atime, err := entry.Time(TimeATime)
+	if err != nil {
+		t.Fatalf("Reading atime failed: %v", err)
+	}
+	if !atime.Equal(old.Truncate(time.Second)) && atime.Sub(old).Abs() > time.Second {
+		t.Errorf("Expected atime %v, got %v", old, atime)
+	}
+
+	reference := old.Add(36 * time.Hour)
+	if ok, _ := OlderThan(TimeMTime, 24*time.Hour, reference).Match(entry); !ok {
+		t.Error("Expected entry to be older than 24h at the reference time")
+	}
+	if ok, _ := NewerThan(TimeMTime, 24*time.Hour, reference).Match(entry); ok {
+		t.Error("Expected entry not to be newer than 24h at the reference time")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// identityOf extracts the device, inode and modification time from the stat
+// data already held in info, so no additional system call is needed.
+func identityOf(path string, info fs.FileInfo) (FileIdentity, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileIdentity{}, fmt.Errorf("no stat data available for %s", path)
+	}
+	return FileIdentity{
+		Dev:     uint64(st.Dev),
+		Ino:     uint64(st.Ino),
+		ModTime: info.ModTime(),
+		Size:    fileSize(info),
+	}, nil
+}
+
+// spaceOfInfo extracts the identity, link count and allocated blocks from the
+// stat data already held in info. Blocks are always 512 bytes in stat.
+func spaceOfInfo(info fs.FileInfo) (fileSpace, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileSpace{}, false
+	}
+	return fileSpace{
+		dev:   uint64(st.Dev),
+		ino:   uint64(st.Ino),
+		nlink: uint64(st.Nlink),
+		alloc: int64(st.Blocks) * 512,
+	}, true
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// identityOf returns the volume serial number and file index of path, which
+// together identify a file on Windows the way (device, inode) does on Unix.
+// The handle is opened without data access and without following reparse points.
+func identityOf(path string, info fs.FileInfo) (FileIdentity, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return FileIdentity{}, fmt.Errorf("cannot get absolute path: %w", err)
+	}
+	pathUTF16, err := windows.UTF16PtrFromString(toExtendedLengthPath(absPath))
+	if err != nil {
+		return FileIdentity{}, fmt.Errorf("failed to convert path to UTF-16: %w", err)
+	}
+
+	handle, err := windows.CreateFile(
+		pathUTF16,
+		0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT,
+		0,
+	)
+	if err != nil {
+		return FileIdentity{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer windows.CloseHandle(handle)
+
+	var data windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &data); err != nil {
+		return FileIdentity{}, fmt.Errorf("GetFileInformationByHandle failed for %s: %w", path, err)
+	}
+
+	return FileIdentity{
+		Dev:     uint64(data.VolumeSerialNumber),
+		Ino:     uint64(data.FileIndexHigh)<<32 | uint64(data.FileIndexLow),
+		ModTime: info.ModTime(),
+		Size:    fileSize(info),
+	}, nil
+}
+
+// spaceOfInfo reports no allocation data: the attribute data the walk holds on
+// Windows carries neither link counts nor allocation sizes, and opening every
+// file to read them would slow the scan down.
+func spaceOfInfo(info fs.FileInfo) (fileSpace, bool) {
+	return fileSpace{}, false
+}
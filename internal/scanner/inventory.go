@@ -0,0 +1,297 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+// Inventory is a compact, index-addressed list of the entries a scan marked for
+// deletion, in bottom-up order. Instead of one absolute path per entry it keeps
+// a directory table, where each directory points to its parent, and a name
+// table holding every name once, back to back. Paths are rebuilt on demand by
+// Path, so deep trees do not pay for their repeated prefixes.
+//
+// An Inventory is built by the scanner and read-only afterwards; its methods
+// are safe for concurrent use.
+type Inventory struct {
+	root   string // Scanned root exactly as given to the scanner
+	prefix string // Prepended to root-relative paths so they match filepath.WalkDir's
+
+	names   []byte     // Name table
+	dirs    []invDir   // Directory table; dirs[0] is the root
+	entries []invEntry // Entries to delete
+
+	flatBytes int64 // Memory the entries would take as a flat list of paths (see FlatBytes)
+}
+
+// invName locates a name in the name table.
+type invName struct {
+	off uint32
+	len uint16
+}
+
+// invDir is a directory in the directory table.
+type invDir struct {
+	parent  int32  // Index of the parent directory (-1 for the root)
+	depth   uint16 // Number of directories above it
+	pathLen uint32 // Length of its path, for sizing rebuilt paths
+	name    invName
+}
+
+// invEntry is an entry to delete. A directory entry refers to its own row of
+// the directory table, a file to the row of the directory holding it. The
+// fields of its name are inlined to keep it at 12 bytes.
+type invEntry struct {
+	dir     int32
+	nameOff uint32 // File name (unused for directories)
+	nameLen uint16
+	isDir   bool
+}
+
+// fileName returns the location of a file entry's name.
+func (e invEntry) fileName() invName {
+	return invName{off: e.nameOff, len: e.nameLen}
+}
+
+// rootDir is the directory table index of the scanned root.
+const rootDir = 0
+
+// newInventory creates an empty inventory for a scan of root.
+func newInventory(root string) *Inventory {
+	prefix := filepath.Clean(root)
+	switch {
+	case prefix == ".":
+		prefix = ""
+	case !os.IsPathSeparator(prefix[len(prefix)-1]):
+		prefix += string(filepath.Separator)
+	}
+	return &Inventory{
+		root:   root,
+		prefix: prefix,
+		dirs:   []invDir{{parent: -1, pathLen: uint32(len(root))}},
+	}
+}
+
+// addName appends name to the name table.
+func (inv *Inventory) addName(name string) (invName, error) {
+	if len(name) > math.MaxUint16 || len(inv.names)+len(name) > math.MaxUint32 {
+		return invName{}, fmt.Errorf("scan inventory is full: too many or too long names")
+	}
+	n := invName{off: uint32(len(inv.names)), len: uint16(len(name))}
+	inv.names = append(inv.names, name...)
+	return n, nil
+}
+
+// name returns a name from the name table.
+func (inv *Inventory) name(n invName) []byte {
+	return inv.names[n.off : n.off+uint32(n.len)]
+}
+
+// addDir adds the directory name inside directory parent to the directory
+// table and returns its index.
+func (inv *Inventory) addDir(parent int32, name string) (int32, error) {
+	n, err := inv.addName(name)
+	if err != nil {
+		return 0, err
+	}
+	p := inv.dirs[parent]
+	pathLen := uint32(len(inv.prefix)) + uint32(len(name))
+	if parent != rootDir {
+		pathLen = p.pathLen + 1 + uint32(len(name))
+	}
+	inv.dirs = append(inv.dirs, invDir{parent: parent, depth: p.depth + 1, pathLen: pathLen, name: n})
+	return int32(len(inv.dirs) - 1), nil
+}
+
+// appendFile adds the file name inside directory dir to the entries.
+func (inv *Inventory) appendFile(dir int32, name string) error {
+	n, err := inv.addName(name)
+	if err != nil {
+		return err
+	}
+	inv.entries = append(inv.entries, invEntry{dir: dir, nameOff: n.off, nameLen: n.len})
+	inv.addFlat(inv.filePathLen(dir, len(name)))
+	return nil
+}
+
+// appendDir adds directory dir to the entries.
+func (inv *Inventory) appendDir(dir int32) {
+	inv.entries = append(inv.entries, invEntry{dir: dir, isDir: true})
+	inv.addFlat(int(inv.dirs[dir].pathLen))
+}
+
+// filePathLen returns the length of the path of a file named name in dir.
+func (inv *Inventory) filePathLen(dir int32, name int) int {
+	if dir == rootDir {
+		return len(inv.prefix) + name
+	}
+	return int(inv.dirs[dir].pathLen) + 1 + name
+}
+
+// addFlat accounts for one path of pathLen bytes in the flat layout: the
+// string and its header in Files plus the IsDirectory flag.
+func (inv *Inventory) addFlat(pathLen int) {
+	inv.flatBytes += int64(pathLen) + int64(unsafe.Sizeof("")) + 1
+}
+
+// Len returns the number of entries.
+func (inv *Inventory) Len() int {
+	return len(inv.entries)
+}
+
+// IsDir reports whether entry i is a directory.
+func (inv *Inventory) IsDir(i int) bool {
+	return inv.entries[i].isDir
+}
+
+// Depth returns the number of directories between the root and entry i. The
+// root itself has depth 0; children are always deeper than their parent.
+func (inv *Inventory) Depth(i int) int {
+	e := inv.entries[i]
+	depth := int(inv.dirs[e.dir].depth)
+	if !e.isDir {
+		depth++
+	}
+	return depth
+}
+
+// Path rebuilds the path of entry i, as filepath.WalkDir reported it.
+func (inv *Inventory) Path(i int) string {
+	e := inv.entries[i]
+	if e.isDir {
+		return inv.dirPath(e.dir)
+	}
+	return inv.buildPath(e.dir, inv.name(e.fileName()), inv.filePathLen(e.dir, int(e.nameLen)))
+}
+
+// dirPath rebuilds the path of row dir of the directory table.
+func (inv *Inventory) dirPath(dir int32) string {
+	if dir == rootDir {
+		return inv.root
+	}
+	return inv.buildPath(dir, nil, int(inv.dirs[dir].pathLen))
+}
+
+// buildPath writes the path of directory dir, followed by the file name if
+// there is one, into a string of size bytes.
+func (inv *Inventory) buildPath(dir int32, file []byte, size int) string {
+	// Collect the directories from dir up to the root, then write their
+	// names top-down
+	var stack [32]int32
+	chain := stack[:0]
+	for d := dir; d != rootDir; d = inv.dirs[d].parent {
+		chain = append(chain, d)
+	}
+
+	var b strings.Builder
+	b.Grow(size)
+	b.WriteString(inv.prefix)
+	for j := len(chain) - 1; j >= 0; j-- {
+		b.Write(inv.name(inv.dirs[chain[j]].name))
+		if j > 0 {
+			b.WriteByte(filepath.Separator)
+		}
+	}
+	if file != nil {
+		if len(chain) > 0 {
+			b.WriteByte(filepath.Separator)
+		}
+		b.Write(file)
+	}
+	return b.String()
+}
+
+// dirRef is a row of the directory table that formats as its path, so that
+// log messages only rebuild the paths that are actually logged.
+type dirRef struct {
+	inv *Inventory
+	dir int32
+}
+
+// String returns the directory's path.
+func (r dirRef) String() string {
+	return r.inv.dirPath(r.dir)
+}
+
+// MemoryBytes returns the memory held by the inventory's tables.
+func (inv *Inventory) MemoryBytes() int64 {
+	return int64(cap(inv.names)) +
+		int64(cap(inv.dirs))*int64(unsafe.Sizeof(invDir{})) +
+		int64(cap(inv.entries))*int64(unsafe.Sizeof(invEntry{}))
+}
+
+// FlatBytes returns the memory the same entries take as ScanResult.Files and
+// ScanResult.IsDirectory, the layout used without SetCompact.
+func (inv *Inventory) FlatBytes() int64 {
+	return inv.flatBytes
+}
+
+// String summarizes the inventory's size, e.g. for logging.
+func (inv *Inventory) String() string {
+	return fmt.Sprintf("%d entries in %d directories, %d bytes of names", len(inv.entries), len(inv.dirs), len(inv.names))
+}
+
+// expandInventory fills Files and IsDirectory from the Inventory.
+func (r *ScanResult) expandInventory() {
+	n := r.Inventory.Len()
+	r.Files = make([]string, n)
+	r.IsDirectory = make([]bool, n)
+	for i := 0; i < n; i++ {
+		r.Files[i] = r.Inventory.Path(i)
+		r.IsDirectory[i] = r.Inventory.IsDir(i)
+	}
+}
+
+// flat reports whether the result's entries are read from Files rather than
+// the Inventory: the result was not scanned with SetCompact, or comes from the
+// Windows parallel scanner, which does not build an Inventory.
+func (r *ScanResult) flat() bool {
+	return r.Files != nil || r.Inventory == nil
+}
+
+// Len returns the number of entries to delete. Len, Path, IsDir, Depth and
+// UTF16 address entries by index and work for compact and flat results alike.
+func (r *ScanResult) Len() int {
+	if r.flat() {
+		return len(r.Files)
+	}
+	return r.Inventory.Len()
+}
+
+// Path returns the path of entry i.
+func (r *ScanResult) Path(i int) string {
+	if r.flat() {
+		return r.Files[i]
+	}
+	return r.Inventory.Path(i)
+}
+
+// IsDir reports whether entry i is a directory.
+func (r *ScanResult) IsDir(i int) bool {
+	if r.flat() {
+		return i < len(r.IsDirectory) && r.IsDirectory[i]
+	}
+	return r.Inventory.IsDir(i)
+}
+
+// Depth returns the depth of entry i in the tree: entries are never shallower
+// than the directories holding them.
+func (r *ScanResult) Depth(i int) int {
+	if r.flat() {
+		return strings.Count(filepath.ToSlash(r.Files[i]), "/")
+	}
+	return r.Inventory.Depth(i)
+}
+
+// UTF16 returns the pre-converted UTF-16 path of entry i, or nil if there is
+// none (see FilesUTF16).
+func (r *ScanResult) UTF16(i int) *uint16 {
+	if i < len(r.FilesUTF16) {
+		return r.FilesUTF16[i]
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// createDeepTree creates a chain of depth nested directories under root with
+// files files in each.
+func createDeepTree(t testing.TB, root string, depth, files int) {
+	t.Helper()
+	dir := root
+	for i := 0; i < depth; i++ {
+		dir = filepath.Join(dir, fmt.Sprintf("level-%02d-with-a-longish-name", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		for j := 0; j < files; j++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file-%04d.log", j)), nil, 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+		}
+	}
+}
+
+// TestScanner_Compact tests that a compact scan lists the same entries as a
+// flat one, for root paths in any form.
+func TestScanner_Compact(t *testing.T) {
+	tmpDir := t.TempDir()
+	createDeepTree(t, tmpDir, 4, 3)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Chdir failed: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	sub := filepath.Join("level-00-with-a-longish-name", "level-01-with-a-longish-name")
+	roots := []string{tmpDir, tmpDir + string(filepath.Separator), ".", sub, "." + string(filepath.Separator) + sub}
+	for _, root := range roots {
+		flat, err := NewScanner(root, nil).Scan()
+		if err != nil {
+			t.Fatalf("Scan of %q failed: %v", root, err)
+		}
+		s := NewScanner(root, nil)
+		s.SetCompact(true)
+		compact, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Compact scan of %q failed: %v", root, err)
+		}
+
+		if compact.Files != nil || compact.IsDirectory != nil {
+			t.Errorf("%q: expected no flat lists in a compact result", root)
+		}
+		if compact.Len() != len(flat.Files) || compact.Len() != compact.TotalToDelete {
+			t.Fatalf("%q: compact result has %d entries, flat %d", root, compact.Len(), len(flat.Files))
+		}
+		for i := 0; i < compact.Len(); i++ {
+			if compact.Path(i) != flat.Files[i] || compact.IsDir(i) != flat.IsDirectory[i] {
+				t.Errorf("%q entry %d: compact %q (dir %v), flat %q (dir %v)",
+					root, i, compact.Path(i), compact.IsDir(i), flat.Files[i], flat.IsDirectory[i])
+			}
+		}
+
+		// Entries are never shallower than the directories holding them
+		depth := make(map[string]int)
+		for i := 0; i < compact.Len(); i++ {
+			depth[filepath.Clean(compact.Path(i))] = compact.Depth(i)
+		}
+		for path, d := range depth {
+			dir := filepath.Dir(path)
+			if parent, ok := depth[dir]; ok && dir != path && parent >= d {
+				t.Errorf("%q: %s has depth %d, its parent %d", root, path, d, parent)
+			}
+		}
+	}
+}
+
+// TestInventory_Memory tests that the inventory of a deep tree is smaller than
+// the flat list of its paths.
+func TestInventory_Memory(t *testing.T) {
+	tmpDir := t.TempDir()
+	createDeepTree(t, tmpDir, 12, 50)
+
+	s := NewScanner(tmpDir, nil)
+	s.SetCompact(true)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var flat int64
+	for i := 0; i < result.Len(); i++ {
+		flat += int64(len(result.Path(i))) + int64(unsafe.Sizeof("")) + 1
+	}
+	if got := result.Inventory.FlatBytes(); got != flat {
+		t.Errorf("FlatBytes = %d, want %d", got, flat)
+	}
+	if inv := result.Inventory.MemoryBytes(); inv*3 > flat {
+		t.Errorf("Expected the inventory (%d bytes) to be under a third of the flat layout (%d bytes)", inv, flat)
+	}
+}
+
+// createDirTree creates dirs directories under root, each holding subdirs
+// subdirectories with one file in each: a tree with as many directories as
+// files.
+func createDirTree(t testing.TB, root string, dirs, subdirs int) {
+	t.Helper()
+	for i := 0; i < dirs; i++ {
+		for j := 0; j < subdirs; j++ {
+			dir := filepath.Join(root, fmt.Sprintf("project-%04d", i), fmt.Sprintf("module-%03d", j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "index.js"), nil, 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+		}
+	}
+}
+
+// peakHeap runs fn and returns the highest number of bytes in heap objects
+// above the live heap before it, sampled every 100µs. The garbage collector
+// runs whenever the heap grows by 5%, so that the peak reflects the data fn
+// holds rather than garbage waiting to be collected.
+func peakHeap(fn func()) int64 {
+	defer debug.SetGCPercent(debug.SetGCPercent(5))
+
+	const heapObjects = "/memory/classes/heap/objects:bytes"
+	read := func() int64 {
+		sample := []metrics.Sample{{Name: heapObjects}}
+		metrics.Read(sample)
+		return int64(sample[0].Value.Uint64())
+	}
+
+	runtime.GC()
+	base := read()
+	stop := make(chan struct{})
+	sampled := make(chan int64)
+	go func() {
+		ticker := time.NewTicker(100 * time.Microsecond)
+		defer ticker.Stop()
+		var peak int64
+		for {
+			peak = max(peak, read())
+			select {
+			case <-stop:
+				sampled <- peak
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	fn()
+	peak := max(read(), 0)
+	close(stop)
+	return max(peak, <-sampled) - base
+}
+
+// BenchmarkScanLayout compares the flat and compact layouts on a deep tree
+// and on a tree of many small directories: the peak heap of the scan, which
+// includes what the walk holds while it runs, and the heap retained by the
+// scan result.
+func BenchmarkScanLayout(b *testing.B) {
+	trees := []struct {
+		name   string
+		create func(testing.TB, string)
+	}{
+		{"deep", func(tb testing.TB, root string) { createDeepTree(tb, root, 20, 500) }},
+		{"dirs", func(tb testing.TB, root string) { createDirTree(tb, root, 100, 50) }},
+	}
+	for _, tree := range trees {
+		tmpDir := b.TempDir()
+		tree.create(b, tmpDir)
+
+		for _, compact := range []bool{false, true} {
+			name := tree.name + "/flat"
+			if compact {
+				name = tree.name + "/compact"
+			}
+			b.Run(name, func(b *testing.B) {
+				var peak, retained int64
+				var entries int
+				for i := 0; i < b.N; i++ {
+					var before, after runtime.MemStats
+					runtime.GC()
+					runtime.ReadMemStats(&before)
+
+					var result *ScanResult
+					peak += peakHeap(func() {
+						s := NewScanner(tmpDir, nil)
+						s.SetCompact(compact)
+						var err error
+						if result, err = s.Scan(); err != nil {
+							b.Fatalf("Scan failed: %v", err)
+						}
+					})
+
+					runtime.GC()
+					runtime.ReadMemStats(&after)
+					retained += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+					entries = result.Len()
+					runtime.KeepAlive(result)
+				}
+				b.ReportMetric(float64(peak)/float64(b.N)/float64(entries), "peak-heap-B/entry")
+				b.ReportMetric(float64(retained)/float64(b.N)/float64(entries), "heap-B/entry")
+			})
+		}
+	}
+}
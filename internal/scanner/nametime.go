@@ -0,0 +1,234 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NameTime extracts a timestamp embedded in a file name, such as the date of
+// "db-2026-09-01T0300.sql.gz" or "app.log.20260901", so that retention can use
+// the time a file was made rather than its modification time, which copying
+// resets. Create one with ParseNameTime.
+type NameTime struct {
+	spec   string
+	layout string         // Go time layout, searched for anywhere in the name
+	width  int            // Length of a timestamp written with layout
+	re     *regexp.Regexp // Or: regular expression with named groups
+}
+
+// NameFallback says what happens to a file whose name holds no timestamp.
+type NameFallback int
+
+const (
+	NameFallbackKeep      NameFallback = iota // Never delete the file (default)
+	NameFallbackDelete                        // Delete the file whatever its age
+	NameFallbackTimestamp                     // Use the timestamp selected by SetAgeBy
+)
+
+// String returns the name ParseNameFallback accepts for f.
+func (f NameFallback) String() string {
+	switch f {
+	case NameFallbackDelete:
+		return "delete"
+	case NameFallbackTimestamp:
+		return "timestamp"
+	default:
+		return "keep"
+	}
+}
+
+// ParseNameFallback parses "keep", "delete" or "timestamp".
+func ParseNameFallback(s string) (NameFallback, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "keep":
+		return NameFallbackKeep, nil
+	case "delete":
+		return NameFallbackDelete, nil
+	case "timestamp":
+		return NameFallbackTimestamp, nil
+	}
+	return 0, fmt.Errorf("unknown fallback %q (expected keep, delete or timestamp)", s)
+}
+
+// nameTimeGroups are the named groups a NameTime regular expression may use.
+var nameTimeGroups = map[string]bool{
+	"year": true, "month": true, "day": true, "hour": true, "minute": true, "second": true, "epoch": true,
+}
+
+// ParseNameTime parses a timestamp specification, which is either a regular
+// expression with named groups or a Go time layout:
+//
+//   - A regular expression is recognised by its named groups, e.g.
+//     `(?P<year>\d{4})(?P<month>\d\d)(?P<day>\d\d)`. The groups are year, month
+//     (number or English name), day, hour, minute and second, or epoch for Unix
+//     seconds; groups that are left out default to the start of the period.
+//   - Anything else is a Go time layout such as "2006-01-02T1504" or "20060102",
+//     searched for anywhere in the name. The layout must produce timestamps of a
+//     fixed width, so month names and unpadded numbers are not allowed.
+//
+// Times without a zone are in local time.
+func ParseNameTime(spec string) (*NameTime, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("empty timestamp specification")
+	}
+	if strings.Contains(spec, "(?P<") || strings.Contains(spec, "(?<") {
+		re, err := regexp.Compile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", spec, err)
+		}
+		names := make(map[string]bool)
+		for _, name := range re.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			if !nameTimeGroups[name] {
+				return nil, fmt.Errorf("unknown group %q in %q (expected year, month, day, hour, minute, second or epoch)", name, spec)
+			}
+			names[name] = true
+		}
+		if !names["year"] && !names["epoch"] {
+			return nil, fmt.Errorf("regular expression %q needs a year or epoch group", spec)
+		}
+		return &NameTime{spec: spec, re: re}, nil
+	}
+
+	// A fixed-width layout writes every time with the same number of characters
+	// and keeps the year, which a layout lacking one would lose
+	probes := []time.Time{
+		time.Date(2031, time.November, 27, 13, 45, 58, 0, time.UTC),
+		time.Date(2009, time.May, 3, 4, 5, 6, 0, time.UTC),
+	}
+	width := len(probes[0].Format(spec))
+	for _, probe := range probes {
+		s := probe.Format(spec)
+		if len(s) != width {
+			return nil, fmt.Errorf("time layout %q does not have a fixed width (use zero-padded numbers, not month or day names)", spec)
+		}
+		parsed, err := time.Parse(spec, s)
+		if err != nil || parsed.Year() != probe.Year() {
+			return nil, fmt.Errorf("time layout %q must include the year (e.g. 2006-01-02)", spec)
+		}
+	}
+	return &NameTime{spec: spec, layout: spec, width: width}, nil
+}
+
+// String returns the specification nt was parsed from.
+func (nt *NameTime) String() string {
+	return nt.spec
+}
+
+// Parse returns the timestamp in name and whether one was found.
+func (nt *NameTime) Parse(name string) (time.Time, bool) {
+	if nt.re != nil {
+		return nt.parseRegexp(name)
+	}
+
+	// Try every window of the layout's width, leftmost first. A window next to
+	// further digits is part of a longer number, not a timestamp.
+	isDigit := func(i int) bool { return i >= 0 && i < len(name) && name[i] >= '0' && name[i] <= '9' }
+	for i := 0; i+nt.width <= len(name); i++ {
+		end := i + nt.width
+		if (isDigit(i) && isDigit(i-1)) || (isDigit(end-1) && isDigit(end)) {
+			continue
+		}
+		if t, err := time.ParseInLocation(nt.layout, name[i:end], time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseRegexp assembles a time from the named groups of the first match.
+func (nt *NameTime) parseRegexp(name string) (time.Time, bool) {
+	m := nt.re.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, false
+	}
+	fields := map[string]int{"month": 1, "day": 1}
+	for i, group := range nt.re.SubexpNames() {
+		if group == "" || m[i] == "" {
+			continue
+		}
+		if group == "epoch" {
+			seconds, err := strconv.ParseInt(m[i], 10, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return time.Unix(seconds, 0), true
+		}
+		value, err := strconv.Atoi(m[i])
+		if err != nil && group == "month" {
+			value, err = monthNumber(m[i])
+		}
+		if err != nil {
+			return time.Time{}, false
+		}
+		fields[group] = value
+	}
+
+	year := fields["year"]
+	if year < 100 {
+		year += 2000
+	}
+	t := time.Date(year, time.Month(fields["month"]), fields["day"],
+		fields["hour"], fields["minute"], fields["second"], 0, time.Local)
+	// Reject values time.Date would normalise, such as month 13
+	if t.Month() != time.Month(fields["month"]) || t.Day() != fields["day"] || t.Hour() != fields["hour"] ||
+		t.Minute() != fields["minute"] || t.Second() != fields["second"] {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// monthNumber converts an English month name or its three-letter abbreviation.
+func monthNumber(s string) (int, error) {
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(s, m.String()) || strings.EqualFold(s, m.String()[:3]) {
+			return int(m), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown month %q", s)
+}
+
+// SetAgeFromName measures the age of files by the timestamp in their name (see
+// ParseNameTime) instead of the timestamp selected by SetAgeBy, for age limits
+// and for the ranking of SetKeepNewest and SetMaxTotalSize. fallback decides
+// what happens to files whose name holds no timestamp. Directories keep using
+// the SetAgeBy timestamp. A nil nt disables name timestamps.
+func (o *scanOptions) SetAgeFromName(nt *NameTime, fallback NameFallback) {
+	o.nameTime = nt
+	o.nameFallback = fallback
+}
+
+// nameAge says how the age of an entry is determined.
+type nameAge int
+
+const (
+	ageFromStat nameAge = iota // Use the timestamp selected by SetAgeBy
+	ageFromName                // Use the timestamp in the name
+	ageKeep                    // The name holds no timestamp: keep the entry
+	ageDelete                  // The name holds no timestamp: delete the entry
+)
+
+// nameAgeOf returns the timestamp in the name of d, if SetAgeFromName applies
+// to it, and how its age is to be determined.
+func (o *scanOptions) nameAgeOf(d fs.DirEntry) (time.Time, nameAge) {
+	if o.nameTime == nil || d.IsDir() {
+		return time.Time{}, ageFromStat
+	}
+	if t, ok := o.nameTime.Parse(d.Name()); ok {
+		return t, ageFromName
+	}
+	switch o.nameFallback {
+	case NameFallbackDelete:
+		return time.Time{}, ageDelete
+	case NameFallbackTimestamp:
+		return time.Time{}, ageFromStat
+	default:
+		return time.Time{}, ageKeep
+	}
+}
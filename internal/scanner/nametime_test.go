@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestParseNameTime tests timestamps found in names by layouts and regular expressions.
+func TestParseNameTime(t *testing.T) {
+	tests := []struct {
+		spec string
+		name string
+		want time.Time
+		ok   bool
+	}{
+		{"2006-01-02T1504", "db-2026-09-01T0300.sql.gz", time.Date(2026, 9, 1, 3, 0, 0, 0, time.Local), true},
+		{"20060102", "app.log.20260901", time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local), true},
+		{"20060102", "app.log.2026090112", time.Time{}, false}, // Part of a longer number
+		{"20060102", "app.log.20261301", time.Time{}, false},   // No month 13
+		{"2006-01-02", "x-2026-09-01-2026-10-01", time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local), true},
+		{"2006-01-02", "notes.txt", time.Time{}, false},
+		{`(?P<year>\d{4})_(?P<month>\d\d)_(?P<day>\d\d)`, "dump_2025_12_24.tar", time.Date(2025, 12, 24, 0, 0, 0, 0, time.Local), true},
+		{`(?P<day>\d\d)(?P<month>[A-Za-z]{3})(?P<year>\d\d)`, "report-07Mar26.pdf", time.Date(2026, 3, 7, 0, 0, 0, 0, time.Local), true},
+		{`-(?P<epoch>\d{10})\.`, "snap-1700000000.img", time.Unix(1700000000, 0), true},
+		{`(?P<year>\d{4})(?P<month>\d\d)`, "log-202613", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		nt, err := ParseNameTime(tt.spec)
+		if err != nil {
+			t.Errorf("ParseNameTime(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		got, ok := nt.Parse(tt.name)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("%q.Parse(%q) = %v, %v; want %v, %v", tt.spec, tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"Jan 2 2006",               // Variable width
+		"01-02",                    // No year
+		`(?P<year>\d{4}`,           // Bad regular expression
+		`(?P<week>\d\d)`,           // Unknown group
+		`(?P<month>\d\d)(?P<day>)`, // No year
+	}
+	for _, spec := range invalid {
+		if _, err := ParseNameTime(spec); err == nil {
+			t.Errorf("Expected ParseNameTime(%q) to fail", spec)
+		}
+	}
+
+	for _, s := range []string{"keep", "delete", "timestamp"} {
+		f, err := ParseNameFallback(s)
+		if err != nil || f.String() != s {
+			t.Errorf("ParseNameFallback(%q) = %v, %v", s, f, err)
+		}
+	}
+	if _, err := ParseNameFallback("mtime"); err == nil {
+		t.Error("Expected an unknown fallback to be rejected")
+	}
+}
+
+// TestScanner_AgeFromName tests that name timestamps replace the modification
+// time for age limits and keep-newest, and the fallbacks for undated names.
+func TestScanner_AgeFromName(t *testing.T) {
+	tmpDir := t.TempDir()
+	reference := time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+	files := map[string]int{ // Name -> mtime age in days, which the names contradict
+		"db-2026-09-01T0300.sql.gz": 0,
+		"db-2026-09-20T0300.sql.gz": 0,
+		"db-2026-09-30T0300.sql.gz": 90,
+		"notes.txt":                 90,
+		"README":                    0,
+	}
+	for name, days := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		mtime := reference.Add(-time.Duration(days) * 24 * time.Hour)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set file times: %v", err)
+		}
+	}
+	nt, err := ParseNameTime("2006-01-02T1504")
+	if err != nil {
+		t.Fatalf("ParseNameTime failed: %v", err)
+	}
+
+	scan := func(fallback NameFallback, configure func(s *Scanner)) string {
+		t.Helper()
+		s := NewScanner(tmpDir, nil)
+		s.SetReferenceTime(reference)
+		s.SetAgeFromName(nt, fallback)
+		configure(s)
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		return deletedNames(t, tmpDir, result)
+	}
+	olderThan := func(s *Scanner) { s.SetOlderThan(7 * 24 * time.Hour) }
+
+	if got, want := scan(NameFallbackKeep, olderThan), "db-2026-09-01T0300.sql.gz db-2026-09-20T0300.sql.gz"; got != want {
+		t.Errorf("keep fallback: deleted %q, want %q", got, want)
+	}
+	if got, want := scan(NameFallbackDelete, olderThan), "README db-2026-09-01T0300.sql.gz db-2026-09-20T0300.sql.gz notes.txt"; got != want {
+		t.Errorf("delete fallback: deleted %q, want %q", got, want)
+	}
+	if got, want := scan(NameFallbackTimestamp, olderThan), "db-2026-09-01T0300.sql.gz db-2026-09-20T0300.sql.gz notes.txt"; got != want {
+		t.Errorf("timestamp fallback: deleted %q, want %q", got, want)
+	}
+
+	// Ranked by name, the file with the old mtime is the newest
+	keepNewest := func(s *Scanner) { s.SetKeepNewest(1) }
+	if got, want := scan(NameFallbackKeep, keepNewest), "db-2026-09-01T0300.sql.gz db-2026-09-20T0300.sql.gz"; got != want {
+		t.Errorf("keep-newest 1: deleted %q, want %q", got, want)
+	}
+}
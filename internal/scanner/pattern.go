@@ -0,0 +1,247 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Matcher matches paths against an ordered list of gitignore-style patterns.
+//
+// Supported syntax follows gitignore(5):
+//   - Blank lines and lines starting with "#" are ignored ("\#" matches a literal "#")
+//   - A leading "!" negates the pattern, re-including paths an earlier pattern matched
+//   - A trailing "/" matches directories only
+//   - A pattern containing "/" (other than a trailing one) is anchored to the scan
+//     root; otherwise it matches at any depth
+//   - "*", "?" and "[...]" match within a single path component, "**" matches
+//     across components ("**/name", "dir/**", "a/**/b")
+//
+// The last pattern that matches a path decides the result. Matching is
+// case-insensitive on Windows.
+type Matcher struct {
+	patterns []pattern
+}
+
+// pattern is a single compiled pattern line.
+type pattern struct {
+	source  string         // Original pattern text, for error messages
+	negate  bool           // Pattern started with "!"
+	dirOnly bool           // Pattern ended with "/"
+	re      *regexp.Regexp // Compiled match against the slash-separated relative path
+}
+
+// NewMatcher compiles the given pattern lines, in order. Comment and blank lines
+// are skipped. Returns an error naming the first pattern that cannot be compiled.
+func NewMatcher(lines []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, line := range lines {
+		p, ok, err := compilePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m, nil
+}
+
+// ReadPatternFile reads pattern lines from a gitignore-style file.
+func ReadPatternFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read pattern file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read pattern file %s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// Empty reports whether the matcher has no patterns (a nil Matcher is empty).
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.patterns) == 0
+}
+
+// Match reports whether relPath, a slash-separated path relative to the scan
+// root, is matched by the patterns. Only the path itself is tested; the scanner
+// is responsible for applying a directory's result to its contents.
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		p := m.patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(relPath) {
+			return !p.negate
+		}
+	}
+	return false
+}
+
+// compilePattern compiles one pattern line. ok is false for blank and comment lines.
+func compilePattern(line string) (p pattern, ok bool, err error) {
+	p.source = line
+	line = strings.TrimSuffix(line, "\r")
+	line = trimUnescapedTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return p, false, nil
+	}
+
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return p, false, nil
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	body, err := globToRegexp(line)
+	if err != nil {
+		return p, false, fmt.Errorf("invalid pattern %q: %w", p.source, err)
+	}
+
+	expr := "^" + body + "$"
+	if !anchored {
+		expr = "^(?:.*/)?" + body + "$"
+	}
+	if runtime.GOOS == "windows" {
+		expr = "(?i)" + expr
+	}
+
+	p.re, err = regexp.Compile(expr)
+	if err != nil {
+		return p, false, fmt.Errorf("invalid pattern %q: %w", p.source, err)
+	}
+	return p, true, nil
+}
+
+// trimUnescapedTrailingSpaces removes trailing spaces unless they are escaped with a backslash.
+func trimUnescapedTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// globToRegexp converts the glob part of a pattern to a regular expression body.
+func globToRegexp(glob string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(glob); {
+		switch {
+		case i == 0 && strings.HasPrefix(glob, "**/"):
+			// Leading "**/": match in any directory
+			sb.WriteString("(?:.*/)?")
+			i += 3
+		case strings.HasPrefix(glob[i:], "/**/"):
+			// "/**/": zero or more directories
+			sb.WriteString("/(?:.*/)?")
+			i += 4
+		case glob[i:] == "/**":
+			// Trailing "/**": everything inside
+			sb.WriteString("/.*")
+			i += 3
+		case glob[i] == '*':
+			// Any other "*" or "**" stays within one path component
+			sb.WriteString("[^/]*")
+			for i < len(glob) && glob[i] == '*' {
+				i++
+			}
+		case glob[i] == '?':
+			sb.WriteString("[^/]")
+			i++
+		case glob[i] == '[':
+			class, n, ok := convertCharClass(glob[i:])
+			if ok {
+				sb.WriteString(class)
+				i += n
+			} else {
+				sb.WriteString(`\[`)
+				i++
+			}
+		case glob[i] == '\\':
+			if i+1 >= len(glob) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			sb.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i += 2
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// convertCharClass converts a "[...]" bracket expression at the start of s.
+// Returns the regexp class, the number of bytes consumed, and false if the
+// bracket is not closed (in which case "[" is literal).
+func convertCharClass(s string) (string, int, bool) {
+	i := 1
+	var sb strings.Builder
+	sb.WriteString("[")
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		sb.WriteString("^")
+		i++
+	}
+	// A "]" right after the opening bracket is literal
+	if i < len(s) && s[i] == ']' {
+		sb.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ']':
+			sb.WriteString("]")
+			return sb.String(), i + 1, true
+		case '\\':
+			// Escaped character inside the class
+			if i+1 >= len(s) {
+				return "", 0, false
+			}
+			i++
+			writeClassLiteral(&sb, s[i])
+		case '[':
+			sb.WriteString(`\[`)
+		case '/':
+			// A bracket expression never matches a separator
+			return "", 0, false
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", 0, false
+}
+
+// writeClassLiteral writes c as a literal inside a regexp character class.
+func writeClassLiteral(sb *strings.Builder, c byte) {
+	isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+	if !isAlnum {
+		sb.WriteByte('\\')
+	}
+	sb.WriteByte(c)
+}
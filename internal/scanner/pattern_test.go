@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMatcherGitignoreSemantics tests anchoring, "**", negation and
+// directory-only patterns against the gitignore rules.
+func TestMatcherGitignoreSemantics(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"basename at any depth", []string{"*.log"}, "a/b/app.log", false, true},
+		{"basename no match", []string{"*.log"}, "a/b/app.db", false, false},
+		{"star stays in component", []string{"a/*.log"}, "a/b/app.log", false, false},
+		{"leading slash anchors", []string{"/build"}, "build", true, true},
+		{"leading slash anchors only root", []string{"/build"}, "src/build", true, false},
+		{"middle slash anchors", []string{"src/gen"}, "src/gen", true, true},
+		{"middle slash not at depth", []string{"src/gen"}, "x/src/gen", true, false},
+		{"leading double star", []string{"**/cache"}, "a/b/cache", true, true},
+		{"leading double star at root", []string{"**/cache"}, "cache", true, true},
+		{"trailing double star", []string{"logs/**"}, "logs/2024/app.log", false, true},
+		{"trailing double star not dir itself", []string{"logs/**"}, "logs", true, false},
+		{"middle double star zero dirs", []string{"a/**/b"}, "a/b", true, true},
+		{"middle double star many dirs", []string{"a/**/b"}, "a/x/y/b", true, true},
+		{"dir only matches dir", []string{"keep/"}, "x/keep", true, true},
+		{"dir only skips file", []string{"keep/"}, "x/keep", false, false},
+		{"negation re-includes", []string{"*.log", "!important.log"}, "important.log", false, false},
+		{"last match wins", []string{"!important.log", "*.log"}, "important.log", false, true},
+		{"question mark", []string{"file?.txt"}, "file1.txt", false, true},
+		{"character class", []string{"file[0-9].txt"}, "file7.txt", false, true},
+		{"negated character class", []string{"file[!0-9].txt"}, "file7.txt", false, false},
+		{"escaped wildcard", []string{`literal\*.txt`}, "literal*.txt", false, true},
+		{"escaped wildcard literal only", []string{`literal\*.txt`}, "literalX.txt", false, false},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"escaped bang", []string{`\!bang`}, "!bang", false, true},
+		{"comment ignored", []string{"# *.log"}, "app.log", false, false},
+		{"trailing spaces trimmed", []string{"*.tmp   "}, "x.tmp", false, true},
+		{"dot is literal", []string{"*.tmp"}, "xtmp", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.patterns)
+			if err != nil {
+				t.Fatalf("NewMatcher(%q) failed: %v", tt.patterns, err)
+			}
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, dir=%v) with %q = %v, want %v", tt.path, tt.isDir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMatcherEmpty tests that nil and comment-only matchers are empty and match nothing.
+func TestMatcherEmpty(t *testing.T) {
+	var nilMatcher *Matcher
+	if !nilMatcher.Empty() || nilMatcher.Match("a", false) {
+		t.Error("Expected nil matcher to be empty and match nothing")
+	}
+
+	m, err := NewMatcher([]string{"", "# comment", "   "})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	if !m.Empty() {
+		t.Error("Expected matcher with only blank and comment lines to be empty")
+	}
+}
+
+// TestReadPatternFile tests reading patterns from a gitignore-style file.
+func TestReadPatternFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(path, []byte("# keep databases\r\n*.db\r\n\r\nkeep/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+
+	lines, err := ReadPatternFile(path)
+	if err != nil {
+		t.Fatalf("ReadPatternFile failed: %v", err)
+	}
+	m, err := NewMatcher(lines)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	if !m.Match("data/app.db", false) || !m.Match("keep", true) || m.Match("app.log", false) {
+		t.Errorf("Unexpected matches for patterns %q", lines)
+	}
+
+	if _, err := ReadPatternFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing pattern file")
+	}
+}
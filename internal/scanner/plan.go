@@ -0,0 +1,277 @@
+package scanner
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PlanFormat identifies plan files; PlanVersion is the version WritePlan
+// writes and the newest ReadPlan accepts.
+const (
+	PlanFormat  = "fast-file-deletion-plan"
+	PlanVersion = 1
+)
+
+// A plan file records the exact list of entries a scan marked for deletion, so
+// that it can be reviewed and executed later. It is a JSON Lines file:
+//
+//   - A PlanHeader
+//   - One PlanEntry per entry, in deletion (bottom-up) order
+//   - A trailer {"checksum":"sha256:..."} holding the SHA-256 of every byte
+//     before it
+//
+// One entry per line keeps plans of millions of entries streamable and lets
+// reviewers search them with line-oriented tools.
+
+// PlanHeader describes the scan a plan was made from.
+type PlanHeader struct {
+	Format         string            `json:"format"`
+	Version        int               `json:"version"`
+	Created        time.Time         `json:"created"`
+	Root           string            `json:"root"` // Absolute path of the scanned directory
+	RootDev        uint64            `json:"rootDev"`
+	RootIno        uint64            `json:"rootIno"`
+	Settings       map[string]string `json:"settings,omitempty"` // Options the scan was made with
+	Entries        int               `json:"entries"`
+	Bytes          int64             `json:"bytes"`          // Total size of the files
+	AllocatedBytes int64             `json:"allocatedBytes"` // See SpaceUsage
+	Warnings       int               `json:"warnings"`       // Scan warnings; the entries concerned are not in the plan
+}
+
+// PlanEntry is one entry of a plan.
+type PlanEntry struct {
+	Path  string    `json:"path"` // Relative to the root, slash-separated ("." for the root itself)
+	Dir   bool      `json:"dir,omitempty"`
+	Size  int64     `json:"size"`
+	Dev   uint64    `json:"dev"`
+	Ino   uint64    `json:"ino"`
+	MTime time.Time `json:"mtime"`
+}
+
+// planTrailer ends a plan.
+type planTrailer struct {
+	Checksum string `json:"checksum"`
+}
+
+// Plan is a plan read by ReadPlan.
+type Plan struct {
+	Header PlanHeader
+
+	// Result lists the plan's entries with their recorded identities, ready
+	// for the engine. Its Revalidate only checks identities, as the plan,
+	// not the scan options, decides what is deleted.
+	Result *ScanResult
+}
+
+// WritePlan writes result as a plan file to w. The result must have been
+// scanned with SetRecordIdentity. settings records the options the scan was
+// made with, for reviewers.
+func WritePlan(w io.Writer, result *ScanResult, settings map[string]string) error {
+	n := result.Len()
+	if len(result.Identities) != n {
+		return fmt.Errorf("cannot write plan: the scan did not record identities")
+	}
+
+	root := result.ScannedPath
+	rootInfo, err := os.Lstat(root)
+	if err != nil {
+		return fmt.Errorf("cannot write plan: %w", err)
+	}
+	rootID, err := identityOf(root, rootInfo)
+	if err != nil {
+		return fmt.Errorf("cannot write plan: %w", err)
+	}
+
+	hash := sha256.New()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(io.MultiWriter(bw, hash))
+	enc.SetEscapeHTML(false)
+
+	header := PlanHeader{
+		Format:         PlanFormat,
+		Version:        PlanVersion,
+		Created:        time.Now().UTC(),
+		Root:           root,
+		RootDev:        rootID.Dev,
+		RootIno:        rootID.Ino,
+		Settings:       settings,
+		Entries:        n,
+		Bytes:          result.TotalSizeBytes,
+		AllocatedBytes: result.Space.AllocatedBytes,
+		Warnings:       len(result.Warnings),
+	}
+	if err := enc.Encode(header); err != nil {
+		return fmt.Errorf("cannot write plan: %w", err)
+	}
+
+	for i := 0; i < n; i++ {
+		rel, err := planPath(root, result.Path(i))
+		if err != nil {
+			return fmt.Errorf("cannot write plan: %w", err)
+		}
+		id := result.Identities[i]
+		entry := PlanEntry{Path: rel, Dir: result.IsDir(i), Size: id.Size, Dev: id.Dev, Ino: id.Ino, MTime: id.ModTime}
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("cannot write plan: %w", err)
+		}
+	}
+
+	trailer := planTrailer{Checksum: "sha256:" + hex.EncodeToString(hash.Sum(nil))}
+	if err := json.NewEncoder(bw).Encode(trailer); err != nil {
+		return fmt.Errorf("cannot write plan: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot write plan: %w", err)
+	}
+	return nil
+}
+
+// planPath returns path relative to the absolute root, slash-separated.
+func planPath(root, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// ReadPlan reads a plan file written by WritePlan. It fails if the file is not
+// a plan, was written by a newer version, is truncated or does not match its
+// checksum, or lists entries outside its root.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	hash := sha256.New()
+
+	// next returns the next line, adding the previous one to the checksum
+	var pending []byte
+	next := func() ([]byte, error) {
+		if pending != nil {
+			hash.Write(pending)
+			hash.Write([]byte{'\n'})
+		}
+		if !lines.Scan() {
+			if err := lines.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+		pending = lines.Bytes()
+		return pending, nil
+	}
+
+	line, err := next()
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	var header PlanHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Format != PlanFormat {
+		return nil, fmt.Errorf("not a plan file")
+	}
+	if header.Version < 1 || header.Version > PlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d (this version reads up to %d)", header.Version, PlanVersion)
+	}
+	if !filepath.IsAbs(header.Root) || header.Entries < 0 {
+		return nil, fmt.Errorf("invalid plan header")
+	}
+
+	// The header's count is only trusted once the checksum matches
+	capacity := min(header.Entries, 1<<20)
+	result := &ScanResult{
+		ScannedPath:    header.Root,
+		Files:          make([]string, 0, capacity),
+		IsDirectory:    make([]bool, 0, capacity),
+		Identities:     make([]FileIdentity, 0, capacity),
+		TotalToDelete:  header.Entries,
+		TotalSizeBytes: header.Bytes,
+		Space:          SpaceUsage{ApparentBytes: header.Bytes, AllocatedBytes: header.AllocatedBytes},
+	}
+	for i := 0; i < header.Entries; i++ {
+		line, err := next()
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan: entry %d: %w", i+1, err)
+		}
+		var entry PlanEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("invalid plan: entry %d: %w", i+1, err)
+		}
+		path, err := planEntryPath(header.Root, entry.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan: entry %d: %w", i+1, err)
+		}
+		result.Files = append(result.Files, path)
+		result.IsDirectory = append(result.IsDirectory, entry.Dir)
+		result.Identities = append(result.Identities, FileIdentity{Dev: entry.Dev, Ino: entry.Ino, ModTime: entry.MTime, Size: entry.Size})
+	}
+
+	line, err = next()
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan: missing checksum: %w", err)
+	}
+	var trailer planTrailer
+	if err := json.Unmarshal(line, &trailer); err != nil || trailer.Checksum == "" {
+		return nil, fmt.Errorf("invalid plan: expected the checksum after %d entries", header.Entries)
+	}
+	if want := "sha256:" + hex.EncodeToString(hash.Sum(nil)); trailer.Checksum != want {
+		return nil, fmt.Errorf("plan checksum mismatch: the file was modified or damaged")
+	}
+	if lines.Scan() {
+		return nil, fmt.Errorf("invalid plan: unexpected data after the checksum")
+	}
+
+	return &Plan{Header: header, Result: result}, nil
+}
+
+// planEntryPath returns the path of a plan entry below root, refusing entries
+// that would leave it.
+func planEntryPath(root, rel string) (string, error) {
+	if rel == "." {
+		return root, nil
+	}
+	if rel == "" || strings.HasPrefix(rel, "/") || filepath.IsAbs(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("path %q is not relative to the root", rel)
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".." || part == "." || part == "" {
+			return "", fmt.Errorf("path %q leaves the root or is not clean", rel)
+		}
+	}
+	return filepath.Join(root, filepath.FromSlash(rel)), nil
+}
+
+// ErrPlanRootChanged is returned by CheckRoot when the plan's root directory
+// is no longer the directory that was scanned.
+var ErrPlanRootChanged = errors.New("the plan's root directory was replaced since the scan")
+
+// CheckRoot verifies that the plan's root directory still exists and is the
+// directory that was scanned, not a new directory at the same path.
+func (p *Plan) CheckRoot() error {
+	info, err := os.Lstat(p.Header.Root)
+	if err != nil {
+		return fmt.Errorf("cannot check the plan's root directory: %w", err)
+	}
+	if !info.IsDir() {
+		return ErrPlanRootChanged
+	}
+	id, err := identityOf(p.Header.Root, info)
+	if err != nil {
+		return fmt.Errorf("cannot check the plan's root directory: %w", err)
+	}
+	if id.Dev != p.Header.RootDev || id.Ino != p.Header.RootIno {
+		return ErrPlanRootChanged
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPlan scans root with identities and returns the result and its plan.
+func writeTestPlan(t *testing.T, root string) (*ScanResult, []byte) {
+	t.Helper()
+	s := NewScanner(root, nil)
+	s.SetRecordIdentity(true)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, result, map[string]string{"keep-days": "0"}); err != nil {
+		t.Fatalf("WritePlan failed: %v", err)
+	}
+	return result, buf.Bytes()
+}
+
+// TestPlan_RoundTrip tests that a plan reads back as the scanned entries with
+// their identities.
+func TestPlan_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	createWideTree(t, tmpDir, 3, 4)
+
+	result, data := writeTestPlan(t, tmpDir)
+	plan, err := ReadPlan(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadPlan failed: %v", err)
+	}
+
+	if plan.Header.Version != PlanVersion || plan.Header.Root != result.ScannedPath || plan.Header.Settings["keep-days"] != "0" {
+		t.Errorf("Unexpected header: %+v", plan.Header)
+	}
+	got := plan.Result
+	if got.Len() != result.Len() || got.TotalSizeBytes != result.TotalSizeBytes {
+		t.Fatalf("Plan has %d entries of %d bytes, scan %d of %d", got.Len(), got.TotalSizeBytes, result.Len(), result.TotalSizeBytes)
+	}
+	for i := 0; i < result.Len(); i++ {
+		abs, _ := filepath.Abs(result.Path(i))
+		if got.Path(i) != abs || got.IsDir(i) != result.IsDir(i) {
+			t.Errorf("Entry %d: plan %q (dir %v), scan %q (dir %v)", i, got.Path(i), got.IsDir(i), abs, result.IsDir(i))
+		}
+		if !got.Identities[i].ModTime.Equal(result.Identities[i].ModTime) || got.Identities[i].Ino != result.Identities[i].Ino ||
+			got.Identities[i].Size != result.Identities[i].Size {
+			t.Errorf("Entry %d: identity %+v, scan %+v", i, got.Identities[i], result.Identities[i])
+		}
+		if !got.Revalidate(i) {
+			t.Errorf("Entry %d (%s) failed revalidation although unchanged", i, got.Path(i))
+		}
+	}
+	if err := plan.CheckRoot(); err != nil {
+		t.Errorf("CheckRoot failed: %v", err)
+	}
+
+	// A rewritten file no longer matches its identity
+	changed := filepath.Join(tmpDir, "d000", "sub", "f000")
+	if err := os.WriteFile(changed, []byte("123456"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite file: %v", err)
+	}
+	for i := 0; i < got.Len(); i++ {
+		if got.Path(i) == changed && got.Revalidate(i) {
+			t.Errorf("Expected the rewritten file to fail revalidation")
+		}
+	}
+}
+
+// TestPlan_Invalid tests that damaged, tampered or foreign files are refused.
+func TestPlan_Invalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	createWideTree(t, tmpDir, 1, 2)
+	_, data := writeTestPlan(t, tmpDir)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not a plan", "{\"hello\":1}\n", "not a plan"},
+		{"newer version", strings.Replace(string(data), `"version":1`, `"version":99`, 1), "unsupported plan version"},
+		{"truncated", strings.Join(lines[:len(lines)-3], ""), "invalid plan"},
+		{"tampered", strings.Replace(string(data), "f001", "f002", 1), "checksum mismatch"},
+		{"trailing data", string(data) + "{}\n", "after the checksum"},
+	}
+	for _, tt := range tests {
+		if _, err := ReadPlan(strings.NewReader(tt.data)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	for _, rel := range []string{"../outside", "/etc/passwd", "a/../../b", ""} {
+		if _, err := planEntryPath(tmpDir, rel); err == nil {
+			t.Errorf("Expected plan path %q to be refused", rel)
+		}
+	}
+}
+
+// TestPlan_RootReplaced tests that a plan is refused once its root directory
+// was replaced by another one at the same path.
+func TestPlan_RootReplaced(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	_, data := writeTestPlan(t, root)
+	plan, err := ReadPlan(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadPlan failed: %v", err)
+	}
+
+	// Keep the old directory so its inode cannot be reused
+	if err := os.Rename(root, root+".old"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := plan.CheckRoot(); !errors.Is(err, ErrPlanRootChanged) {
+		t.Errorf("Expected ErrPlanRootChanged, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultKeepMarker is the conventional name of the sentinel file that
+	// protects the directory holding it, and everything beneath it, from deletion.
+	DefaultKeepMarker = ".ffdkeep"
+
+	// DefaultPolicyFile is the conventional name of the per-directory file that
+	// overrides the age retention settings for the directory's subtree.
+	DefaultPolicyFile = ".ffdpolicy"
+)
+
+// Policy holds the age retention settings in effect for a directory tree.
+// The scan options supply the policy for the root; a policy file in a directory
+// overrides it for that directory's subtree.
+type Policy struct {
+	KeepDays  int           // Only delete entries older than this many days (0 = no limit)
+	OlderThan time.Duration // Only delete entries older than this (0 = no limit)
+	NewerThan time.Duration // Only delete entries newer than this (0 = no limit)
+	AgeBy     TimeField     // Timestamp the ages are measured on
+}
+
+// HasAgeFilter reports whether the policy limits deletion by age.
+func (p Policy) HasAgeFilter() bool {
+	return p.KeepDays > 0 || p.OlderThan > 0 || p.NewerThan > 0
+}
+
+// String describes the policy in policy file syntax, e.g. "keep-days=3, age-by=atime".
+func (p Policy) String() string {
+	var parts []string
+	if p.KeepDays > 0 {
+		parts = append(parts, fmt.Sprintf("keep-days=%d", p.KeepDays))
+	}
+	if p.OlderThan > 0 {
+		parts = append(parts, "older-than="+formatAge(p.OlderThan))
+	}
+	if p.NewerThan > 0 {
+		parts = append(parts, "newer-than="+formatAge(p.NewerThan))
+	}
+	if len(parts) == 0 {
+		return "no age limit"
+	}
+	if p.AgeBy != TimeMTime {
+		parts = append(parts, "age-by="+p.AgeBy.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatAge formats whole days and weeks the way ParseAge accepts them.
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d%(7*day) == 0:
+		return fmt.Sprintf("%dw", d/(7*day))
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	default:
+		return d.String()
+	}
+}
+
+// DirPolicy is a policy loaded from the policy file of a directory.
+type DirPolicy struct {
+	Dir    string // Directory holding the policy file
+	Policy Policy // Effective policy for the directory's subtree
+}
+
+// ParsePolicy reads a policy file. Blank lines and lines starting with "#" are
+// ignored; every other line is "key = value". Keys that are present override
+// the corresponding settings of base, normally the policy of the parent
+// directory; keys that are absent are inherited from it:
+//
+//	keep-days = 30    # only delete entries older than 30 days (0 = no limit)
+//	older-than = 36h  # only delete entries older than an age (0 = no limit)
+//	newer-than = 2w   # only delete entries newer than an age (0 = no limit)
+//	age-by = atime    # mtime, atime, ctime or btime
+//
+// Returns an error naming the first line that cannot be parsed.
+func ParsePolicy(r io.Reader, base Policy) (Policy, error) {
+	p := base
+	sc := bufio.NewScanner(r)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return base, fmt.Errorf("line %d: expected key = value, got %q", lineNo, line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "keep-days":
+			p.KeepDays, err = strconv.Atoi(value)
+			if err == nil && p.KeepDays < 0 {
+				err = fmt.Errorf("must be 0 or greater")
+			}
+		case "older-than":
+			p.OlderThan, err = parsePolicyAge(value)
+		case "newer-than":
+			p.NewerThan, err = parsePolicyAge(value)
+		case "age-by":
+			p.AgeBy, err = ParseTimeField(value)
+		default:
+			err = fmt.Errorf("unknown key (expected keep-days, older-than, newer-than or age-by)")
+		}
+		if err != nil {
+			return base, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return base, err
+	}
+
+	if p.OlderThan > 0 && p.NewerThan > 0 && p.NewerThan <= p.OlderThan {
+		return base, fmt.Errorf("newer-than (%s) must be greater than older-than (%s)",
+			formatAge(p.NewerThan), formatAge(p.OlderThan))
+	}
+	return p, nil
+}
+
+// parsePolicyAge parses an age for a policy file, where a bare "0" clears the limit.
+func parsePolicyAge(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	return ParseAge(value)
+}
+
+// ReadPolicyFile reads the policy file at path, with base as in ParsePolicy.
+// The returned error wraps fs.ErrNotExist when the file does not exist.
+func ReadPolicyFile(path string, base Policy) (Policy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return base, err
+	}
+	defer file.Close()
+
+	p, err := ParsePolicy(file, base)
+	if err != nil {
+		return base, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return p, nil
+}
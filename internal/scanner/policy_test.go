@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParsePolicy tests that policy keys override the base and absent keys are inherited.
+func TestParsePolicy(t *testing.T) {
+	base := Policy{KeepDays: 30, AgeBy: TimeATime}
+
+	p, err := ParsePolicy(strings.NewReader(`
+# build outputs are cheap to recreate
+keep-days = 3
+older-than = 36h   # also require 36 hours
+`), base)
+	if err != nil {
+		t.Fatalf("ParsePolicy failed: %v", err)
+	}
+	want := Policy{KeepDays: 3, OlderThan: 36 * time.Hour, AgeBy: TimeATime}
+	if p != want {
+		t.Errorf("ParsePolicy = %+v, want %+v", p, want)
+	}
+	if got := p.String(); got != "keep-days=3, older-than=36h0m0s, age-by=atime" {
+		t.Errorf("String() = %q", got)
+	}
+
+	p, err = ParsePolicy(strings.NewReader("keep-days = 0\nNewer-Than = 2w\nage-by = mtime\n"), base)
+	if err != nil {
+		t.Fatalf("ParsePolicy failed: %v", err)
+	}
+	if p.KeepDays != 0 || p.NewerThan != 14*24*time.Hour || p.AgeBy != TimeMTime {
+		t.Errorf("Expected keep-days cleared and newer-than 2w, got %+v", p)
+	}
+	if got := p.String(); got != "newer-than=2w" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, bad := range []string{
+		"keep-days",
+		"keep-days = -1",
+		"keep-days = soon",
+		"older-than = 7",
+		"age-by = yesterday",
+		"delete = everything",
+		"older-than = 2d\nnewer-than = 1d",
+	} {
+		if _, err := ParsePolicy(strings.NewReader(bad), base); err == nil {
+			t.Errorf("Expected ParsePolicy(%q) to fail", bad)
+		}
+	}
+}
+
+// TestScanner_KeepMarkerAndPolicy tests that keep-markers protect whole subtrees,
+// that policy files override the age settings for their subtree, and that
+// unreadable policies protect the directory instead of widening deletion.
+func TestScanner_KeepMarkerAndPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	reference := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// name -> age before the reference time
+	files := map[string]time.Duration{
+		"top.txt":                 10 * 24 * time.Hour,
+		"kept/.ffdkeep":           0,
+		"kept/old.txt":            90 * 24 * time.Hour,
+		"kept/sub/old.txt":        90 * 24 * time.Hour,
+		"short/.ffdpolicy":        0,
+		"short/four-days.txt":     4 * 24 * time.Hour,
+		"short/two-days.txt":      2 * 24 * time.Hour,
+		"short/long/.ffdpolicy":   0,
+		"short/long/ten-days.txt": 10 * 24 * time.Hour,
+		"broken/.ffdpolicy":       0,
+		"broken/old.txt":          90 * 24 * time.Hour,
+	}
+	contents := map[string]string{
+		"short/.ffdpolicy":      "keep-days = 3\n",
+		"short/long/.ffdpolicy": "keep-days = 30\n",
+		"broken/.ffdpolicy":     "delete = everything\n",
+	}
+	for name, age := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(contents[name]), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		mtime := reference.Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set file times: %v", err)
+		}
+	}
+
+	keepDays := 7
+	s := NewScanner(tmpDir, &keepDays)
+	s.SetReferenceTime(reference)
+	s.SetKeepMarker(DefaultKeepMarker)
+	s.SetPolicyFile(DefaultPolicyFile)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	deleted := make(map[string]bool)
+	for _, path := range result.Files {
+		rel, _ := filepath.Rel(tmpDir, path)
+		deleted[filepath.ToSlash(rel)] = true
+	}
+	want := map[string]bool{"top.txt": true, "short/four-days.txt": true}
+	if len(deleted) != len(want) {
+		t.Errorf("Expected %v to be deleted, got %v", want, result.Files)
+	}
+	for name := range want {
+		if !deleted[name] {
+			t.Errorf("Expected %s to be deleted, got %v", name, result.Files)
+		}
+	}
+
+	protected := strings.Join(result.Protected, "\n")
+	for _, dir := range []string{"kept", "broken"} {
+		if !strings.Contains(protected, filepath.Join(tmpDir, dir)) {
+			t.Errorf("Expected %s to be listed as protected, got %v", dir, result.Protected)
+		}
+	}
+	if len(result.Protected) != 2 {
+		t.Errorf("Expected 2 protected directories, got %v", result.Protected)
+	}
+	if len(result.Policies) != 2 || result.Policies[0].Policy.KeepDays != 3 || result.Policies[1].Policy.KeepDays != 30 {
+		t.Errorf("Expected policies keep-days=3 then keep-days=30, got %+v", result.Policies)
+	}
+
+	// A keep-marker in the target itself protects everything, including the target
+	if err := os.WriteFile(filepath.Join(tmpDir, DefaultKeepMarker), nil, 0644); err != nil {
+		t.Fatalf("Failed to create marker: %v", err)
+	}
+	result, err = s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.TotalToDelete != 0 || len(result.Files) != 0 || len(result.Protected) != 1 {
+		t.Errorf("Expected a protected target to delete nothing, got %v (protected %v)", result.Files, result.Protected)
+	}
+}
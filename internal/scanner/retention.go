@@ -0,0 +1,166 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+)
+
+// SetKeepNewest retains the n newest files of every directory (0 disables the
+// limit). Files are ranked by the timestamp selected with SetAgeBy; the ranking
+// covers every file that passes the patterns and filter, so files already kept
+// by an age limit count towards n. See SetKeepNewestGroups to rank files by
+// name within a directory.
+func (o *scanOptions) SetKeepNewest(n int) {
+	o.keepNewest = n
+}
+
+// SetKeepNewestGroups splits each directory into groups for SetKeepNewest: files
+// whose name matches the same glob (e.g. "app-*.log") are ranked together, and
+// files matching none of the globs form one more group. The first matching glob
+// decides a file's group. Returns an error naming the first invalid glob.
+func (o *scanOptions) SetKeepNewestGroups(globs []string) error {
+	groups := make([]*regexp.Regexp, 0, len(globs))
+	for _, glob := range globs {
+		re, err := compileGlob(glob)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, re)
+	}
+	o.keepNewestGroups = groups
+	return nil
+}
+
+// SetMaxTotalSize trims the tree down to limit bytes (0 disables the limit):
+// the oldest deletable files, by the timestamp selected with SetAgeBy, are
+// deleted until the files the scan found add up to no more than limit.
+// With SetAgeBy(TimeATime) this evicts the least recently used files first.
+func (o *scanOptions) SetMaxTotalSize(limit int64) {
+	o.maxTotalSize = limit
+}
+
+// hasRetentionLimits reports whether a count or size limit is set. Both can only
+// be applied once the whole tree has been inventoried.
+func (o *scanOptions) hasRetentionLimits() bool {
+	return o.keepNewest > 0 || o.maxTotalSize > 0
+}
+
+// inventoryFile is a file that passed the patterns and filter, held back until
+// the walk is complete so that count and size limits can rank it against the
+// rest of the tree.
+type inventoryFile struct {
+	path      string
+	dir       int32 // Row of the directory holding it in the inventory's directory table
+	size      int64
+	modified  time.Time    // Timestamp selected by SetAgeBy or SetAgeFromName
+	modTime   time.Time    // Modification time, for SetDedupe
+	identity  FileIdentity // Only with SetRecordIdentity
+	deletable bool         // Passed the age limits
+	space     fileSpace    // Allocation data, if spaceOK
+	spaceOK   bool
+	owner     int // Owning uid, -1 if unknown
+}
+
+// newInventoryFile records a file found during the walk. deletable is the
+// outcome of the age limits for the file. With SetAgeFromName, files are
+// ranked by the timestamp in their name; a file whose name holds none is
+// ranked oldest or by its SetAgeBy timestamp, as its fallback says (files the
+// fallback keeps are not inventoried).
+func (s *Scanner) newInventoryFile(path string, dir int32, d fs.DirEntry, id FileIdentity, deletable bool) (inventoryFile, error) {
+	info, err := d.Info()
+	if err != nil {
+		return inventoryFile{}, err
+	}
+	ts, source := s.nameAgeOf(d)
+	if source == ageFromStat {
+		ts, err = timeOfField(path, info, s.ageBy)
+		if err != nil {
+			return inventoryFile{}, err
+		}
+	}
+	sp, ok := spaceOfInfo(info)
+	return inventoryFile{path: path, dir: dir, size: info.Size(), modified: ts, modTime: info.ModTime(), identity: id, deletable: deletable, space: sp, spaceOK: ok, owner: ownerOf(path, info)}, nil
+}
+
+// applyRetentionLimits decides which deletable files of the inventory the count
+// and size limits keep, returning true for every file that must be retained.
+// treeBytes is the size of all files the scan found, deletable or not.
+func (s *Scanner) applyRetentionLimits(files []inventoryFile, treeBytes int64) []bool {
+	keep := make([]bool, len(files))
+	for i, f := range files {
+		keep[i] = !f.deletable
+	}
+
+	// Newest first, by path for files with equal timestamps
+	newer := func(a, b inventoryFile) bool {
+		if !a.modified.Equal(b.modified) {
+			return a.modified.After(b.modified)
+		}
+		return a.path < b.path
+	}
+
+	if s.keepNewest > 0 {
+		groups := make(map[string][]int)
+		for i, f := range files {
+			key := s.keepNewestGroup(f.path)
+			groups[key] = append(groups[key], i)
+		}
+		kept := 0
+		for _, members := range groups {
+			sort.Slice(members, func(a, b int) bool { return newer(files[members[a]], files[members[b]]) })
+			for _, i := range members[:min(len(members), s.keepNewest)] {
+				if !keep[i] {
+					kept++
+				}
+				keep[i] = true
+			}
+		}
+		logger.Info("Keeping the %d newest files in each of %d groups (%d files that could otherwise be deleted)",
+			s.keepNewest, len(groups), kept)
+	}
+
+	if s.maxTotalSize > 0 {
+		oldest := make([]int, 0, len(files))
+		for i := range files {
+			if !keep[i] {
+				oldest = append(oldest, i)
+			}
+		}
+		sort.Slice(oldest, func(a, b int) bool { return newer(files[oldest[b]], files[oldest[a]]) })
+
+		remaining := treeBytes
+		trimmed := 0
+		for _, i := range oldest {
+			if remaining <= s.maxTotalSize {
+				keep[i] = true
+				continue
+			}
+			remaining -= files[i].size
+			trimmed++
+		}
+		logger.Info("Size limit %d bytes: tree holds %d bytes, deleting the %d oldest files leaves %d bytes",
+			s.maxTotalSize, treeBytes, trimmed, remaining)
+		if remaining > s.maxTotalSize {
+			logger.Warning("Size limit cannot be met: %d bytes remain in files that must be retained", remaining)
+		}
+	}
+
+	return keep
+}
+
+// keepNewestGroup returns the key of the SetKeepNewest group that path belongs to.
+func (s *Scanner) keepNewestGroup(path string) string {
+	dir, name := filepath.Split(path)
+	for i, re := range s.keepNewestGroups {
+		if re.MatchString(name) {
+			return fmt.Sprintf("%s\x00%d", dir, i)
+		}
+	}
+	return dir
+}
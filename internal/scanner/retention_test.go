@@ -0,0 +1,155 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createAgedFiles creates files of the given size whose mtime is the given
+// number of days before reference and whose atime is the reverse order, so
+// that the two timestamps rank the files oppositely.
+func createAgedFiles(t *testing.T, dir string, reference time.Time, size int, ages map[string]int) {
+	t.Helper()
+	for name, days := range ages {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		mtime := reference.Add(-time.Duration(days) * 24 * time.Hour)
+		atime := reference.Add(-time.Duration(100-days) * 24 * time.Hour)
+		if err := os.Chtimes(path, atime, mtime); err != nil {
+			t.Fatalf("Failed to set file times: %v", err)
+		}
+	}
+}
+
+// deletedNames returns the sorted, slash-separated paths of the files a scan
+// marked for deletion, relative to root.
+func deletedNames(t *testing.T, root string, result *ScanResult) string {
+	t.Helper()
+	var names []string
+	for i, path := range result.Files {
+		if result.IsDirectory[i] {
+			continue
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			t.Fatalf("Unexpected path %s: %v", path, err)
+		}
+		names = append(names, filepath.ToSlash(rel))
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// TestScanner_KeepNewest tests per-directory and per-group keep-newest limits
+// and how they combine with an age limit.
+func TestScanner_KeepNewest(t *testing.T) {
+	tmpDir := t.TempDir()
+	reference := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	createAgedFiles(t, tmpDir, reference, 10, map[string]int{
+		"a/app-1.log": 1, "a/app-2.log": 2, "a/app-3.log": 3, "a/app-4.log": 4, "a/app-5.log": 5,
+		"a/db-6.log": 6, "a/db-7.log": 7, "a/db-8.log": 8,
+		"b/only.log": 9,
+	})
+
+	scan := func(keepDays *int, configure func(s *Scanner)) *ScanResult {
+		t.Helper()
+		s := NewScanner(tmpDir, keepDays)
+		s.SetReferenceTime(reference)
+		configure(s)
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		return result
+	}
+
+	result := scan(nil, func(s *Scanner) { s.SetKeepNewest(2) })
+	if got, want := deletedNames(t, tmpDir, result), "a/app-3.log a/app-4.log a/app-5.log a/db-6.log a/db-7.log a/db-8.log"; got != want {
+		t.Errorf("--keep-newest 2: deleted %q, want %q", got, want)
+	}
+	for _, path := range result.Files {
+		if path == tmpDir || filepath.Base(path) == "a" || filepath.Base(path) == "b" {
+			t.Errorf("Directory holding kept files marked for deletion: %s", path)
+		}
+	}
+
+	result = scan(nil, func(s *Scanner) {
+		s.SetKeepNewest(2)
+		if err := s.SetKeepNewestGroups([]string{"app-*", "db-*"}); err != nil {
+			t.Fatalf("SetKeepNewestGroups failed: %v", err)
+		}
+	})
+	if got, want := deletedNames(t, tmpDir, result), "a/app-3.log a/app-4.log a/app-5.log a/db-8.log"; got != want {
+		t.Errorf("--keep-newest 2 with groups: deleted %q, want %q", got, want)
+	}
+
+	// Files retained by the age limit count towards the newest files
+	keepDays := 3
+	result = scan(&keepDays, func(s *Scanner) { s.SetKeepNewest(2) })
+	if got, want := deletedNames(t, tmpDir, result), "a/app-4.log a/app-5.log a/db-6.log a/db-7.log a/db-8.log"; got != want {
+		t.Errorf("--keep-days 3 --keep-newest 2: deleted %q, want %q", got, want)
+	}
+	if result.TotalToDelete != 5 || result.TotalRetained != 6 {
+		t.Errorf("Expected 5 to delete and 6 retained (4 files, 2 directories), got %d and %d",
+			result.TotalToDelete, result.TotalRetained)
+	}
+
+	if err := NewScanner(tmpDir, nil).SetKeepNewestGroups([]string{`bad\`}); err == nil {
+		t.Error("Expected an invalid group glob to be rejected")
+	}
+}
+
+// TestScanner_MaxTotalSize tests that the size limit deletes the oldest files
+// first, by mtime or atime, until the tree fits.
+func TestScanner_MaxTotalSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	reference := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	createAgedFiles(t, tmpDir, reference, 100, map[string]int{
+		"1.bin": 1, "2.bin": 2, "sub/3.bin": 3, "sub/4.bin": 4, "5.bin": 5,
+	})
+
+	s := NewScanner(tmpDir, nil)
+	s.SetMaxTotalSize(250)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got, want := deletedNames(t, tmpDir, result), "5.bin sub/3.bin sub/4.bin"; got != want {
+		t.Errorf("--max-total-size 250: deleted %q, want %q", got, want)
+	}
+	if result.TotalSizeBytes != 300 {
+		t.Errorf("Expected 300 bytes to delete, got %d", result.TotalSizeBytes)
+	}
+	if !result.IsDirectory[len(result.Files)-1] || filepath.Base(result.Files[len(result.Files)-1]) != "sub" {
+		t.Errorf("Expected the emptied sub directory to be deleted last, got %v", result.Files)
+	}
+
+	// By access time the order is reversed: the least recently used go first
+	s.SetAgeBy(TimeATime)
+	result, err = s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got, want := deletedNames(t, tmpDir, result), "1.bin 2.bin sub/3.bin"; got != want {
+		t.Errorf("--max-total-size 250 --age-by atime: deleted %q, want %q", got, want)
+	}
+
+	// A tree that already fits is left alone
+	s.SetMaxTotalSize(1000)
+	result, err = s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.TotalToDelete != 0 {
+		t.Errorf("Expected nothing to delete under the limit, got %v", result.Files)
+	}
+}
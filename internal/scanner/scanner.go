@@ -3,12 +3,17 @@
 package scanner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/yourusername/fast-file-deletion/internal/dedupe"
 	"github.com/yourusername/fast-file-deletion/internal/logger"
 )
 
@@ -18,21 +23,223 @@ import (
 type Scanner struct {
 	rootPath string
 	keepDays *int
+	workers  int // Workers for the work of a scan that runs in parallel (0 = one per CPU)
+	scanOptions
+
+	// policies maps directories to the policy loaded from their policy file
+	// during Scan; it is read-only afterwards so Revalidate can use it.
+	policies map[string]Policy
+}
+
+// scanOptions holds optional scan settings shared by Scanner and ParallelScanner.
+// It is embedded in both types so that its setters are available on either.
+type scanOptions struct {
+	recordIdentity bool        // Record inode, device and mtime for each entry
+	include        *Matcher    // Only entries matching these patterns are deleted (nil = all)
+	exclude        *Matcher    // Entries matching these patterns are never deleted or traversed
+	filter         Filter      // Only entries matching this filter are deleted (nil = all)
+	keepMarker     string      // Name of the sentinel file that protects a subtree ("" = disabled)
+	policyFile     string      // Name of the per-directory policy file ("" = disabled)
+	keepDirs       KeepDirs    // Directories kept whatever their contents
+	selection      Select      // Kinds of entries the scan is restricted to (0 = all)
+	subtrees       *subtreeSet // Subtrees the scan is restricted to (nil = the whole tree)
+
+	keepNewest       int              // Retain this many newest files per directory or group (0 = no limit)
+	keepNewestGroups []*regexp.Regexp // Name globs splitting directories into keepNewest groups
+	maxTotalSize     int64            // Trim the tree to this many bytes, oldest first (0 = no limit)
+
+	dedupe *dedupe.Options // Delete only the copies of duplicate files (nil = disabled)
+
+	ageBy     TimeField     // Timestamp used for keepDays, olderThan and newerThan
+	olderThan time.Duration // Only delete entries older than this (0 = no limit)
+	newerThan time.Duration // Only delete entries newer than this (0 = no limit)
+	reference time.Time     // Instant ages are measured from (zero = time of evaluation)
+
+	nameTime     *NameTime    // Measure file ages by the timestamp in their name (nil = disabled)
+	nameFallback NameFallback // What happens to files whose name holds no timestamp
+
+	progress         ProgressFunc  // Receives scan progress (nil = disabled)
+	progressInterval time.Duration // How often progress is reported
+
+	compact    bool // Leave Files and IsDirectory empty, keeping only the Inventory
+	summaryTop int  // Groups per list of the ScanResult.Summary (0 = no summary)
+}
+
+// SetCompact leaves ScanResult.Files and ScanResult.IsDirectory nil so that
+// the entries to delete are only held by the ScanResult.Inventory, which
+// rebuilds each path when it is needed. Use the ScanResult's Len, Path and
+// IsDir methods (or engine.DeleteEntries) to read a compact result. On deep
+// trees this takes a fraction of the memory of the flat lists.
+func (o *scanOptions) SetCompact(enabled bool) {
+	o.compact = enabled
+}
+
+// KeepDirs selects which directories a scan keeps even when everything
+// beneath them is deleted.
+type KeepDirs int
+
+const (
+	// KeepNoDirs deletes every directory the cleanup empties, and the root
+	// directory itself when everything beneath it is deleted.
+	KeepNoDirs KeepDirs = iota
+	// KeepRoot empties the root directory but keeps it, with its
+	// permissions, owner and any open handles to it.
+	KeepRoot
+	// KeepAllDirs deletes only files (and other non-directories), keeping
+	// the whole directory structure with its permissions, owners and ACLs.
+	KeepAllDirs
+)
+
+// SetKeepDirs selects which directories are kept. Kept directories below the
+// root count as retained.
+func (o *scanOptions) SetKeepDirs(k KeepDirs) {
+	o.keepDirs = k
+}
+
+// SetRecordIdentity enables recording of a FileIdentity for every entry marked
+// for deletion. The identities allow ScanResult.Revalidate to detect entries
+// that were replaced or rewritten between the scan and the deletion.
+func (o *scanOptions) SetRecordIdentity(enabled bool) {
+	o.recordIdentity = enabled
+}
+
+// SetInclude restricts deletion to entries matched by m, or contained in a
+// directory matched by m. Other entries are retained but directories are still
+// traversed. A nil or empty matcher includes everything.
+func (o *scanOptions) SetInclude(m *Matcher) {
+	o.include = m
+}
+
+// SetExclude prevents entries matched by m from being deleted. Excluded
+// directories are pruned: they are counted as retained and not traversed.
+func (o *scanOptions) SetExclude(m *Matcher) {
+	o.exclude = m
+}
+
+// SetFilter restricts deletion to entries matched by f (see ParseWhere).
+// Entries f rejects are retained; rejected directories are still traversed.
+func (o *scanOptions) SetFilter(f Filter) {
+	o.filter = f
+}
+
+// SetKeepMarker enables keep-markers: a directory holding a file with this name
+// (see DefaultKeepMarker) is retained along with its whole subtree, which is not
+// traversed. The empty name (default) disables keep-markers.
+func (o *scanOptions) SetKeepMarker(name string) {
+	o.keepMarker = name
+}
+
+// SetPolicyFile enables per-directory policy files (see DefaultPolicyFile and
+// ParsePolicy) that override the age settings for the subtree of the directory
+// holding them. Policy files themselves are always retained. The empty name
+// (default) disables policy files.
+func (o *scanOptions) SetPolicyFile(name string) {
+	o.policyFile = name
+}
+
+// SetAgeBy selects the timestamp that age filters (keepDays, SetOlderThan,
+// SetNewerThan) compare: modification (default), access, status change or birth time.
+func (o *scanOptions) SetAgeBy(field TimeField) {
+	o.ageBy = field
+}
+
+// SetOlderThan restricts deletion to entries older than age (0 disables the limit).
+func (o *scanOptions) SetOlderThan(age time.Duration) {
+	o.olderThan = age
+}
+
+// SetNewerThan restricts deletion to entries newer than age (0 disables the limit).
+func (o *scanOptions) SetNewerThan(age time.Duration) {
+	o.newerThan = age
+}
+
+// SetReferenceTime fixes the instant that ages are measured from, so scheduled
+// and test runs are reproducible. The zero time (default) means "now".
+func (o *scanOptions) SetReferenceTime(t time.Time) {
+	o.reference = t
+}
+
+// now returns the instant ages are measured from.
+func (o *scanOptions) now() time.Time {
+	if o.reference.IsZero() {
+		return time.Now()
+	}
+	return o.reference
+}
+
+// hasFilters reports whether include/exclude patterns or a filter are set.
+func (o *scanOptions) hasFilters() bool {
+	return !o.include.Empty() || !o.exclude.Empty() || o.filter != nil || o.subtrees != nil
+}
+
+// isControlFile reports whether name is the keep-marker or policy file name.
+func (o *scanOptions) isControlFile(name string) bool {
+	return name != "" && (name == o.keepMarker || name == o.policyFile)
+}
+
+// sequentialScanOptions returns the options set that only the sequential
+// Scanner implements, so ParallelScanner must delegate to it when any is set.
+func (o *scanOptions) sequentialScanOptions() []string {
+	var names []string
+	add := func(set bool, name string) {
+		if set {
+			names = append(names, name)
+		}
+	}
+	add(o.recordIdentity, "identity recording")
+	add(o.keepDirs != KeepNoDirs, "kept directories")
+	add(o.selection != 0, "entry selection")
+	add(o.filter != nil, "filter")
+	add(o.subtrees != nil, "subtrees")
+	add(o.hasRetentionLimits(), "retention limits")
+	add(o.dedupe != nil, "dedupe")
+	add(o.nameTime != nil, "name timestamps")
+	add(o.progress != nil, "progress")
+	add(o.ageBy != TimeMTime || o.olderThan > 0 || o.newerThan > 0 || !o.reference.IsZero(), "age options")
+	return names
+}
+
+// FileIdentity records what an entry looked like when it was scanned so that it
+// can be recognised again immediately before deletion.
+type FileIdentity struct {
+	Dev     uint64    // Device (volume serial number on Windows)
+	Ino     uint64    // Inode (file index on Windows)
+	ModTime time.Time // Modification time at scan
+	Size    int64     // Size at scan (files only)
 }
 
 // ScanResult contains the results of a directory scan.
 // It includes statistics about files scanned, files to delete, files retained,
 // and the total size of files to be deleted.
 type ScanResult struct {
-	ScannedPath    string        // Absolute path that was scanned (for TOCTOU protection)
-	Files          []string      // List of files to delete (bottom-up order)
-	FilesUTF16     []*uint16     // Pre-converted UTF-16 paths (Windows only)
-	IsDirectory    []bool        // Flags indicating if each path is a directory
-	TotalScanned   int           // Total number of files and directories scanned
-	TotalToDelete  int           // Number of files and directories marked for deletion
-	TotalRetained  int           // Number of files retained due to age filtering
-	TotalSizeBytes int64         // Total size of files to delete (in bytes)
-	ScanDuration   time.Duration // Time taken to complete the scan
+	ScannedPath       string         // Absolute path that was scanned (for TOCTOU protection)
+	Files             []string       // List of files to delete (bottom-up order; nil with SetCompact)
+	FilesUTF16        []*uint16      // Pre-converted UTF-16 paths (Windows only)
+	IsDirectory       []bool         // Flags indicating if each path is a directory (nil with SetCompact)
+	Identities        []FileIdentity // Identity of each path at scan time (only with SetRecordIdentity)
+	TotalScanned      int            // Total number of files and directories scanned
+	TotalToDelete     int            // Number of files and directories marked for deletion
+	TotalRetained     int            // Number of files and directories retained by age filtering or filters
+	TotalRetainedDirs int            // Number of retained entries that are directories (included in TotalRetained)
+	TotalSizeBytes    int64          // Total size of files to delete (in bytes), counting every path
+	Space             SpaceUsage     // Space the deletion releases, accounting for hardlinks and allocation
+	Summary           *Summary       // Breakdown of the files to delete (only with SetSummary)
+	ScanDuration      time.Duration  // Time taken to complete the scan
+	Protected         []string       // Directories retained with their subtree because they hold the keep-marker
+	Policies          []DirPolicy    // Directories whose policy file overrides the age settings
+	Inventory         *Inventory     // Entries to delete in compact form; Files and IsDirectory are its expansion
+	Warnings          []ScanWarning  // Problems that did not stop the scan; the entries concerned are retained
+	SkippedSubtrees   int            // Directories whose contents could not be (fully) scanned (see Warnings)
+	Duplicates        []dedupe.Group // Groups of identical files whose copies are deleted (only with SetDedupe)
+
+	// scanner is the scanner that produced this result; it supplies the age
+	// filter when entries are revalidated before deletion.
+	scanner *Scanner
+
+	// survivors maps each duplicate copy to delete to the copy of its group
+	// that is kept, as it was found, so that Revalidate can check that the
+	// content is still there before the copy goes (only with SetDedupe).
+	survivors map[string]dedupe.File
 }
 
 // NewScanner creates a new Scanner instance.
@@ -50,26 +257,66 @@ func NewScanner(rootPath string, keepDays *int) *Scanner {
 	}
 }
 
+// SetWorkers sets how many workers a scan uses for the work it runs in
+// parallel: hashing the files compared by SetDedupe. The walk itself is
+// sequential. Zero (the default) uses one worker per CPU.
+func (s *Scanner) SetWorkers(n int) {
+	s.workers = n
+}
+
 // Scan traverses the directory tree and builds a list of files to delete.
 // Files are ordered bottom-up (files before their parent directories) for safe deletion.
 // This ordering ensures that directories are empty when we attempt to delete them.
 //
 // The scan process:
 //  1. Walks the directory tree using filepath.WalkDir for efficiency
-//  2. Applies age filtering if keepDays is set
-//  3. Separates files and directories
-//  4. Orders directories deepest-first for bottom-up deletion
-//  5. Calculates total size for progress reporting
-//  6. Tracks directory flags for skip-double-call optimization
+//  2. Retains subtrees holding the keep-marker and loads policy files
+//  3. Applies include/exclude patterns, pruning excluded directories
+//  4. Applies the filter (if set) and age filtering (if keepDays is set)
+//     and, once the tree is inventoried, the keep-newest and total size limits
+//     and duplicate detection
+//  5. Separates files and directories
+//  6. Orders directories deepest-first for bottom-up deletion
+//  7. Calculates total size for progress reporting
+//  8. Tracks directory flags for skip-double-call optimization
+//
+// A directory is only deleted if every descendant is deleted, so directories emptied
+// by the cleanup are removed while directories still holding retained entries are
+// kept and counted in TotalRetainedDirs rather than failing at deletion time. Under
+// age filtering a directory's age is that of its newest descendant, so the age
+// filter itself is only consulted for directories that were already empty. When
+// any age filter, pattern or filter is set the root directory is never deleted.
+// SetKeepDirs keeps the root directory, or every directory, regardless.
 //
 // Returns ScanResult with file list and statistics, or an error if scanning fails.
 //
 // Validates Requirements: 4.5
 func (s *Scanner) Scan() (*ScanResult, error) {
+	return s.ScanContext(context.Background())
+}
+
+// ScanContext is Scan with cancellation: once ctx is done the walk stops and the
+// error wraps ctx.Err(). Progress set with SetProgress is reported as the walk
+// goes.
+func (s *Scanner) ScanContext(ctx context.Context) (*ScanResult, error) {
 	logger.Info("Starting scan of directory: %s", s.rootPath)
 	if s.keepDays != nil {
 		logger.Info("Age filter enabled: keeping files newer than %d days", *s.keepDays)
 	}
+	if s.olderThan > 0 {
+		logger.Info("Age filter enabled: deleting entries older than %s", s.olderThan)
+	}
+	if s.newerThan > 0 {
+		logger.Info("Age filter enabled: deleting entries newer than %s", s.newerThan)
+	}
+	if s.hasAgeFilter() {
+		logger.Info("Ages measured by %s from %s", s.ageBy, s.now().Format(time.RFC3339))
+	}
+	if s.nameTime != nil {
+		logger.Info("File ages taken from names matching %q (no timestamp in name: %s)", s.nameTime, s.nameFallback)
+	}
+
+	start := time.Now()
 
 	// Validate that the root path exists before scanning
 	if _, err := os.Stat(s.rootPath); err != nil {
@@ -85,119 +332,583 @@ func (s *Scanner) Scan() (*ScanResult, error) {
 		return nil, fmt.Errorf("cannot get absolute path: %w", err)
 	}
 
+	s.policies = nil
+	inv := newInventory(s.rootPath)
 	result := &ScanResult{
 		ScannedPath: absPath,
-		Files:       make([]string, 0),
-		IsDirectory: make([]bool, 0),
+		Inventory:   inv,
+		scanner:     s,
+	}
+	if s.recordIdentity {
+		result.Identities = make([]FileIdentity, 0)
 	}
 
+	// With count or size limits, or when deleting duplicates, files are only
+	// decided once the walk is complete
+	limited := s.hasRetentionLimits() || s.dedupe != nil
+	var inventory []inventoryFile
+	var treeBytes int64
+
 	// Track directories separately to add them after files (bottom-up)
-	directories := make([]string, 0)
+	directories := make([]dirCandidate, 0)
+
+	// Every directory visited has a row in the inventory's directory table,
+	// which the entries beneath it refer to. What the walk tracks about a
+	// directory is kept by row too, so no table of the scan is keyed by path:
+	// only the directories from the root to the current one are held by path
+	stack := walkStack{}
+	stack.push(filepath.Clean(s.rootPath), rootDir)
+	state := []dirState{{candidate: -1}}
+	add := func(dir int32, name string, isDir bool, id FileIdentity) error {
+		if isDir {
+			inv.appendDir(dir)
+		} else if err := inv.appendFile(dir, name); err != nil {
+			return err
+		}
+		if s.recordIdentity {
+			result.Identities = append(result.Identities, id)
+		}
+		return nil
+	}
+
+	// Directories holding retained entries must survive. retain is given the
+	// row of the directory holding the retained entry
+	filtered := s.hasFilters()
+	retain := func(dir int32, isDir bool) {
+		result.TotalRetained++
+		if isDir {
+			result.TotalRetainedDirs++
+		}
+		for ; dir != rootDir && !state[dir].retained; dir = inv.dirs[dir].parent {
+			state[dir].retained = true
+		}
+	}
+
+	tracker := s.newProgressTracker()
+	var space spaceTracker
+	summary := newSummaryTracker(s.summaryTop)
+	done := ctx.Done()
 
 	// Walk the directory tree
 	err = filepath.WalkDir(s.rootPath, func(path string, d fs.DirEntry, err error) error {
+		select {
+		case <-done:
+			return ctx.Err()
+		default:
+		}
+
 		if err != nil {
-			// If we can't access a path, log it but continue
-			logger.LogFileWarning(path, fmt.Sprintf("Cannot access: %v", err))
+			// The root could not be accessed or a directory could not be
+			// read. Anything beneath it is left behind, so the directory
+			// must be kept, but the rest of the tree is still scanned.
+			if d == nil {
+				result.warn(path, "access", err, true)
+				return nil
+			}
+			result.warn(path, "read directory", err, true)
+			if row, ok := stack.rowOf(filepath.Clean(path)); ok && state[row].candidate >= 0 {
+				directories[state[row].candidate].skipped = true
+			}
 			return nil
 		}
 
 		// Skip the root directory itself (we'll handle it separately)
 		if path == s.rootPath {
+			if s.loadControlFiles(path, result) {
+				logger.Info("Target directory is protected, nothing will be deleted")
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
 		result.TotalScanned++
+		tracker.visit(path, d.IsDir(), result.TotalSizeBytes)
+		parent, _ := stack.rowOf(filepath.Dir(path))
+		var row int32 // The directory's own row
+		if d.IsDir() {
+			row, err = inv.addDir(parent, d.Name())
+			if err != nil {
+				return err
+			}
+			stack.push(path, row)
+			state = append(state, dirState{candidate: -1})
+		}
+		if c := state[parent].candidate; c >= 0 {
+			directories[c].hasChildren = true
+		}
+		if s.maxTotalSize > 0 && !d.IsDir() {
+			treeBytes += s.getFileSize(path, d)
+		}
+
+		if d.IsDir() && s.loadControlFiles(path, result) {
+			retain(parent, true)
+			logger.Debug("Retaining (protected subtree): %s", path)
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && s.isControlFile(d.Name()) {
+			retain(parent, false)
+			logger.Debug("Retaining policy file: %s", path)
+			return nil
+		}
+
+		if filtered {
+			rel := s.relPath(path)
+
+			if s.subtrees != nil {
+				switch s.subtrees.relation(rel) {
+				case subtreeOutside:
+					retain(parent, d.IsDir())
+					logger.Debug("Retaining (outside the selected subtrees): %s", path)
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				case subtreeAncestor:
+					// Traversed to reach a selected subtree, but kept
+					retain(parent, true)
+					return nil
+				}
+			}
+
+			if s.exclude.Match(rel, d.IsDir()) {
+				retain(parent, d.IsDir())
+				logger.Debug("Retaining (excluded by pattern): %s", path)
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !s.include.Empty() {
+				included := state[parent].included || s.include.Match(rel, d.IsDir())
+				if !included {
+					// Not included, but the directory may still contain included entries
+					retain(parent, d.IsDir())
+					logger.Debug("Retaining (not matched by include patterns): %s", path)
+					return nil
+				}
+				if d.IsDir() {
+					state[row].included = true
+				}
+			}
 
-		// Check if this file/directory should be deleted based on age
+			if s.filter != nil {
+				entry := newEntry(path, rel, strings.Count(rel, "/")+1, d)
+				matched, err := s.filter.Match(entry)
+				if err != nil {
+					result.warn(path, "evaluate filter", err, false)
+				}
+				if !matched {
+					retain(parent, d.IsDir())
+					logger.Debug("Retaining (not matched by filter): %s", path)
+					return nil
+				}
+				// Reuse the file info the filter loaded, if any
+				d = entry.dirEntry()
+			}
+		}
+
+		if s.selection != 0 && !d.IsDir() && !s.selects(path, d) {
+			retain(parent, false)
+			logger.Debug("Retaining (not of a selected kind): %s", path)
+			return nil
+		}
+
+		var id FileIdentity
+		if s.recordIdentity {
+			id, err = identityOfEntry(path, d)
+			if err != nil {
+				// Without an identity the entry cannot be revalidated, so keep it
+				result.warn(path, "record identity", err, false)
+				retain(parent, d.IsDir())
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			// Whether a directory can go depends on its descendants, so it
+			// is decided once the walk is complete
+			state[row].candidate = int32(len(directories))
+			directories = append(directories, dirCandidate{row: row, entry: d, identity: id})
+			return nil
+		}
+
+		// Check if this file should be deleted based on age. The age, size
+		// and space checks share one stat
+		d = statOnce(d)
 		shouldDel, fileSize, err := s.shouldDelete(path, d)
 		if err != nil {
-			// If we can't determine age, skip this file but continue
-			logger.LogFileWarning(path, fmt.Sprintf("Cannot determine age: %v", err))
+			// If we can't determine age, keep this file but continue
+			result.warn(path, "determine age", err, false)
+			retain(parent, false)
+			return nil
+		}
+
+		if limited {
+			if s.dedupe != nil && !d.Type().IsRegular() {
+				retain(parent, false)
+				logger.Debug("Retaining (not a regular file): %s", path)
+				return nil
+			}
+			if _, source := s.nameAgeOf(d); source == ageKeep {
+				// Kept for lack of a timestamp in the name, and not ranked
+				// against files whose age comes from their name
+				retain(parent, false)
+				return nil
+			}
+			// Retained files still count towards the newest files of their group
+			f, err := s.newInventoryFile(path, parent, d, id, shouldDel)
+			if err != nil {
+				result.warn(path, "determine age", err, false)
+				retain(parent, false)
+				return nil
+			}
+			inventory = append(inventory, f)
+			if !f.deletable {
+				retain(parent, false)
+				logger.Debug("Retaining file (too new): %s", path)
+			}
 			return nil
 		}
 
 		if shouldDel {
 			result.TotalToDelete++
 			result.TotalSizeBytes += fileSize
+			if info, err := d.Info(); err == nil {
+				space.addFile(info)
+				if summary != nil {
+					summary.addFile(s.relPath(path), fileSize, s.now().Sub(s.summaryTime(path, d, info)), ownerOf(path, info))
+				}
+			}
 
-			if d.IsDir() {
-				// Store directories separately to add them after files
-				directories = append(directories, path)
-			} else {
-				// Add files immediately
-				result.Files = append(result.Files, path)
-				result.IsDirectory = append(result.IsDirectory, false)
+			// Add files immediately
+			if err := add(parent, d.Name(), false, id); err != nil {
+				return err
 			}
 		} else {
-			result.TotalRetained++
+			retain(parent, false)
 			logger.Debug("Retaining file (too new): %s", path)
 		}
 
 		return nil
 	})
 
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		logger.Info("Scan cancelled after %d entries", result.TotalScanned)
+		return nil, fmt.Errorf("scan cancelled: %w", ctxErr)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan directory: %w", err)
 	}
 
-	// Add directories in reverse order (deepest first) for bottom-up deletion
+	if limited {
+		keep := s.applyRetentionLimits(inventory, treeBytes)
+		var copies map[string]bool
+		if s.dedupe != nil {
+			if copies, err = s.findDuplicates(ctx, inventory, keep, result); err != nil {
+				logger.Info("Scan cancelled while comparing files")
+				return nil, fmt.Errorf("scan cancelled: %w", err)
+			}
+		}
+		for i, f := range inventory {
+			if !f.deletable {
+				continue // Already counted as retained during the walk
+			}
+			if keep[i] {
+				retain(f.dir, false)
+				logger.Debug("Retaining file (within count or size limit): %s", f.path)
+				continue
+			}
+			if copies != nil && !copies[f.path] {
+				retain(f.dir, false)
+				logger.Debug("Retaining file (not a duplicate copy): %s", f.path)
+				continue
+			}
+			result.TotalToDelete++
+			result.TotalSizeBytes += f.size
+			space.add(f.size, f.space, f.spaceOK)
+			if summary != nil {
+				summary.addFile(s.relPath(f.path), f.size, s.now().Sub(f.modified), f.owner)
+			}
+			if err := add(f.dir, filepath.Base(f.path), false, f.identity); err != nil {
+				return nil, fmt.Errorf("failed to scan directory: %w", err)
+			}
+		}
+	}
+
+	// Add directories in reverse order (deepest first) for bottom-up deletion.
+	// Walk order lists a directory before its descendants, so by the time a
+	// directory is reached here every descendant has already been decided.
 	for i := len(directories) - 1; i >= 0; i-- {
-		result.Files = append(result.Files, directories[i])
-		result.IsDirectory = append(result.IsDirectory, true)
+		dir := directories[i]
+		parent := inv.dirs[dir.row].parent
+		ref := dirRef{inv, dir.row}
+		if s.keepsDirs() {
+			retain(parent, true)
+			logger.Debug("Retaining directory (directories are kept): %s", ref)
+			continue
+		}
+		if dir.skipped {
+			// Its contents are unknown, so it may hold anything
+			retain(parent, true)
+			logger.Debug("Retaining directory (could not be read): %s", ref)
+			continue
+		}
+		if state[dir.row].retained {
+			// Still holds retained entries, so it cannot be deleted
+			retain(parent, true)
+			logger.Debug("Retaining directory (contains retained entries): %s", ref)
+			continue
+		}
+		if !dir.hasChildren {
+			path := inv.dirPath(dir.row)
+			if s.policyFor(path).HasAgeFilter() {
+				// An empty directory has no descendants to take its age
+				// from, so its own timestamp decides
+				shouldDel, _, err := s.shouldDelete(path, dir.entry)
+				if err != nil {
+					result.warn(path, "determine age", err, false)
+				}
+				if err != nil || !shouldDel {
+					retain(parent, true)
+					logger.Debug("Retaining empty directory (too new): %s", path)
+					continue
+				}
+			}
+		}
+		result.TotalToDelete++
+		if info, err := dir.entry.Info(); err == nil {
+			space.addDir(info)
+		}
+		if err := add(dir.row, "", true, dir.identity); err != nil {
+			return nil, fmt.Errorf("failed to scan directory: %w", err)
+		}
 	}
 
 	// Finally, add the root directory itself if we're deleting everything
 	// Only add root directory when no age filter is set (deleting all files)
-	// Don't add it when doing partial deletion with age filtering
-	if s.keepDays == nil || *s.keepDays == 0 {
-		result.Files = append(result.Files, s.rootPath)
-		result.IsDirectory = append(result.IsDirectory, true)
+	// Don't add it when doing partial deletion with age filtering or patterns,
+	// when anything beneath it was retained, protected or could not be read,
+	// or when the root is kept or the scan is restricted to kinds of entries
+	if s.keepDirs == KeepNoDirs && s.selection == 0 && !s.hasAgeFilter() && !filtered && !limited && result.TotalRetained == 0 && len(result.Protected) == 0 && result.SkippedSubtrees == 0 {
+		info, err := os.Lstat(s.rootPath)
+		if err == nil {
+			space.addDir(info)
+		}
+		if s.recordIdentity {
+			if err != nil {
+				return nil, fmt.Errorf("cannot record identity of root directory: %w", err)
+			}
+			id, err := identityOf(s.rootPath, info)
+			if err != nil {
+				return nil, fmt.Errorf("cannot record identity of root directory: %w", err)
+			}
+			result.Identities = append(result.Identities, id)
+		}
+		inv.appendDir(rootDir)
 		result.TotalToDelete++
 	}
 
+	if !s.compact {
+		result.expandInventory()
+	}
+	result.Space = space.finish()
+	result.ScanDuration = time.Since(start)
+	result.Summary = summary.finish()
+	logger.Info("Scan space: %d bytes apparent, %d bytes allocated to release, %d bytes still linked elsewhere (%d hardlinked files)",
+		result.Space.ApparentBytes, result.Space.AllocatedBytes, result.Space.LinkedBytes, result.Space.HardlinkedFiles)
+	logger.Info("Scan inventory: %s; %d KB (%d KB as a flat path list)",
+		inv, (inv.MemoryBytes()+1023)/1024, (inv.FlatBytes()+1023)/1024)
+
 	logger.Info("Scan complete: %d scanned, %d to delete, %d retained",
 		result.TotalScanned, result.TotalToDelete, result.TotalRetained)
+	if len(result.Warnings) > 0 {
+		logger.Warning("Scan completed with %d warnings (%d subtrees skipped)", len(result.Warnings), result.SkippedSubtrees)
+	}
+	tracker.finish(result.TotalSizeBytes)
 
 	return result, nil
 }
 
+// dirCandidate is a directory that passed the filters during the walk. Whether
+// it is deleted is decided after the walk, once its descendants are known.
+type dirCandidate struct {
+	row         int32 // Row in the inventory's directory table
+	entry       fs.DirEntry
+	identity    FileIdentity
+	hasChildren bool // The walk found at least one entry in it, whether deleted or retained
+	skipped     bool // Its contents could not be read
+}
+
+// dirState is what the walk tracks about a directory, by its row in the
+// inventory's directory table.
+type dirState struct {
+	candidate int32 // Index in the directory candidates (-1 = not a candidate)
+	retained  bool  // Holds retained entries, so it must be kept
+	included  bool  // Matched by the include patterns, with everything beneath it
+}
+
+// walkStack holds the directories from the root to the one the walk is in,
+// with their rows in the inventory's directory table. filepath.WalkDir visits
+// the tree depth first, so the directory holding an entry is always on it.
+type walkStack struct {
+	paths []string
+	rows  []int32
+}
+
+// push enters directory path, whose row is row.
+func (w *walkStack) push(path string, row int32) {
+	w.paths = append(w.paths, path)
+	w.rows = append(w.rows, row)
+}
+
+// rowOf returns the row of directory dir, leaving the directories below it,
+// which the walk is done with.
+func (w *walkStack) rowOf(dir string) (int32, bool) {
+	for n := len(w.paths); n > 0; n-- {
+		if w.paths[n-1] == dir {
+			w.paths, w.rows = w.paths[:n], w.rows[:n]
+			return w.rows[n-1], true
+		}
+	}
+	return rootDir, false
+}
+
+// hasAgeFilter reports whether keepDays, olderThan or newerThan limit deletion by age.
+func (s *Scanner) hasAgeFilter() bool {
+	return s.basePolicy().HasAgeFilter()
+}
+
+// basePolicy returns the age settings of the scan options, which apply wherever
+// no policy file overrides them.
+func (s *Scanner) basePolicy() Policy {
+	p := Policy{OlderThan: s.olderThan, NewerThan: s.newerThan, AgeBy: s.ageBy}
+	if s.keepDays != nil {
+		p.KeepDays = *s.keepDays
+	}
+	return p
+}
+
+// policyFor returns the age settings that apply to path: those of the policy
+// file in the nearest directory above it, or the scan options if there is none.
+func (s *Scanner) policyFor(path string) Policy {
+	if len(s.policies) > 0 {
+		for dir := filepath.Dir(path); len(dir) > len(s.rootPath); dir = filepath.Dir(dir) {
+			if p, ok := s.policies[dir]; ok {
+				return p
+			}
+		}
+		if p, ok := s.policies[s.rootPath]; ok {
+			return p
+		}
+	}
+	return s.basePolicy()
+}
+
+// loadControlFiles looks for the keep-marker and policy file in dir. It returns
+// true if the directory is protected: it holds the keep-marker, or its policy
+// file cannot be read, since a policy that is not understood must not widen
+// deletion. A valid policy is recorded and applies to the directory's subtree.
+func (s *Scanner) loadControlFiles(dir string, result *ScanResult) bool {
+	if s.keepMarker != "" {
+		if _, err := os.Lstat(filepath.Join(dir, s.keepMarker)); err == nil {
+			result.Protected = append(result.Protected, dir)
+			return true
+		}
+	}
+
+	if s.policyFile != "" {
+		policyPath := filepath.Join(dir, s.policyFile)
+		p, err := ReadPolicyFile(policyPath, s.policyFor(policyPath))
+		if err == nil {
+			if s.policies == nil {
+				s.policies = make(map[string]Policy)
+			}
+			s.policies[dir] = p
+			result.Policies = append(result.Policies, DirPolicy{Dir: dir, Policy: p})
+			logger.Info("Policy file %s: %s", policyPath, p)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			// The subtree is protected rather than scanned
+			result.warn(dir, "apply policy file", err, true)
+			result.Protected = append(result.Protected, dir)
+			return true
+		}
+	}
+
+	return false
+}
+
 // shouldDelete determines if a file or directory should be deleted based on age filtering.
 // Returns (shouldDelete, fileSize, error).
 //
 // Age filtering logic:
-//   - If keepDays is nil or 0, all files are marked for deletion
-//   - Otherwise, only files older than keepDays are marked for deletion
-//   - Age is calculated using the file's modification time (mtime)
+//   - If no age filter is set (keepDays nil or 0, no olderThan/newerThan), all files
+//     are marked for deletion
+//   - keepDays and olderThan only mark entries older than the limit
+//   - newerThan only marks entries newer than the limit
+//   - Age is measured from the reference time (default: now) to the timestamp
+//     selected by SetAgeBy (default: modification time), or for files to the
+//     timestamp in their name with SetAgeFromName
+//   - A policy file in a directory above path replaces these settings (see policyFor)
 //
 // The function returns the file size for progress reporting (0 for directories).
 func (s *Scanner) shouldDelete(path string, d fs.DirEntry) (bool, int64, error) {
-	// If no age filter is set, delete everything
-	if s.keepDays == nil {
-		return true, s.getFileSize(path, d), nil
+	policy := s.policyFor(path)
+
+	// Files whose name holds no timestamp are kept whatever the age settings
+	timestamp, source := s.nameAgeOf(d)
+	if source == ageKeep {
+		return false, s.getFileSize(path, d), nil
 	}
 
-	// If keepDays is 0, delete everything (edge case)
-	if *s.keepDays == 0 {
+	// If no age filter is set, delete everything
+	if !policy.HasAgeFilter() {
 		return true, s.getFileSize(path, d), nil
 	}
 
-	// Get file info to check modification time
-	info, err := d.Info()
-	if err != nil {
-		return false, 0, fmt.Errorf("failed to get file info: %w", err)
+	switch source {
+	case ageDelete:
+		return true, s.getFileSize(path, d), nil
+	case ageFromStat:
+		// Get file info to check the selected timestamp
+		info, err := d.Info()
+		if err != nil {
+			return false, 0, fmt.Errorf("failed to get file info: %w", err)
+		}
+		timestamp, err = timeOfField(path, info, policy.AgeBy)
+		if err != nil {
+			return false, 0, err
+		}
 	}
 
-	// Calculate file age based on modification time
-	fileAge := time.Since(info.ModTime())
-	keepDuration := time.Duration(*s.keepDays) * 24 * time.Hour
+	// Calculate file age relative to the reference time
+	fileAge := s.now().Sub(timestamp)
 
 	// Delete if file is older than the retention period
-	shouldDel := fileAge > keepDuration
+	shouldDel := true
+	if policy.KeepDays > 0 {
+		keepDuration := time.Duration(policy.KeepDays) * 24 * time.Hour
+		shouldDel = fileAge > keepDuration
+	}
+	if policy.OlderThan > 0 {
+		shouldDel = shouldDel && fileAge > policy.OlderThan
+	}
+	if policy.NewerThan > 0 {
+		shouldDel = shouldDel && fileAge < policy.NewerThan
+	}
 
 	return shouldDel, s.getFileSize(path, d), nil
 }
 
+// relPath returns path relative to the scan root, with forward slashes.
+func (s *Scanner) relPath(path string) string {
+	rel, err := filepath.Rel(s.rootPath, path)
+	if err != nil {
+		rel = path
+	}
+	return filepath.ToSlash(rel)
+}
+
 // getFileSize returns the size of a file or 0 for directories.
 // This is used for progress reporting and statistics.
 // If the file info cannot be retrieved, returns 0.
@@ -214,10 +925,162 @@ func (s *Scanner) getFileSize(path string, d fs.DirEntry) int64 {
 	return info.Size()
 }
 
+// summaryTime returns the timestamp a file's age is measured from in the
+// summary: the one the age filters use, or the modification time if that is
+// not available.
+func (s *Scanner) summaryTime(path string, d fs.DirEntry, info fs.FileInfo) time.Time {
+	if ts, source := s.nameAgeOf(d); source == ageFromName {
+		return ts
+	}
+	if ts, err := timeOfField(path, info, s.ageBy); err == nil {
+		return ts
+	}
+	return info.ModTime()
+}
+
+// ownerOf returns the uid owning a file, or -1 if it is not known.
+func ownerOf(path string, info fs.FileInfo) int {
+	uid, _, err := ownerOfInfo(path, info)
+	if err != nil {
+		return -1
+	}
+	return uid
+}
+
+// statOnceEntry is a DirEntry whose file info is loaded at most once.
+type statOnceEntry struct {
+	fs.DirEntry
+	info   fs.FileInfo
+	err    error
+	loaded bool
+}
+
+// statOnce wraps d so that repeated Info calls share one stat.
+func statOnce(d fs.DirEntry) fs.DirEntry {
+	if _, ok := d.(*statOnceEntry); ok {
+		return d
+	}
+	return &statOnceEntry{DirEntry: d}
+}
+
+// Info returns the file info, loading it on the first call.
+func (e *statOnceEntry) Info() (fs.FileInfo, error) {
+	if !e.loaded {
+		e.info, e.err = e.DirEntry.Info()
+		e.loaded = true
+	}
+	return e.info, e.err
+}
+
+// fileSize returns the size a FileIdentity records: that of a file, and 0 for
+// a directory, whose size changes as its children are deleted.
+func fileSize(info fs.FileInfo) int64 {
+	if info.IsDir() {
+		return 0
+	}
+	return info.Size()
+}
+
+// identityOfEntry returns the FileIdentity of a directory entry found during the walk.
+func identityOfEntry(path string, d fs.DirEntry) (FileIdentity, error) {
+	info, err := d.Info()
+	if err != nil {
+		return FileIdentity{}, err
+	}
+	return identityOf(path, info)
+}
+
+// Revalidate re-stats entry i of the result immediately before it is deleted and
+// reports whether it may still be deleted. It returns false when:
+//   - The entry no longer exists or cannot be stat'ed
+//   - The device or inode differs from the scan (the path was replaced)
+//   - A file's modification time or size differs from the scan (the file was rewritten)
+//   - A file no longer passes the scanner's age filter or Filter
+//   - A duplicate copy's surviving copy (see SetDedupe) was removed, replaced
+//     or modified, so deleting the copy could lose the content
+//
+// Directories are only checked for identity: deleting their children during the
+// same run updates their modification time, so mtime cannot be compared.
+//
+// Results without recorded identities (see SetRecordIdentity) only check the
+// survivors of duplicate copies.
+// Revalidate is safe to call concurrently from multiple deletion workers.
+func (r *ScanResult) Revalidate(i int) bool {
+	if i < 0 || i >= r.Len() {
+		return true
+	}
+	path := r.Path(i)
+	if !r.survivorIntact(path) {
+		return false
+	}
+	if i >= len(r.Identities) {
+		return true
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		logger.Debug("Revalidation failed, cannot stat: %s (%v)", path, err)
+		return false
+	}
+
+	isDir := r.IsDir(i)
+	if info.IsDir() != isDir {
+		logger.Debug("Revalidation failed, type changed: %s", path)
+		return false
+	}
+
+	current, err := identityOf(path, info)
+	if err != nil {
+		logger.Debug("Revalidation failed, cannot read identity: %s (%v)", path, err)
+		return false
+	}
+
+	recorded := r.Identities[i]
+	if current.Dev != recorded.Dev || current.Ino != recorded.Ino {
+		logger.Debug("Revalidation failed, identity changed: %s", path)
+		return false
+	}
+	if isDir {
+		return true
+	}
+	if !current.ModTime.Equal(recorded.ModTime) || current.Size != recorded.Size {
+		logger.Debug("Revalidation failed, modified since scan: %s", path)
+		return false
+	}
+
+	if r.scanner != nil {
+		d := fs.FileInfoToDirEntry(info)
+		shouldDel, _, err := r.scanner.shouldDelete(path, d)
+		if err != nil || !shouldDel {
+			logger.Debug("Revalidation failed, no longer passes age filter: %s", path)
+			return false
+		}
+
+		if r.scanner.filter != nil {
+			rel := r.scanner.relPath(path)
+			entry := newEntry(path, rel, strings.Count(rel, "/")+1, d)
+			matched, err := r.scanner.filter.Match(entry)
+			if err != nil || !matched {
+				logger.Debug("Revalidation failed, no longer passes filter: %s", path)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 // ParallelScanner extends Scanner with concurrent directory traversal capabilities.
 // It provides Windows-optimized parallel scanning using FindFirstFileEx and worker pools
 // for improved performance on large directory trees.
+//
+// Only the plain scan, optionally with keepDays and include/exclude patterns,
+// walks in parallel. With any other option set, and on platforms other than
+// Windows, Scan runs the sequential Scanner instead, which uses the workers only
+// for hashing duplicates; on Windows this is logged as a warning naming the
+// options responsible.
 type ParallelScanner struct {
+	scanOptions
 	rootPath        string
 	keepDays        *int
 	workers         int  // Number of parallel scan workers
@@ -254,3 +1117,12 @@ func NewParallelScanner(rootPath string, keepDays *int, workers int) *ParallelSc
 		preConvertUTF16: false, // Will be set to true on Windows in platform-specific code
 	}
 }
+
+// newSequentialScanner returns a Scanner with the same root, age filter and
+// options as the ParallelScanner, used as a fallback and on non-Windows platforms.
+func (ps *ParallelScanner) newSequentialScanner() *Scanner {
+	s := NewScanner(ps.rootPath, ps.keepDays)
+	s.scanOptions = ps.scanOptions
+	s.workers = ps.workers
+	return s
+}
@@ -17,7 +17,7 @@ func (ps *ParallelScanner) Scan() (*ScanResult, error) {
 	startTime := time.Now()
 
 	// Use the sequential scanner as a fallback on non-Windows platforms
-	scanner := NewScanner(ps.rootPath, ps.keepDays)
+	scanner := ps.newSequentialScanner()
 	result, err := scanner.Scan()
 	if err != nil {
 		return nil, err
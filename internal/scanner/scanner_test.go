@@ -845,3 +845,501 @@ func TestParallelScanner_BasicScan(t *testing.T) {
 		t.Error("Expected FilesUTF16 to be initialized")
 	}
 }
+
+// TestScanner_RecordIdentity tests that identities are recorded in step with the
+// file list when identity recording is enabled, and not recorded otherwise.
+func TestScanner_RecordIdentity(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "subdir"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{"a.txt", "subdir/b.txt"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	result, err := NewScanner(tmpDir, nil).Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Identities != nil {
+		t.Errorf("Expected no identities without SetRecordIdentity, got %d", len(result.Identities))
+	}
+
+	scanner := NewScanner(tmpDir, nil)
+	scanner.SetRecordIdentity(true)
+	result, err = scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Identities) != len(result.Files) {
+		t.Fatalf("Expected %d identities, got %d", len(result.Files), len(result.Identities))
+	}
+	for i, path := range result.Files {
+		if result.Identities[i].Ino == 0 {
+			t.Errorf("Expected non-zero inode for %s", path)
+		}
+		if !result.Revalidate(i) {
+			t.Errorf("Expected unchanged entry to revalidate: %s", path)
+		}
+	}
+}
+
+// TestScanResult_RevalidateDetectsChanges tests that entries rewritten, replaced
+// or removed after the scan fail revalidation, while untouched entries pass.
+func TestScanResult_RevalidateDetectsChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldTime := time.Now().Add(-10 * 24 * time.Hour)
+
+	names := []string{"untouched.log", "rewritten.log", "replaced.log", "removed.log"}
+	for _, name := range names {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte("old content"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if err := os.Chtimes(path, oldTime, oldTime); err != nil {
+			t.Fatalf("Failed to set file time: %v", err)
+		}
+	}
+
+	keepDays := 5
+	scanner := NewScanner(tmpDir, &keepDays)
+	scanner.SetRecordIdentity(true)
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	// Rewrite one file (new mtime, too new for the age filter)
+	if err := os.WriteFile(filepath.Join(tmpDir, "rewritten.log"), []byte("new content"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite file: %v", err)
+	}
+
+	// Replace one file with a new inode carrying the same old mtime
+	replaced := filepath.Join(tmpDir, "replaced.log")
+	tmpReplacement := filepath.Join(tmpDir, "replacement.tmp")
+	if err := os.WriteFile(tmpReplacement, []byte("old content"), 0644); err != nil {
+		t.Fatalf("Failed to create replacement: %v", err)
+	}
+	if err := os.Chtimes(tmpReplacement, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to set replacement time: %v", err)
+	}
+	if err := os.Rename(tmpReplacement, replaced); err != nil {
+		t.Fatalf("Failed to replace file: %v", err)
+	}
+
+	// Remove one file entirely
+	if err := os.Remove(filepath.Join(tmpDir, "removed.log")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	expected := map[string]bool{
+		"untouched.log": true,
+		"rewritten.log": false,
+		"replaced.log":  false,
+		"removed.log":   false,
+	}
+	for i, path := range result.Files {
+		want, ok := expected[filepath.Base(path)]
+		if !ok {
+			continue
+		}
+		if got := result.Revalidate(i); got != want {
+			t.Errorf("Revalidate(%s) = %v, want %v", filepath.Base(path), got, want)
+		}
+	}
+}
+
+// TestScanner_IncludeExcludePatterns tests that include and exclude patterns
+// select what is deleted, that excluded directories are pruned without being
+// traversed, and that directories holding retained entries survive.
+func TestScanner_IncludeExcludePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := []string{
+		"app.log",
+		"build.tmp",
+		"state.db",
+		"notes.txt",
+		"logs/old.log",
+		"logs/old.db",
+		"cache/a.tmp",
+		"keep/archive.log",
+		"nested/keep/deep.tmp",
+	}
+	for _, name := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	include, err := NewMatcher([]string{"*.tmp", "*.log"})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	exclude, err := NewMatcher([]string{"*.db", "keep/"})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+
+	scanner := NewScanner(tmpDir, nil)
+	scanner.SetInclude(include)
+	scanner.SetExclude(exclude)
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	deleted := make(map[string]bool)
+	for _, path := range result.Files {
+		rel, _ := filepath.Rel(tmpDir, path)
+		deleted[filepath.ToSlash(rel)] = true
+	}
+
+	for _, want := range []string{"app.log", "build.tmp", "logs/old.log", "cache/a.tmp"} {
+		if !deleted[want] {
+			t.Errorf("Expected %s to be deleted", want)
+		}
+	}
+	for _, keep := range []string{"state.db", "notes.txt", "logs/old.db", "keep", "keep/archive.log",
+		"nested/keep", "nested/keep/deep.tmp", "logs", "cache", "nested", "."} {
+		if deleted[keep] {
+			t.Errorf("Expected %s to be retained", keep)
+		}
+	}
+
+	// Pruned directories are counted once; their contents are never scanned
+	// Scanned: 8 top-level entries + 2 in logs + 1 in cache + nested/keep
+	if result.TotalScanned != 12 {
+		t.Errorf("Expected 12 scanned entries (excluded directories pruned), got %d", result.TotalScanned)
+	}
+	if result.TotalToDelete != len(result.Files) || result.TotalToDelete != 4 {
+		t.Errorf("Expected 4 entries to delete, got TotalToDelete=%d, Files=%d", result.TotalToDelete, len(result.Files))
+	}
+	if result.TotalScanned != result.TotalToDelete+result.TotalRetained {
+		t.Errorf("Expected scanned (%d) = to delete (%d) + retained (%d)",
+			result.TotalScanned, result.TotalToDelete, result.TotalRetained)
+	}
+}
+
+// TestScanner_ExcludeOnlyKeepsParentDirectories tests that without include
+// patterns, every directory not holding an excluded entry is deleted.
+func TestScanner_ExcludeOnlyKeepsParentDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a/b/data.db", "a/b/c.txt", "x/y.txt"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	exclude, err := NewMatcher([]string{"*.db"})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	ps := NewParallelScanner(tmpDir, nil, 2)
+	ps.SetExclude(exclude)
+	result, err := ps.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	deleted := make(map[string]bool)
+	for _, path := range result.Files {
+		rel, _ := filepath.Rel(tmpDir, path)
+		deleted[filepath.ToSlash(rel)] = true
+	}
+	want := map[string]bool{"a/b/c.txt": true, "x/y.txt": true, "x": true}
+	for rel := range deleted {
+		if !want[rel] {
+			t.Errorf("Unexpected deletion of %s", rel)
+		}
+	}
+	for rel := range want {
+		if !deleted[rel] {
+			t.Errorf("Expected %s to be deleted", rel)
+		}
+	}
+}
+
+// TestScanner_Filter tests that only entries matching the filter are deleted and
+// that directories rejected by the filter are still traversed.
+func TestScanner_Filter(t *testing.T) {
+	tmpDir := t.TempDir()
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	files := []struct {
+		name    string
+		size    int
+		modTime time.Time
+	}{
+		{"big-old.bin", 4096, old},
+		{"small-old.bin", 10, old},
+		{"big-new.bin", 4096, time.Now()},
+		{"sub/big-old.bin", 4096, old},
+		{"sub/big-old.keep", 4096, old},
+	}
+	for _, f := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if err := os.Chtimes(path, f.modTime, f.modTime); err != nil {
+			t.Fatalf("Failed to set file time: %v", err)
+		}
+	}
+
+	filter, err := ParseWhere("type = f and size > 1K and mtime older than 7d and not name ~ *.keep", time.Now())
+	if err != nil {
+		t.Fatalf("ParseWhere failed: %v", err)
+	}
+	scanner := NewScanner(tmpDir, nil)
+	scanner.SetFilter(filter)
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	deleted := make(map[string]bool)
+	for _, path := range result.Files {
+		rel, _ := filepath.Rel(tmpDir, path)
+		deleted[filepath.ToSlash(rel)] = true
+	}
+	if len(deleted) != 2 || !deleted["big-old.bin"] || !deleted["sub/big-old.bin"] {
+		t.Errorf("Expected only big-old.bin and sub/big-old.bin to be deleted, got %v", deleted)
+	}
+	if result.TotalScanned != 6 || result.TotalRetained != 4 {
+		t.Errorf("Expected 6 scanned and 4 retained, got %d scanned and %d retained",
+			result.TotalScanned, result.TotalRetained)
+	}
+	if result.TotalSizeBytes != 8192 {
+		t.Errorf("Expected 8192 bytes to delete, got %d", result.TotalSizeBytes)
+	}
+}
+
+// TestScanner_AgeOptions tests --older-than/--newer-than windows, the age field
+// selection and a fixed reference time.
+func TestScanner_AgeOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	reference := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// name -> (mtime, atime) ages before the reference time
+	files := map[string][2]time.Duration{
+		"hour.txt":  {time.Hour, time.Hour},
+		"day.txt":   {30 * time.Hour, 30 * time.Hour},
+		"week.txt":  {8 * 24 * time.Hour, 8 * 24 * time.Hour},
+		"cache.bin": {8 * 24 * time.Hour, 2 * time.Hour}, // old but recently read
+	}
+	for name, ages := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if err := os.Chtimes(path, reference.Add(-ages[1]), reference.Add(-ages[0])); err != nil {
+			t.Fatalf("Failed to set file times: %v", err)
+		}
+	}
+
+	scan := func(configure func(s *Scanner)) map[string]bool {
+		t.Helper()
+		s := NewScanner(tmpDir, nil)
+		s.SetReferenceTime(reference)
+		configure(s)
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		deleted := make(map[string]bool)
+		for _, path := range result.Files {
+			if path != tmpDir {
+				deleted[filepath.Base(path)] = true
+			}
+		}
+		return deleted
+	}
+
+	deleted := scan(func(s *Scanner) { s.SetOlderThan(36 * time.Hour) })
+	if len(deleted) != 2 || !deleted["week.txt"] || !deleted["cache.bin"] {
+		t.Errorf("--older-than 36h: expected week.txt and cache.bin, got %v", deleted)
+	}
+
+	deleted = scan(func(s *Scanner) {
+		s.SetOlderThan(36 * time.Hour)
+		s.SetAgeBy(TimeATime)
+	})
+	if len(deleted) != 1 || !deleted["week.txt"] {
+		t.Errorf("--older-than 36h --age-by atime: expected week.txt, got %v", deleted)
+	}
+
+	deleted = scan(func(s *Scanner) {
+		s.SetOlderThan(2 * time.Hour)
+		s.SetNewerThan(2 * 24 * time.Hour)
+	})
+	if len(deleted) != 1 || !deleted["day.txt"] {
+		t.Errorf("--older-than 2h --newer-than 2d: expected day.txt, got %v", deleted)
+	}
+
+	keepDays := 7
+	s := NewScanner(tmpDir, &keepDays)
+	s.SetReferenceTime(reference)
+	s.SetAgeBy(TimeATime)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.TotalToDelete != 1 || filepath.Base(result.Files[0]) != "week.txt" {
+		t.Errorf("--keep-days 7 --age-by atime: expected only week.txt, got %v", result.Files)
+	}
+}
+
+// TestScanner_AgeFilterDirectorySemantics tests that under age filtering a
+// directory is deleted only when everything beneath it is, regardless of its own
+// timestamp, and that directories still holding newer files are counted separately.
+func TestScanner_AgeFilterDirectorySemantics(t *testing.T) {
+	tmpDir := t.TempDir()
+	reference := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := reference.Add(-30 * 24 * time.Hour)
+	recent := reference.Add(-time.Hour)
+
+	// Entries are listed parents first; times are applied in reverse so that
+	// creating children does not disturb the times set on their parents
+	entries := []struct {
+		path  string
+		isDir bool
+		mtime time.Time
+	}{
+		{"mixed", true, old},              // old, but holds a new file
+		{"mixed/old.txt", false, old},     // deleted
+		{"mixed/new.txt", false, recent},  // retained
+		{"stale", true, recent},           // touched recently, but only holds old files
+		{"stale/old.txt", false, old},     // deleted
+		{"stale/sub", true, old},          // deleted once emptied
+		{"stale/sub/old.txt", false, old}, // deleted
+		{"empty-old", true, old},          // deleted: empty and old itself
+		{"empty-new", true, recent},       // retained: empty but new itself
+	}
+	for _, e := range entries {
+		path := filepath.Join(tmpDir, filepath.FromSlash(e.path))
+		var err error
+		if e.isDir {
+			err = os.Mkdir(path, 0755)
+		} else {
+			err = os.WriteFile(path, []byte("x"), 0644)
+		}
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", e.path, err)
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		path := filepath.Join(tmpDir, filepath.FromSlash(entries[i].path))
+		if err := os.Chtimes(path, entries[i].mtime, entries[i].mtime); err != nil {
+			t.Fatalf("Failed to set times on %s: %v", entries[i].path, err)
+		}
+	}
+
+	keepDays := 7
+	s := NewScanner(tmpDir, &keepDays)
+	s.SetReferenceTime(reference)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	position := make(map[string]int)
+	for i, path := range result.Files {
+		rel, _ := filepath.Rel(tmpDir, path)
+		position[filepath.ToSlash(rel)] = i
+	}
+
+	for _, want := range []string{"mixed/old.txt", "stale/old.txt", "stale/sub/old.txt", "stale/sub", "stale", "empty-old"} {
+		if _, ok := position[want]; !ok {
+			t.Errorf("Expected %s to be deleted, got %v", want, result.Files)
+		}
+	}
+	for _, kept := range []string{"mixed", "mixed/new.txt", "empty-new", "."} {
+		if _, ok := position[kept]; ok {
+			t.Errorf("Expected %s to be retained, got %v", kept, result.Files)
+		}
+	}
+	if position["stale/sub/old.txt"] > position["stale/sub"] || position["stale/sub"] > position["stale"] {
+		t.Errorf("Expected bottom-up order for stale/, got %v", result.Files)
+	}
+
+	if result.TotalToDelete != 6 || result.TotalRetained != 3 || result.TotalRetainedDirs != 2 {
+		t.Errorf("Expected 6 to delete, 3 retained (2 directories), got %d, %d (%d)",
+			result.TotalToDelete, result.TotalRetained, result.TotalRetainedDirs)
+	}
+}
+
+// TestScanner_KeepDirs tests that KeepRoot empties the tree but keeps the root
+// directory, and that KeepAllDirs deletes only the files, keeping every
+// directory.
+func TestScanner_KeepDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a", "a/b", "empty"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	for _, file := range []string{"top.txt", "a/one.txt", "a/b/two.txt"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, filepath.FromSlash(file)), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", file, err)
+		}
+	}
+
+	tests := []struct {
+		keep          KeepDirs
+		deleted       []string
+		retainedDirs  int
+		totalToDelete int
+	}{
+		{KeepNoDirs, []string{"top.txt", "a/one.txt", "a/b/two.txt", "empty", "a/b", "a", "."}, 0, 7},
+		{KeepRoot, []string{"top.txt", "a/one.txt", "a/b/two.txt", "empty", "a/b", "a"}, 0, 6},
+		{KeepAllDirs, []string{"top.txt", "a/one.txt", "a/b/two.txt"}, 3, 3},
+	}
+	for _, tt := range tests {
+		s := NewScanner(tmpDir, nil)
+		s.SetKeepDirs(tt.keep)
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+
+		position := make(map[string]int)
+		for i, path := range result.Files {
+			rel, _ := filepath.Rel(tmpDir, path)
+			position[filepath.ToSlash(rel)] = i
+		}
+		if len(position) != len(tt.deleted) {
+			t.Errorf("KeepDirs %d: deleted %v, want %v", tt.keep, result.Files, tt.deleted)
+		}
+		for _, want := range tt.deleted {
+			if _, ok := position[want]; !ok {
+				t.Errorf("KeepDirs %d: expected %s to be deleted, got %v", tt.keep, want, result.Files)
+			}
+		}
+		// Directories must come after everything they contain
+		for _, pair := range [][2]string{{"a/b/two.txt", "a/b"}, {"a/b", "a"}, {"a", "."}} {
+			inner, ok1 := position[pair[0]]
+			outer, ok2 := position[pair[1]]
+			if ok1 && ok2 && inner > outer {
+				t.Errorf("KeepDirs %d: %s deleted after %s", tt.keep, pair[0], pair[1])
+			}
+		}
+		if result.TotalToDelete != tt.totalToDelete || result.TotalRetainedDirs != tt.retainedDirs ||
+			result.TotalRetained != tt.retainedDirs {
+			t.Errorf("KeepDirs %d: %d to delete, %d retained (%d directories); want %d, %d (%d)", tt.keep,
+				result.TotalToDelete, result.TotalRetained, result.TotalRetainedDirs,
+				tt.totalToDelete, tt.retainedDirs, tt.retainedDirs)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package scanner
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -133,27 +134,43 @@ func (ps *ParallelScanner) Scan() (*ScanResult, error) {
 		logger.Info("Age filter enabled: keeping files newer than %d days", *ps.keepDays)
 	}
 
-	// Try parallel scanning with FindFirstFileEx
-	result, err := ps.parallelScanWithFindFirstFileEx()
-	if err != nil {
-		logger.Warning("Parallel scan failed, falling back to sequential scan: %v", err)
-		
-		// Fall back to sequential scanner
-		scanner := NewScanner(ps.rootPath, ps.keepDays)
-		result, err = scanner.Scan()
+	var result *ScanResult
+	var err error
+	options := ps.sequentialScanOptions()
+	if len(options) > 0 {
+		// Some scan options are only implemented by the sequential scanner
+		logger.Warning("Scanning sequentially instead of with %d parallel workers; the parallel scan does not support: %s",
+			ps.workers, strings.Join(options, ", "))
+		result, err = ps.sequentialScanWithUTF16()
 		if err != nil {
 			return nil, err
 		}
-		
-		// Convert paths to UTF-16 for the fallback result
-		result.FilesUTF16 = make([]*uint16, 0, len(result.Files))
-		for _, path := range result.Files {
-			utf16Path, convErr := convertToUTF16(path)
-			if convErr != nil {
-				logger.LogFileWarning(path, fmt.Sprintf("Failed to convert to UTF-16: %v", convErr))
-				continue
+	} else {
+		// Try parallel scanning with FindFirstFileEx
+		result, err = ps.parallelScanWithFindFirstFileEx()
+		if err != nil {
+			logger.Warning("Parallel scan failed, falling back to sequential scan: %v", err)
+
+			// Fall back to sequential scanner
+			result, err = ps.sequentialScanWithUTF16()
+			if err != nil {
+				return nil, err
+			}
+		} else if result.SkippedSubtrees > 0 {
+			// Only the sequential scanner keeps unreadable directories and
+			// reports them as warnings
+			logger.Info("%d directories could not be read, rescanning sequentially", result.SkippedSubtrees)
+			result, err = ps.sequentialScanWithUTF16()
+			if err != nil {
+				return nil, err
+			}
+		} else if ps.foundControlFiles(result) {
+			// Keep-markers and policy files are only honoured by the sequential scanner
+			logger.Info("Found keep-markers or policy files, rescanning sequentially")
+			result, err = ps.sequentialScanWithUTF16()
+			if err != nil {
+				return nil, err
 			}
-			result.FilesUTF16 = append(result.FilesUTF16, utf16Path)
 		}
 	}
 
@@ -166,6 +183,43 @@ func (ps *ParallelScanner) Scan() (*ScanResult, error) {
 	return result, nil
 }
 
+// foundControlFiles reports whether a parallel scan result contains a keep-marker
+// or policy file, which the parallel scanner does not interpret.
+func (ps *ParallelScanner) foundControlFiles(result *ScanResult) bool {
+	if ps.keepMarker == "" && ps.policyFile == "" {
+		return false
+	}
+	for _, path := range result.Files {
+		if ps.isControlFile(filepath.Base(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sequentialScanWithUTF16 runs the sequential scanner and pre-converts its
+// paths to UTF-16 so the result matches the parallel scanner's output.
+func (ps *ParallelScanner) sequentialScanWithUTF16() (*ScanResult, error) {
+	scanner := ps.newSequentialScanner()
+	result, err := scanner.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert paths to UTF-16 for the fallback result
+	result.FilesUTF16 = make([]*uint16, 0, len(result.Files))
+	for _, path := range result.Files {
+		utf16Path, convErr := convertToUTF16(path)
+		if convErr != nil {
+			logger.LogFileWarning(path, fmt.Sprintf("Failed to convert to UTF-16: %v", convErr))
+			continue
+		}
+		result.FilesUTF16 = append(result.FilesUTF16, utf16Path)
+	}
+
+	return result, nil
+}
+
 // parallelScanWithFindFirstFileEx performs parallel directory traversal using FindFirstFileEx.
 // This is the core Windows-optimized scanning implementation.
 func (ps *ParallelScanner) parallelScanWithFindFirstFileEx() (*ScanResult, error) {
@@ -175,6 +229,9 @@ func (ps *ParallelScanner) parallelScanWithFindFirstFileEx() (*ScanResult, error
 		return nil, fmt.Errorf("cannot get absolute path: %w", err)
 	}
 
+	// Paths are built from the clean root, so filepath.Dir leads back up to it
+	root := filepath.Clean(ps.rootPath)
+
 	// Initialize result structure
 	result := &ScanResult{
 		ScannedPath: absPath,
@@ -199,12 +256,17 @@ func (ps *ParallelScanner) parallelScanWithFindFirstFileEx() (*ScanResult, error
 		totalToDelete atomic.Int64
 		totalRetained atomic.Int64
 		totalSize     atomic.Int64
+		skippedDirs   atomic.Int64 // Directories that could not be (fully) read
 	)
 
+	// Directories holding retained entries, which must be kept with the
+	// directories above them (see retainDir)
+	var retained sync.Map
+
 
 	// Work queue for directories to process
 	// Buffered channel to allow workers to queue subdirectories without blocking
-	workQueue := make(chan string, ps.workers*10)
+	workQueue := make(chan dirTask, ps.workers*10)
 	
 	// Track pending work to know when to close the queue
 	var pendingWork atomic.Int64
@@ -219,20 +281,24 @@ func (ps *ParallelScanner) parallelScanWithFindFirstFileEx() (*ScanResult, error
 		go func(workerID int) {
 			defer wg.Done()
 			
-			for dirPath := range workQueue {
+			for task := range workQueue {
 				// Process this directory into worker-local buffer (NO LOCKS in hot path!)
 				err := ps.processDirectoryIntoBuffer(
-					dirPath,
+					task,
+					root,
 					&workerBuffers[workerID].paths,
 					&totalScanned,
 					&totalToDelete,
 					&totalRetained,
 					&totalSize,
+					&skippedDirs,
+					&retained,
 					workQueue,
 					&pendingWork,
 				)
 				if err != nil {
-					logger.LogFileWarning(dirPath, fmt.Sprintf("Worker %d failed to process directory: %v", workerID, err))
+					skippedDirs.Add(1)
+					logger.LogFileWarning(task.path, fmt.Sprintf("Worker %d failed to process directory: %v", workerID, err))
 				}
 				
 				// Decrement pending work count
@@ -246,7 +312,7 @@ func (ps *ParallelScanner) parallelScanWithFindFirstFileEx() (*ScanResult, error
 	}
 
 	// Enqueue the root directory to start processing
-	workQueue <- ps.rootPath
+	workQueue <- dirTask{path: root}
 
 	// Wait for all workers to complete
 	wg.Wait()
@@ -259,11 +325,26 @@ func (ps *ParallelScanner) parallelScanWithFindFirstFileEx() (*ScanResult, error
 		totalPaths += len(workerBuffers[i].paths)
 	}
 
-	// Single allocation for all paths
+	// Single allocation for all paths. Directories are decided now that
+	// everything beneath them is: a directory is only deleted if nothing
+	// beneath it is retained, so under keepDays directories still holding
+	// newer files are kept instead of failing at deletion time
 	pathInfos := make([]PathInfo, 0, totalPaths)
+	retainedDirs := 0
 	for i := range workerBuffers {
-		pathInfos = append(pathInfos, workerBuffers[i].paths...)
+		for _, pathInfo := range workerBuffers[i].paths {
+			if pathInfo.IsDirectory {
+				if _, ok := retained.Load(pathInfo.UTF8Path); ok {
+					retainedDirs++
+					logger.Debug("Retaining directory (contains retained entries): %s", pathInfo.UTF8Path)
+					continue
+				}
+				totalToDelete.Add(1)
+			}
+			pathInfos = append(pathInfos, pathInfo)
+		}
 	}
+	totalRetained.Add(int64(retainedDirs))
 
 	// Sort pathInfos by depth (deepest first) for bottom-up ordering
 	// This ensures children are deleted before parents
@@ -278,42 +359,66 @@ func (ps *ParallelScanner) parallelScanWithFindFirstFileEx() (*ScanResult, error
 		result.FilesUTF16 = append(result.FilesUTF16, pathInfo.UTF16Path)
 	}
 
-	// Add the root directory itself at the end
-	rootUTF16, err := convertToUTF16(ps.rootPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert root path to UTF-16: %w", err)
+	// Add the root directory itself at the end, unless anything beneath it
+	// is retained or the age filter or patterns are set, as the sequential
+	// scanner does
+	filtered := !ps.include.Empty() || !ps.exclude.Empty()
+	if _, rootRetained := retained.Load(root); !rootRetained && !ps.hasKeepDays() && !filtered {
+		rootUTF16, err := convertToUTF16(ps.rootPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert root path to UTF-16: %w", err)
+		}
+		result.Files = append(result.Files, ps.rootPath)
+		result.FilesUTF16 = append(result.FilesUTF16, rootUTF16)
+		totalToDelete.Add(1)
 	}
-	result.Files = append(result.Files, ps.rootPath)
-	result.FilesUTF16 = append(result.FilesUTF16, rootUTF16)
-	totalToDelete.Add(1)
 
 	// Set final statistics
 	result.TotalScanned = int(totalScanned.Load())
 	result.TotalToDelete = int(totalToDelete.Load())
 	result.TotalRetained = int(totalRetained.Load())
+	result.TotalRetainedDirs = retainedDirs
 	result.TotalSizeBytes = totalSize.Load()
+	result.SkippedSubtrees = int(skippedDirs.Load())
+	// FindFirstFileEx reports no link counts or allocation sizes
+	result.Space = SpaceUsage{ApparentBytes: result.TotalSizeBytes, AllocatedBytes: result.TotalSizeBytes}
 
 	return result, nil
 }
 
+// dirTask is a directory queued for the parallel walk.
+type dirTask struct {
+	path     string
+	modTime  time.Time // Last write time, which decides the age of an empty directory
+	included bool      // Matched by the include patterns, and so is everything beneath it
+}
+
 // processDirectoryIntoBuffer processes a single directory using FindFirstFileEx.
 // It enumerates all entries in the directory and:
 //   - Adds files to the worker-local buffer (NO LOCKS - lock-free hot path!)
-//   - Enqueues subdirectories for parallel processing
+//   - Adds subdirectories to the buffer too, to be decided once the walk is
+//     complete, and enqueues them for parallel processing
+//   - Applies the include/exclude patterns, pruning excluded directories
+//   - Records the directories holding retained entries in retained
 //   - Tracks pending work count for proper queue closure
 //
 // This function is called by worker goroutines. Each worker writes to its own buffer,
 // eliminating the mutex contention that existed in the previous implementation.
 func (ps *ParallelScanner) processDirectoryIntoBuffer(
-	dirPath string,
+	task dirTask,
+	root string,
 	localBuffer *[]PathInfo,
 	totalScanned *atomic.Int64,
 	totalToDelete *atomic.Int64,
 	totalRetained *atomic.Int64,
 	totalSize *atomic.Int64,
-	workQueue chan<- string,
+	skippedDirs *atomic.Int64,
+	retained *sync.Map,
+	workQueue chan<- dirTask,
 	pendingWork *atomic.Int64,
 ) error {
+	dirPath := task.path
+
 	// Convert directory path to UTF-16 for Windows API
 	searchPath := filepath.Join(dirPath, "*")
 	searchPathUTF16, err := syscall.UTF16PtrFromString(searchPath)
@@ -336,6 +441,7 @@ func (ps *ParallelScanner) processDirectoryIntoBuffer(
 	if err != nil {
 		// If we can't access this directory, log and continue
 		if err == windows.ERROR_ACCESS_DENIED {
+			skippedDirs.Add(1)
 			logger.LogFileWarning(dirPath, "Access denied")
 			return nil
 		}
@@ -346,11 +452,15 @@ func (ps *ParallelScanner) processDirectoryIntoBuffer(
 	// Calculate depth for ordering (count path separators)
 	depth := countPathSeparators(dirPath)
 
-	// Pre-allocate path builder for efficient string concatenation
+	// Pre-allocate path builder for efficient string concatenation. Only a
+	// root such as C:\ ends with a separator
 	var pathBuilder strings.Builder
 	pathBuilder.Grow(len(dirPath) + 1 + 256)
+	needSeparator := !os.IsPathSeparator(dirPath[len(dirPath)-1])
+	filtered := !ps.include.Empty() || !ps.exclude.Empty()
 
 	// Process all entries in this directory
+	hasChildren := false
 	for {
 		// Get the filename from Win32finddata
 		filename := windows.UTF16ToString(findData.FileName[:])
@@ -363,16 +473,20 @@ func (ps *ParallelScanner) processDirectoryIntoBuffer(
 				if err == windows.ERROR_NO_MORE_FILES {
 					break
 				}
+				skippedDirs.Add(1)
 				logger.LogFileWarning(dirPath, fmt.Sprintf("FindNextFile failed: %v", err))
 				break
 			}
 			continue
 		}
+		hasChildren = true
 
 		// Build full path efficiently using string builder
 		pathBuilder.Reset()
 		pathBuilder.WriteString(dirPath)
-		pathBuilder.WriteByte(filepath.Separator)
+		if needSeparator {
+			pathBuilder.WriteByte(filepath.Separator)
+		}
 		pathBuilder.WriteString(filename)
 		fullPath := pathBuilder.String()
 
@@ -394,11 +508,13 @@ func (ps *ParallelScanner) processDirectoryIntoBuffer(
 			switch action {
 			case SkipEntry:
 				totalRetained.Add(1)
+				retainDir(retained, root, dirPath)
 				err = windows.FindNextFile(handle, &findData)
 				if err != nil {
 					if err == windows.ERROR_NO_MORE_FILES {
 						break
 					}
+					skippedDirs.Add(1)
 					logger.LogFileWarning(dirPath, fmt.Sprintf("FindNextFile failed: %v", err))
 					break
 				}
@@ -410,17 +526,94 @@ func (ps *ParallelScanner) processDirectoryIntoBuffer(
 			}
 		}
 
-		// Check if this entry should be deleted based on age
-		shouldDel, fileSize := ps.shouldDeleteFromFindData(fullPath, &findData, isDir)
+		// Patterns see links to directories as files, as filepath.WalkDir does
+		included := task.included || ps.include.Empty()
+		excluded := false
+		if filtered {
+			rel := strings.TrimPrefix(filepath.ToSlash(fullPath[len(root):]), "/")
+			excluded = ps.exclude.Match(rel, isDir && shouldTraverse)
+			if !included && !excluded {
+				included = ps.include.Match(rel, isDir && shouldTraverse)
+			}
+		}
 
-		if shouldDel {
-			totalToDelete.Add(1)
+		if excluded {
+			totalRetained.Add(1)
+			retainDir(retained, root, dirPath)
+			logger.Debug("Retaining (excluded by pattern): %s", fullPath)
+		} else if isDir && shouldTraverse {
+			// Whether a directory can go depends on its descendants, so it
+			// is added now and decided once the walk is complete. A directory
+			// the include patterns do not match is kept, but may still contain
+			// included entries
+			if !included {
+				retainDir(retained, root, fullPath)
+				logger.Debug("Retaining (not matched by include patterns): %s", fullPath)
+			}
+			utf16Path, convErr := convertToUTF16(fullPath)
+			if convErr != nil {
+				logger.LogFileWarning(fullPath, fmt.Sprintf("Failed to convert to UTF-16: %v", convErr))
+				retainDir(retained, root, dirPath)
+			} else {
+				pathInfo := PathInfo{
+					UTF8Path:    fullPath,
+					UTF16Path:   utf16Path,
+					IsDirectory: true,
+					Depth:       depth,
+				}
+				*localBuffer = append(*localBuffer, pathInfo)
+			}
+
+			// Increment pending work before enqueuing
+			pendingWork.Add(1)
+			subdir := dirTask{
+				path:     fullPath,
+				modTime:  time.Unix(0, findData.LastWriteTime.Nanoseconds()),
+				included: included,
+			}
+
+			select {
+			case workQueue <- subdir:
+				// Successfully enqueued
+			default:
+				// Queue is full, process synchronously to avoid deadlock
+				logger.Debug("Work queue full, processing directory synchronously: %s", fullPath)
+				err := ps.processDirectoryIntoBuffer(
+					subdir,
+					root,
+					localBuffer,
+					totalScanned,
+					totalToDelete,
+					totalRetained,
+					totalSize,
+					skippedDirs,
+					retained,
+					workQueue,
+					pendingWork,
+				)
+				if err != nil {
+					skippedDirs.Add(1)
+					logger.LogFileWarning(fullPath, fmt.Sprintf("Failed to process subdirectory: %v", err))
+				}
+				// Decrement since we processed it synchronously
+				pendingWork.Add(-1)
+			}
+		} else if !included {
+			totalRetained.Add(1)
+			retainDir(retained, root, dirPath)
+			logger.Debug("Retaining (not matched by include patterns): %s", fullPath)
+		} else if shouldDel, fileSize := ps.shouldDeleteFromFindData(fullPath, &findData, isDir); shouldDel {
+			// Files, and links to directories, which are deleted as they are
+			if !isDir {
+				totalToDelete.Add(1)
+			}
 			totalSize.Add(fileSize)
 
 			// Convert to UTF-16 for deletion
 			utf16Path, convErr := convertToUTF16(fullPath)
 			if convErr != nil {
 				logger.LogFileWarning(fullPath, fmt.Sprintf("Failed to convert to UTF-16: %v", convErr))
+				retainDir(retained, root, dirPath)
 			} else {
 				// Add to worker-local buffer (NO LOCK - this is the key optimization!)
 				pathInfo := PathInfo{
@@ -432,37 +625,9 @@ func (ps *ParallelScanner) processDirectoryIntoBuffer(
 				}
 				*localBuffer = append(*localBuffer, pathInfo)
 			}
-
-			// If this is a directory and we should traverse it, enqueue it for processing
-			if isDir && shouldTraverse {
-				// Increment pending work before enqueuing
-				pendingWork.Add(1)
-
-				select {
-				case workQueue <- fullPath:
-					// Successfully enqueued
-				default:
-					// Queue is full, process synchronously to avoid deadlock
-					logger.Debug("Work queue full, processing directory synchronously: %s", fullPath)
-					err := ps.processDirectoryIntoBuffer(
-						fullPath,
-						localBuffer,
-						totalScanned,
-						totalToDelete,
-						totalRetained,
-						totalSize,
-						workQueue,
-						pendingWork,
-					)
-					if err != nil {
-						logger.LogFileWarning(fullPath, fmt.Sprintf("Failed to process subdirectory: %v", err))
-					}
-					// Decrement since we processed it synchronously
-					pendingWork.Add(-1)
-				}
-			}
 		} else {
 			totalRetained.Add(1)
+			retainDir(retained, root, dirPath)
 			logger.Debug("Retaining file (too new): %s", fullPath)
 		}
 
@@ -472,14 +637,33 @@ func (ps *ParallelScanner) processDirectoryIntoBuffer(
 			if err == windows.ERROR_NO_MORE_FILES {
 				break
 			}
+			skippedDirs.Add(1)
 			logger.LogFileWarning(dirPath, fmt.Sprintf("FindNextFile failed: %v", err))
 			break
 		}
 	}
 
+	// An empty directory has no descendants to take its age from, so its
+	// own timestamp decides. The root has none and is decided by the caller
+	if !hasChildren && dirPath != root && !ps.isExpired(task.modTime) {
+		retainDir(retained, root, dirPath)
+		logger.Debug("Retaining empty directory (too new): %s", dirPath)
+	}
+
 	return nil
 }
 
+// retainDir records that dir holds a retained entry, and so must be kept
+// along with every directory above it up to root. Marking stops at the
+// first directory already marked, whose ancestors are marked as well.
+func retainDir(retained *sync.Map, root, dir string) {
+	for {
+		if _, loaded := retained.LoadOrStore(dir, struct{}{}); loaded || len(dir) <= len(root) {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
 
 // processDirectoryWithTracking processes a single directory using FindFirstFileEx.
 // It enumerates all entries in the directory and:
@@ -696,32 +880,29 @@ func (ps *ParallelScanner) processDirectoryWithTracking(
 // based on age filtering, using data from Win32finddata.
 // Returns (shouldDelete, fileSize).
 func (ps *ParallelScanner) shouldDeleteFromFindData(path string, findData *windows.Win32finddata, isDir bool) (bool, int64) {
-	// If no age filter is set, delete everything
-	if ps.keepDays == nil || *ps.keepDays == 0 {
-		fileSize := int64(0)
-		if !isDir {
-			fileSize = int64(findData.FileSizeHigh)<<32 | int64(findData.FileSizeLow)
-		}
-		return true, fileSize
+	fileSize := int64(0)
+	if !isDir {
+		fileSize = int64(findData.FileSizeHigh)<<32 | int64(findData.FileSizeLow)
 	}
 
-	// Convert FILETIME to time.Time
 	// LastWriteTime is the modification time
-	modTime := time.Unix(0, findData.LastWriteTime.Nanoseconds())
-
-	// Calculate file age based on modification time
-	fileAge := time.Since(modTime)
-	keepDuration := time.Duration(*ps.keepDays) * 24 * time.Hour
+	return ps.isExpired(time.Unix(0, findData.LastWriteTime.Nanoseconds())), fileSize
+}
 
-	// Delete if file is older than the retention period
-	shouldDel := fileAge > keepDuration
+// hasKeepDays reports whether the age filter is set.
+func (ps *ParallelScanner) hasKeepDays() bool {
+	return ps.keepDays != nil && *ps.keepDays > 0
+}
 
-	fileSize := int64(0)
-	if !isDir {
-		fileSize = int64(findData.FileSizeHigh)<<32 | int64(findData.FileSizeLow)
+// isExpired reports whether an entry last modified at modTime is older than
+// the retention period, and so may be deleted. Without an age filter every
+// entry may be deleted.
+func (ps *ParallelScanner) isExpired(modTime time.Time) bool {
+	if !ps.hasKeepDays() {
+		return true
 	}
-
-	return shouldDel, fileSize
+	keepDuration := time.Duration(*ps.keepDays) * 24 * time.Hour
+	return time.Since(modTime) > keepDuration
 }
 
 // convertToUTF16 converts a UTF-8 path to UTF-16 with extended-length path support.
@@ -1386,3 +1386,105 @@ func TestUTF16PreConversionSpecialCharacters(t *testing.T) {
 		}
 	}
 }
+
+// TestParallelScanMatchesSequentialWithPatterns verifies that the parallel walk
+// applies keepDays and include/exclude patterns itself, selecting the same
+// entries as the sequential scanner: directories still holding newer or
+// unmatched files are kept, and so is the root.
+func TestParallelScanMatchesSequentialWithPatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+
+	files := map[string]bool{ // Path -> old
+		"old/a.log":      true,
+		"old/deep/b.log": true,
+		"mixed/new.log":  false,
+		"mixed/old.log":  true,
+		"mixed/c.txt":    true,
+		"skip/x.log":     true,
+	}
+	for name, isOld := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if isOld {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatalf("Failed to set file time: %v", err)
+			}
+		}
+	}
+	for _, dir := range []string{"old/deep", "old", "mixed", "skip"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(dir))
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("Failed to set directory time: %v", err)
+		}
+	}
+
+	include, err := NewMatcher([]string{"*.log"})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	exclude, err := NewMatcher([]string{"skip/"})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	keepDays := 5
+
+	seqScanner := NewScanner(tmpDir, &keepDays)
+	seqScanner.SetInclude(include)
+	seqScanner.SetExclude(exclude)
+	seqResult, err := seqScanner.Scan()
+	if err != nil {
+		t.Fatalf("Sequential scan failed: %v", err)
+	}
+
+	parScanner := NewParallelScanner(tmpDir, &keepDays, 4)
+	parScanner.SetInclude(include)
+	parScanner.SetExclude(exclude)
+	if options := parScanner.sequentialScanOptions(); len(options) > 0 {
+		t.Fatalf("Expected a parallel walk, but it falls back for: %v", options)
+	}
+	parResult, err := parScanner.Scan()
+	if err != nil {
+		t.Fatalf("Parallel scan failed: %v", err)
+	}
+
+	seqPaths := make(map[string]bool)
+	for _, path := range seqResult.Files {
+		seqPaths[path] = true
+	}
+	parPaths := make(map[string]bool)
+	for _, path := range parResult.Files {
+		parPaths[path] = true
+	}
+	for path := range seqPaths {
+		if !parPaths[path] {
+			t.Errorf("Sequential scan deletes %s, parallel scan does not", path)
+		}
+	}
+	for path := range parPaths {
+		if !seqPaths[path] {
+			t.Errorf("Parallel scan deletes %s, sequential scan does not", path)
+		}
+	}
+	if seqResult.TotalRetained != parResult.TotalRetained {
+		t.Errorf("Sequential and parallel scans retained different numbers: %d vs %d",
+			seqResult.TotalRetained, parResult.TotalRetained)
+	}
+
+	// old/ goes entirely; mixed/ keeps new.log and c.txt, skip/ is excluded
+	for _, name := range []string{"old/a.log", "old/deep/b.log", "old/deep", "old", "mixed/old.log"} {
+		if !parPaths[filepath.Join(tmpDir, filepath.FromSlash(name))] {
+			t.Errorf("Expected %s to be deleted", name)
+		}
+	}
+	for _, name := range []string{"mixed/new.log", "mixed/c.txt", "mixed", "skip", "skip/x.log", ""} {
+		if parPaths[filepath.Join(tmpDir, filepath.FromSlash(name))] {
+			t.Errorf("Expected %q to be retained", name)
+		}
+	}
+}
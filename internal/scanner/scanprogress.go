@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultProgressInterval is how often scan progress is reported when
+// SetProgress is given no interval.
+const DefaultProgressInterval = 250 * time.Millisecond
+
+// ScanProgress is a snapshot of a running scan, passed to the function set
+// with SetProgress.
+type ScanProgress struct {
+	Entries     int           // Files and directories visited
+	Dirs        int           // Directories visited
+	DirsQueued  int           // Directories entered whose contents are still being walked
+	Bytes       int64         // Size of the files marked for deletion so far
+	CurrentPath string        // Entry being visited
+	Elapsed     time.Duration // Time since the walk started
+	Rate        float64       // Entries visited per second
+	Done        bool          // Set on the final report, once the scan is complete
+}
+
+// ProgressFunc receives scan progress. It is called on the scanning goroutine,
+// so it should return quickly.
+type ProgressFunc func(ScanProgress)
+
+// SetProgress reports the progress of Scan and ScanContext to fn about every
+// interval (DefaultProgressInterval if 0), plus a final report with Done set
+// when the scan completes. Files held back for SetKeepNewest and
+// SetMaxTotalSize only count towards Bytes in the final report. A nil fn
+// disables progress reporting.
+func (o *scanOptions) SetProgress(fn ProgressFunc, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	o.progress = fn
+	o.progressInterval = interval
+}
+
+// progressCheckEvery is how many entries are visited between clock reads.
+const progressCheckEvery = 64
+
+// progressTracker accumulates the progress of one scan. A nil tracker, used
+// when no ProgressFunc is set, ignores all calls.
+type progressTracker struct {
+	fn       ProgressFunc
+	interval time.Duration
+	start    time.Time
+	last     time.Time
+	p        ScanProgress
+	open     []string // Directories on the walk's path from the root to the current entry
+}
+
+// newProgressTracker returns a tracker for a scan starting now, or nil if no
+// ProgressFunc is set.
+func (o *scanOptions) newProgressTracker() *progressTracker {
+	if o.progress == nil {
+		return nil
+	}
+	now := time.Now()
+	return &progressTracker{fn: o.progress, interval: o.progressInterval, start: now, last: now}
+}
+
+// visit records an entry found by the walk, reporting progress when the
+// interval has passed. bytes is the running total of bytes to delete.
+func (t *progressTracker) visit(path string, isDir bool, bytes int64) {
+	if t == nil {
+		return
+	}
+
+	// The walk is depth-first, so directories that are not ancestors of path
+	// have been finished
+	for len(t.open) > 0 {
+		top := t.open[len(t.open)-1]
+		if strings.HasPrefix(path, top) && len(path) > len(top) && os.IsPathSeparator(path[len(top)]) {
+			break
+		}
+		t.open = t.open[:len(t.open)-1]
+	}
+	if isDir {
+		t.open = append(t.open, path)
+		t.p.Dirs++
+	}
+	t.p.Entries++
+	t.p.Bytes = bytes
+	t.p.CurrentPath = path
+
+	if t.p.Entries%progressCheckEvery != 0 {
+		return
+	}
+	if now := time.Now(); now.Sub(t.last) >= t.interval {
+		t.last = now
+		t.report(now)
+	}
+}
+
+// finish sends the final report.
+func (t *progressTracker) finish(bytes int64) {
+	if t == nil {
+		return
+	}
+	t.open = nil
+	t.p.Bytes = bytes
+	t.p.CurrentPath = ""
+	t.p.Done = true
+	t.report(time.Now())
+}
+
+// report passes the current progress to the ProgressFunc.
+func (t *progressTracker) report(now time.Time) {
+	t.p.DirsQueued = len(t.open)
+	t.p.Elapsed = now.Sub(t.start)
+	if seconds := t.p.Elapsed.Seconds(); seconds > 0 {
+		t.p.Rate = float64(t.p.Entries) / seconds
+	}
+	t.fn(t.p)
+}
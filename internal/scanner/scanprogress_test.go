@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createWideTree creates dirs directories of files files each under root.
+func createWideTree(t *testing.T, root string, dirs, files int) {
+	t.Helper()
+	for i := 0; i < dirs; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%03d", i), "sub")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		for j := 0; j < files; j++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%03d", j)), []byte("12345"), 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+		}
+	}
+}
+
+// TestScanner_Progress tests that progress is reported during the scan and
+// that the final report matches the result.
+func TestScanner_Progress(t *testing.T) {
+	tmpDir := t.TempDir()
+	createWideTree(t, tmpDir, 10, 50)
+
+	var reports []ScanProgress
+	s := NewScanner(tmpDir, nil)
+	s.SetProgress(func(p ScanProgress) { reports = append(reports, p) }, time.Nanosecond)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("Expected intermediate and final reports, got %d", len(reports))
+	}
+	for i, p := range reports[:len(reports)-1] {
+		if p.Done {
+			t.Errorf("Report %d marked done before the end", i)
+		}
+		if p.CurrentPath == "" || p.DirsQueued < 1 || p.DirsQueued > 2 {
+			t.Errorf("Report %d: unexpected path %q or queued directories %d", i, p.CurrentPath, p.DirsQueued)
+		}
+		if i > 0 && p.Entries <= reports[i-1].Entries {
+			t.Errorf("Report %d: entries did not increase (%d after %d)", i, p.Entries, reports[i-1].Entries)
+		}
+	}
+
+	final := reports[len(reports)-1]
+	if !final.Done || final.Entries != result.TotalScanned || final.Dirs != 20 || final.DirsQueued != 0 {
+		t.Errorf("Unexpected final report: %+v (scanned %d)", final, result.TotalScanned)
+	}
+	if final.Bytes != 10*50*5 || final.Bytes != result.TotalSizeBytes {
+		t.Errorf("Expected %d bytes in the final report, got %d", result.TotalSizeBytes, final.Bytes)
+	}
+}
+
+// TestScanner_ScanContextCancel tests that a cancelled context stops the walk.
+func TestScanner_ScanContextCancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	createWideTree(t, tmpDir, 10, 50)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var visited int
+	s := NewScanner(tmpDir, nil)
+	s.SetProgress(func(p ScanProgress) {
+		visited = p.Entries
+		cancel()
+	}, time.Nanosecond)
+
+	result, err := s.ScanContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got %v (result %v)", err, result)
+	}
+	if visited == 0 || visited >= 520 {
+		t.Errorf("Expected the scan to stop early, progress reported %d entries", visited)
+	}
+
+	// An already cancelled context scans nothing
+	if _, err := NewScanner(tmpDir, nil).ScanContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation error for a done context, got %v", err)
+	}
+}
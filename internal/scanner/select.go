@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Select restricts a scan to entries of particular kinds, for janitorial
+// cleanups that must not touch real files. The kinds combine.
+type Select uint8
+
+const (
+	// SelectEmptyDirs selects empty directories, including directories that
+	// become empty once the selected entries beneath them are deleted, like
+	// find -empty -delete. The root directory is never selected.
+	SelectEmptyDirs Select = 1 << iota
+	// SelectBrokenSymlinks selects symbolic links whose target does not
+	// exist, like find -xtype l -delete.
+	SelectBrokenSymlinks
+)
+
+// SetSelect restricts the scan to the selected kinds of entries (0 selects
+// everything). Everything else is retained, and the age settings, patterns
+// and filters further restrict the selection.
+func (o *scanOptions) SetSelect(sel Select) {
+	o.selection = sel
+}
+
+// selects reports whether a non-directory entry is of a selected kind.
+func (o *scanOptions) selects(path string, d fs.DirEntry) bool {
+	if o.selection&SelectBrokenSymlinks == 0 || d.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	return isBrokenSymlink(path)
+}
+
+// keepsDirs reports whether the options keep every directory below the root.
+func (o *scanOptions) keepsDirs() bool {
+	return o.keepDirs == KeepAllDirs || (o.selection != 0 && o.selection&SelectEmptyDirs == 0) || o.dedupe != nil
+}
+
+// isBrokenSymlink reports whether the symbolic link at path cannot be
+// followed because its target, or a directory on the way to it, is missing,
+// or because it loops. Links that cannot be followed for other reasons, such
+// as permissions, are not broken.
+func isBrokenSymlink(path string) bool {
+	_, err := os.Stat(path)
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) || errors.Is(err, syscall.ELOOP)
+}
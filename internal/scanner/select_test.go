@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeSelectTree creates a tree with real files, empty directories, a
+// directory that only holds empty directories, and valid and broken symbolic
+// links.
+func makeSelectTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"empty", "nested", "nested/a", "nested/a/b", "data", "data/empty", "links"} {
+		if err := os.Mkdir(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "data", "file.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	links := map[string]string{
+		"links/dangling": "missing",
+		"links/valid":    "../data/file.txt",
+		"data/dangling":  "file.txt/below-a-file",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Skipf("Cannot create symbolic links: %v", err)
+		}
+	}
+	return root
+}
+
+// scanSelected scans root restricted to sel and returns the relative paths to
+// delete.
+func scanSelected(t *testing.T, root string, sel Select) (*ScanResult, map[string]int) {
+	t.Helper()
+	s := NewScanner(root, nil)
+	s.SetSelect(sel)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	position := make(map[string]int)
+	for i, path := range result.Files {
+		rel, _ := filepath.Rel(root, path)
+		position[filepath.ToSlash(rel)] = i
+	}
+	return result, position
+}
+
+// TestScanner_SelectEmptyDirs tests that only empty directories are selected,
+// including directories that only hold empty directories, deepest first, and
+// never the root.
+func TestScanner_SelectEmptyDirs(t *testing.T) {
+	root := makeSelectTree(t)
+	result, position := scanSelected(t, root, SelectEmptyDirs)
+
+	want := []string{"empty", "nested/a/b", "nested/a", "nested", "data/empty"}
+	if len(position) != len(want) {
+		t.Errorf("Expected %v to be deleted, got %v", want, result.Files)
+	}
+	for _, rel := range want {
+		if _, ok := position[rel]; !ok {
+			t.Errorf("Expected %s to be deleted, got %v", rel, result.Files)
+		}
+	}
+	if position["nested/a/b"] > position["nested/a"] || position["nested/a"] > position["nested"] {
+		t.Errorf("Expected bottom-up order, got %v", result.Files)
+	}
+	if result.TotalToDelete != len(want) || result.TotalRetained != result.TotalScanned-len(want) {
+		t.Errorf("Expected %d to delete and the rest retained, got %d to delete, %d retained of %d",
+			len(want), result.TotalToDelete, result.TotalRetained, result.TotalScanned)
+	}
+}
+
+// TestScanner_SelectBrokenSymlinks tests that only links whose target is
+// missing are selected, and no directory.
+func TestScanner_SelectBrokenSymlinks(t *testing.T) {
+	root := makeSelectTree(t)
+	result, position := scanSelected(t, root, SelectBrokenSymlinks)
+
+	if len(position) != 2 {
+		t.Errorf("Expected the two broken links to be deleted, got %v", result.Files)
+	}
+	for _, rel := range []string{"links/dangling", "data/dangling"} {
+		if _, ok := position[rel]; !ok {
+			t.Errorf("Expected %s to be deleted, got %v", rel, result.Files)
+		}
+	}
+	for _, dir := range result.IsDirectory {
+		if dir {
+			t.Errorf("Expected no directory to be deleted, got %v", result.Files)
+		}
+	}
+}
+
+// TestScanner_SelectCombined tests that directories emptied by deleting their
+// broken links are deleted with them.
+func TestScanner_SelectCombined(t *testing.T) {
+	root := makeSelectTree(t)
+	if err := os.Remove(filepath.Join(root, "links", "valid")); err != nil {
+		t.Fatalf("Failed to remove link: %v", err)
+	}
+	result, position := scanSelected(t, root, SelectEmptyDirs|SelectBrokenSymlinks)
+
+	for _, rel := range []string{"links/dangling", "links", "data/dangling", "empty"} {
+		if _, ok := position[rel]; !ok {
+			t.Errorf("Expected %s to be deleted, got %v", rel, result.Files)
+		}
+	}
+	if _, ok := position["data"]; ok {
+		t.Errorf("Expected data/ to be retained for its file, got %v", result.Files)
+	}
+	if position["links/dangling"] > position["links"] {
+		t.Errorf("Expected the link to be deleted before its directory, got %v", result.Files)
+	}
+}
@@ -0,0 +1,99 @@
+package scanner
+
+import "io/fs"
+
+// SpaceUsage is the disk space taken by the entries a scan marked for deletion.
+//
+// TotalSizeBytes adds up the size of every path, so a file with several links
+// in the tree counts several times, and sparse or compressed files count their
+// full length. SpaceUsage counts each file once and separates what the files
+// claim to hold from the blocks deleting them releases.
+type SpaceUsage struct {
+	ApparentBytes   int64 // Sizes of the files to delete, counting hardlinked files once
+	AllocatedBytes  int64 // Blocks released: files whose every link is deleted, and directories
+	LinkedBytes     int64 // Blocks of files to delete that stay linked elsewhere, so are not released
+	HardlinkedFiles int   // Paths to delete whose file has more than one link
+	Exact           bool  // Allocation and link counts come from stat data; otherwise AllocatedBytes is ApparentBytes
+}
+
+// fileSpace is the allocation and link data of one file.
+type fileSpace struct {
+	dev, ino uint64
+	nlink    uint64
+	alloc    int64 // Allocated bytes
+}
+
+// linkedFile is a file with several links, some of which may be deleted.
+type linkedFile struct {
+	nlink uint64 // Links the file had when first seen
+	seen  uint64 // Links marked for deletion
+	size  int64
+	alloc int64
+}
+
+// spaceTracker accumulates the SpaceUsage of the entries marked for deletion.
+// Only files with several links are remembered, so the cost is nothing for
+// trees without hardlinks.
+type spaceTracker struct {
+	usage   SpaceUsage
+	inexact bool
+	links   map[[2]uint64]*linkedFile
+}
+
+// addFile accounts for a file marked for deletion.
+func (t *spaceTracker) addFile(info fs.FileInfo) {
+	sp, ok := spaceOfInfo(info)
+	t.add(info.Size(), sp, ok)
+}
+
+// add accounts for a file of size bytes marked for deletion. ok reports
+// whether sp holds its stat data.
+func (t *spaceTracker) add(size int64, sp fileSpace, ok bool) {
+	if !ok {
+		t.inexact = true
+		t.usage.ApparentBytes += size
+		t.usage.AllocatedBytes += size
+		return
+	}
+	if sp.nlink <= 1 {
+		t.usage.ApparentBytes += size
+		t.usage.AllocatedBytes += sp.alloc
+		return
+	}
+
+	t.usage.HardlinkedFiles++
+	if t.links == nil {
+		t.links = make(map[[2]uint64]*linkedFile)
+	}
+	key := [2]uint64{sp.dev, sp.ino}
+	l, ok := t.links[key]
+	if !ok {
+		l = &linkedFile{nlink: sp.nlink, size: size, alloc: sp.alloc}
+		t.links[key] = l
+	}
+	l.seen++
+}
+
+// addDir accounts for a directory marked for deletion, which releases its own
+// blocks.
+func (t *spaceTracker) addDir(info fs.FileInfo) {
+	if sp, ok := spaceOfInfo(info); ok {
+		t.usage.AllocatedBytes += sp.alloc
+	}
+}
+
+// finish returns the usage, settling hardlinked files: their blocks are only
+// released if every link was marked for deletion.
+func (t *spaceTracker) finish() SpaceUsage {
+	usage := t.usage
+	for _, l := range t.links {
+		usage.ApparentBytes += l.size
+		if l.seen >= l.nlink {
+			usage.AllocatedBytes += l.alloc
+		} else {
+			usage.LinkedBytes += l.alloc
+		}
+	}
+	usage.Exact = !t.inexact
+	return usage
+}
@@ -0,0 +1,90 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// allocatedBytes returns the blocks allocated to path.
+func allocatedBytes(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("Lstat failed: %v", err)
+	}
+	return info.Sys().(*syscall.Stat_t).Blocks * 512
+}
+
+// TestScanner_Space tests that hardlinked files are counted once, that blocks
+// still linked from outside the scanned tree are not counted as released, and
+// that sparse files count their allocation rather than their length.
+func TestScanner_Space(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "target")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	data := make([]byte, 64<<10)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// Two links inside the tree: released
+	inside := filepath.Join(root, "inside")
+	if err := os.WriteFile(inside, data, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Link(inside, filepath.Join(root, "inside-link")); err != nil {
+		t.Skipf("Hardlinks not supported: %v", err)
+	}
+
+	// One link inside, one outside: not released
+	shared := filepath.Join(root, "shared")
+	if err := os.WriteFile(shared, data, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Link(shared, filepath.Join(base, "outside-link")); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+
+	// Sparse: 1 MiB long, nothing written
+	sparse := filepath.Join(root, "sparse")
+	if err := os.WriteFile(sparse, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Truncate(sparse, 1<<20); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+
+	result, err := NewScanner(root, nil).Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	space := result.Space
+
+	size := int64(len(data))
+	if result.TotalSizeBytes != 3*size+1<<20 {
+		t.Errorf("TotalSizeBytes = %d, want every path counted (%d)", result.TotalSizeBytes, 3*size+1<<20)
+	}
+	if space.ApparentBytes != 2*size+1<<20 {
+		t.Errorf("ApparentBytes = %d, want hardlinks counted once (%d)", space.ApparentBytes, 2*size+1<<20)
+	}
+	if !space.Exact || space.HardlinkedFiles != 3 {
+		t.Errorf("Expected exact accounting of 3 hardlinked paths, got %+v", space)
+	}
+
+	if want := allocatedBytes(t, shared); space.LinkedBytes != want {
+		t.Errorf("LinkedBytes = %d, want the allocation of the shared file (%d)", space.LinkedBytes, want)
+	}
+	want := allocatedBytes(t, inside) + allocatedBytes(t, sparse) + allocatedBytes(t, root)
+	if space.AllocatedBytes != want {
+		t.Errorf("AllocatedBytes = %d, want %d", space.AllocatedBytes, want)
+	}
+	if allocatedBytes(t, sparse) < 1<<20 && space.AllocatedBytes >= space.ApparentBytes {
+		t.Errorf("Expected the sparse file to allocate less than its apparent size: %+v", space)
+	}
+}
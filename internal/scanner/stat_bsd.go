@@ -0,0 +1,29 @@
+//go:build darwin || freebsd || netbsd
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// timeOfInfo returns the access, change or birth time from the stat data held in info.
+func timeOfInfo(path string, info fs.FileInfo, field TimeField) (time.Time, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no stat data available for %s", path)
+	}
+
+	switch field {
+	case TimeATime:
+		return time.Unix(st.Atimespec.Unix()), nil
+	case TimeCTime:
+		return time.Unix(st.Ctimespec.Unix()), nil
+	case TimeBTime:
+		return time.Unix(st.Birthtimespec.Unix()), nil
+	default:
+		return info.ModTime(), nil
+	}
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// timeOfInfo returns the access, change or birth time of an entry. Access and
+// change times come from the stat data in info; the birth time is not part of
+// stat on Linux and needs a statx call.
+func timeOfInfo(path string, info fs.FileInfo, field TimeField) (time.Time, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no stat data available for %s", path)
+	}
+
+	switch field {
+	case TimeATime:
+		return time.Unix(st.Atim.Unix()), nil
+	case TimeCTime:
+		return time.Unix(st.Ctim.Unix()), nil
+	case TimeBTime:
+		var stx unix.Statx_t
+		err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("statx failed for %s: %w", path, err)
+		}
+		if stx.Mask&unix.STATX_BTIME == 0 {
+			return time.Time{}, fmt.Errorf("birth time not recorded by this filesystem: %s", path)
+		}
+		return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), nil
+	default:
+		return info.ModTime(), nil
+	}
+}
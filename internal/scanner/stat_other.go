@@ -0,0 +1,17 @@
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !solaris
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// timeOfInfo supports only the modification time on this platform.
+func timeOfInfo(path string, info fs.FileInfo, field TimeField) (time.Time, error) {
+	if field == TimeMTime {
+		return info.ModTime(), nil
+	}
+	return time.Time{}, fmt.Errorf("%s is not available on this platform", field)
+}
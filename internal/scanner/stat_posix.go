@@ -0,0 +1,30 @@
+//go:build openbsd || dragonfly || solaris
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// timeOfInfo returns the access or change time from the stat data held in info.
+// These systems do not expose a birth time.
+func timeOfInfo(path string, info fs.FileInfo, field TimeField) (time.Time, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no stat data available for %s", path)
+	}
+
+	switch field {
+	case TimeATime:
+		return time.Unix(st.Atim.Unix()), nil
+	case TimeCTime:
+		return time.Unix(st.Ctim.Unix()), nil
+	case TimeBTime:
+		return time.Time{}, fmt.Errorf("birth time is not available on this platform")
+	default:
+		return info.ModTime(), nil
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// ownerOfInfo extracts the owning uid and gid from the stat data held in info.
+func ownerOfInfo(path string, info fs.FileInfo) (int, int, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fmt.Errorf("no stat data available for %s", path)
+	}
+	return int(st.Uid), int(st.Gid), nil
+}
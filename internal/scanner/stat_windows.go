@@ -0,0 +1,35 @@
+//go:build windows
+
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// ownerOfInfo is not supported on Windows, where files are owned by SIDs.
+func ownerOfInfo(path string, info fs.FileInfo) (int, int, error) {
+	return 0, 0, fmt.Errorf("uid/gid are not available on Windows")
+}
+
+// timeOfInfo returns the access or creation time from the attribute data held
+// in info. Windows does not track a POSIX status change time.
+func timeOfInfo(path string, info fs.FileInfo, field TimeField) (time.Time, error) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no attribute data available for %s", path)
+	}
+
+	switch field {
+	case TimeATime:
+		return time.Unix(0, data.LastAccessTime.Nanoseconds()), nil
+	case TimeCTime:
+		return time.Time{}, fmt.Errorf("ctime is not available on Windows (use btime for creation time)")
+	case TimeBTime:
+		return time.Unix(0, data.CreationTime.Nanoseconds()), nil
+	default:
+		return info.ModTime(), nil
+	}
+}
@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"path"
+)
+
+// SetSubtrees restricts deletion to the given subtrees of the root, given
+// relative to it and slash-separated (as sweep finds them). The walk only
+// enters the subtrees and the directories on the way to them; everything else
+// is retained without being read, and the directories on the way are kept.
+// A nil list removes the restriction.
+func (o *scanOptions) SetSubtrees(rels []string) {
+	if rels == nil {
+		o.subtrees = nil
+		return
+	}
+	set := &subtreeSet{selected: make(map[string]bool), ancestors: make(map[string]bool)}
+	for _, rel := range rels {
+		rel = path.Clean(rel)
+		set.selected[rel] = true
+		for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			set.ancestors[dir] = true
+		}
+	}
+	o.subtrees = set
+}
+
+// subtreeSet holds the subtrees a scan is restricted to.
+type subtreeSet struct {
+	selected  map[string]bool // The subtrees' roots
+	ancestors map[string]bool // Directories on the way to them
+}
+
+// subtreeRelation is where an entry lies relative to the selected subtrees.
+type subtreeRelation int
+
+const (
+	subtreeOutside  subtreeRelation = iota // Neither in a subtree nor on the way to one
+	subtreeInside                          // In a subtree, or the root of one
+	subtreeAncestor                        // A directory on the way to a subtree
+)
+
+// relation returns where the entry at rel, relative to the scan root, lies.
+func (t *subtreeSet) relation(rel string) subtreeRelation {
+	if t.selected["."] {
+		return subtreeInside
+	}
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		if t.selected[p] {
+			return subtreeInside
+		}
+	}
+	if t.ancestors[rel] {
+		return subtreeAncestor
+	}
+	return subtreeOutside
+}
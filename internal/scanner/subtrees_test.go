@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestScanner_Subtrees tests that only the selected subtrees are deleted, that
+// the directories on the way to them are kept, and that nothing else is read.
+func TestScanner_Subtrees(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		"app/package.json", "app/node_modules/a/index.js", "app/node_modules/b.js",
+		"app/src/main.js", "lib/core/target/debug/out.o", "lib/core/Cargo.toml", "notes.txt",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", file, err)
+		}
+	}
+
+	s := NewScanner(root, nil)
+	s.SetSubtrees([]string{"app/node_modules", "lib/core/target"})
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	deleted := make(map[string]bool)
+	for _, path := range result.Files {
+		rel, _ := filepath.Rel(root, path)
+		deleted[filepath.ToSlash(rel)] = true
+	}
+	want := []string{
+		"app/node_modules", "app/node_modules/a", "app/node_modules/a/index.js", "app/node_modules/b.js",
+		"lib/core/target", "lib/core/target/debug", "lib/core/target/debug/out.o",
+	}
+	if len(deleted) != len(want) {
+		t.Errorf("Expected %v to be deleted, got %v", want, result.Files)
+	}
+	for _, rel := range want {
+		if !deleted[rel] {
+			t.Errorf("Expected %s to be deleted, got %v", rel, result.Files)
+		}
+	}
+	// app/src is skipped without being read, so its file is not counted
+	if result.TotalScanned != 14 {
+		t.Errorf("Expected 14 entries scanned, got %d", result.TotalScanned)
+	}
+}
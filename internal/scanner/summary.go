@@ -0,0 +1,176 @@
+package scanner
+
+import (
+	"os/user"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSummaryTop is how many subtrees, extensions and owners a summary
+// lists unless SetSummary is given another limit.
+const DefaultSummaryTop = 10
+
+// Summary breaks down the files a scan marked for deletion, so that what is
+// about to be deleted can be checked before confirming. Each list holds at
+// most the number of groups passed to SetSummary, largest first; the number of
+// groups left out is counted alongside.
+type Summary struct {
+	Subtrees          []SummaryGroup // Subdirectories of the root ("." for files directly in it)
+	SubtreesOmitted   int
+	Extensions        []SummaryGroup // Lower-case file extensions ("" for files without one)
+	ExtensionsOmitted int
+	Owners            []SummaryGroup // User names, or numeric uids without a name (empty on Windows)
+	OwnersOmitted     int
+	Ages              []AgeBucket // Every bucket of the age histogram, youngest first
+}
+
+// SummaryGroup is the number and size of the files to delete in one group of
+// a Summary.
+type SummaryGroup struct {
+	Name  string
+	Files int
+	Bytes int64
+}
+
+// AgeBucket is a bar of the age histogram: the files to delete whose age,
+// measured like the age filters measure it, is below Max (and at least the Max
+// of the previous bucket).
+type AgeBucket struct {
+	Label string
+	Max   time.Duration // 0 for the last, unbounded bucket
+	Files int
+	Bytes int64
+}
+
+// ageBuckets are the bounds of the age histogram.
+var ageBuckets = []AgeBucket{
+	{Label: "under 1 day", Max: 24 * time.Hour},
+	{Label: "1-7 days", Max: 7 * 24 * time.Hour},
+	{Label: "7-30 days", Max: 30 * 24 * time.Hour},
+	{Label: "30-90 days", Max: 90 * 24 * time.Hour},
+	{Label: "90-365 days", Max: 365 * 24 * time.Hour},
+	{Label: "over 1 year"},
+}
+
+// SetSummary makes the scan fill ScanResult.Summary, listing at most top
+// groups of each kind (0 disables the summary).
+func (o *scanOptions) SetSummary(top int) {
+	o.summaryTop = top
+}
+
+// summaryTracker aggregates the Summary of the files marked for deletion.
+type summaryTracker struct {
+	top        int
+	subtrees   map[string]*SummaryGroup
+	extensions map[string]*SummaryGroup
+	owners     map[int]*SummaryGroup
+	ages       []AgeBucket
+}
+
+// newSummaryTracker returns a tracker listing top groups of each kind, or nil
+// if top is 0.
+func newSummaryTracker(top int) *summaryTracker {
+	if top <= 0 {
+		return nil
+	}
+	return &summaryTracker{
+		top:        top,
+		subtrees:   make(map[string]*SummaryGroup),
+		extensions: make(map[string]*SummaryGroup),
+		owners:     make(map[int]*SummaryGroup),
+		ages:       append([]AgeBucket(nil), ageBuckets...),
+	}
+}
+
+// addFile adds a file to delete. rel is its slash-separated path relative to
+// the root, age how old it is and uid its owner (-1 if unknown). A nil tracker
+// ignores the file.
+func (t *summaryTracker) addFile(rel string, size int64, age time.Duration, uid int) {
+	if t == nil {
+		return
+	}
+
+	top := "."
+	if dir, _, ok := strings.Cut(rel, "/"); ok {
+		top = dir
+	}
+	addToGroup(t.subtrees, top, size)
+
+	name := path.Base(rel)
+	ext := strings.ToLower(path.Ext(name))
+	if ext == strings.ToLower(name) {
+		ext = "" // A dot file such as .bashrc has no extension
+	}
+	addToGroup(t.extensions, ext, size)
+
+	if uid >= 0 {
+		addToGroup(t.owners, uid, size)
+	}
+
+	i := 0
+	for i < len(t.ages)-1 && age >= t.ages[i].Max {
+		i++
+	}
+	t.ages[i].Files++
+	t.ages[i].Bytes += size
+}
+
+// addToGroup counts a file of size bytes in the group key of groups.
+func addToGroup[K comparable](groups map[K]*SummaryGroup, key K, size int64) {
+	g, ok := groups[key]
+	if !ok {
+		g = &SummaryGroup{}
+		groups[key] = g
+	}
+	g.Files++
+	g.Bytes += size
+}
+
+// finish returns the summary, or nil for a nil tracker.
+func (t *summaryTracker) finish() *Summary {
+	if t == nil {
+		return nil
+	}
+	summary := &Summary{Ages: t.ages}
+	summary.Subtrees, summary.SubtreesOmitted = topGroups(t.subtrees, t.top, func(name string) string { return name })
+	summary.Extensions, summary.ExtensionsOmitted = topGroups(t.extensions, t.top, func(ext string) string { return ext })
+	summary.Owners, summary.OwnersOmitted = topGroups(t.owners, t.top, ownerName)
+	return summary
+}
+
+// topGroups names the groups, sorts them largest first (by bytes, then files,
+// then name) and returns the first top of them and how many were left out.
+func topGroups[K comparable](groups map[K]*SummaryGroup, top int, name func(K) string) ([]SummaryGroup, int) {
+	list := make([]SummaryGroup, 0, len(groups))
+	for key, g := range groups {
+		g.Name = name(key)
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Name < b.Name
+	})
+	if len(list) <= top {
+		return list, 0
+	}
+	return list[:top], len(list) - top
+}
+
+// ownerName returns the name of the user with the given uid, or the uid itself
+// if it has no name.
+func ownerName(uid int) string {
+	id := strconv.Itoa(uid)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
@@ -0,0 +1,84 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestScanner_Summary tests the breakdown of the files to delete by subtree,
+// extension, owner and age.
+func TestScanner_Summary(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+	files := []struct {
+		path string
+		size int
+		age  time.Duration
+	}{
+		{"build/obj/a.o", 300, time.Hour},
+		{"build/obj/b.O", 200, 2 * time.Hour},
+		{"build/out.bin", 100, 3 * 24 * time.Hour},
+		{"logs/app.log", 50, 40 * 24 * time.Hour},
+		{"logs/.hidden", 10, 400 * 24 * time.Hour},
+		{"README", 5, 400 * 24 * time.Hour},
+	}
+	for _, f := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+	}
+
+	s := NewScanner(tmpDir, nil)
+	s.SetReferenceTime(now)
+	s.SetSummary(2)
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	summary := result.Summary
+	if summary == nil {
+		t.Fatal("Expected a summary")
+	}
+
+	wantSubtrees := []SummaryGroup{{"build", 3, 600}, {"logs", 2, 60}}
+	if !slices.Equal(summary.Subtrees, wantSubtrees) || summary.SubtreesOmitted != 1 {
+		t.Errorf("Subtrees = %v (+%d), want %v (+1)", summary.Subtrees, summary.SubtreesOmitted, wantSubtrees)
+	}
+	wantExtensions := []SummaryGroup{{".o", 2, 500}, {".bin", 1, 100}}
+	if !slices.Equal(summary.Extensions, wantExtensions) || summary.ExtensionsOmitted != 2 {
+		t.Errorf("Extensions = %v (+%d), want %v (+2)", summary.Extensions, summary.ExtensionsOmitted, wantExtensions)
+	}
+	if len(summary.Owners) != 1 || summary.Owners[0].Files != len(files) {
+		t.Errorf("Expected every file under one owner, got %v", summary.Owners)
+	}
+
+	wantAges := []int{2, 1, 0, 1, 0, 2}
+	if len(summary.Ages) != len(wantAges) {
+		t.Fatalf("Expected %d age buckets, got %v", len(wantAges), summary.Ages)
+	}
+	for i, want := range wantAges {
+		if summary.Ages[i].Files != want {
+			t.Errorf("Age bucket %q has %d files, want %d", summary.Ages[i].Label, summary.Ages[i].Files, want)
+		}
+	}
+
+	// Without SetSummary there is none
+	result, err = NewScanner(tmpDir, nil).Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Summary != nil {
+		t.Errorf("Expected no summary by default")
+	}
+}
@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeUnits maps size suffixes to byte multipliers. Units are binary, as in
+// du and find: 1K = 1024 bytes.
+var sizeUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
+
+// sizePattern splits a size into number and unit.
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+// ParseSize parses a size such as "512", "100M", "1.5G" or "10KiB" into bytes.
+func ParseSize(s string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512, 100K, 1.5G)", s)
+	}
+	multiplier, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q in %q (expected B, K, M, G or T)", m[2], s)
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	bytes := value * float64(multiplier)
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(bytes), nil
+}
+
+// ageUnits maps age suffixes to durations. Days and weeks are fixed 24-hour
+// and 7-day periods.
+var ageUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+// agePart matches one number-unit pair of an age.
+var agePart = regexp.MustCompile(`^(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h|d|w)`)
+
+// ParseAge parses an age such as "36h", "7d", "2w" or "1d12h". It accepts the
+// units of time.ParseDuration plus "d" (days) and "w" (weeks). A unit is required.
+func ParseAge(s string) (time.Duration, error) {
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return 0, fmt.Errorf("invalid age %q (expected e.g. 36h, 7d, 2w)", s)
+	}
+
+	var total float64
+	for rest != "" {
+		m := agePart.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, fmt.Errorf("invalid age %q (expected e.g. 36h, 7d, 2w; units: s, m, h, d, w)", s)
+		}
+		value, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q: %w", s, err)
+		}
+		total += value * float64(ageUnits[m[2]])
+		rest = rest[len(m[0]):]
+	}
+
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("age %q is too large", s)
+	}
+	return time.Duration(total), nil
+}
+
+// timeLayouts are the layouts accepted by ParseTime, tried in order.
+// Layouts without a zone are interpreted in local time.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses an instant given as RFC 3339 ("2024-01-31T18:00:00Z"),
+// local date and time ("2024-01-31T18:00:00", "2024-01-31 18:00:00") or a local
+// date ("2024-01-31", meaning midnight).
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected 2006-01-02, 2006-01-02T15:04:05 or RFC 3339)", s)
+}
@@ -0,0 +1,101 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseSize tests size parsing with binary units.
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"1K", 1024},
+		{"10KiB", 10 * 1024},
+		{"100M", 100 << 20},
+		{"1.5G", 3 << 29},
+		{"2tb", 2 << 40},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseSize(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "M", "-1K", "10X", "1.2.3K"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("Expected ParseSize(%q) to fail", bad)
+		}
+	}
+}
+
+// TestParseAge tests age parsing with day and week units.
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"36h", 36 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"1.5d", 36 * time.Hour},
+		{"500ms", 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got, err := ParseAge(tt.in)
+		if err != nil {
+			t.Errorf("ParseAge(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "7", "d", "-1d", "7 days", "3y"} {
+		if _, err := ParseAge(bad); err == nil {
+			t.Errorf("Expected ParseAge(%q) to fail", bad)
+		}
+	}
+}
+
+// TestParseTime tests the accepted reference time layouts.
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-01-31T18:00:00Z", time.Date(2024, 1, 31, 18, 0, 0, 0, time.UTC)},
+		{"2024-01-31T18:00:00+02:00", time.Date(2024, 1, 31, 16, 0, 0, 0, time.UTC)},
+		{"2024-01-31T18:00:00", time.Date(2024, 1, 31, 18, 0, 0, 0, time.Local)},
+		{"2024-01-31 18:00:00", time.Date(2024, 1, 31, 18, 0, 0, 0, time.Local)},
+		{"2024-01-31", time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in)
+		if err != nil {
+			t.Errorf("ParseTime(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "yesterday", "31/01/2024", "2024-13-01"} {
+		if _, err := ParseTime(bad); err == nil {
+			t.Errorf("Expected ParseTime(%q) to fail", bad)
+		}
+	}
+}
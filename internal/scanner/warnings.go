@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+
+	"github.com/yourusername/fast-file-deletion/internal/logger"
+)
+
+// WarningKind classifies the error behind a ScanWarning.
+type WarningKind int
+
+const (
+	// WarningOther is an error that is not a file system error, such as an
+	// invalid policy file.
+	WarningOther WarningKind = iota
+	// WarningPermission means access was denied.
+	WarningPermission
+	// WarningNotFound means the entry disappeared during the scan.
+	WarningNotFound
+	// WarningIO is any other error reported by the file system.
+	WarningIO
+)
+
+// String returns a short description of the kind, e.g. "permission denied".
+func (k WarningKind) String() string {
+	switch k {
+	case WarningPermission:
+		return "permission denied"
+	case WarningNotFound:
+		return "not found"
+	case WarningIO:
+		return "I/O error"
+	default:
+		return "other"
+	}
+}
+
+// ClassifyError returns the WarningKind of err.
+func ClassifyError(err error) WarningKind {
+	var errno syscall.Errno
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return WarningPermission
+	case errors.Is(err, fs.ErrNotExist):
+		return WarningNotFound
+	case errors.As(err, &errno), errors.As(err, &pathErr):
+		return WarningIO
+	default:
+		return WarningOther
+	}
+}
+
+// ScanWarning is a problem met during a scan that did not stop it. The entry
+// concerned is retained, so a scan with warnings deletes less, never more.
+type ScanWarning struct {
+	Path    string      // Entry the problem concerns
+	Op      string      // What the scanner was doing, e.g. "read directory"
+	Kind    WarningKind // Classification of Err
+	Err     error       // The underlying error
+	Skipped bool        // Path is a directory whose contents were not (fully) scanned
+}
+
+// String formats the warning as "path: cannot op: error".
+func (w ScanWarning) String() string {
+	return fmt.Sprintf("%s: cannot %s: %v", w.Path, w.Op, w.Err)
+}
+
+// warn records a warning in the result and logs it. skipped marks path as a
+// directory whose subtree was left unscanned.
+func (r *ScanResult) warn(path, op string, err error, skipped bool) {
+	r.Warnings = append(r.Warnings, ScanWarning{
+		Path:    path,
+		Op:      op,
+		Kind:    ClassifyError(err),
+		Err:     err,
+		Skipped: skipped,
+	})
+	if skipped {
+		r.SkippedSubtrees++
+	}
+	logger.LogFileWarning(path, fmt.Sprintf("Cannot %s: %v", op, err))
+}
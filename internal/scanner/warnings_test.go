@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestClassifyError tests the classification of scan errors.
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want WarningKind
+	}{
+		{&fs.PathError{Op: "open", Path: "/x", Err: fs.ErrPermission}, WarningPermission},
+		{fmt.Errorf("wrapped: %w", fs.ErrNotExist), WarningNotFound},
+		{&fs.PathError{Op: "readdirent", Path: "/x", Err: syscall.EIO}, WarningIO},
+		{errors.New("invalid policy file"), WarningOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestScanner_Warnings tests that directories the scan cannot read or apply are
+// reported and retained along with the directories above them.
+func TestScanner_Warnings(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a/broken", "a/locked/deep", "b"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	files := map[string]string{
+		"a/f":              "data",
+		"a/broken/.policy": "no-such-setting = 1\n",
+		"a/broken/g":       "data",
+		"a/locked/deep/h":  "data",
+		"b/i":              "data",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	// Root can read any directory, so only the policy file fails then
+	locked := filepath.Join(tmpDir, "a", "locked")
+	unreadable := os.Geteuid() != 0
+	if unreadable {
+		if err := os.Chmod(locked, 0); err != nil {
+			t.Fatalf("Failed to lock directory: %v", err)
+		}
+		defer os.Chmod(locked, 0755)
+	}
+
+	s := NewScanner(tmpDir, nil)
+	s.SetPolicyFile(".policy")
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	want := []ScanWarning{{Path: filepath.Join(tmpDir, "a", "broken"), Op: "apply policy file", Kind: WarningOther, Skipped: true}}
+	if unreadable {
+		// Looking for the policy file is the first thing to fail
+		want = append(want, ScanWarning{Path: locked, Op: "apply policy file", Kind: WarningPermission, Skipped: true})
+	}
+	if len(result.Warnings) != len(want) || result.SkippedSubtrees != len(want) {
+		t.Fatalf("Expected %d warnings and skipped subtrees, got %v (%d skipped)", len(want), result.Warnings, result.SkippedSubtrees)
+	}
+	for i, w := range want {
+		got := result.Warnings[i]
+		if got.Path != w.Path || got.Op != w.Op || got.Kind != w.Kind || got.Skipped != w.Skipped || got.Err == nil {
+			t.Errorf("Warning %d: got %+v, want %+v", i, got, w)
+		}
+	}
+
+	for _, path := range result.Files {
+		if path == tmpDir || path == filepath.Join(tmpDir, "a") || (unreadable && path == locked) {
+			t.Errorf("Directory above an unscanned subtree marked for deletion: %s", path)
+		}
+	}
+	wantDeleted := "a/f a/locked/deep/h b/i"
+	if unreadable {
+		wantDeleted = "a/f b/i"
+	}
+	if got := deletedNames(t, tmpDir, result); got != wantDeleted {
+		t.Errorf("Deleted %q, want %q", got, wantDeleted)
+	}
+
+	if !unreadable {
+		return
+	}
+	result, err = NewScanner(tmpDir, nil).Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Path != locked || result.Warnings[0].Op != "read directory" ||
+		result.Warnings[0].Kind != WarningPermission || result.SkippedSubtrees != 1 {
+		t.Errorf("Expected a read directory warning for %s, got %v", locked, result.Warnings)
+	}
+	if got, want := deletedNames(t, tmpDir, result), "a/broken/.policy a/broken/g a/f b/i"; got != want {
+		t.Errorf("Without policy files: deleted %q, want %q", got, want)
+	}
+	for _, path := range result.Files {
+		if path == locked || path == filepath.Join(tmpDir, "a") {
+			t.Errorf("Directory above an unreadable subtree marked for deletion: %s", path)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseWhere compiles a filter expression such as
+//
+//	size > 100M and mtime older than 7d and not name ~ "*.keep"
+//
+// Predicates:
+//   - size OP SIZE                      (SIZE: 512, 100K, 1.5G; binary units)
+//   - mtime|atime|ctime|btime older than AGE, newer than AGE  (AGE: 36h, 7d, 2w)
+//   - mtime|atime|ctime|btime OP DATE   (DATE: see ParseTime)
+//   - name|path = TEXT, != TEXT         (exact match)
+//   - name|path ~ GLOB                  (glob; "**" crosses directories in path)
+//   - name|path =~ REGEX                (regular expression, unanchored)
+//   - uid|gid|depth OP NUMBER
+//   - type = f|d|l|p|s|c|b              (or file, dir, symlink, fifo, socket, char, block)
+//
+// OP is one of =, !=, <, <=, >, >=. Predicates combine with and/&&, or/||,
+// not/! and parentheses; "not" binds tightest, then "and", then "or". Values
+// containing spaces, parentheses or any of = ! < > ~ & | must be quoted with ' or ".
+//
+// Ages are measured from reference. Paths are relative to the scan root and
+// use forward slashes on every platform.
+func ParseWhere(expr string, reference time.Time) (Filter, error) {
+	tokens, err := tokenizeWhere(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{tokens: tokens, reference: reference}
+	if p.atEnd() {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("unexpected %q in filter expression", p.peek().text)
+	}
+	return f, nil
+}
+
+// whereToken is a lexical token of a filter expression.
+type whereToken struct {
+	text   string
+	quoted bool // Quoted values are never keywords or operators
+	op     bool // Operator or parenthesis
+}
+
+// whereOperators lists the operators of the expression language, longest first.
+var whereOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "=", "<", ">", "~", "!"}
+
+// tokenizeWhere splits an expression into words, quoted strings, operators and parentheses.
+func tokenizeWhere(expr string) ([]whereToken, error) {
+	var tokens []whereToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, whereToken{text: string(r), op: true})
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			var sb strings.Builder
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' && j+1 < len(runes) && runes[j+1] == r {
+					j++
+				}
+				sb.WriteRune(runes[j])
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated quote in filter expression")
+			}
+			tokens = append(tokens, whereToken{text: sb.String(), quoted: true})
+			i = j + 1
+		case strings.ContainsRune("=!<>~&|", r):
+			op := ""
+			for _, candidate := range whereOperators {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unknown operator %q in filter expression", string(r))
+			}
+			tokens = append(tokens, whereToken{text: op, op: true})
+			i += len([]rune(op))
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()=!<>~&|", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, whereToken{text: string(runes[i:j])})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// whereParser is a recursive-descent parser over the token list.
+type whereParser struct {
+	tokens    []whereToken
+	pos       int
+	reference time.Time
+}
+
+func (p *whereParser) atEnd() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *whereParser) peek() whereToken {
+	if p.atEnd() {
+		return whereToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+// next returns the next token, or an error naming what was expected.
+func (p *whereParser) next(expected string) (whereToken, error) {
+	if p.atEnd() {
+		return whereToken{}, fmt.Errorf("expected %s at end of filter expression", expected)
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+// acceptKeyword consumes the next token if it is one of the given keywords or operators.
+func (p *whereParser) acceptKeyword(words ...string) bool {
+	t := p.peek()
+	if p.atEnd() || t.quoted {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *whereParser) parseOr() (Filter, error) {
+	f, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []Filter{f}
+	for p.acceptKeyword("or", "||") {
+		f, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return Or(filters...), nil
+}
+
+func (p *whereParser) parseAnd() (Filter, error) {
+	f, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	filters := []Filter{f}
+	for p.acceptKeyword("and", "&&") {
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return And(filters...), nil
+}
+
+func (p *whereParser) parseUnary() (Filter, error) {
+	if p.acceptKeyword("not", "!") {
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(f), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *whereParser) parsePrimary() (Filter, error) {
+	if p.acceptKeyword("(") {
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword(")") {
+			return nil, fmt.Errorf("missing closing parenthesis in filter expression")
+		}
+		return f, nil
+	}
+
+	field, err := p.next("a field name")
+	if err != nil {
+		return nil, err
+	}
+	if field.op || field.quoted {
+		return nil, fmt.Errorf("expected a field name, got %q", field.text)
+	}
+
+	name := strings.ToLower(field.text)
+	switch name {
+	case "true", "false":
+		result := name == "true"
+		return FilterFunc(func(*Entry) (bool, error) { return result, nil }), nil
+	case "size":
+		return p.parseSize()
+	case "mtime", "atime", "ctime", "btime":
+		timeField, _ := ParseTimeField(name)
+		return p.parseTime(timeField)
+	case "name", "path":
+		return p.parseText(name)
+	case "uid", "gid", "depth":
+		return p.parseNumber(name)
+	case "type":
+		return p.parseType()
+	default:
+		return nil, fmt.Errorf("unknown field %q (expected size, mtime, atime, ctime, btime, name, path, uid, gid, type or depth)", field.text)
+	}
+}
+
+// parseCompareOp reads a numeric comparison operator.
+func (p *whereParser) parseCompareOp(field string) (CompareOp, error) {
+	t, err := p.next("a comparison operator after " + field)
+	if err != nil {
+		return 0, err
+	}
+	if t.op {
+		switch t.text {
+		case "=", "==":
+			return OpEqual, nil
+		case "!=":
+			return OpNotEqual, nil
+		case "<":
+			return OpLess, nil
+		case "<=":
+			return OpLessEqual, nil
+		case ">":
+			return OpGreater, nil
+		case ">=":
+			return OpGreaterEqual, nil
+		}
+	}
+	return 0, fmt.Errorf("expected =, !=, <, <=, > or >= after %s, got %q", field, t.text)
+}
+
+// value reads the value operand of a predicate.
+func (p *whereParser) value(field string) (string, error) {
+	t, err := p.next("a value for " + field)
+	if err != nil {
+		return "", err
+	}
+	if t.op {
+		return "", fmt.Errorf("expected a value for %s, got %q", field, t.text)
+	}
+	return t.text, nil
+}
+
+func (p *whereParser) parseSize() (Filter, error) {
+	op, err := p.parseCompareOp("size")
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.value("size")
+	if err != nil {
+		return nil, err
+	}
+	size, err := ParseSize(v)
+	if err != nil {
+		return nil, err
+	}
+	return SizeFilter(op, size), nil
+}
+
+func (p *whereParser) parseTime(field TimeField) (Filter, error) {
+	if p.acceptKeyword("older", "newer") {
+		older := strings.EqualFold(p.tokens[p.pos-1].text, "older")
+		p.acceptKeyword("than")
+		v, err := p.value(field.String())
+		if err != nil {
+			return nil, err
+		}
+		age, err := ParseAge(v)
+		if err != nil {
+			return nil, err
+		}
+		if older {
+			return OlderThan(field, age, p.reference), nil
+		}
+		return NewerThan(field, age, p.reference), nil
+	}
+
+	op, err := p.parseCompareOp(field.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w (or use \"%s older than AGE\")", err, field)
+	}
+	v, err := p.value(field.String())
+	if err != nil {
+		return nil, err
+	}
+	t, err := ParseTime(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w (for ages use \"older than\")", err)
+	}
+	return TimeFilter(field, op, t), nil
+}
+
+func (p *whereParser) parseText(field string) (Filter, error) {
+	t, err := p.next("an operator after " + field)
+	if err != nil {
+		return nil, err
+	}
+	if !t.op {
+		return nil, fmt.Errorf("expected =, !=, ~ or =~ after %s, got %q", field, t.text)
+	}
+	v, err := p.value(field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.text {
+	case "=", "==", "!=":
+		equal := func(s string) bool {
+			if runtime.GOOS == "windows" {
+				return strings.EqualFold(s, v)
+			}
+			return s == v
+		}
+		want := t.text != "!="
+		return FilterFunc(func(e *Entry) (bool, error) {
+			if field == "name" {
+				return equal(e.Name()) == want, nil
+			}
+			return equal(e.RelPath) == want, nil
+		}), nil
+	case "~":
+		if field == "name" {
+			return NameGlob(v)
+		}
+		return PathGlob(v)
+	case "=~":
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", v, err)
+		}
+		if field == "name" {
+			return NameRegexp(re), nil
+		}
+		return PathRegexp(re), nil
+	default:
+		return nil, fmt.Errorf("expected =, !=, ~ or =~ after %s, got %q", field, t.text)
+	}
+}
+
+func (p *whereParser) parseNumber(field string) (Filter, error) {
+	op, err := p.parseCompareOp(field)
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.value(field)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q: expected a number", field, v)
+	}
+	switch field {
+	case "uid":
+		return UIDFilter(op, n), nil
+	case "gid":
+		return GIDFilter(op, n), nil
+	default:
+		return DepthFilter(op, n), nil
+	}
+}
+
+// whereTypes maps type names accepted by "type =" to file mode type bits.
+var whereTypes = map[string]fs.FileMode{
+	"f": 0, "file": 0,
+	"d": fs.ModeDir, "dir": fs.ModeDir, "directory": fs.ModeDir,
+	"l": fs.ModeSymlink, "symlink": fs.ModeSymlink, "link": fs.ModeSymlink,
+	"p": fs.ModeNamedPipe, "fifo": fs.ModeNamedPipe, "pipe": fs.ModeNamedPipe,
+	"s": fs.ModeSocket, "socket": fs.ModeSocket,
+	"c": fs.ModeDevice | fs.ModeCharDevice, "char": fs.ModeDevice | fs.ModeCharDevice,
+	"b": fs.ModeDevice, "block": fs.ModeDevice,
+}
+
+func (p *whereParser) parseType() (Filter, error) {
+	op, err := p.parseCompareOp("type")
+	if err != nil {
+		return nil, err
+	}
+	if op != OpEqual && op != OpNotEqual {
+		return nil, fmt.Errorf("type only supports = and !=")
+	}
+	v, err := p.value("type")
+	if err != nil {
+		return nil, err
+	}
+	mode, ok := whereTypes[strings.ToLower(v)]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q (expected f, d, l, p, s, c or b)", v)
+	}
+	if op == OpNotEqual {
+		return Not(TypeFilter(mode)), nil
+	}
+	return TypeFilter(mode), nil
+}
@@ -0,0 +1,101 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseWhere tests evaluation of filter expressions against a file entry.
+func TestParseWhere(t *testing.T) {
+	now := time.Now()
+	entry, counting := newTestEntry(t, "big.log", 2048, now.Add(-10*24*time.Hour))
+	entry.RelPath = "logs/app/big.log"
+	entry.Depth = 3
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"size > 1K", true},
+		{"size>1K", true},
+		{"size <= 1K", false},
+		{"mtime older than 7d", true},
+		{"mtime newer than 7d", false},
+		{"mtime older 1w", true},
+		{"mtime < " + now.Format("2006-01-02"), true},
+		{"name ~ *.log", true},
+		{"name ~ '*.keep'", false},
+		{"name = big.log", true},
+		{"name != big.log", false},
+		{"name =~ ^big", true},
+		{"path ~ logs/**/*.log", true},
+		{"path ~ 'logs/*.log'", false},
+		{"path =~ app/", true},
+		{"type = f", true},
+		{"type = dir", false},
+		{"type != d", true},
+		{"depth = 3", true},
+		{"depth < 2", false},
+		{"size > 1K and mtime older than 7d and not name ~ *.keep", true},
+		{"size > 1M or name ~ *.log", true},
+		{"size > 1M || name ~ *.txt", false},
+		{"!(size > 1M) && type = f", true},
+		{"not not true", true},
+		{"false or (true and not false)", true},
+		{"NAME ~ *.LOG OR Size > 1k", true},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseWhere(tt.expr, now)
+		if err != nil {
+			t.Errorf("ParseWhere(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		got, err := f.Match(entry)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.expr, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	if counting.infoCalls != 1 {
+		t.Errorf("Expected stat data to be loaded once for all expressions, got %d calls", counting.infoCalls)
+	}
+}
+
+// TestParseWhereErrors tests that malformed expressions are rejected with a useful message.
+func TestParseWhereErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		message string
+	}{
+		{"", "empty"},
+		{"colour = red", "unknown field"},
+		{"size > ", "expected a value"},
+		{"size ~ 1K", "expected ="},
+		{"size > big", "invalid size"},
+		{"mtime > 7d", "older than"},
+		{"mtime older than 7", "invalid age"},
+		{"(size > 1K", "missing closing parenthesis"},
+		{"size > 1K)", "unexpected"},
+		{"name ~ 'unterminated", "unterminated quote"},
+		{"name =~ '('", "invalid regular expression"},
+		{"type = x", "unknown type"},
+		{"type < f", "only supports"},
+		{"uid = root", "expected a number"},
+		{"size > 1K and", "expected a field name"},
+	}
+	for _, tt := range tests {
+		_, err := ParseWhere(tt.expr, time.Now())
+		if err == nil {
+			t.Errorf("Expected ParseWhere(%q) to fail", tt.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("ParseWhere(%q) error %q does not mention %q", tt.expr, err, tt.message)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package sweep
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Preset describes one kind of build artifact directory.
+type Preset struct {
+	Name        string   // Unique name, e.g. "node"
+	Description string   // What the directories hold, for listings
+	Dirs        []string // Names of the artifact directories
+	Markers     []string // Glob patterns, one of which must match an entry beside the directory
+}
+
+// presetName is the form of a preset name.
+var presetName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// builtin holds the built-in presets, most common first. When presets share a
+// directory name, the first whose marker is found claims the directory.
+var builtin = []Preset{
+	{Name: "node", Description: "npm, yarn and pnpm packages", Dirs: []string{"node_modules"}, Markers: []string{"package.json"}},
+	{Name: "next", Description: "Next.js build output", Dirs: []string{".next"}, Markers: []string{"next.config.*"}},
+	{Name: "nuxt", Description: "Nuxt build output", Dirs: []string{".nuxt", ".output"}, Markers: []string{"nuxt.config.*"}},
+	{Name: "rust", Description: "Cargo build output", Dirs: []string{"target"}, Markers: []string{"Cargo.toml"}},
+	{Name: "maven", Description: "Maven build output", Dirs: []string{"target"}, Markers: []string{"pom.xml"}},
+	{Name: "gradle", Description: "Gradle caches and build output", Dirs: []string{".gradle", "build"},
+		Markers: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}},
+	{Name: "python", Description: "Python bytecode", Dirs: []string{"__pycache__"}, Markers: []string{"*.py"}},
+	{Name: "venv", Description: "Python virtual environments", Dirs: []string{".venv", "venv"},
+		Markers: []string{"pyproject.toml", "requirements*.txt", "setup.py", "setup.cfg", "Pipfile"}},
+	{Name: "pytools", Description: "Python test and lint caches", Dirs: []string{".pytest_cache", ".mypy_cache", ".ruff_cache", ".tox"},
+		Markers: []string{"pyproject.toml", "setup.py", "setup.cfg", "tox.ini", "pytest.ini"}},
+	{Name: "dotnet", Description: ".NET build output", Dirs: []string{"bin", "obj"}, Markers: []string{"*.csproj", "*.fsproj", "*.vbproj"}},
+	{Name: "swift", Description: "Swift Package Manager build output", Dirs: []string{".build"}, Markers: []string{"Package.swift"}},
+	{Name: "elixir", Description: "Mix build output and dependencies", Dirs: []string{"_build", "deps"}, Markers: []string{"mix.exs"}},
+	{Name: "haskell", Description: "Stack and Cabal build output", Dirs: []string{".stack-work", "dist-newstyle"},
+		Markers: []string{"stack.yaml", "*.cabal", "cabal.project"}},
+	{Name: "dart", Description: "Dart and Flutter tool output", Dirs: []string{".dart_tool"}, Markers: []string{"pubspec.yaml"}},
+	{Name: "zig", Description: "Zig caches and build output", Dirs: []string{".zig-cache", "zig-cache", "zig-out"}, Markers: []string{"build.zig"}},
+	{Name: "terraform", Description: "Terraform providers and modules", Dirs: []string{".terraform"}, Markers: []string{"*.tf"}},
+}
+
+// Builtin returns the built-in presets.
+func Builtin() []Preset {
+	return slices.Clone(builtin)
+}
+
+// DefaultPresetFile returns the user's preset file, which is loaded when it
+// exists, in the user's configuration directory.
+func DefaultPresetFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "fast-file-deletion", "sweep-presets.conf")
+}
+
+// ParsePresets reads user-defined presets. Blank lines and lines starting with
+// "#" are ignored; a "[name]" line starts a preset and the lines after it are
+// "key = value", with space-separated lists:
+//
+//	[bazel]
+//	description = Bazel output trees
+//	dirs = bazel-bin bazel-out bazel-testlogs
+//	markers = WORKSPACE WORKSPACE.bazel MODULE.bazel
+//
+// Every preset needs dirs and markers. Returns an error naming the first line
+// that cannot be parsed.
+func ParsePresets(r io.Reader) ([]Preset, error) {
+	var presets []Preset
+	seen := make(map[string]bool)
+	var current *Preset
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		if len(current.Dirs) == 0 || len(current.Markers) == 0 {
+			return fmt.Errorf("preset %s: dirs and markers are required", current.Name)
+		}
+		presets = append(presets, *current)
+		return nil
+	}
+
+	sc := bufio.NewScanner(r)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(line[1:], "]")
+			name = strings.TrimSpace(name)
+			if !ok || !presetName.MatchString(name) {
+				return nil, fmt.Errorf("line %d: expected [name] with lowercase letters, digits, - and _, got %q", lineNo, line)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("line %d: preset %s is defined twice", lineNo, name)
+			}
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			seen[name] = true
+			current = &Preset{Name: name}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", lineNo, line)
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %q is outside a [name] section", lineNo, line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "description":
+			current.Description = value
+		case "dirs":
+			current.Dirs = strings.Fields(value)
+			err = checkNames(current.Dirs)
+		case "markers":
+			current.Markers = strings.Fields(value)
+			err = checkPatterns(current.Markers)
+		default:
+			err = fmt.Errorf("unknown key (expected description, dirs or markers)")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+// checkNames checks that directory names are plain names.
+func checkNames(names []string) error {
+	for _, name := range names {
+		if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("%q is not a directory name", name)
+		}
+	}
+	return nil
+}
+
+// checkPatterns checks that marker patterns are valid globs for a single name.
+func checkPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, `/\`) {
+			return fmt.Errorf("%q must match a name in the same directory", pattern)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// LoadPresets returns base, normally Builtin, merged with the presets of the
+// given files, in order. A preset replaces an earlier one of the same name.
+// Missing files are errors unless optional is set.
+func LoadPresets(base []Preset, files []string, optional bool) ([]Preset, error) {
+	presets := slices.Clone(base)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read presets: %w", err)
+		}
+		user, err := ParsePresets(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid presets %s: %w", file, err)
+		}
+		for _, p := range user {
+			if i := indexOf(presets, p.Name); i >= 0 {
+				presets[i] = p
+			} else {
+				presets = append(presets, p)
+			}
+		}
+	}
+	return presets, nil
+}
+
+// Choose returns the named presets, in the order given. Returns an error
+// listing the available presets if a name is unknown.
+func Choose(presets []Preset, names []string) ([]Preset, error) {
+	chosen := make([]Preset, 0, len(names))
+	for _, name := range names {
+		i := indexOf(presets, name)
+		if i < 0 {
+			available := make([]string, len(presets))
+			for j, p := range presets {
+				available[j] = p.Name
+			}
+			return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(available, ", "))
+		}
+		chosen = append(chosen, presets[i])
+	}
+	return chosen, nil
+}
+
+// indexOf returns the index of the preset named name, or -1.
+func indexOf(presets []Preset, name string) int {
+	for i, p := range presets {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
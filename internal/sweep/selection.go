@@ -0,0 +1,52 @@
+package sweep
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSelection parses a selection of the artifacts numbered 1 to n, as
+// typed at a prompt: "all" (or "a"), "none" or nothing, or numbers and ranges
+// separated by commas or spaces, such as "1,3 5-7". Returns the selected
+// indexes, 0-based, in ascending order and without duplicates.
+func ParseSelection(input string, n int) ([]int, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	switch input {
+	case "all", "a":
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	case "", "none", "n":
+		return nil, nil
+	}
+
+	selected := make([]bool, n)
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q: expected a number or a range like 2-5", field)
+		}
+		if first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("invalid selection %q: choose from 1 to %d", field, n)
+		}
+		for i := first; i <= last; i++ {
+			selected[i-1] = true
+		}
+	}
+
+	var indexes []int
+	for i, ok := range selected {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,203 @@
+// Package sweep finds build artifact directories, such as node_modules or a
+// Cargo target directory, below a root so that they can be deleted. A
+// directory only counts as an artifact when the project marker of its preset,
+// such as package.json or Cargo.toml, is beside it: a directory that merely
+// has the right name is left alone.
+package sweep
+
+import (
+	"cmp"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// skipDirs are directories that are never searched: version control
+// metadata holds no build output, and can be very large.
+var skipDirs = map[string]bool{".git": true, ".hg": true, ".svn": true}
+
+// Artifact is a build artifact directory found by Find.
+type Artifact struct {
+	Path    string // Absolute path of the directory
+	Rel     string // Path relative to the root, slash-separated
+	Preset  string // Name of the preset that matched
+	Marker  string // Name of the marker found beside it
+	Entries int    // Entries in the directory, itself included
+	Bytes   int64  // Total size of the files in it
+}
+
+// Project is a directory holding one or more artifacts.
+type Project struct {
+	Dir       string     // Absolute path of the project directory
+	Artifacts []Artifact // Largest first
+	Bytes     int64      // Total size of the artifacts
+}
+
+// Result is the outcome of Find.
+type Result struct {
+	Projects   []Project // Largest first
+	Unreadable int       // Directories that could not be read
+}
+
+// Artifacts returns all artifacts, project by project, in the order of the
+// projects. This is the order selections refer to.
+func (r *Result) Artifacts() []Artifact {
+	var all []Artifact
+	for _, p := range r.Projects {
+		all = append(all, p.Artifacts...)
+	}
+	return all
+}
+
+// Bytes returns the total size of all artifacts.
+func (r *Result) Bytes() int64 {
+	var total int64
+	for _, p := range r.Projects {
+		total += p.Bytes
+	}
+	return total
+}
+
+// Find searches root for the artifact directories of the presets. Artifacts
+// are not searched further, so node_modules inside node_modules is part of
+// the outer one. Unreadable directories are skipped and counted. Returns the
+// artifacts grouped by project, or ctx's error if it is cancelled.
+func Find(ctx context.Context, root string, presets []Preset) (*Result, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string][]Preset)
+	for _, p := range presets {
+		for _, dir := range p.Dirs {
+			byName[dir] = append(byName[dir], p)
+		}
+	}
+
+	result := &Result{}
+	projects := make(map[string]*Project)
+	siblings := make(map[string][]string)
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if p == root {
+				return err
+			}
+			if d.IsDir() {
+				result.Unreadable++
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() || p == root {
+			return nil
+		}
+		if skipDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		candidates := byName[d.Name()]
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		parent := filepath.Dir(p)
+		names, ok := siblings[parent]
+		if !ok {
+			names = readNames(parent)
+			siblings[parent] = names
+		}
+		preset, marker := matchMarker(candidates, names)
+		if preset == "" {
+			return nil
+		}
+
+		entries, bytes, err := sizeOf(ctx, p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		project, ok := projects[parent]
+		if !ok {
+			project = &Project{Dir: parent}
+			projects[parent] = project
+		}
+		project.Artifacts = append(project.Artifacts, Artifact{
+			Path: p, Rel: filepath.ToSlash(rel), Preset: preset, Marker: marker, Entries: entries, Bytes: bytes,
+		})
+		project.Bytes += bytes
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, project := range projects {
+		slices.SortFunc(project.Artifacts, func(a, b Artifact) int {
+			return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Rel, b.Rel))
+		})
+		result.Projects = append(result.Projects, *project)
+	}
+	slices.SortFunc(result.Projects, func(a, b Project) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Dir, b.Dir))
+	})
+	return result, nil
+}
+
+// readNames returns the names of the entries in dir, or none if it cannot be
+// read (the walk reports the error).
+func readNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+// matchMarker returns the first candidate preset with a marker among names,
+// and the name that matched. The preset is "" if none matches.
+func matchMarker(candidates []Preset, names []string) (preset, marker string) {
+	for _, p := range candidates {
+		for _, pattern := range p.Markers {
+			for _, name := range names {
+				if ok, _ := path.Match(pattern, name); ok {
+					return p.Name, name
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// sizeOf returns the number of entries in dir, itself included, and the total
+// size of its files. Unreadable parts are left out.
+func sizeOf(ctx context.Context, dir string) (entries int, bytes int64, err error) {
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if d != nil && d.IsDir() && p != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		entries++
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				bytes += info.Size()
+			}
+		}
+		return nil
+	})
+	return entries, bytes, err
+}
@@ -0,0 +1,204 @@
+package sweep
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files, relative to root and slash-separated, each
+// holding its own name.
+func writeFiles(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", file, err)
+		}
+	}
+}
+
+// TestFind tests that artifact directories are only found beside their
+// markers, are not searched further, and are grouped by project.
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root,
+		"web/package.json", "web/node_modules/left-pad/index.js", "web/node_modules/x/node_modules/y.js",
+		"web/.next/cache.bin",
+		"crate/Cargo.toml", "crate/target/debug/app",
+		"java/pom.xml", "java/target/classes/A.class",
+		"app/App.csproj", "app/bin/Debug/app.dll", "app/obj/project.assets.json",
+		"docs/bin/tool.sh",                    // no .csproj beside it
+		"scripts/target/readme.txt",           // no Cargo.toml or pom.xml
+		"repo/.git/node_modules/package.json", // never searched
+	)
+
+	result, err := Find(context.Background(), root, Builtin())
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	found := make(map[string]string)
+	for _, a := range result.Artifacts() {
+		found[a.Rel] = a.Preset
+	}
+	want := map[string]string{
+		"web/node_modules": "node", "crate/target": "rust", "java/target": "maven",
+		"app/bin": "dotnet", "app/obj": "dotnet",
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("Expected %v, got %v", want, found)
+	}
+
+	for _, p := range result.Projects {
+		var total int64
+		for _, a := range p.Artifacts {
+			if filepath.Dir(a.Path) != p.Dir {
+				t.Errorf("Artifact %s is not in project %s", a.Path, p.Dir)
+			}
+			total += a.Bytes
+		}
+		if total != p.Bytes {
+			t.Errorf("Project %s: expected %d bytes, got %d", p.Dir, total, p.Bytes)
+		}
+	}
+	for _, a := range result.Artifacts() {
+		if a.Rel == "web/node_modules" {
+			size := int64(len("web/node_modules/left-pad/index.js") + len("web/node_modules/x/node_modules/y.js"))
+			if a.Bytes != size || a.Entries != 6 {
+				t.Errorf("Expected node_modules to hold 6 entries and %d bytes, got %d and %d", size, a.Entries, a.Bytes)
+			}
+		}
+	}
+}
+
+// TestFind_ChosenPresets tests that only the chosen presets are searched for.
+func TestFind_ChosenPresets(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "web/package.json", "web/node_modules/a.js", "crate/Cargo.toml", "crate/target/app")
+
+	presets, err := Choose(Builtin(), []string{"rust"})
+	if err != nil {
+		t.Fatalf("Choose failed: %v", err)
+	}
+	result, err := Find(context.Background(), root, presets)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	artifacts := result.Artifacts()
+	if len(artifacts) != 1 || artifacts[0].Rel != "crate/target" {
+		t.Errorf("Expected only crate/target, got %v", artifacts)
+	}
+
+	if _, err := Choose(Builtin(), []string{"cobol"}); err == nil || !strings.Contains(err.Error(), "node") {
+		t.Errorf("Expected an error listing the presets, got %v", err)
+	}
+}
+
+// TestParsePresets tests the preset file syntax and its errors.
+func TestParsePresets(t *testing.T) {
+	presets, err := ParsePresets(strings.NewReader(`
+# Bazel output trees
+[bazel]
+description = Bazel output
+dirs = bazel-bin bazel-out   # symlinks in practice
+markers = WORKSPACE MODULE.bazel
+
+[node]
+dirs = node_modules
+markers = package.json deno.json
+`))
+	if err != nil {
+		t.Fatalf("ParsePresets failed: %v", err)
+	}
+	want := []Preset{
+		{Name: "bazel", Description: "Bazel output", Dirs: []string{"bazel-bin", "bazel-out"}, Markers: []string{"WORKSPACE", "MODULE.bazel"}},
+		{Name: "node", Dirs: []string{"node_modules"}, Markers: []string{"package.json", "deno.json"}},
+	}
+	if !reflect.DeepEqual(presets, want) {
+		t.Errorf("Expected %+v, got %+v", want, presets)
+	}
+
+	for _, bad := range []string{
+		"dirs = out",                          // outside a section
+		"[Big Name]\ndirs = out\nmarkers = x", // invalid name
+		"[a]\ndirs = out",                     // no markers
+		"[a]\ndirs = ../out\nmarkers = x",     // not a name
+		"[a]\ndirs = out\nmarkers = [x",       // bad pattern
+		"[a]\ndirs = out\nmarkers = x\ncolor = red",
+		"[a]\ndirs = out\nmarkers = x\n[a]\ndirs = out\nmarkers = x",
+	} {
+		if _, err := ParsePresets(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+// TestLoadPresets tests that user presets replace built-in ones of the same
+// name and add the others, and that missing files are only errors when
+// required.
+func TestLoadPresets(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "presets.conf")
+	if err := os.WriteFile(file, []byte("[node]\ndirs = node_modules\nmarkers = deno.json\n[bazel]\ndirs = bazel-out\nmarkers = WORKSPACE\n"), 0644); err != nil {
+		t.Fatalf("Failed to write presets: %v", err)
+	}
+	presets, err := LoadPresets(Builtin(), []string{file}, false)
+	if err != nil {
+		t.Fatalf("LoadPresets failed: %v", err)
+	}
+	if len(presets) != len(Builtin())+1 {
+		t.Errorf("Expected %d presets, got %d", len(Builtin())+1, len(presets))
+	}
+	if presets[0].Name != "node" || presets[0].Markers[0] != "deno.json" {
+		t.Errorf("Expected node to be replaced in place, got %+v", presets[0])
+	}
+	if presets[len(presets)-1].Name != "bazel" {
+		t.Errorf("Expected bazel to be added last, got %+v", presets[len(presets)-1])
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.conf")
+	if _, err := LoadPresets(Builtin(), []string{missing}, true); err != nil {
+		t.Errorf("Expected an optional missing file to be ignored, got %v", err)
+	}
+	if _, err := LoadPresets(Builtin(), []string{missing}, false); err == nil {
+		t.Error("Expected a required missing file to be an error")
+	}
+}
+
+// TestParseSelection tests selections typed at the prompt.
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []int
+	}{
+		{"all", []int{0, 1, 2, 3, 4}},
+		{" A ", []int{0, 1, 2, 3, 4}},
+		{"", nil},
+		{"none", nil},
+		{"2", []int{1}},
+		{"1,3 5", []int{0, 2, 4}},
+		{"4-5, 1-2,2", []int{0, 1, 3, 4}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSelection(tt.input, 5)
+		if err != nil {
+			t.Errorf("ParseSelection(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSelection(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"0", "6", "3-1", "x", "1-", "2-9"} {
+		if _, err := ParseSelection(bad, 5); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
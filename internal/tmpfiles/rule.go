@@ -0,0 +1,104 @@
+package tmpfiles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// Expand returns the existing directories the rule applies to. The path of an
+// "e" line is a glob; other line types name a single directory. A rule whose
+// directory does not exist expands to nothing, as systemd-tmpfiles skips it.
+func (r Rule) Expand() ([]string, error) {
+	paths := []string{r.Path}
+	if strings.HasPrefix(r.Type, "e") {
+		matches, err := filepath.Glob(r.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid glob %q: %w", r.Source, r.Path, err)
+		}
+		paths = matches
+	}
+
+	var dirs []string
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs = append(dirs, path)
+		}
+	}
+	return dirs, nil
+}
+
+// String formats the rule like its tmpfiles.d line, e.g. "d /var/tmp/builds 10d".
+func (r Rule) String() string {
+	age := r.Age.String()
+	if r.Age > 0 && r.Age%(24*time.Hour) == 0 {
+		age = fmt.Sprintf("%dd", r.Age/(24*time.Hour))
+	}
+	if r.KeepTopLevel {
+		age = "~" + age
+	}
+	return fmt.Sprintf("%s %s %s", r.Type, r.Path, age)
+}
+
+// Filter returns a scanner.Filter that selects the entries the rule cleans up
+// beneath one of its directories, as of the reference time now:
+//   - Entries matching an exclusion (or beneath an "x" exclusion) are kept
+//   - With KeepTopLevel, entries directly inside the directory are kept
+//   - Otherwise an entry is cleaned up when every timestamp selected for its
+//     kind (FileTimes or DirTimes) is older than Age. Timestamps the platform
+//     cannot provide are ignored; an entry with none left is kept.
+func (r Rule) Filter(now time.Time, exclusions []Exclusion) scanner.Filter {
+	cutoff := now.Add(-r.Age)
+	return scanner.FilterFunc(func(e *scanner.Entry) (bool, error) {
+		if r.KeepTopLevel && e.Depth == 1 {
+			return false, nil
+		}
+		if excluded(e.Path, e.Depth, exclusions) {
+			return false, nil
+		}
+
+		fields := r.FileTimes
+		if e.IsDir() {
+			fields = r.DirTimes
+		}
+		compared := 0
+		for _, field := range fields {
+			t, err := e.Time(field)
+			if err != nil {
+				continue // Not available on this platform or file system
+			}
+			if !t.Before(cutoff) {
+				return false, nil
+			}
+			compared++
+		}
+		return compared > 0, nil
+	})
+}
+
+// excluded reports whether an exclusion matches path or, for "x" exclusions, one
+// of the ancestors of path beneath the directory being cleaned.
+func excluded(path string, depth int, exclusions []Exclusion) bool {
+	for _, ex := range exclusions {
+		if ok, _ := filepath.Match(ex.Pattern, path); ok {
+			return true
+		}
+	}
+	dir := path
+	for i := 1; i < depth; i++ {
+		dir = filepath.Dir(dir)
+		for _, ex := range exclusions {
+			if !ex.Contents {
+				continue
+			}
+			if ok, _ := filepath.Match(ex.Pattern, dir); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
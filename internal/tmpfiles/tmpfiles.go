@@ -0,0 +1,350 @@
+// Package tmpfiles reads the age-based cleanup parts of systemd tmpfiles.d(5)
+// configuration so that fast-file-deletion can run them in place of
+// "systemd-tmpfiles --clean".
+//
+// Lines of type d, D, e, v, q, Q and C with an age field become cleanup Rules;
+// lines of type x and X become Exclusions that apply to every rule. All other
+// line types (file creation, r/R removal, ownership and attribute changes) are
+// ignored, as are boot-only ("!") lines, which "--clean" does not run either.
+//
+// Differences from systemd-tmpfiles: entries are not skipped because they are
+// on another file system or locked, and only the %%, %t, %T, %V, %S, %C and
+// %L specifiers (system mode) are expanded.
+package tmpfiles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// Rule is a tmpfiles.d line that asks for the contents of a directory to be
+// cleaned up by age.
+type Rule struct {
+	Source string // File and line the rule came from, e.g. "/etc/tmpfiles.d/x.conf:3"
+	Type   string // Line type including modifiers, e.g. "d" or "e-"
+	Path   string // Directory to clean; for "e" lines a glob, see Expand
+
+	Age          time.Duration       // Entries are old when every selected timestamp is older than this
+	FileTimes    []scanner.TimeField // Timestamps compared for everything but directories
+	DirTimes     []scanner.TimeField // Timestamps compared for directories
+	KeepTopLevel bool                // Age prefixed with "~": entries directly inside Path are kept
+}
+
+// Exclusion is an x or X line: entries matching Pattern are never cleaned up.
+type Exclusion struct {
+	Source   string // File and line the exclusion came from
+	Pattern  string // Shell glob matched against absolute paths
+	Contents bool   // "x": also everything beneath a matching directory; "X": only the path itself
+}
+
+// Config is the cleanup configuration read from one or more tmpfiles.d files.
+type Config struct {
+	Rules      []Rule
+	Exclusions []Exclusion
+	Warnings   []string // Lines that were skipped, with the reason
+}
+
+// cleanupTypes are the line types whose age field requests cleanup.
+const cleanupTypes = "dDevqQC"
+
+// defaultTimes is the time-type specifier used when an age has none: all
+// timestamps of files, and all but the ctime of directories, which cleaning
+// a directory changes.
+const defaultTimes = "abcmABM"
+
+// ReadFiles reads the tmpfiles.d files matched by the given globs. As with
+// tmpfiles.d directories, a file whose base name was already seen is skipped,
+// so earlier globs take precedence (list /etc/tmpfiles.d before
+// /usr/lib/tmpfiles.d), and files are processed in order of their base names.
+// It is an error for a glob to match nothing.
+func ReadFiles(globs []string) (*Config, error) {
+	byName := make(map[string]string)
+	var names []string
+	for _, glob := range globs {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tmpfiles.d path %q: %w", glob, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no tmpfiles.d files match %q", glob)
+		}
+		for _, path := range matches {
+			name := filepath.Base(path)
+			if _, seen := byName[name]; seen {
+				continue
+			}
+			byName[name] = path
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	config := &Config{}
+	for _, name := range names {
+		file, err := os.Open(byName[name])
+		if err != nil {
+			return nil, fmt.Errorf("cannot read tmpfiles.d file: %w", err)
+		}
+		err = config.parse(file, byName[name])
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// Parse reads one tmpfiles.d file; name identifies it in Sources and warnings.
+// Lines that cannot be used are skipped and reported in Warnings.
+func Parse(r io.Reader, name string) (*Config, error) {
+	config := &Config{}
+	if err := config.parse(r, name); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// parse appends the rules and exclusions of one file to c.
+func (c *Config) parse(r io.Reader, name string) error {
+	seen := make(map[string]string)
+	for _, rule := range c.Rules {
+		seen[rule.Path] = rule.Source
+	}
+
+	sc := bufio.NewScanner(r)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		source := fmt.Sprintf("%s:%d", name, lineNo)
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields, err := splitFields(line)
+		if err != nil {
+			c.warn(source, err.Error())
+			continue
+		}
+		if len(fields) < 2 {
+			c.warn(source, "missing path")
+			continue
+		}
+		lineType, modifiers := fields[0][:1], fields[0][1:]
+		if strings.Contains(modifiers, "!") {
+			continue // Boot-only lines are not part of a cleanup run
+		}
+
+		path, err := expandSpecifiers(fields[1])
+		if err != nil {
+			c.warn(source, err.Error())
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			c.warn(source, fmt.Sprintf("path %q is not absolute", path))
+			continue
+		}
+		path = filepath.Clean(path)
+
+		switch {
+		case lineType == "x" || lineType == "X":
+			c.Exclusions = append(c.Exclusions, Exclusion{Source: source, Pattern: path, Contents: lineType == "x"})
+
+		case strings.Contains(cleanupTypes, lineType):
+			if len(fields) < 6 || fields[5] == "-" || fields[5] == "" {
+				continue // No age: the line only creates or adjusts the directory
+			}
+			rule, err := parseAge(fields[5])
+			if err != nil {
+				c.warn(source, err.Error())
+				continue
+			}
+			if first, dup := seen[path]; dup {
+				c.warn(source, fmt.Sprintf("duplicate line for %s (first at %s), ignoring", path, first))
+				continue
+			}
+			seen[path] = source
+			rule.Source, rule.Type, rule.Path = source, fields[0], path
+			c.Rules = append(c.Rules, rule)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("cannot read tmpfiles.d file %s: %w", name, err)
+	}
+	return nil
+}
+
+// warn records a skipped line.
+func (c *Config) warn(source, reason string) {
+	c.Warnings = append(c.Warnings, fmt.Sprintf("%s: %s", source, reason))
+}
+
+// splitFields splits a line on whitespace, honouring single and double quotes
+// and backslash escapes.
+func splitFields(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped, inField = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				field.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inField = r, true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// specifiers are the system-mode values of the supported % specifiers.
+var specifiers = map[byte]string{
+	'%': "%",
+	't': "/run",
+	'T': "/tmp",
+	'V': "/var/tmp",
+	'S': "/var/lib",
+	'C': "/var/cache",
+	'L': "/var/log",
+}
+
+// expandSpecifiers replaces % specifiers in a path.
+func expandSpecifiers(path string) (string, error) {
+	if !strings.Contains(path, "%") {
+		return path, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '%' {
+			b.WriteByte(path[i])
+			continue
+		}
+		if i+1 == len(path) {
+			return "", fmt.Errorf("path %q ends with %%", path)
+		}
+		value, ok := specifiers[path[i+1]]
+		if !ok {
+			return "", fmt.Errorf("unsupported specifier %%%c in %q", path[i+1], path)
+		}
+		b.WriteString(value)
+		i++
+	}
+	return b.String(), nil
+}
+
+// parseAge parses an age field: an optional time-type specifier followed by a
+// colon ("cmA:"), an optional "~", and a time span ("10d", "1h30min").
+func parseAge(field string) (Rule, error) {
+	var rule Rule
+	times, span, ok := strings.Cut(field, ":")
+	if !ok {
+		times, span = defaultTimes, field
+	}
+	for _, c := range times {
+		var f scanner.TimeField
+		switch c {
+		case 'a', 'A':
+			f = scanner.TimeATime
+		case 'b', 'B':
+			f = scanner.TimeBTime
+		case 'c', 'C':
+			f = scanner.TimeCTime
+		case 'm', 'M':
+			f = scanner.TimeMTime
+		default:
+			return Rule{}, fmt.Errorf("invalid time type %q in age %q (expected a, b, c, m or A, B, C, M)", c, field)
+		}
+		if c >= 'a' {
+			rule.FileTimes = append(rule.FileTimes, f)
+		} else {
+			rule.DirTimes = append(rule.DirTimes, f)
+		}
+	}
+
+	if strings.HasPrefix(span, "~") {
+		rule.KeepTopLevel = true
+		span = span[1:]
+	}
+	age, err := parseTimespan(span)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid age %q: %w", field, err)
+	}
+	rule.Age = age
+	return rule, nil
+}
+
+// timespanUnits are the units systemd accepts in time spans.
+var timespanUnits = map[string]time.Duration{
+	"": time.Second, "s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+	"us": time.Microsecond, "usec": time.Microsecond, "µs": time.Microsecond,
+	"ms": time.Millisecond, "msec": time.Millisecond,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"M": 2629800 * time.Second, "month": 2629800 * time.Second, "months": 2629800 * time.Second,
+	"y": 31557600 * time.Second, "year": 31557600 * time.Second, "years": 31557600 * time.Second,
+}
+
+// timespanPart matches one number-unit pair of a time span.
+var timespanPart = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*([a-zA-Zµ]*)`)
+
+// parseTimespan parses a systemd time span such as "10d", "1h30min" or "90"
+// (seconds).
+func parseTimespan(s string) (time.Duration, error) {
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return 0, fmt.Errorf("empty time span")
+	}
+	var total time.Duration
+	for rest != "" {
+		m := timespanPart.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, fmt.Errorf("cannot parse %q", rest)
+		}
+		unit, ok := timespanUnits[m[2]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q", m[2])
+		}
+		value, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(value * float64(unit))
+		rest = strings.TrimSpace(rest[len(m[0]):])
+	}
+	return total, nil
+}
@@ -0,0 +1,194 @@
+package tmpfiles
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/fast-file-deletion/internal/scanner"
+)
+
+// TestParse tests which tmpfiles.d lines become rules, exclusions and warnings.
+func TestParse(t *testing.T) {
+	config, err := Parse(strings.NewReader(`
+# Cleanup rules
+d /var/tmp/builds 0755 root root 10d
+D  /run/cache  -  -  -  cmA:~1h30min
+e /srv/spool/*  -  -  -  2w
+v "/srv/with space" 0700 - - 1day
+d /var/tmp/nocleanup 0755 root root -
+d! /var/tmp/boot-only - - - 1d
+f /var/tmp/file.conf 0644 - - 1d
+x /var/tmp/builds/keep-*
+X %V/builds
+d /var/tmp/builds - - - 20d
+d relative/path - - - 1d
+d /var/tmp/bad-age - - - 10parsecs
+d /var/tmp/bad-spec%z - - - 1d
+`), "test.conf")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(config.Rules) != 4 {
+		t.Fatalf("Expected 4 rules, got %d: %+v", len(config.Rules), config.Rules)
+	}
+
+	d := config.Rules[0]
+	if d.Path != "/var/tmp/builds" || d.Age != 10*24*time.Hour || d.KeepTopLevel || d.Source != "test.conf:3" {
+		t.Errorf("Unexpected d rule: %+v", d)
+	}
+	if len(d.FileTimes) != 4 || len(d.DirTimes) != 3 {
+		t.Errorf("Expected default time types abcm/ABM, got %v and %v", d.FileTimes, d.DirTimes)
+	}
+	if got := d.String(); got != "d /var/tmp/builds 10d" {
+		t.Errorf("String() = %q", got)
+	}
+
+	D := config.Rules[1]
+	if D.Age != 90*time.Minute || !D.KeepTopLevel {
+		t.Errorf("Unexpected D rule: %+v", D)
+	}
+	if len(D.FileTimes) != 2 || D.FileTimes[0] != scanner.TimeCTime || D.FileTimes[1] != scanner.TimeMTime ||
+		len(D.DirTimes) != 1 || D.DirTimes[0] != scanner.TimeATime {
+		t.Errorf("Expected time types cm/A, got %v and %v", D.FileTimes, D.DirTimes)
+	}
+
+	if config.Rules[2].Type != "e" || config.Rules[2].Path != "/srv/spool/*" {
+		t.Errorf("Unexpected e rule: %+v", config.Rules[2])
+	}
+	if config.Rules[3].Path != "/srv/with space" || config.Rules[3].Age != 24*time.Hour {
+		t.Errorf("Unexpected quoted v rule: %+v", config.Rules[3])
+	}
+
+	if len(config.Exclusions) != 2 ||
+		config.Exclusions[0] != (Exclusion{Source: "test.conf:10", Pattern: "/var/tmp/builds/keep-*", Contents: true}) ||
+		config.Exclusions[1] != (Exclusion{Source: "test.conf:11", Pattern: "/var/tmp/builds", Contents: false}) {
+		t.Errorf("Unexpected exclusions: %+v", config.Exclusions)
+	}
+
+	// Duplicate path, relative path, bad age and unsupported specifier
+	if len(config.Warnings) != 4 {
+		t.Errorf("Expected 4 warnings, got %d: %v", len(config.Warnings), config.Warnings)
+	}
+}
+
+// TestReadFilesPrecedence tests that a file name seen in an earlier glob hides
+// the same name in later globs, and that files are read in name order.
+func TestReadFilesPrecedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	etc := filepath.Join(tmpDir, "etc")
+	lib := filepath.Join(tmpDir, "lib")
+	files := map[string]string{
+		filepath.Join(etc, "b.conf"): "d /b 0755 - - 1d\n",
+		filepath.Join(lib, "b.conf"): "d /b-vendor 0755 - - 1d\n",
+		filepath.Join(lib, "a.conf"): "d /a 0755 - - 1d\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	config, err := ReadFiles([]string{filepath.Join(etc, "*.conf"), filepath.Join(lib, "*.conf")})
+	if err != nil {
+		t.Fatalf("ReadFiles failed: %v", err)
+	}
+	var paths []string
+	for _, rule := range config.Rules {
+		paths = append(paths, rule.Path)
+	}
+	if strings.Join(paths, " ") != "/a /b" {
+		t.Errorf("Expected rules /a then /b, got %v", paths)
+	}
+
+	if _, err := ReadFiles([]string{filepath.Join(tmpDir, "none", "*.conf")}); err == nil {
+		t.Error("Expected an error for a glob matching no files")
+	}
+}
+
+// TestRuleFilter tests a rule's filter on a real tree: age, the "~" prefix and
+// x/X exclusions.
+func TestRuleFilter(t *testing.T) {
+	root := t.TempDir()
+	reference := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := reference.Add(-30 * 24 * time.Hour)
+	recent := reference.Add(-time.Hour)
+
+	entries := []struct {
+		path  string
+		isDir bool
+		mtime time.Time
+	}{
+		{"old.log", false, old},
+		{"new.log", false, recent},
+		{"job", true, old},
+		{"job/old.o", false, old},
+		{"keep-me", true, old},
+		{"keep-me/old.o", false, old},
+		{"shell", true, old},
+		{"shell/old.o", false, old},
+	}
+	for _, e := range entries {
+		path := filepath.Join(root, filepath.FromSlash(e.path))
+		var err error
+		if e.isDir {
+			err = os.Mkdir(path, 0755)
+		} else {
+			err = os.WriteFile(path, []byte("x"), 0644)
+		}
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", e.path, err)
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		path := filepath.Join(root, filepath.FromSlash(entries[i].path))
+		if err := os.Chtimes(path, entries[i].mtime, entries[i].mtime); err != nil {
+			t.Fatalf("Failed to set times: %v", err)
+		}
+	}
+
+	scan := func(rule Rule, exclusions []Exclusion) string {
+		t.Helper()
+		s := scanner.NewScanner(root, nil)
+		s.SetFilter(rule.Filter(reference, exclusions))
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var names []string
+		for _, path := range result.Files {
+			rel, _ := filepath.Rel(root, path)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		sort.Strings(names)
+		return strings.Join(names, " ")
+	}
+
+	// mtime only, so that reading the files during the test does not matter
+	rule := Rule{Path: root, Age: 10 * 24 * time.Hour,
+		FileTimes: []scanner.TimeField{scanner.TimeMTime}, DirTimes: []scanner.TimeField{scanner.TimeMTime}}
+
+	if got, want := scan(rule, nil), "job job/old.o keep-me keep-me/old.o old.log shell shell/old.o"; got != want {
+		t.Errorf("Plain rule cleaned %q, want %q", got, want)
+	}
+
+	exclusions := []Exclusion{
+		{Pattern: filepath.Join(root, "keep-*"), Contents: true},
+		{Pattern: filepath.Join(root, "shell"), Contents: false},
+	}
+	if got, want := scan(rule, exclusions), "job job/old.o old.log shell/old.o"; got != want {
+		t.Errorf("Rule with x/X exclusions cleaned %q, want %q", got, want)
+	}
+
+	rule.KeepTopLevel = true
+	if got, want := scan(rule, nil), "job/old.o keep-me/old.o shell/old.o"; got != want {
+		t.Errorf("Rule with ~ cleaned %q, want %q", got, want)
+	}
+}